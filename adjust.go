@@ -0,0 +1,122 @@
+package goKeyValueStore
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrKeyNotFound is returned by Expire and Persist if the key does not exist or is expired.
+var ErrKeyNotFound = errors.New("key does not exist")
+
+// Expire changes the TTL of an existing key to ttl milliseconds from now without changing its value. A TTL of 0
+// means that the key never expires, like in Set. The new deadline is persisted. Missing and expired keys return
+// ErrKeyNotFound.
+func (d *KeyValueStore) Expire(key string, ttl int) error {
+	d.lazyInit()
+	deadline := neverExpire
+	if ttl != 0 {
+		deadline = d.now().Add(time.Duration(ttl) * time.Millisecond).UnixMilli()
+	}
+	return d.setDeadline("expire", d.storageKey(key), deadline)
+}
+
+// Persist removes the expiration of an existing key without changing its value, so it is kept like a key set
+// with a TTL of 0, also after a restart. Missing and expired keys return ErrKeyNotFound.
+func (d *KeyValueStore) Persist(key string) error {
+	d.lazyInit()
+	return d.setDeadline("persist", d.storageKey(key), neverExpire)
+}
+
+// setDeadline changes the deleteTimestamp of an existing key and persists it.
+func (d *KeyValueStore) setDeadline(op string, key string, deadline int64) error {
+	defer d.afterWrite()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed.Load() {
+		return ErrClosed
+	}
+	node, ok := d.data[key]
+	if !ok || d.nodeIsExpired(node) {
+		return d.keyError(op, key, ErrKeyNotFound)
+	}
+	updated, err := node.withDeleteTimestamp(deadline)
+	if err != nil {
+		return err
+	}
+	updated.Revision = d.nextRevision()
+	d.stampWriter(updated)
+	d.putNode(updated)
+	return d.saveInCache(updated)
+}
+
+// AdjustTTL shifts the expiration of all live key-value pairs for which filter returns true by delta.
+// A nil filter matches all keys. Keys without expiration are skipped. A negative delta can make keys expire,
+// in which case they are removed like any other expired key. The new deadlines are persisted in batches and
+// the number of adjusted keys is returned.
+func (d *KeyValueStore) AdjustTTL(delta time.Duration, filter func(key string) bool) (int, error) {
+	d.lazyInit()
+	d.mu.RLock()
+	keys := []string{}
+	for key, node := range d.data {
+		if node.DeleteTimestamp == neverExpire || d.nodeIsExpired(node) {
+			continue
+		}
+		if filter == nil || filter(key) {
+			keys = append(keys, key)
+		}
+	}
+	d.mu.RUnlock()
+	defer d.afterWrite()
+	adjusted := 0
+	for start := 0; start < len(keys); start += sweepBatchSize {
+		end := min(start+sweepBatchSize, len(keys))
+		d.mu.Lock()
+		for _, key := range keys[start:end] {
+			node, ok := d.data[key]
+			if !ok || node.DeleteTimestamp == neverExpire || d.nodeIsExpired(node) {
+				continue // the key was changed since it was collected
+			}
+			updated, err := node.withDeleteTimestamp(shiftTimestamp(node.DeleteTimestamp, delta))
+			if err != nil {
+				d.mu.Unlock()
+				return adjusted, err
+			}
+			updated.Revision = d.nextRevision()
+			d.stampWriter(updated)
+			d.putNode(updated)
+			adjusted++
+			err = d.saveInCache(updated)
+			if err != nil {
+				d.mu.Unlock()
+				return adjusted, err
+			}
+		}
+		d.mu.Unlock()
+	}
+	return adjusted, nil
+}
+
+// withDeleteTimestamp returns a copy of the node with another deleteTimestamp.
+// Lazy values are loaded so the copy can be persisted.
+func (n *node) withDeleteTimestamp(deleteTimestamp int64) (*node, error) {
+	value, err := n.value()
+	if err != nil {
+		return nil, err
+	}
+	return &node{Key: n.Key, Value: value, DeleteTimestamp: deleteTimestamp, UpdatedAt: n.UpdatedAt,
+		CreatedAt: n.CreatedAt, Sequence: n.Sequence, Source: n.Source, Instance: n.Instance, Run: n.Run}, nil
+}
+
+// shiftTimestamp adds delta to a deleteTimestamp. The result is clamped so that it never overflows
+// and never turns into neverExpire.
+func shiftTimestamp(timestamp int64, delta time.Duration) int64 {
+	ms := delta.Milliseconds()
+	if ms > 0 && timestamp > neverExpire-1-ms {
+		return neverExpire - 1
+	}
+	if ms < 0 && timestamp < math.MinInt64-ms {
+		return math.MinInt64
+	}
+	return timestamp + ms
+}
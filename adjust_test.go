@@ -0,0 +1,135 @@
+package goKeyValueStore_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func getTestStoreWithClock(t *testing.T, dir string, clock *fakeClock) *goKeyValueStore.KeyValueStore {
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithClock(clock.Now), goKeyValueStore.WithCleanerStopped(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestAdjustTTLExtends(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, clock)
+	store.Set("session:1", "value1", 1000)
+	store.Set("session:2", "value2", 1000)
+	store.Set("user:1", "value3", 1000)
+	store.Set("config", "value4", 0)
+	adjusted, err := store.AdjustTTL(2*time.Hour, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	if adjusted != 3 {
+		t.Errorf("Expected 3 adjusted keys, got %d", adjusted)
+	}
+	clock.Advance(time.Hour)
+	if store.Length() != 4 {
+		t.Errorf("Expected length to be 4, got %d", store.Length())
+	}
+	restored := getTestStoreWithClock(t, dir, clock)
+	if restored.Length() != 4 {
+		t.Errorf("Expected extended deadlines to survive a restart, got length %d", restored.Length())
+	}
+	clock.Advance(2 * time.Hour)
+	if restored.Length() != 1 {
+		t.Errorf("Expected length to be 1, got %d", restored.Length())
+	}
+}
+
+func TestAdjustTTLShrinksPrefix(t *testing.T) {
+	clock := newFakeClock()
+	store := getTestStoreWithClock(t, t.TempDir(), clock)
+	store.Set("session:1", "value1", 60000)
+	store.Set("session:2", "value2", 10000)
+	store.Set("user:1", "value3", 60000)
+	adjusted, err := store.AdjustTTL(-30*time.Second, func(key string) bool {
+		return strings.HasPrefix(key, "session:")
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if adjusted != 2 {
+		t.Errorf("Expected 2 adjusted keys, got %d", adjusted)
+	}
+	if _, ok := store.Get("session:2"); ok {
+		t.Errorf("Expected session:2 to be expired")
+	}
+	clock.Advance(31 * time.Second)
+	if _, ok := store.Get("session:1"); ok {
+		t.Errorf("Expected session:1 to be expired")
+	}
+	if _, ok := store.Get("user:1"); !ok {
+		t.Errorf("Expected user:1 to be present")
+	}
+}
+
+func TestExpireExtends(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, clock)
+	store.Set("session", "value", 1000)
+	if err := store.Expire("session", 5000); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(2 * time.Second)
+	if value, ok := store.Get("session"); !ok || value != "value" {
+		t.Errorf("Expected session to be extended, got %v", value)
+	}
+	restarted := getTestStoreWithClock(t, dir, clock)
+	if ttl, ok := restarted.TTL("session"); !ok || ttl != 3*time.Second {
+		t.Errorf("Expected the new TTL to survive a restart, got %v, %v", ttl, ok)
+	}
+	restarted.Expire("session", 0)
+	clock.Advance(time.Hour)
+	if ttl, ok := restarted.TTL("session"); !ok || ttl != goKeyValueStore.NoExpiration {
+		t.Errorf("Expected a TTL of 0 to never expire, got %v, %v", ttl, ok)
+	}
+}
+
+func TestExpireShortens(t *testing.T) {
+	clock := newFakeClock()
+	store := getTestStoreWithClock(t, t.TempDir(), clock)
+	store.Set("session", "value", 60000)
+	if err := store.Expire("session", 1000); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(1001 * time.Millisecond)
+	if _, ok := store.Get("session"); ok {
+		t.Errorf("Expected session to expire sooner")
+	}
+	if err := store.Expire("session", 1000); !errors.Is(err, goKeyValueStore.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound for an expired key, got %v", err)
+	}
+	if err := store.Expire("missing", 1000); !errors.Is(err, goKeyValueStore.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound for a missing key, got %v", err)
+	}
+}
+
+func TestPersist(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, clock)
+	store.Set("session", "value", 1000)
+	if err := store.Persist("session"); err != nil {
+		t.Fatal(err)
+	}
+	restarted := getTestStoreWithClock(t, dir, clock)
+	clock.Advance(time.Hour)
+	restarted.CleanNow()
+	if value, ok := restarted.Get("session"); !ok || value != "value" {
+		t.Errorf("Expected session to never expire after a restart, got %v", value)
+	}
+	if err := restarted.Persist("missing"); !errors.Is(err, goKeyValueStore.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound for a missing key, got %v", err)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build go1.23
+
+package goKeyValueStore
+
+import "iter"
+
+// All returns an iterator over all non-expired key-value pairs for use with range:
+//
+//	for key, value := range store.All() {
+//		...
+//	}
+//
+// Like Range, it iterates a snapshot taken when the loop starts, so the loop body may call other methods of the
+// store and no lock is held between iterations.
+func (d *KeyValueStore) All() iter.Seq2[string, any] {
+	d.lazyInit()
+	return func(yield func(string, any) bool) {
+		d.Range(yield)
+	}
+}
@@ -0,0 +1,43 @@
+//go:build go1.23
+
+package goKeyValueStore_test
+
+import (
+	"testing"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestAll(t *testing.T) {
+	store := getTestStore()
+	visited := map[string]any{}
+	for key, value := range store.All() {
+		visited[key] = value
+		store.Delete(key)
+	}
+	if len(visited) != 3 || visited["key1"] != "value1" || store.Length() != 0 {
+		t.Errorf("Expected 3 keys to be visited and deleted, got %v and %d left", visited, store.Length())
+	}
+}
+
+func TestAllBreak(t *testing.T) {
+	store := getTestStore()
+	visited := 0
+	for key := range store.All() {
+		if key == "" {
+			continue
+		}
+		visited++
+		break
+	}
+	if visited != 1 {
+		t.Errorf("Expected the loop to stop after 1 key, got %d", visited)
+	}
+}
+
+func TestAllZeroValue(t *testing.T) {
+	var store goKeyValueStore.KeyValueStore
+	for range store.All() {
+		t.Error("Expected an empty store")
+	}
+}
@@ -0,0 +1,95 @@
+package goKeyValueStore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrTypeMismatch is returned by Append if the value of the key and the suffix are not both strings or both byte
+// slices.
+var ErrTypeMismatch = errors.New("value and suffix have different types")
+
+// Append appends suffix to the string or []byte stored at key and returns the new length in bytes. A missing or
+// expired key is set to suffix. suffix must be a string or a []byte of the same type as the stored value,
+// otherwise ErrTypeMismatch is returned and the value is left unchanged. Values read from the cache folder are
+// JSON, where byte slices are base64 strings, so after a restart a []byte value is a string and only strings can
+// be appended. The TTL in milliseconds is set like in Set. The whole operation holds the write lock, so concurrent
+// appends are never lost.
+func (d *KeyValueStore) Append(key string, suffix any, ttl int) (int, error) {
+	d.lazyInit()
+	key = d.storageKey(key)
+	length, err := d.append(key, suffix, ttl)
+	d.audit(context.Background(), "set", key, true, err)
+	return length, err
+}
+
+// append appends suffix to the string or []byte stored at key and returns the new length.
+func (d *KeyValueStore) append(key string, suffix any, ttl int) (int, error) {
+	switch suffix.(type) {
+	case string, []byte:
+	default:
+		return 0, fmt.Errorf("%w: suffix is %T", ErrTypeMismatch, suffix)
+	}
+	err := d.takeWriteTokens(1)
+	if err != nil {
+		d.recordSetResult(key, err)
+		return 0, err
+	}
+	defer d.afterWrite()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed.Load() {
+		return 0, ErrClosed
+	}
+	value := suffix
+	if b, ok := suffix.([]byte); ok {
+		value = bytes.Clone(b)
+	}
+	if old, ok := d.data[key]; ok && !d.nodeIsExpired(old) {
+		current, err := old.value()
+		if err != nil {
+			return 0, d.keyError("load value", key, err)
+		}
+		switch current := current.(type) {
+		case string:
+			s, ok := suffix.(string)
+			if !ok {
+				return 0, d.keyError("append", key, fmt.Errorf("%w: string and %T", ErrTypeMismatch, suffix))
+			}
+			value = current + s
+		case []byte:
+			b, ok := suffix.([]byte)
+			if !ok {
+				return 0, d.keyError("append", key, fmt.Errorf("%w: []byte and %T", ErrTypeMismatch, suffix))
+			}
+			value = append(current[:len(current):len(current)], b...)
+		default:
+			return 0, d.keyError("append", key, fmt.Errorf("%w: %T and %T", ErrTypeMismatch, current, suffix))
+		}
+	}
+	err = d.checkValue(value)
+	if err == nil {
+		err = d.makeRoomInQuotas(key)
+	}
+	if err != nil {
+		d.recordSetResult(key, err)
+		return 0, err
+	}
+	length := 0
+	switch value := value.(type) {
+	case string:
+		length = len(value)
+	case []byte:
+		length = len(value)
+	}
+	node := d.newNode(key, value, ttl)
+	d.putNode(node)
+	err = d.saveInCache(node)
+	d.recordSetResult(key, err)
+	if err != nil {
+		return length, err
+	}
+	return length, d.evictOverflow(key)
+}
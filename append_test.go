@@ -0,0 +1,79 @@
+package goKeyValueStore_test
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestAppend(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, clock)
+	if length, err := store.Append("log", "first", 1000); err != nil || length != 5 {
+		t.Errorf("Expected a missing key to be created, got %d, %v", length, err)
+	}
+	if length, _ := store.Append("log", ",second", 0); length != 12 {
+		t.Errorf("Expected length 12, got %d", length)
+	}
+	if ttl, _ := store.TTL("log"); ttl != goKeyValueStore.NoExpiration {
+		t.Errorf("Expected a TTL of 0 to never expire like in Set, got %s", ttl)
+	}
+	restarted := getTestStoreWithClock(t, dir, clock)
+	restarted.Append("log", ",third", 1000)
+	if value, _ := restarted.Get("log"); value != "first,second,third" {
+		t.Errorf("Expected the loaded value to be appended to, got %v", value)
+	}
+	clock.Advance(1001 * time.Millisecond)
+	if length, _ := restarted.Append("log", "new", 0); length != 3 {
+		t.Errorf("Expected an expired key to start again, got %d", length)
+	}
+}
+
+func TestAppendBytes(t *testing.T) {
+	store, _ := goKeyValueStore.NewKeyValueStore(1, "")
+	suffix := []byte("ab")
+	store.Append("data", suffix, 0)
+	suffix[0] = 'x'
+	store.Append("data", []byte("cd"), 0)
+	if value, _ := store.Get("data"); !bytes.Equal(value.([]byte), []byte("abcd")) {
+		t.Errorf("Expected abcd, got %s", value)
+	}
+}
+
+func TestAppendTypeMismatch(t *testing.T) {
+	store, _ := goKeyValueStore.NewKeyValueStore(1, "")
+	store.Set("text", "value", 0)
+	store.Set("data", []byte("value"), 0)
+	store.Set("number", 1, 0)
+	for key, suffix := range map[string]any{"text": []byte("x"), "data": "x", "number": "x", "missing": 1} {
+		if _, err := store.Append(key, suffix, 0); !errors.Is(err, goKeyValueStore.ErrTypeMismatch) {
+			t.Errorf("Expected ErrTypeMismatch for %s, got %v", key, err)
+		}
+	}
+	if value, _ := store.Get("text"); value != "value" {
+		t.Errorf("Expected text to be unchanged, got %v", value)
+	}
+}
+
+func TestAppendConcurrent(t *testing.T) {
+	store, _ := goKeyValueStore.NewKeyValueStore(1, "")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 50; n++ {
+				store.Append("log", "x", 0)
+			}
+		}()
+	}
+	wg.Wait()
+	if value, _ := store.Get("log"); len(value.(string)) != 400 {
+		t.Errorf("Expected 400 appended bytes, got %d", len(value.(string)))
+	}
+}
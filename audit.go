@@ -0,0 +1,158 @@
+package goKeyValueStore
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// auditBufferSize is the number of audit records that may wait to be written before new records are dropped.
+const auditBufferSize = 4096
+
+// An AuditRecord is written by WithAudit for every operation on an audited key. Values are never recorded.
+type AuditRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	Op        string    `json:"op"` // set, get, or delete
+	Key       string    `json:"key"`
+	Actor     string    `json:"actor,omitempty"`
+	// OK is true if a write succeeded or a read found the key. Error holds the error of a failed write.
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// actorKey is the context key of the actor set with ContextWithActor.
+type actorKey struct{}
+
+// ContextWithActor returns a context that names the actor recorded in audit records of operations called with it,
+// e.g. SetCtx.
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// An auditLog writes audit records to a writer in the background.
+type auditLog struct {
+	prefixes []string
+	w        io.Writer
+	limit    int
+	mu       sync.Mutex
+	pending  []AuditRecord
+	flushed  []chan struct{}
+	wake     chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	dropped  atomic.Uint64
+}
+
+// newAuditLog creates an audit log and starts its writer.
+func newAuditLog(prefixes []string, w io.Writer, limit int) *auditLog {
+	log := &auditLog{
+		prefixes: prefixes,
+		w:        w,
+		limit:    limit,
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go log.write()
+	return log
+}
+
+// audits reports whether operations on key are audited.
+func (l *auditLog) audits(key string) bool {
+	for _, prefix := range l.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// add queues a record. It never blocks on the writer; if the buffer is full, the record is dropped.
+func (l *auditLog) add(record AuditRecord) {
+	l.mu.Lock()
+	if len(l.pending) >= l.limit {
+		l.mu.Unlock()
+		l.dropped.Add(1)
+		return
+	}
+	l.pending = append(l.pending, record)
+	l.mu.Unlock()
+	l.signal()
+}
+
+// flush waits until all queued records are written.
+func (l *auditLog) flush() {
+	done := make(chan struct{})
+	l.mu.Lock()
+	l.flushed = append(l.flushed, done)
+	l.mu.Unlock()
+	l.signal()
+	select {
+	case <-done:
+	case <-l.done:
+	}
+}
+
+// close writes the queued records and stops the writer.
+func (l *auditLog) close() {
+	close(l.stop)
+	<-l.done
+}
+
+// signal wakes the writer up.
+func (l *auditLog) signal() {
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+}
+
+// write writes queued records as JSON lines. Records that can not be written are counted as dropped.
+func (l *auditLog) write() {
+	defer close(l.done)
+	encoder := json.NewEncoder(l.w)
+	for {
+		select {
+		case <-l.wake:
+			l.writePending(encoder)
+		case <-l.stop:
+			l.writePending(encoder)
+			return
+		}
+	}
+}
+
+// writePending writes the queued records.
+func (l *auditLog) writePending(encoder *json.Encoder) {
+	l.mu.Lock()
+	pending, flushed := l.pending, l.flushed
+	l.pending, l.flushed = nil, nil
+	l.mu.Unlock()
+	for i, record := range pending {
+		err := encoder.Encode(record)
+		if err != nil {
+			l.dropped.Add(uint64(len(pending) - i))
+			break
+		}
+	}
+	for _, done := range flushed {
+		close(done)
+	}
+}
+
+// audit records an operation on a key if the key is audited. ok reports whether the operation succeeded.
+func (d *KeyValueStore) audit(ctx context.Context, op string, key string, ok bool, err error) {
+	if d.auditLog == nil || !d.auditLog.audits(key) {
+		return
+	}
+	record := AuditRecord{Timestamp: d.now(), Op: op, Key: key, OK: ok && err == nil}
+	record.Actor, _ = ctx.Value(actorKey{}).(string)
+	if err != nil {
+		record.Error = err.Error()
+	}
+	d.auditLog.add(record)
+}
@@ -0,0 +1,122 @@
+package goKeyValueStore_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) records(t *testing.T) []goKeyValueStore.AuditRecord {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	records := []goKeyValueStore.AuditRecord{}
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record goKeyValueStore.AuditRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestAudit(t *testing.T) {
+	var out syncBuffer
+	store, err := goKeyValueStore.NewKeyValueStore(1, "", goKeyValueStore.WithCleanerStopped(true),
+		goKeyValueStore.WithAudit([]string{"secret:"}, &out), goKeyValueStore.WithMaxValueSize(20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := goKeyValueStore.ContextWithActor(context.Background(), "alice")
+	store.SetCtx(ctx, "secret:1", "hidden value", 0)
+	store.SetCtx(ctx, "public:1", "value", 0)
+	store.GetCtx(ctx, "secret:1")
+	store.GetCtx(ctx, "public:1")
+	store.Get("secret:2")
+	store.Set("secret:3", strings.Repeat("x", 100), 0)
+	store.DeleteCtx(ctx, "secret:1")
+	store.FlushAudit()
+	records := out.records(t)
+	expected := []goKeyValueStore.AuditRecord{
+		{Op: "set", Key: "secret:1", Actor: "alice", OK: true},
+		{Op: "get", Key: "secret:1", Actor: "alice", OK: true},
+		{Op: "get", Key: "secret:2", OK: false},
+		{Op: "set", Key: "secret:3", OK: false, Error: goKeyValueStore.ErrValueTooLarge.Error()},
+		{Op: "delete", Key: "secret:1", Actor: "alice", OK: true},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("Expected %d records, got %+v", len(expected), records)
+	}
+	for i, record := range records {
+		if record.Timestamp.IsZero() {
+			t.Errorf("Expected record %d to have a timestamp", i)
+		}
+		record.Timestamp = expected[i].Timestamp
+		if !strings.HasPrefix(record.Error, expected[i].Error) {
+			t.Errorf("Expected record %d to have error %q, got %q", i, expected[i].Error, record.Error)
+		}
+		record.Error = expected[i].Error
+		if record != expected[i] {
+			t.Errorf("Expected record %d to be %+v, got %+v", i, expected[i], record)
+		}
+	}
+	if strings.Contains(out.buf.String(), "hidden value") {
+		t.Errorf("Expected values to never be audited")
+	}
+}
+
+type blockingWriter struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	select {
+	case w.started <- struct{}{}:
+	default:
+	}
+	<-w.release
+	return 0, errors.New("closed")
+}
+
+func TestAuditDropsWhenFull(t *testing.T) {
+	writer := &blockingWriter{started: make(chan struct{}, 1), release: make(chan struct{})}
+	store, err := goKeyValueStore.NewKeyValueStore(1, "", goKeyValueStore.WithCleanerStopped(true),
+		goKeyValueStore.WithAuditBuffer([]string{""}, writer, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("key", "value", 0)
+	<-writer.started
+	for i := 0; i < 20; i++ {
+		store.Get("key")
+	}
+	if dropped := store.Stats().AuditDropped; dropped != 15 {
+		t.Errorf("Expected 15 dropped records, got %d", dropped)
+	}
+	close(writer.release)
+	store.FlushAudit()
+	if dropped := store.Stats().AuditDropped; dropped != 21 {
+		t.Errorf("Expected all 21 records to be dropped by the failing writer, got %d", dropped)
+	}
+}
@@ -0,0 +1,313 @@
+package goKeyValueStore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// ErrBackendUnavailable is wrapped by errors of a Backend that can not reach its storage. The store does not fail
+// writes because of it but reports it to the OnError function and keeps the entries in memory.
+var ErrBackendUnavailable = errors.New("backend is unavailable")
+
+// ErrBackendWithCacheFolder is returned by NewKeyValueStore if WithBackend is combined with a cache folder.
+var ErrBackendWithCacheFolder = errors.New("a backend can not be combined with a cache folder")
+
+// A Record is a key-value pair as it is saved in and loaded from a Backend. DeleteTimestamp is the deadline in
+// Unix milliseconds and math.MaxInt64 for pairs that never expire.
+type Record struct {
+	Key             string
+	Value           any
+	DeleteTimestamp int64
+	Revision        uint64
+	UpdatedAt       int64
+	CreatedAt       int64
+}
+
+// A RecordError is returned by a Backend for a record that it can not decode. The message does not contain Key;
+// the store adds it like to its own errors, so it is redacted with WithKeyRedaction.
+type RecordError struct {
+	Key string
+	Err error
+}
+
+// Error describes Err without the key.
+func (e *RecordError) Error() string {
+	return "invalid record: " + e.Err.Error()
+}
+
+// Unwrap returns Err.
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// A Backend persists the key-value pairs of a store in place of a cache folder, see WithBackend.
+// The store calls Save and Delete while holding its write lock, so calls are never concurrent.
+type Backend interface {
+	// Load calls fn with every record saved in the backend. It is called once when the store is created.
+	// Expired records may be returned; the store deletes them with its next clean run. A record that can not be
+	// decoded is reported as a RecordError.
+	Load(fn func(Record) error) error
+	// Save saves a record and replaces the record of the same key.
+	Save(record Record) error
+	// Delete deletes the record of a key. Deleting a key without a record is not an error.
+	Delete(key string) error
+	// Close is called by Close of the store after the last Save or Delete.
+	Close() error
+}
+
+// A FetchBackend is a Backend that can read single records, for example a tier shared by several stores.
+// Get and GetCtx fetch keys they do not have from it before they report a miss. Fetch is called without the lock
+// of the store, so it may run concurrently with the other methods.
+type FetchBackend interface {
+	Backend
+	// Fetch returns the record of key. The second return value is false if the backend has no record of key.
+	Fetch(key string) (Record, bool, error)
+}
+
+// NopBackend is a Backend that discards all records, so a store with it behaves like a memory-only store.
+type NopBackend struct{}
+
+// Load loads nothing.
+func (NopBackend) Load(func(Record) error) error { return nil }
+
+// Save discards the record.
+func (NopBackend) Save(Record) error { return nil }
+
+// Delete does nothing.
+func (NopBackend) Delete(string) error { return nil }
+
+// Close does nothing.
+func (NopBackend) Close() error { return nil }
+
+// A FileBackend is a Backend that saves every record in its own JSON file in a folder. The files have the
+// format of a cache folder without index, packing, or compression, so the folder can also be opened as the cache
+// folder of a store. Values are loaded as the types encoding/json decodes into any.
+type FileBackend struct {
+	folder string
+	fs     FS
+}
+
+// NewFileBackend creates a FileBackend that saves records in folder through fsys. The folder is resolved to an
+// absolute path and created if needed. A nil fsys is OSFS.
+func NewFileBackend(folder string, fsys FS) (*FileBackend, error) {
+	if fsys == nil {
+		fsys = OSFS{}
+	}
+	folder, err := filepath.Abs(folder)
+	if err != nil {
+		return nil, err
+	}
+	err = fsys.MkdirAll(folder, folderMode)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCacheFolderNotWritable, err)
+	}
+	return &FileBackend{folder: folder, fs: fsys}, nil
+}
+
+// Load reads all files of the folder. Temporary files of interrupted writes are skipped.
+func (b *FileBackend) Load(fn func(Record) error) error {
+	entries, err := b.fs.ReadDir(b.folder)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".store.json") {
+			continue
+		}
+		data, err := b.fs.ReadFile(filepath.Join(b.folder, entry.Name()))
+		if err != nil {
+			return err
+		}
+		var stored node
+		err = json.Unmarshal(data, &stored)
+		if err != nil {
+			return fmt.Errorf("%w %s: %w", ErrCorruptFile, entry.Name(), err)
+		}
+		err = fn(Record{
+			Key:             restoreKey(stored.Key, stored.KeyBytes),
+			Value:           stored.Value,
+			DeleteTimestamp: stored.DeleteTimestamp,
+			Revision:        stored.Revision,
+			UpdatedAt:       stored.UpdatedAt,
+			CreatedAt:       stored.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save writes the record to a temporary file and renames it over the file of the key.
+func (b *FileBackend) Save(record Record) error {
+	data, err := json.Marshal(node{
+		Key:             record.Key,
+		KeyBytes:        rawKey(record.Key),
+		Value:           record.Value,
+		DeleteTimestamp: record.DeleteTimestamp,
+		Revision:        record.Revision,
+		UpdatedAt:       record.UpdatedAt,
+		CreatedAt:       record.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	fileName := b.fileName(record.Key)
+	err = b.fs.WriteFile(fileName+".tmp", data, 0600)
+	if err != nil {
+		return err
+	}
+	return b.fs.Rename(fileName+".tmp", fileName)
+}
+
+// Delete removes the file of the key.
+func (b *FileBackend) Delete(key string) error {
+	err := b.fs.Remove(b.fileName(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Close does nothing; every Save is complete when it returns.
+func (b *FileBackend) Close() error {
+	return nil
+}
+
+// fileName returns the name of the file of a key.
+func (b *FileBackend) fileName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(b.folder, hex.EncodeToString(sum[:])+".store.json")
+}
+
+// persistent returns true if key-value pairs are saved in a cache folder or a backend.
+func (d *KeyValueStore) persistent() bool {
+	return d.cacheFolder != "" || d.backend != nil
+}
+
+// saveInBackend saves a node in the backend with the persist transform applied.
+func (d *KeyValueStore) saveInBackend(node *node) error {
+	value, err := node.value()
+	if err != nil {
+		return d.keyError("load value", node.Key, err)
+	}
+	if d.persistTransform != nil {
+		value, err = d.persistTransform(node.Key, value)
+		if err != nil {
+			return d.keyError("transform value", node.Key, err)
+		}
+	}
+	err = d.backend.Save(Record{
+		Key:             node.Key,
+		Value:           value,
+		DeleteTimestamp: node.DeleteTimestamp,
+		Revision:        node.Revision,
+		UpdatedAt:       node.UpdatedAt,
+		CreatedAt:       node.CreatedAt,
+	})
+	return d.backendError("save in backend", node.Key, err)
+}
+
+// deleteInBackend deletes a key from the backend.
+func (d *KeyValueStore) deleteInBackend(key string) error {
+	return d.backendError("delete from backend", key, d.backend.Delete(key))
+}
+
+// backendError returns the error of a backend operation on a key. ErrBackendUnavailable is queued for the OnError
+// function instead, so the write succeeds in memory. The caller must hold the write lock.
+func (d *KeyValueStore) backendError(op string, key string, err error) error {
+	if err == nil {
+		return nil
+	}
+	err = d.keyError(op, key, err)
+	if errors.Is(err, ErrBackendUnavailable) {
+		d.queueError(err)
+		return nil
+	}
+	return err
+}
+
+// loadBackend loads all records of the backend. If the backend is unavailable, the store starts empty and the
+// error is delivered to the first OnError function.
+func (d *KeyValueStore) loadBackend() error {
+	err := d.backend.Load(func(record Record) error {
+		value, err := d.transformLoaded(record.Key, record.Value, "")
+		if err != nil {
+			return err
+		}
+		// expired nodes are kept until the next clean run removes them together with their record
+		d.putNode(&node{
+			Key:             record.Key,
+			Value:           value,
+			DeleteTimestamp: record.DeleteTimestamp,
+			Revision:        record.Revision,
+			UpdatedAt:       record.UpdatedAt,
+			CreatedAt:       record.CreatedAt,
+		})
+		d.observeRevision(record.Revision)
+		return nil
+	})
+	if errors.Is(err, ErrBackendUnavailable) {
+		d.startErrors = append(d.startErrors, err)
+		return nil
+	}
+	var recordErr *RecordError
+	if errors.As(err, &recordErr) {
+		return d.keyError("load from backend", recordErr.Key, err)
+	}
+	return err
+}
+
+// fetch gets a key the store does not have from a FetchBackend and keeps it in memory. Errors are reported to
+// the OnError function and count as a miss.
+func (d *KeyValueStore) fetch(key string) (any, bool) {
+	fetcher, ok := d.backend.(FetchBackend)
+	if !ok || d.closed.Load() {
+		return nil, false
+	}
+	record, found, err := fetcher.Fetch(key)
+	if err != nil {
+		d.reportError(d.keyError("fetch from backend", key, err))
+		return nil, false
+	}
+	if !found || record.Key != key {
+		return nil, false
+	}
+	value, err := d.transformLoaded(key, record.Value, "")
+	if err != nil {
+		d.reportError(err)
+		return nil, false
+	}
+	defer d.afterWrite()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed.Load() {
+		return nil, false
+	}
+	if current, ok := d.data[key]; ok && !d.nodeIsExpired(current) {
+		// the key was set while it was fetched
+		value, err := current.value()
+		return value, err == nil
+	}
+	fetched := &node{
+		Key:             key,
+		Value:           value,
+		DeleteTimestamp: record.DeleteTimestamp,
+		Revision:        record.Revision,
+		UpdatedAt:       record.UpdatedAt,
+		CreatedAt:       record.CreatedAt,
+	}
+	if d.nodeIsExpired(fetched) {
+		return nil, false
+	}
+	d.putNode(fetched)
+	d.observeRevision(record.Revision)
+	d.evictOverflow("")
+	return value, true
+}
@@ -0,0 +1,123 @@
+package goKeyValueStore_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+// A recordingBackend keeps records in memory and counts calls of Close.
+type recordingBackend struct {
+	records map[string]goKeyValueStore.Record
+	closed  int
+	err     error
+}
+
+func (b *recordingBackend) Load(fn func(goKeyValueStore.Record) error) error {
+	for _, record := range b.records {
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *recordingBackend) Save(record goKeyValueStore.Record) error {
+	if b.err != nil {
+		return b.err
+	}
+	b.records[record.Key] = record
+	return nil
+}
+
+func (b *recordingBackend) Delete(key string) error {
+	delete(b.records, key)
+	return nil
+}
+
+func (b *recordingBackend) Close() error {
+	b.closed++
+	return nil
+}
+
+func TestBackend(t *testing.T) {
+	clock := newFakeClock()
+	backend := &recordingBackend{records: map[string]goKeyValueStore.Record{}}
+	store, err := goKeyValueStore.NewKeyValueStore(1, "", goKeyValueStore.WithClock(clock.Now),
+		goKeyValueStore.WithCleanerStopped(true), goKeyValueStore.WithBackend(backend))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("key1", "value1", 0)
+	store.Set("key2", "value2", 1000)
+	store.Set("key3", "value3", 0)
+	store.Delete("key3")
+	if len(backend.records) != 2 || backend.records["key1"].Value != "value1" {
+		t.Errorf("Expected key1 and key2 in the backend, got %v", backend.records)
+	}
+	backend.err = errors.New("backend is down")
+	if err := store.Set("key4", "value4", 0); !errors.Is(err, backend.err) {
+		t.Errorf("Expected the error of the backend, got %v", err)
+	}
+	backend.err = nil
+	clock.Advance(2 * time.Second)
+	store.CleanNow()
+	if _, ok := backend.records["key2"]; ok {
+		t.Error("Expected the expired key2 to be deleted from the backend")
+	}
+	store.Close()
+	store.Close()
+	if backend.closed != 1 {
+		t.Errorf("Expected the backend to be closed once, got %d", backend.closed)
+	}
+
+	store, err = goKeyValueStore.NewKeyValueStore(1, "", goKeyValueStore.WithBackend(backend))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if value, _ := store.Get("key1"); value != "value1" {
+		t.Errorf("Expected value1 to be loaded from the backend, got %v", value)
+	}
+}
+
+func TestBackendWithCacheFolder(t *testing.T) {
+	_, err := goKeyValueStore.NewKeyValueStore(1, t.TempDir(), goKeyValueStore.WithBackend(goKeyValueStore.NopBackend{}))
+	if !errors.Is(err, goKeyValueStore.ErrBackendWithCacheFolder) {
+		t.Errorf("Expected ErrBackendWithCacheFolder, got %v", err)
+	}
+}
+
+func TestFileBackendFolderCanBeOpened(t *testing.T) {
+	dir := t.TempDir()
+	backend, err := goKeyValueStore.NewFileBackend(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := goKeyValueStore.NewKeyValueStore(1, "", goKeyValueStore.WithBackend(backend))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("key1", "value1", 0)
+	store.Set("key\xff", "value2", 0)
+	store.Set("key3", "value3", 0)
+	store.Delete("key3")
+	store.Close()
+	if count := countCacheFiles(t, dir); count != 2 {
+		t.Errorf("Expected 2 files, got %d", count)
+	}
+
+	store, err = goKeyValueStore.NewKeyValueStore(1, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if value, _ := store.Get("key1"); value != "value1" {
+		t.Errorf("Expected value1, got %v", value)
+	}
+	if value, _ := store.Get("key\xff"); value != "value2" {
+		t.Errorf("Expected value2, got %v", value)
+	}
+}
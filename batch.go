@@ -0,0 +1,232 @@
+package goKeyValueStore
+
+import (
+	"context"
+	"errors"
+)
+
+// An Entry is a key-value pair with a TTL in milliseconds used for bulk writes.
+// If IfAbsent is true, the entry is only applied if its key does not exist, like with GetOrSet. If Source is set,
+// the key is derived from the key Source like with SetDerivedTTL; the source may be an entry applied before it by
+// the same call.
+type Entry struct {
+	Key      string
+	Value    any
+	TTL      int
+	IfAbsent bool
+	Source   string
+}
+
+// An EntryResult is the outcome of writing a single Entry.
+// Applied reports whether the entry was set in memory and Persisted whether it was saved in the cache folder or
+// the backend. Persisted is always false for stores without either.
+type EntryResult struct {
+	Key       string
+	Applied   bool
+	Persisted bool
+	Err       error
+}
+
+// SetManyDetailed sets all entries while holding the write lock once and returns one result per entry.
+// Entries with values that can not be encoded or are too large, new keys rejected by a prefix quota, and entries
+// whose source does not exist are not applied. Entries with IfAbsent whose key exists are not applied either but
+// have no error. All other entries are applied even if other entries of the same call fail. Each entry counts as one
+// write for the write rate limit. If the entries exceed the maximum number of entries, other entries are evicted
+// after all entries were applied; errors of the eviction are delivered to the OnError function.
+func (d *KeyValueStore) SetManyDetailed(entries []Entry) []EntryResult {
+	d.lazyInit()
+	defer d.afterWrite()
+	results := make([]EntryResult, len(entries))
+	keys := make([]string, len(entries))
+	for i, entry := range entries {
+		results[i].Key = entry.Key
+		keys[i] = d.storageKey(entry.Key)
+		results[i].Err = d.checkValue(entry.Value)
+		if results[i].Err == nil {
+			results[i].Err = d.takeWriteTokens(1)
+		}
+		if results[i].Err != nil {
+			d.recordSetResult(keys[i], results[i].Err)
+		}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, entry := range entries {
+		if results[i].Err == nil && d.closed.Load() {
+			results[i].Err = ErrClosed
+		}
+		if results[i].Err != nil {
+			continue
+		}
+		if old, ok := d.data[keys[i]]; ok && entry.IfAbsent && !d.nodeIsExpired(old) {
+			continue
+		}
+		var source *node
+		if entry.Source != "" {
+			source, results[i].Err = d.sourceOf(keys[i], d.storageKey(entry.Source))
+		}
+		if results[i].Err == nil {
+			results[i].Err = d.makeRoomInQuotas(keys[i])
+		}
+		if results[i].Err != nil {
+			d.recordSetResult(keys[i], results[i].Err)
+			continue
+		}
+		node := d.newNode(keys[i], entry.Value, entry.TTL)
+		if source != nil {
+			derive(node, source, entry.TTL)
+		}
+		d.putNode(node)
+		results[i].Applied = true
+		if !d.persistent() {
+			d.recordSetResult(keys[i], nil)
+			continue
+		}
+		err := d.saveInCache(node)
+		d.recordSetResult(keys[i], err)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].Persisted = true
+	}
+	err := d.evictOverflow("")
+	if err != nil {
+		d.queueError(err)
+	}
+	for i, result := range results {
+		d.audit(context.Background(), "set", keys[i], result.Applied, result.Err)
+	}
+	return results
+}
+
+// SetMany sets all entries while holding the write lock once, like SetManyDetailed. Entries that fail do not roll
+// back the others: all entries that could be applied are set in memory, including entries whose files could not
+// be written. The returned error joins the errors of all failed entries and names their keys.
+func (d *KeyValueStore) SetMany(entries []Entry) error {
+	d.lazyInit()
+	var errs []error
+	for _, result := range d.SetManyDetailed(entries) {
+		if result.Err != nil {
+			errs = append(errs, d.keyError("set", d.storageKey(result.Key), result.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// GetAllOrNone gets the values of all keys at the same instant. If any key does not exist or is expired,
+// it returns nil and false. Combined with SetManyDetailed, which applies all entries at once, related keys
+// can be read and written consistently.
+func (d *KeyValueStore) GetAllOrNone(keys ...string) (map[string]any, bool) {
+	d.lazyInit()
+	stored := make([]string, len(keys))
+	for i, key := range keys {
+		stored[i] = d.storageKey(key)
+	}
+	values, ok := d.getAllOrNone(stored)
+	for _, key := range stored {
+		d.audit(context.Background(), "get", key, ok, nil)
+	}
+	if !ok || !d.hashKeys {
+		return values, ok
+	}
+	byKey := make(map[string]any, len(keys))
+	for i, key := range keys {
+		byKey[key] = values[stored[i]]
+	}
+	return byKey, true
+}
+
+// getAllOrNone gets the values of all keys at the same instant or returns nil and false.
+func (d *KeyValueStore) getAllOrNone(keys []string) (map[string]any, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	values := make(map[string]any, len(keys))
+	for _, key := range keys {
+		node, ok := d.data[key]
+		if !ok || d.nodeIsExpired(node) || d.closed.Load() {
+			return nil, false
+		}
+		value, err := node.value()
+		if err != nil {
+			return nil, false
+		}
+		values[key] = value
+	}
+	for _, key := range keys {
+		d.eviction.touch(d.data[key])
+	}
+	return values, true
+}
+
+// GetMany gets the values of keys while holding the read lock once. It returns the values of the keys that exist
+// and are not expired and, in the order of keys, the keys that are missing or expired. Duplicate keys are looked
+// up once.
+func (d *KeyValueStore) GetMany(keys []string) (map[string]any, []string) {
+	d.lazyInit()
+	values := make(map[string]any, len(keys))
+	missing := []string{}
+	seen := make(map[string]bool, len(keys))
+	d.mu.RLock()
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		node, ok := d.data[d.storageKey(key)]
+		if !ok || d.nodeIsExpired(node) || d.closed.Load() {
+			missing = append(missing, key)
+			continue
+		}
+		value, err := node.value()
+		if err != nil {
+			missing = append(missing, key)
+			continue
+		}
+		d.eviction.touch(node)
+		values[key] = value
+	}
+	d.mu.RUnlock()
+	for key := range seen {
+		_, ok := values[key]
+		d.audit(context.Background(), "get", d.storageKey(key), ok, nil)
+	}
+	return values, missing
+}
+
+// DeleteMany deletes keys while holding the write lock once. Keys that do not exist are skipped like in Delete.
+// A failed deletion of a file does not stop the deletion of the other keys; the errors are joined.
+func (d *KeyValueStore) DeleteMany(keys []string) error {
+	d.lazyInit()
+	stored := make([]string, len(keys))
+	for i, key := range keys {
+		stored[i] = d.storageKey(key)
+	}
+	errs, err := d.deleteMany(stored)
+	for i, key := range stored {
+		d.audit(context.Background(), "delete", key, true, errors.Join(err, errs[i]))
+	}
+	if err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// deleteMany deletes keys and returns the error of each key.
+func (d *KeyValueStore) deleteMany(keys []string) ([]error, error) {
+	errs := make([]error, len(keys))
+	defer d.afterWrite()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed.Load() {
+		return errs, ErrClosed
+	}
+	for i, key := range keys {
+		if _, ok := d.data[key]; ok {
+			d.recordEvent(key, EventDeleted, "deleted")
+		}
+		d.removeNode(key)
+		errs[i] = errors.Join(d.deleteInCache(key), d.removeDerived(key))
+	}
+	return errs, nil
+}
@@ -0,0 +1,285 @@
+package goKeyValueStore_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+	"github.com/richi0/goKeyValueStore/faultfs"
+)
+
+func TestSetManyDetailed(t *testing.T) {
+	os.RemoveAll(CACHE_DIR)
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, CACHE_DIR, goKeyValueStore.WithMaxValueSize(20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := store.SetManyDetailed([]goKeyValueStore.Entry{
+		{Key: "key1", Value: "value1", TTL: 1000},
+		{Key: "key2", Value: strings.Repeat("x", 100), TTL: 1000},
+		{Key: "key3", Value: math.NaN(), TTL: 1000},
+		{Key: "key4", Value: "value4", TTL: 0},
+	})
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 results, got %d", len(results))
+	}
+	for _, i := range []int{0, 3} {
+		if !results[i].Applied || !results[i].Persisted || results[i].Err != nil {
+			t.Errorf("Expected %s to be applied and persisted, got %+v", results[i].Key, results[i])
+		}
+	}
+	if results[1].Applied || results[1].Persisted || !errors.Is(results[1].Err, goKeyValueStore.ErrValueTooLarge) {
+		t.Errorf("Expected key2 to be rejected as too large, got %+v", results[1])
+	}
+	var unsupported *json.UnsupportedValueError
+	if results[2].Applied || results[2].Persisted || !errors.As(results[2].Err, &unsupported) {
+		t.Errorf("Expected key3 to be rejected as unsupported, got %+v", results[2])
+	}
+	if store.Length() != 2 {
+		t.Errorf("Expected length to be 2, got %d", store.Length())
+	}
+	for _, key := range []string{"key1", "key4"} {
+		if _, ok := store.Get(key); !ok {
+			t.Errorf("Expected %s to be present", key)
+		}
+	}
+	entries, err := os.ReadDir(CACHE_DIR)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected 2 files, got %d", len(entries))
+	}
+}
+
+func TestSetManyDetailedEntryOptions(t *testing.T) {
+	clock := newFakeClock()
+	store := getTestStoreWithClock(t, "", clock)
+	store.Set("existing", "old", 0)
+	results := store.SetManyDetailed([]goKeyValueStore.Entry{
+		{Key: "existing", Value: "new", IfAbsent: true},
+		{Key: "absent", Value: "new", IfAbsent: true},
+		{Key: "source", Value: "image", TTL: 1000},
+		{Key: "derived", Value: "thumbnail", Source: "source"},
+		{Key: "orphan", Value: "thumbnail", Source: "missing"},
+	})
+	if results[0].Applied || results[0].Err != nil {
+		t.Errorf("Expected the existing key to be kept without error, got %+v", results[0])
+	}
+	if value, _ := store.Get("existing"); value != "old" {
+		t.Errorf("Expected old, got %v", value)
+	}
+	if !results[1].Applied || !results[3].Applied {
+		t.Errorf("Expected absent and derived to be applied, got %+v and %+v", results[1], results[3])
+	}
+	if results[4].Applied || !errors.Is(results[4].Err, goKeyValueStore.ErrSourceNotFound) {
+		t.Errorf("Expected ErrSourceNotFound for orphan, got %+v", results[4])
+	}
+	if ttl, _ := store.TTL("derived"); ttl != time.Second {
+		t.Errorf("Expected derived to expire with its source, got a TTL of %v", ttl)
+	}
+	store.Delete("source")
+	if _, ok := store.Get("derived"); ok {
+		t.Error("Expected derived to be deleted with its source")
+	}
+}
+
+func TestSetManyDetailedPersistenceFailure(t *testing.T) {
+	store := getTestStore()
+	results := store.SetManyDetailed([]goKeyValueStore.Entry{
+		{Key: "key4", Value: math.Inf(1), TTL: 1000},
+		{Key: "key5", Value: "value5", TTL: 1000},
+	})
+	if !results[0].Applied || results[0].Persisted || results[0].Err == nil {
+		t.Errorf("Expected key4 to be applied but not persisted, got %+v", results[0])
+	}
+	if !results[1].Applied || !results[1].Persisted || results[1].Err != nil {
+		t.Errorf("Expected key5 to be applied and persisted, got %+v", results[1])
+	}
+}
+
+func TestSetManyDetailedEvictionFailure(t *testing.T) {
+	fsys := faultfs.New(goKeyValueStore.OSFS{})
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, t.TempDir(), goKeyValueStore.WithMaxEntries(1), goKeyValueStore.WithFilesystem(fsys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	var reported []error
+	store.OnError(func(err error) { reported = append(reported, err) })
+	store.Set("old", "value", 0)
+	fsys.Fail(faultfs.OpRemove, nil)
+	store.SetManyDetailed([]goKeyValueStore.Entry{{Key: "new", Value: "value"}})
+	if len(reported) != 1 || !errors.Is(reported[0], faultfs.ErrInjected) {
+		t.Errorf("Expected the failed eviction to be reported, got %v", reported)
+	}
+}
+
+func TestSetValueTooLarge(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, "", goKeyValueStore.WithMaxValueSize(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Set("key1", strings.Repeat("x", 10), 1000)
+	if !errors.Is(err, goKeyValueStore.ErrValueTooLarge) {
+		t.Errorf("Expected ErrValueTooLarge, got %v", err)
+	}
+	if store.Length() != 0 {
+		t.Errorf("Expected length to be 0, got %d", store.Length())
+	}
+}
+
+func TestGetAllOrNone(t *testing.T) {
+	store := getTestStore()
+	values, ok := store.GetAllOrNone("key1", "key2")
+	if !ok || values["key1"] != "value1" || values["key2"] != "value2" {
+		t.Errorf("Expected key1 and key2, got %v", values)
+	}
+	values, ok = store.GetAllOrNone("key1", "key4")
+	if ok || values != nil {
+		t.Errorf("Expected no values for a missing key, got %v", values)
+	}
+}
+
+func TestGetAllOrNoneConsistentWithSetManyDetailed(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.SetManyDetailed([]goKeyValueStore.Entry{{Key: "value", Value: 0}, {Key: "checksum", Value: 0}})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 1000; i++ {
+			store.SetManyDetailed([]goKeyValueStore.Entry{{Key: "value", Value: i}, {Key: "checksum", Value: i}})
+		}
+	}()
+	for i := 0; i < 5000; i++ {
+		values, ok := store.GetAllOrNone("value", "checksum")
+		if !ok {
+			t.Fatal("Expected both keys to be present")
+		}
+		if values["value"] != values["checksum"] {
+			t.Fatalf("Expected a consistent pair, got %v", values)
+		}
+	}
+	<-done
+}
+
+func TestSetMany(t *testing.T) {
+	fsys := faultfs.New(nil)
+	store, err := goKeyValueStore.NewKeyValueStore(1, t.TempDir(), goKeyValueStore.WithFilesystem(fsys),
+		goKeyValueStore.WithCleanerStopped(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys.FailKeys("key2", faultfs.OpWrite, nil)
+	err = store.SetMany([]goKeyValueStore.Entry{
+		{Key: "key1", Value: "value1"},
+		{Key: "key2", Value: "value2"},
+		{Key: "key3", Value: "value3", TTL: 1000},
+	})
+	if !errors.Is(err, faultfs.ErrInjected) || !strings.Contains(err.Error(), "key2") || strings.Contains(err.Error(), "key1") {
+		t.Errorf("Expected an error naming key2, got %v", err)
+	}
+	if store.Length() != 3 {
+		t.Errorf("Expected all entries to be set in memory, got %d", store.Length())
+	}
+}
+
+// benchmarkEntries returns n entries with distinct keys.
+func benchmarkEntries(n int) []goKeyValueStore.Entry {
+	entries := make([]goKeyValueStore.Entry, n)
+	for i := range entries {
+		entries[i] = goKeyValueStore.Entry{Key: fmt.Sprintf("key%d", i), Value: "value"}
+	}
+	return entries
+}
+
+// benchmarkSet runs set with 1000 entries on a memory-only store and on a store with a cache folder.
+func benchmarkSet(b *testing.B, set func(store *goKeyValueStore.KeyValueStore, entries []goKeyValueStore.Entry)) {
+	entries := benchmarkEntries(1000)
+	for name, folder := range map[string]string{"memory": "", "folder": b.TempDir()} {
+		b.Run(name, func(b *testing.B) {
+			store, _ := goKeyValueStore.NewKeyValueStore(1, folder, goKeyValueStore.WithCleanerStopped(true))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				set(store, entries)
+			}
+		})
+	}
+}
+
+func BenchmarkSetMany(b *testing.B) {
+	benchmarkSet(b, func(store *goKeyValueStore.KeyValueStore, entries []goKeyValueStore.Entry) {
+		store.SetMany(entries)
+	})
+}
+
+func BenchmarkSetLoop(b *testing.B) {
+	benchmarkSet(b, func(store *goKeyValueStore.KeyValueStore, entries []goKeyValueStore.Entry) {
+		for _, entry := range entries {
+			store.Set(entry.Key, entry.Value, entry.TTL)
+		}
+	})
+}
+
+func TestGetMany(t *testing.T) {
+	clock := newFakeClock()
+	store := getTestStoreWithClock(t, "", clock)
+	store.Set("key1", "value1", 0)
+	store.Set("key2", "value2", 1000)
+	store.Set("key3", nil, 0)
+	clock.Advance(1001 * time.Millisecond)
+	values, missing := store.GetMany([]string{"key1", "key2", "key1", "missing", "key3", "missing"})
+	if len(values) != 2 || values["key1"] != "value1" || values["key3"] != nil {
+		t.Errorf("Expected key1 and key3, got %v", values)
+	}
+	if !slices.Equal(missing, []string{"key2", "missing"}) {
+		t.Errorf("Expected key2 and missing to be missing once, got %v", missing)
+	}
+	if _, ok := values["key3"]; !ok {
+		t.Errorf("Expected key3 with a nil value to be found")
+	}
+}
+
+func TestDeleteMany(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, clock)
+	store.Set("user:1:a", "value", 0)
+	store.Set("user:1:b", "value", 1000)
+	store.Set("user:2:a", "value", 0)
+	clock.Advance(1001 * time.Millisecond)
+	if err := store.DeleteMany([]string{"user:1:a", "user:1:b", "user:1:missing", "user:1:a"}); err != nil {
+		t.Fatal(err)
+	}
+	if store.Length() != 1 || countCacheFiles(t, dir) != 1 {
+		t.Errorf("Expected only user:2:a to be left, got %d entries and %d files", store.Length(), countCacheFiles(t, dir))
+	}
+}
+
+func TestDeleteManyReportsFailedRemovals(t *testing.T) {
+	fsys := faultfs.New(nil)
+	store, err := goKeyValueStore.NewKeyValueStore(1, t.TempDir(), goKeyValueStore.WithFilesystem(fsys),
+		goKeyValueStore.WithCleanerStopped(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("key1", "value1", 0)
+	store.Set("key2", "value2", 0)
+	fsys.FailKeys("key1", faultfs.OpRemove, nil)
+	if err := store.DeleteMany([]string{"key1", "key2"}); !errors.Is(err, faultfs.ErrInjected) {
+		t.Errorf("Expected ErrInjected, got %v", err)
+	}
+	if store.Length() != 0 {
+		t.Errorf("Expected both keys to be deleted in memory, got %d", store.Length())
+	}
+}
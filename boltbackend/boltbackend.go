@@ -0,0 +1,107 @@
+// Package boltbackend provides a goKeyValueStore.Backend that saves all entries in a single bbolt database file.
+// It is a module of its own, so the store itself stays free of dependencies.
+package boltbackend
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/richi0/goKeyValueStore"
+	bolt "go.etcd.io/bbolt"
+)
+
+// bucketName is the bucket that holds the entries.
+var bucketName = []byte("entries")
+
+// A Backend saves every entry in its own transaction in a bbolt database. Entries are stored under the SHA-256
+// hash of their key, so keys of any length, including the empty key, can be saved.
+type Backend struct {
+	db *bolt.DB
+}
+
+// A record is an entry as it is saved in the bucket. It has the fields of a cache file.
+type record struct {
+	Key             []byte `json:"key"`
+	Value           any    `json:"value"`
+	DeleteTimestamp int64  `json:"deleteTimestamp"`
+	Revision        uint64 `json:"revision,omitempty"`
+	UpdatedAt       int64  `json:"updatedAt,omitempty"`
+	CreatedAt       int64  `json:"createdAt,omitempty"`
+}
+
+// Open opens the database file at path with options and creates it if needed. A nil options uses the defaults
+// of bbolt, which wait forever for another process that has the file open.
+func Open(path string, options *bolt.Options) (*Backend, error) {
+	db, err := bolt.Open(path, 0600, options)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketName)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Backend{db: db}, nil
+}
+
+// Load iterates the bucket in a single read transaction. Values are loaded as the types encoding/json decodes
+// into any.
+func (b *Backend) Load(fn func(goKeyValueStore.Record) error) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).ForEach(func(k, v []byte) error {
+			var stored record
+			err := json.Unmarshal(v, &stored)
+			if err != nil {
+				return fmt.Errorf("%w %x: %w", goKeyValueStore.ErrCorruptFile, k, err)
+			}
+			return fn(goKeyValueStore.Record{
+				Key:             string(stored.Key),
+				Value:           stored.Value,
+				DeleteTimestamp: stored.DeleteTimestamp,
+				Revision:        stored.Revision,
+				UpdatedAt:       stored.UpdatedAt,
+				CreatedAt:       stored.CreatedAt,
+			})
+		})
+	})
+}
+
+// Save puts the encoded record in the bucket.
+func (b *Backend) Save(r goKeyValueStore.Record) error {
+	data, err := json.Marshal(record{
+		Key:             []byte(r.Key),
+		Value:           r.Value,
+		DeleteTimestamp: r.DeleteTimestamp,
+		Revision:        r.Revision,
+		UpdatedAt:       r.UpdatedAt,
+		CreatedAt:       r.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Put(bucketKey(r.Key), data)
+	})
+}
+
+// Delete deletes the record of key from the bucket.
+func (b *Backend) Delete(key string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Delete(bucketKey(key))
+	})
+}
+
+// Close closes the database.
+func (b *Backend) Close() error {
+	return b.db.Close()
+}
+
+// bucketKey returns the key of an entry in the bucket.
+func bucketKey(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
@@ -0,0 +1,121 @@
+package boltbackend_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+	"github.com/richi0/goKeyValueStore/boltbackend"
+	"github.com/richi0/goKeyValueStore/storetest"
+	bolt "go.etcd.io/bbolt"
+)
+
+// openStore opens a store on the database at path.
+func openStore(path string, opts ...goKeyValueStore.Option) (*goKeyValueStore.KeyValueStore, error) {
+	backend, err := boltbackend.Open(path, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return goKeyValueStore.NewKeyValueStore(1, "", append(opts, goKeyValueStore.WithBackend(backend))...)
+}
+
+func TestConformance(t *testing.T) {
+	storetest.RunConformance(t, func() storetest.StoreUnderTest {
+		clock := storetest.NewClock()
+		path := filepath.Join(t.TempDir(), "store.db")
+		open := func() (*goKeyValueStore.KeyValueStore, error) {
+			return openStore(path, goKeyValueStore.WithClock(clock.Now), goKeyValueStore.WithCleanerStopped(true))
+		}
+		store, err := open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return storetest.StoreUnderTest{Store: store, Advance: clock.Advance, Close: store.Close,
+			Restart: func() (goKeyValueStore.Store, error) {
+				store.Close()
+				restarted, err := open()
+				if err == nil {
+					t.Cleanup(func() { restarted.Close() })
+				}
+				return restarted, err
+			}}
+	})
+}
+
+func TestCloseClosesDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.db")
+	store, err := openStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("", "empty key", 0)
+	err = store.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// bbolt locks the file while it is open, so this times out unless Close closed the database
+	store, err = openStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if value, _ := store.Get(""); value != "empty key" {
+		t.Errorf("Expected the value of the empty key, got %v", value)
+	}
+}
+
+// benchmarkKeys is the number of keys loaded by BenchmarkLoad.
+const benchmarkKeys = 100_000
+
+// BenchmarkLoad compares the startup of a store with 100k keys in bbolt and in a cache folder.
+func BenchmarkLoad(b *testing.B) {
+	entries := make([]goKeyValueStore.Entry, benchmarkKeys)
+	for i := range entries {
+		entries[i] = goKeyValueStore.Entry{Key: fmt.Sprintf("key%d", i), Value: map[string]any{"id": float64(i), "name": "value"}}
+	}
+	setups := []struct {
+		name string
+		open func(dir string, options *bolt.Options) (*goKeyValueStore.KeyValueStore, error)
+	}{
+		{"bolt", func(dir string, options *bolt.Options) (*goKeyValueStore.KeyValueStore, error) {
+			backend, err := boltbackend.Open(filepath.Join(dir, "store.db"), options)
+			if err != nil {
+				return nil, err
+			}
+			return goKeyValueStore.NewKeyValueStore(60, "", goKeyValueStore.WithBackend(backend), goKeyValueStore.WithCleanerStopped(true))
+		}},
+		{"folder", func(dir string, _ *bolt.Options) (*goKeyValueStore.KeyValueStore, error) {
+			return goKeyValueStore.NewKeyValueStore(60, dir, goKeyValueStore.WithCleanerStopped(true))
+		}},
+	}
+	for _, setup := range setups {
+		b.Run(setup.name, func(b *testing.B) {
+			dir := b.TempDir()
+			// the database is filled without syncing every transaction to keep the setup short
+			store, err := setup.open(dir, &bolt.Options{NoSync: true})
+			if err != nil {
+				b.Fatal(err)
+			}
+			err = store.SetMany(entries)
+			if err != nil {
+				b.Fatal(err)
+			}
+			store.Close()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				store, err := setup.open(dir, nil)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if store.Length() != benchmarkKeys {
+					b.Fatalf("Expected %d keys, got %d", benchmarkKeys, store.Length())
+				}
+				b.StopTimer()
+				store.Close()
+				b.StartTimer()
+			}
+		})
+	}
+}
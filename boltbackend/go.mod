@@ -0,0 +1,12 @@
+module github.com/richi0/goKeyValueStore/boltbackend
+
+go 1.22.3
+
+require (
+	github.com/richi0/goKeyValueStore v0.0.0
+	go.etcd.io/bbolt v1.3.11
+)
+
+require golang.org/x/sys v0.4.0 // indirect
+
+replace github.com/richi0/goKeyValueStore => ../
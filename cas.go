@@ -0,0 +1,65 @@
+package goKeyValueStore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+)
+
+// CompareAndSwap sets a key to new if its current value equals old and returns whether it was set. Values are
+// equal if their JSON encodings are equal, so a value of 1 matches the 1.0 that is loaded from the cache folder.
+// The check and the update happen while holding the write lock, so concurrent calls with the same old value
+// swap only once. A ttl of 0 or more sets the TTL in milliseconds like in Set, and a negative ttl keeps the
+// deadline of the current value. Missing and expired keys never swap. The cache folder is only written if the
+// value was swapped.
+func (d *KeyValueStore) CompareAndSwap(key string, old, new any, ttl int) (bool, error) {
+	d.lazyInit()
+	key = d.storageKey(key)
+	swapped, err := d.compareAndSwap(key, old, new, ttl)
+	d.audit(context.Background(), "set", key, swapped, err)
+	return swapped, err
+}
+
+// compareAndSwap sets a key to new if its current value equals old.
+func (d *KeyValueStore) compareAndSwap(key string, old, new any, ttl int) (bool, error) {
+	err := d.checkValue(new)
+	if err == nil {
+		err = d.takeWriteTokens(1)
+	}
+	if err != nil {
+		return false, err
+	}
+	oldData, err := json.Marshal(old)
+	if err != nil {
+		return false, err
+	}
+	defer d.afterWrite()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed.Load() {
+		return false, ErrClosed
+	}
+	current, ok := d.data[key]
+	if !ok || d.nodeIsExpired(current) {
+		return false, nil
+	}
+	value, err := current.value()
+	if err != nil {
+		return false, d.keyError("load value", key, err)
+	}
+	data, err := json.Marshal(value)
+	if err != nil || !bytes.Equal(data, oldData) {
+		return false, nil
+	}
+	node := d.newNode(key, new, max(ttl, 0))
+	if ttl < 0 {
+		node.DeleteTimestamp = current.DeleteTimestamp
+	}
+	d.putNode(node)
+	err = d.saveInCache(node)
+	d.recordSetResult(key, err)
+	if err != nil {
+		return true, err
+	}
+	return true, d.evictOverflow(key)
+}
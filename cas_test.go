@@ -0,0 +1,64 @@
+package goKeyValueStore_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestCompareAndSwap(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, clock)
+	if swapped, err := store.CompareAndSwap("key", nil, "value", 0); swapped || err != nil {
+		t.Errorf("Expected a missing key to not swap, got %v, %v", swapped, err)
+	}
+	store.Set("key", "first", 1000)
+	if swapped, _ := store.CompareAndSwap("key", "other", "second", 0); swapped {
+		t.Errorf("Expected a different value to not swap")
+	}
+	if swapped, err := store.CompareAndSwap("key", "first", "second", -1); !swapped || err != nil {
+		t.Errorf("Expected first to be swapped, got %v, %v", swapped, err)
+	}
+	if ttl, _ := store.TTL("key"); ttl != time.Second {
+		t.Errorf("Expected a negative TTL to keep the deadline, got %s", ttl)
+	}
+	clock.Advance(1001 * time.Millisecond)
+	if swapped, _ := store.CompareAndSwap("key", "second", "third", 0); swapped {
+		t.Errorf("Expected an expired key to not swap")
+	}
+	store.Set("counter", 1, 0)
+	restarted := getTestStoreWithClock(t, dir, clock)
+	if swapped, _ := restarted.CompareAndSwap("counter", 1, 2, 0); !swapped {
+		t.Errorf("Expected 1 to match the loaded value")
+	}
+	if value, ok := getTestStoreWithClock(t, dir, clock).Get("counter"); !ok || value != 2.0 {
+		t.Errorf("Expected the swapped value to be persisted, got %v", value)
+	}
+}
+
+func TestCompareAndSwapConcurrent(t *testing.T) {
+	store, _ := goKeyValueStore.NewKeyValueStore(1, "")
+	store.Set("counter", 0, 0)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 100; n++ {
+				for {
+					value, _ := store.Get("counter")
+					if swapped, _ := store.CompareAndSwap("counter", value, value.(int)+1, 0); swapped {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if value, _ := store.Get("counter"); value != 800 {
+		t.Errorf("Expected 800 increments, got %v", value)
+	}
+}
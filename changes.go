@@ -0,0 +1,222 @@
+package goKeyValueStore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// A Change is a record of the changes feed.
+type Change struct {
+	Key       string    `json:"key"`
+	Op        string    `json:"op"` // set, deleted, expired, or evicted
+	Timestamp time.Time `json:"timestamp"`
+	Revision  uint64    `json:"revision,omitempty"`
+}
+
+// A changesFeed appends changes to a file in the background and prunes records older than the retention.
+type changesFeed struct {
+	path      string
+	retention time.Duration
+	now       func() time.Time
+	mu        sync.Mutex
+	pending   []Change
+	flushed   []chan struct{}
+	wake      chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	prunedAt  time.Time
+}
+
+// ReadChangesFeed reads the records of a changes feed written by WithChangesFeed with a timestamp after since.
+// A torn last line, left by a crash while it was written, is ignored. A missing file has no records.
+func ReadChangesFeed(path string, since time.Time) ([]Change, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Change{}, nil
+		}
+		return nil, err
+	}
+	all, err := parseChanges(data)
+	if err != nil {
+		return nil, err
+	}
+	changes := []Change{}
+	for _, change := range all {
+		if change.Timestamp.After(since) {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+// parseChanges parses the lines of a changes feed. Only the last line may be torn.
+func parseChanges(data []byte) ([]Change, error) {
+	changes := []Change{}
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		var change Change
+		err := json.Unmarshal(line, &change)
+		if err != nil {
+			if i == len(lines)-1 {
+				break // torn last line
+			}
+			return nil, fmt.Errorf("changes feed line %d: %w", i+1, err)
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// newChangesFeed creates a changes feed and starts its writer.
+func newChangesFeed(path string, retention time.Duration, now func() time.Time) *changesFeed {
+	feed := &changesFeed{
+		path:      path,
+		retention: retention,
+		now:       now,
+		wake:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go feed.write()
+	return feed
+}
+
+// add queues a change. It never blocks on the file.
+func (f *changesFeed) add(change Change) {
+	f.mu.Lock()
+	f.pending = append(f.pending, change)
+	f.mu.Unlock()
+	f.signal()
+}
+
+// flush waits until all queued changes are written.
+func (f *changesFeed) flush() {
+	done := make(chan struct{})
+	f.mu.Lock()
+	f.flushed = append(f.flushed, done)
+	f.mu.Unlock()
+	f.signal()
+	select {
+	case <-done:
+	case <-f.done:
+	}
+}
+
+// close writes the queued changes and stops the writer.
+func (f *changesFeed) close() {
+	close(f.stop)
+	<-f.done
+}
+
+// signal wakes the writer up.
+func (f *changesFeed) signal() {
+	select {
+	case f.wake <- struct{}{}:
+	default:
+	}
+}
+
+// write appends queued changes to the file and prunes it at most ten times per retention.
+// Write errors drop the queued changes; the feed is a best-effort hint, not a log.
+func (f *changesFeed) write() {
+	defer close(f.done)
+	for {
+		select {
+		case <-f.wake:
+			f.writePending()
+		case <-f.stop:
+			f.writePending()
+			return
+		}
+	}
+}
+
+// writePending appends the queued changes to the file and prunes it if it is due.
+func (f *changesFeed) writePending() {
+	f.mu.Lock()
+	pending, flushed := f.pending, f.flushed
+	f.pending, f.flushed = nil, nil
+	f.mu.Unlock()
+	if len(pending) > 0 {
+		f.append(pending)
+	}
+	if now := f.now(); now.Sub(f.prunedAt) >= f.retention/10 {
+		f.prune(now)
+		f.prunedAt = now
+	}
+	for _, done := range flushed {
+		close(done)
+	}
+}
+
+// append appends changes to the file with a single write.
+func (f *changesFeed) append(changes []Change) {
+	var buf bytes.Buffer
+	for _, change := range changes {
+		data, err := json.Marshal(change)
+		if err != nil {
+			continue
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	file.Write(buf.Bytes())
+}
+
+// prune rewrites the file without the records older than the retention.
+func (f *changesFeed) prune(now time.Time) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return
+	}
+	changes, err := parseChanges(data)
+	if err != nil {
+		return
+	}
+	cutoff := now.Add(-f.retention)
+	var buf bytes.Buffer
+	writer := bufio.NewWriter(&buf)
+	for _, change := range changes {
+		if change.Timestamp.Before(cutoff) {
+			continue
+		}
+		line, err := json.Marshal(change)
+		if err != nil {
+			continue
+		}
+		writer.Write(line)
+		writer.WriteByte('\n')
+	}
+	writer.Flush()
+	err = os.WriteFile(f.path+".tmp", buf.Bytes(), 0600)
+	if err != nil {
+		return
+	}
+	os.Rename(f.path+".tmp", f.path)
+}
+
+// feedChange adds the change of a key to the changes feed. The caller must hold the write lock.
+func (d *KeyValueStore) feedChange(key string, kind EventKind) {
+	if d.changesFeed == nil || kind == EventFailed {
+		return
+	}
+	change := Change{Key: key, Op: kind.String(), Timestamp: d.now()}
+	if node, ok := d.data[key]; ok && kind == EventSet {
+		change.Revision = node.Revision
+	}
+	d.changesFeed.add(change)
+}
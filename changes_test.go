@@ -0,0 +1,93 @@
+package goKeyValueStore_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func getTestStoreWithChangesFeed(t *testing.T, clock *fakeClock, path string) *goKeyValueStore.KeyValueStore {
+	store, err := goKeyValueStore.NewKeyValueStore(1, "", goKeyValueStore.WithClock(clock.Now),
+		goKeyValueStore.WithCleanerStopped(true), goKeyValueStore.WithChangesFeed(path, 10*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestChangesFeed(t *testing.T) {
+	clock := newFakeClock()
+	path := filepath.Join(t.TempDir(), "changes.ndjson")
+	store := getTestStoreWithChangesFeed(t, clock, path)
+	store.Set("a", "value", 1000)
+	store.Set("b", "value", 0)
+	store.Delete("b")
+	clock.Advance(2 * time.Second)
+	store.CleanNow()
+	store.FlushChangesFeed()
+	changes, err := goKeyValueStore.ReadChangesFeed(path, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := []string{}
+	for _, change := range changes {
+		ops = append(ops, change.Key+":"+change.Op)
+	}
+	expected := []string{"a:set", "b:set", "b:deleted", "a:expired"}
+	if len(ops) != len(expected) {
+		t.Fatalf("Expected changes %v, got %v", expected, ops)
+	}
+	for i := range expected {
+		if ops[i] != expected[i] {
+			t.Errorf("Expected changes %v, got %v", expected, ops)
+			break
+		}
+	}
+	if changes[0].Revision == 0 || !changes[0].Timestamp.Equal(clock.Now().Add(-2*time.Second)) {
+		t.Errorf("Expected revision and timestamp of the set, got %+v", changes[0])
+	}
+}
+
+func TestChangesFeedPrunes(t *testing.T) {
+	clock := newFakeClock()
+	path := filepath.Join(t.TempDir(), "changes.ndjson")
+	store := getTestStoreWithChangesFeed(t, clock, path)
+	store.Set("old", "value", 0)
+	store.FlushChangesFeed()
+	clock.Advance(11 * time.Minute)
+	store.Set("new", "value", 0)
+	store.FlushChangesFeed()
+	changes, err := goKeyValueStore.ReadChangesFeed(path, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Key != "new" {
+		t.Errorf("Expected only the new change to be kept, got %+v", changes)
+	}
+}
+
+func TestReadChangesFeed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "changes.ndjson")
+	feed := `{"key":"a","op":"set","timestamp":"2024-01-01T00:00:00Z","revision":1}
+{"key":"b","op":"set","timestamp":"2024-01-01T00:05:00Z","revision":2}
+{"key":"c","op":"se`
+	os.WriteFile(path, []byte(feed), 0600)
+	changes, err := goKeyValueStore.ReadChangesFeed(path, time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Key != "b" {
+		t.Errorf("Expected only b, got %+v", changes)
+	}
+	os.WriteFile(path, []byte("garbage\n"+feed), 0600)
+	if _, err := goKeyValueStore.ReadChangesFeed(path, time.Time{}); err == nil {
+		t.Error("Expected an error for a damaged line before the last line")
+	}
+	changes, err = goKeyValueStore.ReadChangesFeed(filepath.Join(t.TempDir(), "missing"), time.Time{})
+	if err != nil || len(changes) != 0 {
+		t.Errorf("Expected no changes for a missing file, got %v, %v", changes, err)
+	}
+}
@@ -0,0 +1,69 @@
+package goKeyValueStore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+)
+
+// ErrChecksumMismatch is returned when a cache file does not match the checksum saved in it.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// checksumPrefix names the algorithm of the checksums of cache files.
+const checksumPrefix = "crc32c:"
+
+// checksumPlaceholder is the checksum of a node while its checksum is computed.
+var checksumPlaceholder = []byte(checksumPrefix + "00000000")
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// marshalNode encodes a node with a checksum of its encoding. The checksum is computed over the encoding with
+// checksumPlaceholder as checksum, which is then replaced in place, so it covers every byte but its own.
+// For GzipCodec, the checksum covers the uncompressed encoding. Codecs that do not keep strings as they are
+// get no checksum.
+func (d *KeyValueStore) marshalNode(codec Codec, stored *node) ([]byte, error) {
+	if gzipCodec, ok := codec.(GzipCodec); ok {
+		data, err := d.marshalNode(gzipCodec.inner(), stored)
+		if err != nil {
+			return nil, err
+		}
+		return gzipCodec.compress(data)
+	}
+	stored.Checksum = string(checksumPlaceholder)
+	data, err := codec.Marshal(stored)
+	if err != nil {
+		return nil, err
+	}
+	i := bytes.LastIndex(data, checksumPlaceholder)
+	if i < 0 {
+		stored.Checksum = ""
+		return codec.Marshal(stored)
+	}
+	sum := crc32.Checksum(data, castagnoli)
+	hex.Encode(data[i+len(checksumPrefix):], binary.BigEndian.AppendUint32(nil, sum))
+	return data, nil
+}
+
+// verifyChecksum returns ErrChecksumMismatch if the encoding of a node does not match its checksum.
+func verifyChecksum(codec Codec, data []byte, checksum string) error {
+	if gzipCodec, ok := codec.(GzipCodec); ok {
+		data, err := gunzip(data)
+		if err != nil {
+			return err
+		}
+		return verifyChecksum(gzipCodec.inner(), data, checksum)
+	}
+	i := bytes.LastIndex(data, []byte(checksum))
+	if i < 0 || len(checksum) != len(checksumPlaceholder) {
+		return ErrChecksumMismatch
+	}
+	zeroed := bytes.Clone(data)
+	copy(zeroed[i:], checksumPlaceholder)
+	sum := crc32.Checksum(zeroed, castagnoli)
+	if checksum != checksumPrefix+hex.EncodeToString(binary.BigEndian.AppendUint32(nil, sum)) {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package goKeyValueStore_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestChecksumDetectsGarbledValue(t *testing.T) {
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, newFakeClock())
+	store.Set("key1", "value1", 0)
+	store.Set("key2", "value2", 0)
+	store.Close()
+	file, data := cacheFileOfKey(t, dir, "value1")
+	os.WriteFile(file, bytes.Replace(data, []byte("value1"), []byte("valuX1"), 1), 0600)
+
+	restarted := getTestStoreWithClock(t, dir, newFakeClock())
+	defer restarted.Close()
+	if value, ok := restarted.Get("key1"); ok {
+		t.Errorf("Expected the garbled entry not to be loaded, got %v", value)
+	}
+	if value, ok := restarted.Get("key2"); !ok || value != "value2" {
+		t.Errorf("Expected key2 to be loaded, got %v", value)
+	}
+	var errs []error
+	restarted.OnError(func(err error) {
+		errs = append(errs, err)
+	})
+	if len(errs) != 1 || !errors.Is(errs[0], goKeyValueStore.ErrCorruptFile) || !errors.Is(errs[0], goKeyValueStore.ErrChecksumMismatch) {
+		t.Errorf("Expected a checksum mismatch to be reported, got %v", errs)
+	}
+	if _, err := os.Stat(file + ".corrupt"); err != nil {
+		t.Errorf("Expected the garbled file to be kept aside, got %v", err)
+	}
+}
+
+func TestChecksumCompressed(t *testing.T) {
+	dir := t.TempDir()
+	store, err := goKeyValueStore.NewKeyValueStore(60, dir, goKeyValueStore.WithCompression(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("key1", "value1", 0)
+	store.Close()
+	store, err = goKeyValueStore.NewKeyValueStore(60, dir, goKeyValueStore.WithCompression(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if value, ok := store.Get("key1"); !ok || value != "value1" {
+		t.Errorf("Expected the compressed entry to be verified and loaded, got %v", value)
+	}
+}
+
+func TestChecksumMissingInOldFiles(t *testing.T) {
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, newFakeClock())
+	store.Set("key1", "value1", 0)
+	store.Close()
+	file, data := cacheFileOfKey(t, dir, "value1")
+	old := regexp.MustCompile(`,"checksum":"[^"]*"`).ReplaceAll(data, nil)
+	if bytes.Equal(old, data) {
+		t.Fatalf("Expected a checksum in %s", data)
+	}
+	os.WriteFile(file, old, 0600)
+
+	restarted := getTestStoreWithClock(t, dir, newFakeClock())
+	defer restarted.Close()
+	if value, ok := restarted.Get("key1"); !ok || value != "value1" {
+		t.Errorf("Expected a file without checksum to be loaded, got %v", value)
+	}
+}
+
+// cacheFileOfKey returns the name and content of the cache file in dir that contains value.
+func cacheFileOfKey(t *testing.T, dir string, value string) (string, []byte) {
+	t.Helper()
+	files, _ := filepath.Glob(filepath.Join(dir, "*.store.json"))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(data, []byte(value)) {
+			return file, data
+		}
+	}
+	t.Fatalf("Expected a file with %s", value)
+	return "", nil
+}
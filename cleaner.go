@@ -0,0 +1,156 @@
+package goKeyValueStore
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// A cleaner holds the state of the goroutine that deletes expired key-value pairs.
+type cleaner struct {
+	cleanerMu      sync.Mutex
+	cleanerStopped bool
+	cleanerStop    chan struct{}
+	cleanerDone    chan struct{}
+	sweepMu        sync.Mutex
+	sweeps         int64
+	lastReport     SweepReport
+	onSweep        func(SweepReport)
+}
+
+// A SweepReport describes a single pass of the cleaner or a call of CleanNow.
+// Key-value pairs with a deadline before Cutoff were considered expired.
+type SweepReport struct {
+	Started              time.Time
+	Finished             time.Time
+	Duration             time.Duration
+	Cutoff               time.Time
+	Examined             int
+	Expired              int
+	FileDeletions        int
+	FileDeletionFailures int
+}
+
+// StartCleaning starts the goroutine that deletes expired key-value pairs in the clean interval.
+// The cleaner is started by NewKeyValueStore unless WithCleanerStopped is used.
+// Calling StartCleaning while the cleaner is running or after Close does nothing.
+func (d *KeyValueStore) StartCleaning() {
+	d.lazyInit()
+	d.cleanerMu.Lock()
+	defer d.cleanerMu.Unlock()
+	if d.cleanerStop != nil || d.closed.Load() {
+		return
+	}
+	d.cleanerStop = make(chan struct{})
+	d.cleanerDone = make(chan struct{})
+	go d.clean(d.cleanerStop, d.cleanerDone)
+}
+
+// StopCleaning stops the cleaner and waits until a running sweep has finished.
+// Calling StopCleaning while the cleaner is not running does nothing.
+func (d *KeyValueStore) StopCleaning() {
+	d.lazyInit()
+	d.cleanerMu.Lock()
+	defer d.cleanerMu.Unlock()
+	if d.cleanerStop == nil {
+		return
+	}
+	close(d.cleanerStop)
+	<-d.cleanerDone
+	d.cleanerStop = nil
+	d.cleanerDone = nil
+}
+
+// CleanerStatus reports whether the cleaner is running and the time, duration, and number of
+// removed key-value pairs of the most recent sweep.
+func (d *KeyValueStore) CleanerStatus() (running bool, lastSweep time.Time, lastSweepDuration time.Duration, lastRemoved int) {
+	d.lazyInit()
+	d.cleanerMu.Lock()
+	running = d.cleanerStop != nil
+	d.cleanerMu.Unlock()
+	report, _ := d.LastSweep()
+	return running, report.Started, report.Duration, report.Expired
+}
+
+// LastSweep returns the report of the most recent sweep. The second return value is false if no sweep happened yet.
+func (d *KeyValueStore) LastSweep() (SweepReport, bool) {
+	d.lazyInit()
+	d.sweepMu.Lock()
+	defer d.sweepMu.Unlock()
+	return d.lastReport, d.sweeps > 0
+}
+
+// OnSweep sets a function that is called with the report of every sweep of the cleaner and of CleanNow.
+// It is called without holding any lock of the store, so it may use the store.
+func (d *KeyValueStore) OnSweep(fn func(SweepReport)) {
+	d.lazyInit()
+	d.sweepMu.Lock()
+	defer d.sweepMu.Unlock()
+	d.onSweep = fn
+}
+
+// CleanNow deletes all expired key-value pairs immediately and returns the report of the sweep.
+func (d *KeyValueStore) CleanNow() (SweepReport, error) {
+	d.lazyInit()
+	return d.sweep()
+}
+
+// clean deletes expired key-value pairs until stop is closed. The interval of cleaning is determined by
+// cleanInterval. A change of the interval takes effect immediately.
+func (d *KeyValueStore) clean(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	timer := time.NewTimer(time.Duration(d.cleanInterval.Load()))
+	defer timer.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-timer.C:
+			_, err := d.sweep()
+			if err != nil {
+				d.reportError(err)
+			}
+		case <-d.cleanReset:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		}
+		timer.Reset(time.Duration(d.cleanInterval.Load()))
+	}
+}
+
+// sweep deletes all expired key-value pairs, records the report for LastSweep, and calls the OnSweep function.
+func (d *KeyValueStore) sweep() (SweepReport, error) {
+	started := d.now()
+	cutoff := started.UnixMilli()
+	result, err := d.deleteWhere(func(node *node) bool {
+		return cutoff > node.DeleteTimestamp
+	}, EventExpired, "ttl elapsed")
+	err = errors.Join(err, d.compactSegments(false))
+	d.mu.RLock()
+	d.eviction.tick()
+	d.mu.RUnlock()
+	finished := d.now()
+	report := SweepReport{
+		Started:              started,
+		Finished:             finished,
+		Duration:             finished.Sub(started),
+		Cutoff:               time.UnixMilli(cutoff),
+		Examined:             result.examined,
+		Expired:              result.deleted,
+		FileDeletions:        result.fileDeletions,
+		FileDeletionFailures: result.fileFailures,
+	}
+	d.sweepMu.Lock()
+	d.sweeps++
+	d.lastReport = report
+	onSweep := d.onSweep
+	d.sweepMu.Unlock()
+	if onSweep != nil {
+		onSweep(report)
+	}
+	return report, err
+}
@@ -0,0 +1,196 @@
+package goKeyValueStore_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("Condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStartCleaningTwiceRunsOneLoop(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(0.05, "", goKeyValueStore.WithCleanerStopped(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if running, _, _, _ := store.CleanerStatus(); running {
+		t.Error("Expected cleaner to be stopped")
+	}
+	store.StartCleaning()
+	store.StartCleaning()
+	time.Sleep(500 * time.Millisecond)
+	store.StopCleaning()
+	sweeps := store.Sweeps()
+	if sweeps < 5 || sweeps > 11 {
+		t.Errorf("Expected about 10 sweeps of a single loop, got %d", sweeps)
+	}
+}
+
+func TestStopCleaningHaltsSweeps(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(0.01, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return store.Sweeps() > 0 })
+	store.StopCleaning()
+	store.StopCleaning()
+	if running, _, _, _ := store.CleanerStatus(); running {
+		t.Error("Expected cleaner to be stopped")
+	}
+	sweeps := store.Sweeps()
+	time.Sleep(100 * time.Millisecond)
+	if store.Sweeps() != sweeps {
+		t.Errorf("Expected no sweeps after StopCleaning, got %d more", store.Sweeps()-sweeps)
+	}
+}
+
+func TestCleanerStatus(t *testing.T) {
+	clock := newFakeClock()
+	store, err := goKeyValueStore.NewKeyValueStore(0.2, "", goKeyValueStore.WithClock(clock.Now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.StopCleaning()
+	store.Set("key1", "value1", 1000)
+	store.Set("key2", "value2", 1000)
+	store.Set("key3", "value3", 0)
+	clock.Advance(2 * time.Second)
+	var running bool
+	var duration time.Duration
+	var removed int
+	waitFor(t, func() bool {
+		var lastSweep time.Time
+		running, lastSweep, duration, removed = store.CleanerStatus()
+		return lastSweep.Equal(clock.Now())
+	})
+	if !running {
+		t.Error("Expected cleaner to be running")
+	}
+	if duration != 0 {
+		t.Errorf("Expected sweep duration of 0 on the fake clock, got %s", duration)
+	}
+	if removed != 2 {
+		t.Errorf("Expected 2 removed keys, got %d", removed)
+	}
+	if store.Length() != 1 {
+		t.Errorf("Expected length to be 1, got %d", store.Length())
+	}
+}
+
+func TestCleanNowReport(t *testing.T) {
+	clock := newFakeClock()
+	store := getTestStoreWithClock(t, t.TempDir(), clock)
+	if _, ok := store.LastSweep(); ok {
+		t.Error("Expected no sweep report before the first sweep")
+	}
+	store.Set("key1", "value1", 1000)
+	store.Set("key2", "value2", 1000)
+	store.Set("key3", "value3", 5000)
+	store.Set("key4", "value4", 0)
+	clock.Advance(2 * time.Second)
+	report, err := store.CleanNow()
+	if err != nil {
+		t.Error(err)
+	}
+	if report.Examined != 4 || report.Expired != 2 || report.FileDeletions != 2 || report.FileDeletionFailures != 0 {
+		t.Errorf("Unexpected sweep report %+v", report)
+	}
+	if !report.Started.Equal(clock.Now()) || !report.Cutoff.Equal(clock.Now()) || report.Duration != 0 {
+		t.Errorf("Expected the report to use the fake clock, got %+v", report)
+	}
+	last, ok := store.LastSweep()
+	if !ok || last != report {
+		t.Errorf("Expected LastSweep to return the report, got %+v", last)
+	}
+}
+
+func TestOnSweepCalledWithoutLock(t *testing.T) {
+	clock := newFakeClock()
+	store, err := goKeyValueStore.NewKeyValueStore(0.01, "", goKeyValueStore.WithClock(clock.Now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.StopCleaning()
+	reports := make(chan goKeyValueStore.SweepReport, 100)
+	store.OnSweep(func(report goKeyValueStore.SweepReport) {
+		store.Set("sweeps", report.Started, 0) // deadlocks if the write lock is held
+		reports <- report
+	})
+	store.Set("key1", "value1", 1000)
+	clock.Advance(2 * time.Second)
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case report := <-reports:
+			if report.Expired == 1 {
+				return
+			}
+		case <-deadline:
+			t.Fatal("Expected a sweep report with the expired key")
+		}
+	}
+}
+
+func TestSweepKeepsFilesOfKeysSetDuringSweep(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, clock)
+	fileName := filepath.Join(dir, fmt.Sprintf("%x.store.json", sha256.Sum256([]byte("key"))))
+	for i := 0; i < 200; i++ {
+		store.Set("key", i, 10)
+		clock.Advance(20 * time.Millisecond)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			store.Set("key", i, 10)
+		}()
+		go func() {
+			defer wg.Done()
+			store.CleanNow()
+		}()
+		wg.Wait()
+		if _, ok := store.Get("key"); !ok {
+			continue
+		}
+		if _, err := os.Stat(fileName); err != nil {
+			t.Fatalf("Expected the file of a live key to exist in iteration %d: %v", i, err)
+		}
+	}
+}
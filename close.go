@@ -0,0 +1,49 @@
+package goKeyValueStore
+
+import "errors"
+
+// ErrClosed is returned by writes to a store after Close.
+var ErrClosed = errors.New("store is closed")
+
+// Close stops all goroutines of the store: the cleaner, FollowChanges, WithWarmup, and the writers of
+// WithChangesFeed, WithAudit, WithWriteBehind, and WithFlushInterval, which write their queued records and files
+// first. Afterwards writes return ErrClosed and reads find no keys. The cache folder is left as it is, so a new
+// store on the folder has all entries. The backend of WithBackend is closed and its error returned. Calling Close
+// again does nothing.
+func (d *KeyValueStore) Close() error {
+	d.lazyInit()
+	d.mu.Lock()
+	if d.closed.Load() {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed.Store(true)
+	d.mu.Unlock()
+	d.StopCleaning()
+	if d.revalidator != nil {
+		d.revalidator.cancel()
+	}
+	d.followMu.Lock()
+	followers := d.followers
+	d.followers = nil
+	d.followMu.Unlock()
+	for _, stop := range followers {
+		stop()
+	}
+	d.background.Wait()
+	d.stopReadView()
+	if d.changesFeed != nil {
+		d.changesFeed.close()
+	}
+	if d.auditLog != nil {
+		d.auditLog.close()
+	}
+	if d.writeBehind != nil {
+		d.writeBehind.close()
+		d.afterWrite()
+	}
+	if d.backend != nil {
+		return d.backend.Close()
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package goKeyValueStore_test
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestCloseStopsGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 5; i++ {
+		dir := t.TempDir()
+		store, err := goKeyValueStore.NewKeyValueStore(0.01, dir,
+			goKeyValueStore.WithChangesFeed(filepath.Join(t.TempDir(), "changes"), time.Hour),
+			goKeyValueStore.WithAudit([]string{""}, &bytes.Buffer{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.Set("key", "value", 0)
+		store.FollowChanges(time.Millisecond)
+		if err := store.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(t, func() bool { return runtime.NumGoroutine() <= before })
+}
+
+func TestCloseRejectsOperations(t *testing.T) {
+	dir := t.TempDir()
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("key", "value", 0)
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Errorf("Expected a second Close to succeed, got %v", err)
+	}
+	if err := store.Set("key2", "value2", 0); !errors.Is(err, goKeyValueStore.ErrClosed) {
+		t.Errorf("Expected ErrClosed from Set, got %v", err)
+	}
+	if err := store.Delete("key"); !errors.Is(err, goKeyValueStore.ErrClosed) {
+		t.Errorf("Expected ErrClosed from Delete, got %v", err)
+	}
+	if _, ok := store.Get("key"); ok {
+		t.Errorf("Expected Get to find no keys after Close")
+	}
+	if running, _, _, _ := store.CleanerStatus(); running {
+		t.Errorf("Expected the cleaner to be stopped")
+	}
+	store.StartCleaning()
+	if running, _, _, _ := store.CleanerStatus(); running {
+		t.Errorf("Expected StartCleaning to do nothing after Close")
+	}
+	reopened, err := goKeyValueStore.NewKeyValueStore(0.5, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if value, ok := reopened.Get("key"); !ok || value != "value" {
+		t.Errorf("Expected the cache folder to be kept, got %v", value)
+	}
+}
+
+func TestCloseWritesQueuedAuditRecords(t *testing.T) {
+	var out bytes.Buffer
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, "", goKeyValueStore.WithAudit([]string{""}, &out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("key", "value", 0)
+	store.Close()
+	if !bytes.Contains(out.Bytes(), []byte(`"key":"key"`)) {
+		t.Errorf("Expected the audit record to be written on Close, got %q", out.String())
+	}
+}
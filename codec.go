@@ -0,0 +1,290 @@
+package goKeyValueStore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// ErrCodecMismatch is returned by NewKeyValueStore if the cache folder holds files written with another codec.
+var ErrCodecMismatch = errors.New("cache folder holds files of another codec")
+
+// A Codec encodes the entries saved in the cache folder. Marshal and Unmarshal are called with a pointer to the
+// record of an entry, a struct with exported fields for the key, the value, and the metadata of the entry.
+// The extension is part of the names of the cache files, so folders with files of different codecs are detected.
+type Codec interface {
+	Extension() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec encodes entries as JSON. It is the default codec. Numbers in values are decoded as float64 unless
+// WithUseNumber is enabled, and structs are decoded as maps unless their type is registered with WithType.
+type JSONCodec struct{}
+
+// Extension returns "json".
+func (JSONCodec) Extension() string {
+	return "json"
+}
+
+// Marshal encodes v as JSON.
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes the JSON data into v.
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// GobCodec encodes entries with encoding/gob, so values keep their Go types, including structs, int64, and
+// time.Time, across restarts. The concrete types of values other than basic types, slices of them, []any, and
+// map[string]any must be registered with WithType or gob.Register before they are set or loaded; a file with a
+// value of an unregistered type fails the start of the store. Like JSON, gob only encodes exported fields of
+// structs, and empty slices and maps are decoded as nil. Files can not be read after incompatible changes of
+// the struct types of their values, e.g. a field that changed its type. It can not be combined with
+// WithPackedSmallValues.
+type GobCodec struct{}
+
+func init() {
+	gob.Register([]any{})
+	gob.Register(map[string]any{})
+}
+
+// Extension returns "gob".
+func (GobCodec) Extension() string {
+	return "gob"
+}
+
+// Marshal encodes v with gob.
+func (GobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes the gob data into v.
+func (GobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// GzipCodec compresses the encoding of another codec with gzip. Its extension is the extension of the other
+// codec followed by ".gz". Use WithCompression to compress the files of a store.
+type GzipCodec struct {
+	// Codec encodes the entries before they are compressed. A nil Codec means JSONCodec.
+	Codec Codec
+	// Level is the compression level of compress/gzip. A value of 0 means gzip.DefaultCompression.
+	Level int
+}
+
+// inner returns the codec whose encoding is compressed.
+func (c GzipCodec) inner() Codec {
+	if c.Codec == nil {
+		return JSONCodec{}
+	}
+	return c.Codec
+}
+
+// Extension returns the extension of the compressed codec followed by ".gz".
+func (c GzipCodec) Extension() string {
+	return c.inner().Extension() + ".gz"
+}
+
+// Marshal encodes v with the compressed codec and compresses the result.
+func (c GzipCodec) Marshal(v any) ([]byte, error) {
+	data, err := c.inner().Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return c.compress(data)
+}
+
+// compress compresses data at the level of the codec.
+func (c GzipCodec) compress(data []byte) ([]byte, error) {
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	var buf bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	_, err = writer.Write(data)
+	if err != nil {
+		return nil, err
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decompresses data and decodes it into v with the compressed codec.
+func (c GzipCodec) Unmarshal(data []byte, v any) error {
+	data, err := gunzip(data)
+	if err != nil {
+		return err
+	}
+	return c.inner().Unmarshal(data, v)
+}
+
+// gunzip decompresses gzip data.
+func gunzip(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// useCompression wraps the codec of the store in a GzipCodec if WithCompression is used. Uncompressed files of
+// the codec are read as files of a legacy codec.
+func (d *KeyValueStore) useCompression() {
+	if !d.compress {
+		return
+	}
+	d.legacyCodecs = append(d.legacyCodecs, d.codec)
+	d.codec = GzipCodec{Codec: d.codec, Level: d.compressionLevel}
+}
+
+// unmarshal decodes data with a codec. If WithUseNumber is enabled, numbers in values are decoded by the JSON
+// codec as json.Number instead of float64, even if the JSON is compressed.
+func (d *KeyValueStore) unmarshal(codec Codec, data []byte, v any) error {
+	switch codec := codec.(type) {
+	case GzipCodec:
+		data, err := gunzip(data)
+		if err != nil {
+			return err
+		}
+		return d.unmarshal(codec.inner(), data, v)
+	case JSONCodec:
+		if d.useNumber {
+			decoder := json.NewDecoder(bytes.NewReader(data))
+			decoder.UseNumber()
+			return decoder.Decode(v)
+		}
+	}
+	return codec.Unmarshal(data, v)
+}
+
+// cacheFileSuffix returns the suffix of the names of cache files written with the codec of the store.
+func (d *KeyValueStore) cacheFileSuffix() string {
+	return ".store." + d.codec.Extension()
+}
+
+// cacheFileExtension returns the codec extension of a name with ".store.<extension>" and false for other names
+// and temporary files.
+func cacheFileExtension(name string) (string, bool) {
+	_, extension, ok := strings.Cut(name, ".store.")
+	if !ok || extension == "" || strings.HasSuffix(extension, ".tmp") || strings.HasSuffix(extension, corruptSuffix) {
+		return "", false
+	}
+	return extension, true
+}
+
+// checkCodec returns ErrCodecMismatch if the cache folder holds cache files of a codec that is neither the
+// codec of the store nor a legacy codec.
+func (d *KeyValueStore) checkCodec() error {
+	if d.packing != nil && d.codec != (JSONCodec{}) {
+		return errors.New("packed small values can only be combined with the JSON codec")
+	}
+	entries, err := d.fs.ReadDir(d.cacheFolder)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		hash, _, _ := strings.Cut(entry.Name(), ".")
+		extension, ok := cacheFileExtension(entry.Name())
+		if _, err := hex.DecodeString(hash); ok && err == nil && len(hash) == 64 && extension != d.codec.Extension() &&
+			d.legacyCodec(extension) == nil {
+			return fmt.Errorf("%w: found %s, expected %s", ErrCodecMismatch, entry.Name(), d.codec.Extension())
+		}
+	}
+	return nil
+}
+
+// legacyCodec returns the legacy codec with the extension or nil.
+func (d *KeyValueStore) legacyCodec(extension string) Codec {
+	for _, codec := range d.legacyCodecs {
+		if codec.Extension() == extension && extension != d.codec.Extension() {
+			return codec
+		}
+	}
+	return nil
+}
+
+// migrateLegacyFiles rewrites the cache files of legacy codecs with the codec of the store and removes them.
+// If a key has a file of the codec of the store as well, that file wins.
+func (d *KeyValueStore) migrateLegacyFiles() error {
+	if len(d.legacyCodecs) == 0 {
+		return nil
+	}
+	entries, err := d.fs.ReadDir(d.cacheFolder)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		extension, ok := cacheFileExtension(entry.Name())
+		if !ok {
+			continue
+		}
+		codec := d.legacyCodec(extension)
+		if codec == nil {
+			continue
+		}
+		err := d.migrateFile(codec, entry.Name())
+		if err != nil {
+			return fmt.Errorf("failed to migrate cache file %s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// migrateFile rewrites a cache file of a legacy codec with the codec of the store and removes it.
+// The entry is copied as it was saved, so transforms of WithPersistTransform are not applied again.
+func (d *KeyValueStore) migrateFile(codec Codec, name string) error {
+	legacyName := filepath.Join(d.cacheFolder, name)
+	fileName := legacyName[:len(legacyName)-len(codec.Extension())] + d.codec.Extension()
+	_, err := d.fs.Stat(fileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		data, err := d.readCacheFile(legacyName)
+		if err != nil {
+			return err
+		}
+		var stored node
+		err = d.unmarshal(codec, data, &stored)
+		if err == nil && stored.Checksum != "" {
+			err = verifyChecksum(codec, data, stored.Checksum)
+		}
+		if err != nil {
+			return err
+		}
+		data, err = d.marshalNode(d.codec, &stored)
+		if err != nil {
+			return err
+		}
+		err = d.writeFileAtomic(fileName, data)
+		if err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	return d.diskOp(func() error {
+		return d.fs.Remove(legacyName)
+	})
+}
@@ -0,0 +1,204 @@
+package goKeyValueStore_test
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestCodecs(t *testing.T) {
+	gob.Register(time.Time{})
+	created := time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC)
+	tests := []struct {
+		codec   goKeyValueStore.Codec
+		pattern string
+		number  any
+		time    any
+	}{
+		{goKeyValueStore.JSONCodec{}, "*.store.json", float64(math.MaxInt64), created.Format(time.RFC3339Nano)},
+		{goKeyValueStore.GobCodec{}, "*.store.gob", int64(math.MaxInt64), created},
+	}
+	for _, test := range tests {
+		t.Run(test.codec.Extension(), func(t *testing.T) {
+			dir := t.TempDir()
+			store, err := goKeyValueStore.NewKeyValueStore(60, dir, goKeyValueStore.WithCodec(test.codec))
+			if err != nil {
+				t.Fatal(err)
+			}
+			store.Set("number", int64(math.MaxInt64), 0)
+			store.Set("time", created, 0)
+			store.Set("map", map[string]any{"a": "b"}, 0)
+			store.Close()
+			files, _ := filepath.Glob(filepath.Join(dir, test.pattern))
+			if len(files) != 3 {
+				t.Errorf("Expected 3 files matching %s, got %d", test.pattern, len(files))
+			}
+
+			store, err = goKeyValueStore.NewKeyValueStore(60, dir, goKeyValueStore.WithCodec(test.codec))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+			if value, _ := store.Get("number"); value != test.number {
+				t.Errorf("Expected number to be %#v, got %#v", test.number, value)
+			}
+			if value, _ := store.Get("time"); value != test.time {
+				t.Errorf("Expected time to be %#v, got %#v", test.time, value)
+			}
+			if value, _ := store.Get("map"); value.(map[string]any)["a"] != "b" {
+				t.Errorf("Expected map to be restored, got %#v", value)
+			}
+		})
+	}
+}
+
+func TestCodecMismatch(t *testing.T) {
+	dir := t.TempDir()
+	store, err := goKeyValueStore.NewKeyValueStore(60, dir, goKeyValueStore.WithCodec(goKeyValueStore.GobCodec{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("key1", "value1", 0)
+	store.Close()
+	_, err = goKeyValueStore.NewKeyValueStore(60, dir)
+	if !errors.Is(err, goKeyValueStore.ErrCodecMismatch) {
+		t.Errorf("Expected ErrCodecMismatch, got %v", err)
+	}
+}
+
+func TestGobCodecWithIndex(t *testing.T) {
+	dir := t.TempDir()
+	opts := []goKeyValueStore.Option{goKeyValueStore.WithCodec(goKeyValueStore.GobCodec{}), goKeyValueStore.WithIndex(true)}
+	store, err := goKeyValueStore.NewKeyValueStore(60, dir, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("key1", int64(1), 0)
+	store.Close()
+	store, err = goKeyValueStore.NewKeyValueStore(60, dir, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if value, _ := store.Get("key1"); value != int64(1) {
+		t.Errorf("Expected int64 1, got %#v", value)
+	}
+}
+
+func TestGobCodecRestoresStructs(t *testing.T) {
+	dir := t.TempDir()
+	opts := []goKeyValueStore.Option{
+		goKeyValueStore.WithCodec(goKeyValueStore.GobCodec{}),
+		goKeyValueStore.WithType("profile", profile{}),
+		goKeyValueStore.WithType("address", &address{}),
+	}
+	store, err := goKeyValueStore.NewKeyValueStore(60, dir, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := profile{
+		Name:    "Ada",
+		Age:     math.MaxInt64,
+		Created: time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC),
+		Tags:    []string{"a", "b"},
+		Scores:  map[string]float64{"x": 1.5},
+	}
+	err = store.Set("profile", want, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Set("address", &address{City: "Zurich"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	store, err = goKeyValueStore.NewKeyValueStore(60, dir, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	value, _ := store.Get("profile")
+	got, ok := value.(profile)
+	if !ok {
+		t.Fatalf("Expected a profile, got %T", value)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+	value, _ = store.Get("address")
+	if got, ok := value.(*address); !ok || got.City != "Zurich" {
+		t.Errorf("Expected an *address in Zurich, got %#v", value)
+	}
+}
+
+func TestWithCompression(t *testing.T) {
+	dir := t.TempDir()
+	store, err := goKeyValueStore.NewKeyValueStore(60, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := strings.Repeat(`{"id":1,"name":"compressible"},`, 1000)
+	store.Set("legacy", payload, 0)
+	store.Close()
+	files, _ := filepath.Glob(filepath.Join(dir, "*.store.json"))
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 JSON file, got %d", len(files))
+	}
+	info, err := os.Stat(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	uncompressed := info.Size()
+
+	store, err = goKeyValueStore.NewKeyValueStore(60, dir, goKeyValueStore.WithCompression(0), goKeyValueStore.WithUseNumber(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := store.Get("legacy"); value != payload {
+		t.Errorf("Expected the legacy file to be read")
+	}
+	store.Set("number", int64(math.MaxInt64), 0)
+	store.Close()
+	files, _ = filepath.Glob(filepath.Join(dir, "*.store.json.gz"))
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 compressed files, got %d", len(files))
+	}
+	if legacy, _ := filepath.Glob(filepath.Join(dir, "*.store.json")); len(legacy) != 0 {
+		t.Errorf("Expected the legacy file to be compressed, got %d uncompressed files", len(legacy))
+	}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() >= uncompressed/10 {
+			t.Errorf("Expected %s to be smaller than a tenth of %d bytes, got %d", file, uncompressed, info.Size())
+		}
+	}
+
+	store, err = goKeyValueStore.NewKeyValueStore(60, dir, goKeyValueStore.WithCompression(9), goKeyValueStore.WithUseNumber(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if value, _ := store.Get("legacy"); value != payload {
+		t.Errorf("Expected the payload to survive compression")
+	}
+	if value, _ := store.Get("number"); value != json.Number("9223372036854775807") {
+		t.Errorf("Expected the exact number, got %#v", value)
+	}
+	_, err = goKeyValueStore.NewKeyValueStore(60, dir)
+	if !errors.Is(err, goKeyValueStore.ErrCodecMismatch) {
+		t.Errorf("Expected ErrCodecMismatch without compression, got %v", err)
+	}
+}
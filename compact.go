@@ -0,0 +1,140 @@
+package goKeyValueStore
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// A CompactReport describes a call of Compact. BytesReclaimed is the difference of the size of the files of the
+// store before and after, so it includes the effect of writes that ran at the same time.
+type CompactReport struct {
+	Expired          int
+	OrphansRemoved   int
+	TempFilesRemoved int
+	BytesReclaimed   int64
+}
+
+// Compact reclaims disk space in the cache folder. It deletes expired key-value pairs like CleanNow, removes
+// cache files that belong to no key in the store, e.g. files copied into the folder or left by a crash, removes
+// stale temporary files like Housekeep, and rewrites the segments of WithPackedSmallValues and WithAppendLog.
+// Files marked as corrupt on start are kept. The cache folder is scanned without holding the lock of the store,
+// so reads and writes continue during the scan.
+func (d *KeyValueStore) Compact() (CompactReport, error) {
+	d.lazyInit()
+	if d.cacheFolder == "" {
+		return CompactReport{}, nil
+	}
+	before, err := d.folderSize()
+	if err != nil {
+		return CompactReport{}, err
+	}
+	sweep, err := d.sweep()
+	report := CompactReport{Expired: sweep.Expired}
+	var errs []error
+	if err != nil {
+		errs = append(errs, err)
+	}
+	report.OrphansRemoved, err = d.removeOrphans()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	report.TempFilesRemoved, err = d.Housekeep()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	err = d.compactSegments(true)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	after, err := d.folderSize()
+	if err != nil {
+		errs = append(errs, err)
+	} else {
+		report.BytesReclaimed = before - after
+	}
+	return report, errors.Join(errs...)
+}
+
+// removeOrphans removes the cache files of the codec of the store that belong to no key or to a packed key and
+// returns their number. Each file is read without the lock and removed under it if it is still an orphan.
+func (d *KeyValueStore) removeOrphans() (int, error) {
+	entries, err := d.fs.ReadDir(d.cacheFolder)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	var errs []error
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), d.cacheFileSuffix()) {
+			continue
+		}
+		path := filepath.Join(d.cacheFolder, entry.Name())
+		data, err := d.readCacheFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		var stored node
+		if d.decodeNode(data, &stored) == nil {
+			fileName, err := d.getFileName(stored.Key)
+			if err != nil || fileName != path {
+				stored.Key = "" // the file is not at the place of its key
+			}
+		}
+		ok, err := d.removeOrphan(path, stored.Key)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if ok {
+			removed++
+		}
+	}
+	return removed, errors.Join(errs...)
+}
+
+// removeOrphan removes the file at path unless it is the current file of key.
+func (d *KeyValueStore) removeOrphan(path string, key string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed.Load() {
+		return false, ErrClosed
+	}
+	if _, ok := d.data[key]; ok && key != "" && !d.isPacked(key) {
+		return false, nil
+	}
+	err := d.diskOp(func() error {
+		return d.fs.Remove(path)
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if d.packing != nil {
+		delete(d.packing.loose, key)
+	}
+	return true, nil
+}
+
+// folderSize returns the size of all files in the cache folder.
+func (d *KeyValueStore) folderSize() (int64, error) {
+	entries, err := d.fs.ReadDir(d.cacheFolder)
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue // removed since the directory was read
+		}
+		size += info.Size()
+	}
+	return size, nil
+}
@@ -0,0 +1,50 @@
+package goKeyValueStore_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCompact(t *testing.T) {
+	dir := t.TempDir()
+	clock := newFakeClock()
+	store := getTestStoreWithClock(t, dir, clock)
+	store.Set("live", "value", 0)
+	store.Set("expired", "value", 1000)
+	store.Close()
+	clock.Advance(2 * time.Second)
+	store = getTestStoreWithClock(t, dir, clock)
+	defer store.Close()
+
+	// a file of a key that is not in the store, a file of a live key at the name of another key, and a
+	// temporary file of a crashed write
+	orphan, orphanData := cacheFileOf(t, "orphan", "value")
+	os.WriteFile(filepath.Join(dir, orphan), orphanData, 0600)
+	_, liveData := cacheFileOf(t, "live", "value")
+	misplaced, _ := cacheFileOf(t, "misplaced", "value")
+	os.WriteFile(filepath.Join(dir, misplaced), liveData, 0600)
+	temp := filepath.Join(dir, orphan+".tmp")
+	os.WriteFile(temp, orphanData, 0600)
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(temp, old, old)
+
+	report, err := store.Compact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Expired != 1 || report.OrphansRemoved != 2 || report.TempFilesRemoved != 1 || report.BytesReclaimed <= 0 {
+		t.Errorf("Expected 1 expired entry, 2 orphans, 1 temporary file, and reclaimed bytes, got %+v", report)
+	}
+	if count := countCacheFiles(t, dir); count != 1 {
+		t.Errorf("Expected only the file of the live key to be left, got %d files", count)
+	}
+	if value, ok := store.Get("live"); !ok || value != "value" {
+		t.Errorf("Expected the live key to be kept, got %v", value)
+	}
+	report, err = store.Compact()
+	if err != nil || report.OrphansRemoved != 0 || report.Expired != 0 {
+		t.Errorf("Expected a second compaction to find nothing, got %+v, %v", report, err)
+	}
+}
@@ -0,0 +1,164 @@
+package goKeyValueStore
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// ErrImmutableSetting is returned by Reconfigure for settings that can not be changed at runtime.
+var ErrImmutableSetting = errors.New("setting can not be changed at runtime")
+
+// Config is the effective configuration of a KeyValueStore.
+type Config struct {
+	CleanInterval  time.Duration
+	CacheFolder    string
+	Index          bool
+	MaxValueSize   int
+	MaxEntries     int
+	MaxBytes       int64
+	EvictionPolicy EvictionPolicy
+	KeyRedaction   RedactionMode
+	// WriteRateLimit is the number of writes per second or 0 if writes are not limited.
+	WriteRateLimit  int
+	WriteBurst      int
+	RateLimitPolicy RateLimitPolicy
+	// InstanceID identifies the store, see WithInstanceID, and RunID the store since it was created.
+	InstanceID string
+	RunID      string
+}
+
+// ConfigPatch describes changes to the configuration of a KeyValueStore. Nil fields are left unchanged.
+// CacheFolder, Index, and Codec can not be changed at runtime and are only accepted if they match the current
+// value.
+type ConfigPatch struct {
+	CleanInterval *time.Duration
+	CacheFolder   *string
+	Index         *bool
+	Codec         Codec
+	MaxValueSize  *int
+	MaxEntries    *int
+	MaxBytes      *int64
+	// EvictionPolicy replaces the eviction policy. The new policy starts with the order in which entries were set.
+	EvictionPolicy *EvictionPolicy
+	// WriteRateLimit, WriteBurst, and RateLimitPolicy replace the write rate limit.
+	// A WriteRateLimit of 0 removes the limit.
+	WriteRateLimit  *int
+	WriteBurst      *int
+	RateLimitPolicy *RateLimitPolicy
+}
+
+// Config returns the effective configuration of the store.
+func (d *KeyValueStore) Config() Config {
+	d.lazyInit()
+	config := Config{
+		CleanInterval: time.Duration(d.cleanInterval.Load()),
+		CacheFolder:   d.cacheFolder,
+		Index:         d.useIndex,
+		MaxValueSize:  int(d.maxValueSize.Load()),
+		MaxEntries:    int(d.maxEntries.Load()),
+		MaxBytes:      d.maxBytes.Load(),
+		KeyRedaction:  d.redaction,
+		InstanceID:    d.instanceID,
+		RunID:         d.runID,
+	}
+	d.mu.RLock()
+	config.EvictionPolicy = d.evictionPolicy
+	d.mu.RUnlock()
+	if limiter := d.rateLimiter.Load(); limiter != nil {
+		config.WriteRateLimit = limiter.opsPerSecond
+		config.WriteBurst = limiter.burst
+		config.RateLimitPolicy = limiter.policy
+	}
+	return config
+}
+
+// Reconfigure changes the configuration of the store at runtime. A changed clean interval takes effect
+// immediately and a lowered maximum number of entries or bytes evicts entries before Reconfigure returns.
+// If the patch changes an immutable setting, ErrImmutableSetting is returned and nothing is changed.
+func (d *KeyValueStore) Reconfigure(changes ConfigPatch) error {
+	d.lazyInit()
+	if changes.CacheFolder != nil {
+		cacheFolder, err := resolveCacheFolder(*changes.CacheFolder)
+		if err != nil || cacheFolder != d.cacheFolder {
+			return fmt.Errorf("cache folder: %w", ErrImmutableSetting)
+		}
+	}
+	if changes.Index != nil && *changes.Index != d.useIndex {
+		return fmt.Errorf("index: %w", ErrImmutableSetting)
+	}
+	if changes.Codec != nil && !reflect.DeepEqual(changes.Codec, d.codec) {
+		return fmt.Errorf("codec: %w", ErrImmutableSetting)
+	}
+	if changes.CleanInterval != nil && *changes.CleanInterval <= 0 {
+		return errors.New("clean interval must be positive")
+	}
+	if changes.MaxValueSize != nil {
+		d.maxValueSize.Store(int64(*changes.MaxValueSize))
+	}
+	if changes.WriteRateLimit != nil || changes.WriteBurst != nil || changes.RateLimitPolicy != nil {
+		config := d.Config()
+		if changes.WriteRateLimit != nil {
+			config.WriteRateLimit = *changes.WriteRateLimit
+		}
+		if changes.WriteBurst != nil {
+			config.WriteBurst = *changes.WriteBurst
+		}
+		if changes.RateLimitPolicy != nil {
+			config.RateLimitPolicy = *changes.RateLimitPolicy
+		}
+		d.setRateLimit(config.WriteRateLimit, config.WriteBurst, config.RateLimitPolicy)
+	}
+	if changes.CleanInterval != nil {
+		d.cleanInterval.Store(int64(*changes.CleanInterval))
+		select {
+		case d.cleanReset <- struct{}{}:
+		default:
+		}
+	}
+	if changes.MaxEntries != nil || changes.MaxBytes != nil || changes.EvictionPolicy != nil {
+		if changes.MaxEntries != nil {
+			d.maxEntries.Store(int64(*changes.MaxEntries))
+		}
+		defer d.afterWrite()
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if changes.MaxBytes != nil {
+			d.setMaxBytes(*changes.MaxBytes)
+		}
+		if changes.EvictionPolicy != nil {
+			d.setEvictionPolicy(*changes.EvictionPolicy)
+		}
+		return d.evictOverflow("")
+	}
+	return nil
+}
+
+// setMaxBytes replaces the maximum size of all entries. Sizes are only tracked while a limit is set, so setting
+// the first limit computes the sizes of all entries and removing the limit forgets them. The caller must hold the
+// write lock.
+func (d *KeyValueStore) setMaxBytes(maxBytes int64) {
+	tracked := d.maxBytes.Load() > 0
+	d.maxBytes.Store(maxBytes)
+	if tracked == (maxBytes > 0) {
+		return
+	}
+	d.bytes = 0
+	for _, node := range d.data {
+		node.encodedSize = 0
+		if maxBytes > 0 {
+			node.encodedSize = encodedSize(node)
+			d.bytes += node.encodedSize
+		}
+	}
+}
+
+// setRateLimit replaces the write rate limiter. An opsPerSecond of 0 removes the limit.
+func (d *KeyValueStore) setRateLimit(opsPerSecond int, burst int, policy RateLimitPolicy) {
+	if opsPerSecond <= 0 {
+		d.rateLimiter.Store(nil)
+		return
+	}
+	d.rateLimiter.Store(newRateLimiter(opsPerSecond, burst, policy))
+}
@@ -0,0 +1,153 @@
+package goKeyValueStore_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestConfig(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, CACHE_DIR, goKeyValueStore.WithMaxEntries(10), goKeyValueStore.WithMaxValueSize(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := store.Config()
+	if config.CleanInterval != 500*time.Millisecond {
+		t.Errorf("Expected clean interval to be 500ms, got %s", config.CleanInterval)
+	}
+	if absolute, _ := filepath.Abs(CACHE_DIR); config.CacheFolder != absolute {
+		t.Errorf("Expected cache folder to be %s, got %s", absolute, config.CacheFolder)
+	}
+	if config.MaxEntries != 10 || config.MaxValueSize != 100 {
+		t.Errorf("Expected max entries 10 and max value size 100, got %+v", config)
+	}
+}
+
+func TestMaxEntriesEvictsNearestExpiry(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, t.TempDir(), goKeyValueStore.WithMaxEntries(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("key1", "value1", 0)
+	store.Set("key2", "value2", 1000)
+	store.Set("key3", "value3", 2000)
+	if store.Length() != 2 {
+		t.Errorf("Expected length to be 2, got %d", store.Length())
+	}
+	if _, ok := store.Get("key2"); ok {
+		t.Errorf("Expected key2 to be evicted")
+	}
+}
+
+func TestReconfigureMaxEntriesEvicts(t *testing.T) {
+	store := getTestStore()
+	store.Set("key4", "value4", 0)
+	maxEntries := 1
+	err := store.Reconfigure(goKeyValueStore.ConfigPatch{MaxEntries: &maxEntries})
+	if err != nil {
+		t.Error(err)
+	}
+	if store.Length() != 1 {
+		t.Errorf("Expected length to be 1, got %d", store.Length())
+	}
+	if _, ok := store.Get("key4"); !ok {
+		t.Errorf("Expected key4 to be present")
+	}
+	entries, err := os.ReadDir(CACHE_DIR)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected 1 file, got %d", len(entries))
+	}
+}
+
+func TestReconfigureCleanInterval(t *testing.T) {
+	dir := t.TempDir()
+	store, err := goKeyValueStore.NewKeyValueStore(60, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("key1", "value1", 10)
+	interval := 50 * time.Millisecond
+	err = store.Reconfigure(goKeyValueStore.ConfigPatch{CleanInterval: &interval})
+	if err != nil {
+		t.Error(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected expired files to be removed, got %d", len(entries))
+	}
+}
+
+func TestReconfigureMaxBytesEvicts(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	for i := 0; i < 10; i++ {
+		store.Set(fmt.Sprintf("key%d", i), strings.Repeat("x", 100), 0)
+	}
+	maxBytes := int64(500)
+	err = store.Reconfigure(goKeyValueStore.ConfigPatch{MaxBytes: &maxBytes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if length := store.Length(); length == 0 || length > 4 {
+		t.Errorf("Expected the entries to fit in 500 bytes, got %d entries", length)
+	}
+	if config := store.Config(); config.MaxBytes != 500 {
+		t.Errorf("Expected max bytes 500, got %d", config.MaxBytes)
+	}
+	maxBytes = 0
+	err = store.Reconfigure(goKeyValueStore.ConfigPatch{MaxBytes: &maxBytes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		store.Set(fmt.Sprintf("key%d", i), strings.Repeat("x", 100), 0)
+	}
+	if store.Length() != 10 {
+		t.Errorf("Expected no limit after removing it, got %d entries", store.Length())
+	}
+}
+
+func TestReconfigureImmutableSetting(t *testing.T) {
+	store := getTestStore()
+	folder := "other"
+	maxEntries := 1
+	err := store.Reconfigure(goKeyValueStore.ConfigPatch{CacheFolder: &folder, MaxEntries: &maxEntries})
+	if !errors.Is(err, goKeyValueStore.ErrImmutableSetting) {
+		t.Errorf("Expected ErrImmutableSetting, got %v", err)
+	}
+	if store.Length() != 3 {
+		t.Errorf("Expected length to be 3, got %d", store.Length())
+	}
+	folder = CACHE_DIR
+	err = store.Reconfigure(goKeyValueStore.ConfigPatch{CacheFolder: &folder})
+	if err != nil {
+		t.Errorf("Expected unchanged cache folder to be accepted, got %v", err)
+	}
+	err = store.Reconfigure(goKeyValueStore.ConfigPatch{Codec: goKeyValueStore.GobCodec{}, MaxEntries: &maxEntries})
+	if !errors.Is(err, goKeyValueStore.ErrImmutableSetting) {
+		t.Errorf("Expected ErrImmutableSetting for the codec, got %v", err)
+	}
+	if store.Length() != 3 {
+		t.Errorf("Expected length to be 3, got %d", store.Length())
+	}
+	err = store.Reconfigure(goKeyValueStore.ConfigPatch{Codec: goKeyValueStore.JSONCodec{}})
+	if err != nil {
+		t.Errorf("Expected unchanged codec to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package goKeyValueStore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+)
+
+// ErrNotAnInteger is returned by Incr and Decr if the value of the key is not an integer.
+var ErrNotAnInteger = errors.New("value is not an integer")
+
+// Incr adds delta to the integer stored at key and returns the new value. A missing or expired key is set to delta
+// with a TTL in milliseconds; an existing key keeps its deadline. The whole operation holds the write lock, so
+// concurrent increments are never lost. Integers of any type are accepted, including the float64 and json.Number
+// values read from the cache folder, and the result is stored as int64. Values that are not integers return
+// ErrNotAnInteger and are left unchanged.
+func (d *KeyValueStore) Incr(key string, delta int64, ttl int) (int64, error) {
+	d.lazyInit()
+	key = d.storageKey(key)
+	value, err := d.incr(key, delta, ttl)
+	d.audit(context.Background(), "set", key, true, err)
+	return value, err
+}
+
+// Decr subtracts delta from the integer stored at key like Incr.
+func (d *KeyValueStore) Decr(key string, delta int64, ttl int) (int64, error) {
+	d.lazyInit()
+	key = d.storageKey(key)
+	value, err := d.incr(key, -delta, ttl)
+	d.audit(context.Background(), "set", key, true, err)
+	return value, err
+}
+
+// incr adds delta to the integer stored at key and returns the new value.
+func (d *KeyValueStore) incr(key string, delta int64, ttl int) (int64, error) {
+	err := d.takeWriteTokens(1)
+	if err != nil {
+		d.recordSetResult(key, err)
+		return 0, err
+	}
+	defer d.afterWrite()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed.Load() {
+		return 0, ErrClosed
+	}
+	sum := delta
+	deadline := int64(0)
+	if old, ok := d.data[key]; ok && !d.nodeIsExpired(old) {
+		value, err := old.value()
+		if err != nil {
+			return 0, d.keyError("load value", key, err)
+		}
+		current, ok := toInt64(value)
+		if !ok {
+			return 0, d.keyError("increment", key, ErrNotAnInteger)
+		}
+		sum += current
+		deadline = old.DeleteTimestamp
+	}
+	err = d.makeRoomInQuotas(key)
+	if err != nil {
+		d.recordSetResult(key, err)
+		return 0, err
+	}
+	node := d.newNode(key, sum, ttl)
+	if deadline != 0 {
+		node.DeleteTimestamp = deadline
+	}
+	d.putNode(node)
+	err = d.saveInCache(node)
+	d.recordSetResult(key, err)
+	if err != nil {
+		return sum, err
+	}
+	return sum, d.evictOverflow(key)
+}
+
+// toInt64 converts an integer of any type to int64. Floats are accepted if they hold an integer.
+func toInt64(value any) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint:
+		return int64(v), uint64(v) <= math.MaxInt64
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), v <= math.MaxInt64
+	case float32:
+		return toInt64(float64(v))
+	case float64:
+		if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return 0, false
+		}
+		return int64(v), true
+	case json.Number:
+		i, err := v.Int64()
+		return i, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,69 @@
+package goKeyValueStore_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestIncr(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, clock)
+	if value, err := store.Incr("hits", 5, 1000); err != nil || value != 5 {
+		t.Errorf("Expected a missing key to start at 5, got %d, %v", value, err)
+	}
+	if value, _ := store.Decr("hits", 2, 0); value != 3 {
+		t.Errorf("Expected 3, got %d", value)
+	}
+	if ttl, _ := store.TTL("hits"); ttl != time.Second {
+		t.Errorf("Expected the deadline to be kept, got %s", ttl)
+	}
+	store.Set("count", 40, 0)
+	restarted := getTestStoreWithClock(t, dir, clock)
+	if value, err := restarted.Incr("count", 2, 0); err != nil || value != 42 {
+		t.Errorf("Expected the loaded value to be incremented to 42, got %d, %v", value, err)
+	}
+	if value, err := restarted.Incr("hits", 1, 0); err != nil || value != 4 {
+		t.Errorf("Expected 4 after a restart, got %d, %v", value, err)
+	}
+	clock.Advance(1001 * time.Millisecond)
+	if value, _ := restarted.Incr("hits", 1, 0); value != 1 {
+		t.Errorf("Expected an expired key to start again, got %d", value)
+	}
+}
+
+func TestIncrNotAnInteger(t *testing.T) {
+	store, _ := goKeyValueStore.NewKeyValueStore(1, "")
+	for _, value := range []any{"text", 1.5, nil} {
+		store.Set("key", value, 0)
+		if _, err := store.Incr("key", 1, 0); !errors.Is(err, goKeyValueStore.ErrNotAnInteger) {
+			t.Errorf("Expected ErrNotAnInteger for %v, got %v", value, err)
+		}
+		if current, _ := store.Get("key"); current != value {
+			t.Errorf("Expected %v to be unchanged, got %v", value, current)
+		}
+	}
+}
+
+func TestIncrConcurrent(t *testing.T) {
+	store, _ := goKeyValueStore.NewKeyValueStore(1, t.TempDir(), goKeyValueStore.WithCleanerStopped(true))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 50; n++ {
+				store.Incr("counter", 2, 0)
+				store.Decr("counter", 1, 0)
+			}
+		}()
+	}
+	wg.Wait()
+	if value, _ := store.Get("counter"); value != int64(400) {
+		t.Errorf("Expected 400, got %v", value)
+	}
+}
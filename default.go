@@ -0,0 +1,56 @@
+package goKeyValueStore
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// defaultCleanTimeout is the clean timeout in seconds of the default store.
+const defaultCleanTimeout = 60
+
+var (
+	defaultStore atomic.Pointer[KeyValueStore]
+	defaultOnce  sync.Once
+)
+
+// Default returns the process-wide default store used by the package-level functions.
+// Unless SetDefault was called before, it is created on first use as a memory-only store
+// that removes expired keys every minute.
+func Default() *KeyValueStore {
+	defaultOnce.Do(func() {
+		if defaultStore.Load() != nil {
+			return
+		}
+		store, err := NewKeyValueStore(defaultCleanTimeout, "")
+		if err != nil {
+			panic(err) // a memory-only store can not fail to initialize
+		}
+		defaultStore.CompareAndSwap(nil, store)
+	})
+	return defaultStore.Load()
+}
+
+// SetDefault replaces the default store. It is safe to call SetDefault before or after the first use of Default.
+func SetDefault(s *KeyValueStore) {
+	defaultStore.Store(s)
+}
+
+// Set sets a key-value pair with a TTL in milliseconds in the default store.
+func Set(key string, value any, ttl int) error {
+	return Default().Set(key, value, ttl)
+}
+
+// Get gets a value by key from the default store. If the key does not exist, the second return value is false.
+func Get(key string) (any, bool) {
+	return Default().Get(key)
+}
+
+// Delete deletes a key from the default store. If the key does not exist, this function does nothing.
+func Delete(key string) error {
+	return Default().Delete(key)
+}
+
+// Length returns the number of key-value pairs in the default store.
+func Length() int {
+	return Default().Length()
+}
@@ -0,0 +1,68 @@
+package goKeyValueStore_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestDefaultConcurrentFirstUse(t *testing.T) {
+	stores := make([]*goKeyValueStore.KeyValueStore, 50)
+	var wg sync.WaitGroup
+	for i := range stores {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			stores[i] = goKeyValueStore.Default()
+		}(i)
+	}
+	wg.Wait()
+	for _, store := range stores {
+		if store == nil || store != stores[0] {
+			t.Fatal("Expected all goroutines to get the same default store")
+		}
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	previous := goKeyValueStore.Default()
+	defer goKeyValueStore.SetDefault(previous)
+	store := getTestStore()
+	goKeyValueStore.SetDefault(store)
+	if goKeyValueStore.Default() != store {
+		t.Error("Expected Default to return the replaced store")
+	}
+	if goKeyValueStore.Length() != 3 {
+		t.Errorf("Expected length to be 3, got %d", goKeyValueStore.Length())
+	}
+}
+
+func TestDefaultForwarding(t *testing.T) {
+	previous := goKeyValueStore.Default()
+	defer goKeyValueStore.SetDefault(previous)
+	store := getTestStore()
+	goKeyValueStore.SetDefault(store)
+	err := goKeyValueStore.Set("key4", "value4", 1000)
+	if err != nil {
+		t.Error(err)
+	}
+	val, ok := store.Get("key4")
+	if !ok || val != "value4" {
+		t.Errorf("Expected value4, got %v", val)
+	}
+	val, ok = goKeyValueStore.Get("key1")
+	if !ok || val != "value1" {
+		t.Errorf("Expected value1, got %v", val)
+	}
+	err = goKeyValueStore.Delete("key1")
+	if err != nil {
+		t.Error(err)
+	}
+	if _, ok := store.Get("key1"); ok {
+		t.Errorf("Expected key1 to be deleted")
+	}
+	if goKeyValueStore.Length() != store.Length() {
+		t.Errorf("Expected length to be %d, got %d", store.Length(), goKeyValueStore.Length())
+	}
+}
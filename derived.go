@@ -0,0 +1,137 @@
+package goKeyValueStore
+
+import "errors"
+
+// maxDerivedDepth is the maximum length of a chain of derived keys. Longer chains are rejected by SetDerived.
+const maxDerivedDepth = 16
+
+// ErrSourceNotFound is returned by SetDerived if the source key does not exist.
+var ErrSourceNotFound = errors.New("source key does not exist")
+
+// ErrDerivedCycle is returned by SetDerived if the key is a source of the source key or the chain of sources
+// is longer than 16 keys.
+var ErrDerivedCycle = errors.New("derived keys form a cycle or a too long chain")
+
+// SetDerived sets a key-value pair that is derived from sourceKey, e.g. a thumbnail of an image. The key expires
+// with its source and is deleted when the source is deleted, expires, or is evicted. Keys derived from the key
+// are deleted as well. The dependency is persisted. Setting the key again with Set removes the dependency.
+func (d *KeyValueStore) SetDerived(key string, value any, sourceKey string) error {
+	d.lazyInit()
+	return d.SetDerivedTTL(key, value, sourceKey, 0)
+}
+
+// SetDerivedTTL is like SetDerived with a TTL in milliseconds. The key expires after the TTL or with its
+// source, whichever comes first. A TTL of 0 means the key expires with its source.
+func (d *KeyValueStore) SetDerivedTTL(key string, value any, sourceKey string, ttl int) error {
+	d.lazyInit()
+	key, sourceKey = d.storageKey(key), d.storageKey(sourceKey)
+	err := d.checkValue(value)
+	if err == nil {
+		err = d.takeWriteTokens(1)
+	}
+	if err != nil {
+		d.recordSetResult(key, err)
+		return err
+	}
+	defer d.afterWrite()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed.Load() {
+		return ErrClosed
+	}
+	source, err := d.sourceOf(key, sourceKey)
+	if err != nil {
+		d.recordSetResult(key, err)
+		return d.keyError("set derived key", key, err)
+	}
+	err = d.makeRoomInQuotas(key)
+	if err != nil {
+		d.recordSetResult(key, err)
+		return err
+	}
+	node := d.newNode(key, value, ttl)
+	derive(node, source, ttl)
+	d.putNode(node)
+	err = d.saveInCache(node)
+	d.recordSetResult(key, err)
+	if err != nil {
+		return err
+	}
+	return d.evictOverflow(key)
+}
+
+// sourceOf returns the source of a key derived from sourceKey. It returns ErrSourceNotFound if the source does not
+// exist and ErrDerivedCycle if key can not be derived from it. The caller must hold the write lock.
+func (d *KeyValueStore) sourceOf(key string, sourceKey string) (*node, error) {
+	source, ok := d.data[sourceKey]
+	if !ok || d.nodeIsExpired(source) {
+		return nil, ErrSourceNotFound
+	}
+	if !d.canDerive(key, source) {
+		return nil, ErrDerivedCycle
+	}
+	return source, nil
+}
+
+// derive makes a new node depend on source. It expires after ttl or with source, whichever comes first.
+func derive(node *node, source *node, ttl int) {
+	if ttl == 0 || source.DeleteTimestamp < node.DeleteTimestamp {
+		node.DeleteTimestamp = source.DeleteTimestamp
+	}
+	node.Source = source.Key
+}
+
+// canDerive reports whether key can be derived from source without a cycle or a too long chain.
+func (d *KeyValueStore) canDerive(key string, source *node) bool {
+	for depth := 1; depth < maxDerivedDepth; depth++ {
+		if source.Key == key {
+			return false
+		}
+		next, ok := d.data[source.Source]
+		if source.Source == "" || !ok {
+			return true
+		}
+		source = next
+	}
+	return false
+}
+
+// trackDerived updates the keys derived from the source of a node when the node replaces old or, if node is nil,
+// when old is removed. The caller must hold the write lock.
+func (d *KeyValueStore) trackDerived(old *node, node *node) {
+	if old != nil && old.Source != "" && (node == nil || node.Source != old.Source) {
+		delete(d.derived[old.Source], old.Key)
+		if len(d.derived[old.Source]) == 0 {
+			delete(d.derived, old.Source)
+		}
+	}
+	if node != nil && node.Source != "" {
+		if d.derived == nil {
+			d.derived = map[string]map[string]struct{}{}
+		}
+		if d.derived[node.Source] == nil {
+			d.derived[node.Source] = map[string]struct{}{}
+		}
+		d.derived[node.Source][node.Key] = struct{}{}
+	}
+}
+
+// removeDerived deletes the keys derived from a removed source and the keys derived from them.
+// The caller must hold the write lock.
+func (d *KeyValueStore) removeDerived(source string) error {
+	var errs []error
+	queue := []string{source}
+	for depth := 0; depth < maxDerivedDepth && len(queue) > 0; depth++ {
+		next := []string{}
+		for _, source := range queue {
+			for key := range d.derived[source] {
+				d.removeNode(key)
+				d.recordEvent(key, EventDeleted, "source was removed")
+				errs = append(errs, d.deleteInCache(key))
+				next = append(next, key)
+			}
+		}
+		queue = next
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,94 @@
+package goKeyValueStore_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestDerivedExpiresWithSource(t *testing.T) {
+	clock := newFakeClock()
+	store := getTestStoreWithClock(t, t.TempDir(), clock)
+	store.Set("image", "pixels", 1000)
+	if err := store.SetDerived("thumbnail", "small pixels", "image"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetDerivedTTL("preview", "tiny pixels", "image", 500); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetDerivedTTL("banner", "wide pixels", "image", 5000); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(600 * time.Millisecond)
+	if _, ok := store.Get("preview"); ok {
+		t.Errorf("Expected preview to expire after its own TTL")
+	}
+	clock.Advance(time.Second)
+	for _, key := range []string{"thumbnail", "banner"} {
+		if _, ok := store.Get(key); ok {
+			t.Errorf("Expected %s to expire with its source", key)
+		}
+	}
+}
+
+func TestDerivedDeletedWithSource(t *testing.T) {
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, newFakeClock())
+	store.Set("image", "pixels", 0)
+	store.Set("other", "value", 0)
+	store.SetDerived("thumbnail", "small pixels", "image")
+	store.SetDerived("thumbnail:blurred", "blurred pixels", "thumbnail")
+	store.SetDerived("unrelated", "value", "other")
+	if err := store.Delete("image"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"thumbnail", "thumbnail:blurred"} {
+		if _, ok := store.Get(key); ok {
+			t.Errorf("Expected %s to be deleted with its source", key)
+		}
+	}
+	if _, ok := store.Get("unrelated"); !ok {
+		t.Errorf("Expected unrelated to be present")
+	}
+	if count := countCacheFiles(t, dir); count != 2 {
+		t.Errorf("Expected 2 cache files, got %d", count)
+	}
+}
+
+func TestDerivedSurvivesRestart(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, clock)
+	store.Set("image", "pixels", 0)
+	store.SetDerived("thumbnail", "small pixels", "image")
+	restored := getTestStoreWithClock(t, dir, clock)
+	restored.Delete("image")
+	if _, ok := restored.Get("thumbnail"); ok {
+		t.Errorf("Expected thumbnail to be deleted with its source after a restart")
+	}
+	if count := countCacheFiles(t, dir); count != 0 {
+		t.Errorf("Expected no cache files, got %d", count)
+	}
+}
+
+func TestDerivedRejectsCycles(t *testing.T) {
+	store := getTestStoreWithClock(t, t.TempDir(), newFakeClock())
+	store.Set("a", "value", 0)
+	if err := store.SetDerived("a", "value", "a"); !errors.Is(err, goKeyValueStore.ErrDerivedCycle) {
+		t.Errorf("Expected ErrDerivedCycle for a key derived from itself, got %v", err)
+	}
+	store.SetDerived("b", "value", "a")
+	store.SetDerived("c", "value", "b")
+	if err := store.SetDerived("a", "value", "c"); !errors.Is(err, goKeyValueStore.ErrDerivedCycle) {
+		t.Errorf("Expected ErrDerivedCycle, got %v", err)
+	}
+	if err := store.SetDerived("d", "value", "missing"); !errors.Is(err, goKeyValueStore.ErrSourceNotFound) {
+		t.Errorf("Expected ErrSourceNotFound, got %v", err)
+	}
+	store.Delete("a")
+	if store.Length() != 0 {
+		t.Errorf("Expected the chain to be deleted, got length %d", store.Length())
+	}
+}
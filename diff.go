@@ -0,0 +1,124 @@
+package goKeyValueStore
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// A DiffKind tells how an entry differs between two exports.
+type DiffKind int
+
+const (
+	// DiffAdded entries are only in the second export.
+	DiffAdded DiffKind = iota
+	// DiffRemoved entries are only in the first export.
+	DiffRemoved
+	// DiffChanged entries are in both exports with another value or deadline.
+	DiffChanged
+)
+
+// String returns the name of the kind.
+func (k DiffKind) String() string {
+	switch k {
+	case DiffAdded:
+		return "added"
+	case DiffRemoved:
+		return "removed"
+	case DiffChanged:
+		return "changed"
+	default:
+		return "unknown"
+	}
+}
+
+// A DiffEntry is a key that differs between two exports. For changed entries, ValueChanged and DeadlineChanged
+// tell what changed.
+type DiffEntry struct {
+	Key             string
+	Kind            DiffKind
+	ValueChanged    bool
+	DeadlineChanged bool
+}
+
+// A Diff counts the differences between two exports.
+type Diff struct {
+	Added   int
+	Removed int
+	Changed int
+}
+
+// DiffExports compares two export streams written by Export, e.g. of the same store at two points in time, and
+// calls fn for every differing entry in the order of the keys. Values are compared by their encoding, so values
+// that are equal in Go but encoded differently are changed. The streams are read side by side and every entry is
+// passed to fn as soon as it is found, so the memory use does not grow with the size of the diff. fn may be nil
+// to only count the differences. If fn returns false, DiffExports stops and returns the counts so far without
+// verifying the checksums. Otherwise both checksums are verified at the end of the streams; a damaged stream
+// returns ErrExportChecksum, after fn may have been called for some of its entries.
+func DiffExports(r1, r2 io.Reader, fn func(DiffEntry) bool) (Diff, error) {
+	a, err := newExportReader(r1)
+	if err != nil {
+		return Diff{}, err
+	}
+	b, err := newExportReader(r2)
+	if err != nil {
+		return Diff{}, err
+	}
+	diff := Diff{}
+	recordA, okA, err := a.next()
+	if err != nil {
+		return Diff{}, err
+	}
+	recordB, okB, err := b.next()
+	if err != nil {
+		return Diff{}, err
+	}
+	for okA || okB {
+		compared := 0
+		switch {
+		case !okA:
+			compared = 1
+		case !okB:
+			compared = -1
+		default:
+			compared = strings.Compare(recordA.Key, recordB.Key)
+		}
+		var entry DiffEntry
+		differs := true
+		switch {
+		case compared < 0:
+			diff.Removed++
+			entry = DiffEntry{Key: recordA.Key, Kind: DiffRemoved}
+		case compared > 0:
+			diff.Added++
+			entry = DiffEntry{Key: recordB.Key, Kind: DiffAdded}
+		default:
+			entry = DiffEntry{
+				Key:             recordA.Key,
+				Kind:            DiffChanged,
+				ValueChanged:    recordA.Codec != recordB.Codec || !bytes.Equal(recordA.Value, recordB.Value),
+				DeadlineChanged: recordA.DeleteTimestamp != recordB.DeleteTimestamp,
+			}
+			differs = entry.ValueChanged || entry.DeadlineChanged
+			if differs {
+				diff.Changed++
+			}
+		}
+		if differs && fn != nil && !fn(entry) {
+			return diff, nil
+		}
+		if compared <= 0 {
+			recordA, okA, err = a.next()
+			if err != nil {
+				return Diff{}, err
+			}
+		}
+		if compared >= 0 {
+			recordB, okB, err = b.next()
+			if err != nil {
+				return Diff{}, err
+			}
+		}
+	}
+	return diff, nil
+}
@@ -0,0 +1,56 @@
+package goKeyValueStore
+
+import (
+	"path/filepath"
+	"time"
+)
+
+// readCacheFile reads a file from the cache folder within the limit of concurrent disk operations.
+func (d *KeyValueStore) readCacheFile(name string) ([]byte, error) {
+	var data []byte
+	err := d.diskOp(func() error {
+		var err error
+		data, err = d.fs.ReadFile(name)
+		return err
+	})
+	return data, err
+}
+
+// writeFileAtomic writes a file in the cache folder to a temporary file next to it and renames it over the file,
+// so readers and restarts never see a partly written file. If WithSyncWrites is enabled, the temporary file is
+// synced before the rename and the folder after it.
+func (d *KeyValueStore) writeFileAtomic(name string, data []byte) error {
+	syncFS, sync := d.fs.(SyncFS)
+	sync = sync && d.syncWrites
+	return d.diskOp(func() error {
+		err := d.fs.WriteFile(name+".tmp", data, 0600)
+		if err == nil && sync {
+			err = syncFS.Sync(name + ".tmp")
+		}
+		if err == nil {
+			err = d.fs.Rename(name+".tmp", name)
+		}
+		if err != nil {
+			d.fs.Remove(name + ".tmp")
+			return err
+		}
+		if sync {
+			return syncFS.Sync(filepath.Dir(name))
+		}
+		return nil
+	})
+}
+
+// diskOp runs a file operation. If WithMaxConcurrentDiskOps is set, it waits until fewer operations than
+// the limit are running.
+func (d *KeyValueStore) diskOp(op func() error) error {
+	if d.diskSem != nil {
+		start := time.Now()
+		d.diskSem <- struct{}{}
+		defer func() { <-d.diskSem }()
+		d.diskWait.Add(int64(time.Since(start)))
+	}
+	d.diskInFlight.Add(1)
+	defer d.diskInFlight.Add(-1)
+	return op()
+}
@@ -0,0 +1,111 @@
+package goKeyValueStore_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestMaxConcurrentDiskOps(t *testing.T) {
+	dir := t.TempDir()
+	getTestStoreWithIndex(t, dir, 20)
+	var running, maxRunning atomic.Int64
+	slowReadFile := goKeyValueStore.WithReadFile(func(name string) ([]byte, error) {
+		current := running.Add(1)
+		defer running.Add(-1)
+		for {
+			observed := maxRunning.Load()
+			if current <= observed || maxRunning.CompareAndSwap(observed, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return os.ReadFile(name)
+	})
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithIndex(true),
+		goKeyValueStore.WithMaxConcurrentDiskOps(2), slowReadFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 1; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			value, ok := store.Get(fmt.Sprintf("key%d", i))
+			if !ok || value != fmt.Sprintf("value%d", i) {
+				t.Errorf("Expected value%d, got %v", i, value)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if maxRunning.Load() != 2 {
+		t.Errorf("Expected at most 2 concurrent reads, got %d", maxRunning.Load())
+	}
+	stats := store.Stats()
+	if stats.DiskOpsInFlight != 0 || stats.DiskWait == 0 {
+		t.Errorf("Expected no running operations and some wait time, got %+v", stats)
+	}
+}
+
+// syncRecorder is an FS that records the names it syncs.
+type syncRecorder struct {
+	goKeyValueStore.OSFS
+	mu     sync.Mutex
+	synced []string
+}
+
+func (s *syncRecorder) Sync(name string) error {
+	s.mu.Lock()
+	s.synced = append(s.synced, name)
+	s.mu.Unlock()
+	return s.OSFS.Sync(name)
+}
+
+func TestSyncWrites(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		dir := t.TempDir()
+		fsys := &syncRecorder{}
+		store, err := goKeyValueStore.NewKeyValueStore(60, dir, goKeyValueStore.WithFilesystem(fsys),
+			goKeyValueStore.WithSyncWrites(enabled))
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.Set("key1", "value1", 0)
+		store.Close()
+		files, _ := filepath.Glob(filepath.Join(dir, "*.store.json"))
+		if len(files) != 1 {
+			t.Fatalf("Expected 1 file, got %d", len(files))
+		}
+		want := []string{}
+		if enabled {
+			want = []string{files[0] + ".tmp", dir}
+		}
+		if !slices.Equal(fsys.synced, want) {
+			t.Errorf("Expected syncs %v with sync writes %t, got %v", want, enabled, fsys.synced)
+		}
+	}
+}
+
+func BenchmarkSetSyncWrites(b *testing.B) {
+	for _, enabled := range []bool{false, true} {
+		b.Run(fmt.Sprintf("sync=%t", enabled), func(b *testing.B) {
+			store, err := goKeyValueStore.NewKeyValueStore(60, b.TempDir(), goKeyValueStore.WithSyncWrites(enabled))
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer store.Close()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				store.Set(fmt.Sprintf("key%d", i%100), "value", 0)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package goKeyValueStore
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrLowDiskSpace is returned when a key-value pair is not persisted because the free disk space of the
+// cache folder is below the configured minimum. The key-value pair is still set in memory.
+var ErrLowDiskSpace = errors.New("not enough free disk space")
+
+// diskCheckInterval is how long the result of a free disk space check is reused.
+const diskCheckInterval = time.Second
+
+// A Stats holds statistics about a KeyValueStore.
+type Stats struct {
+	// LowDiskSpace is true while new key-value pairs are not persisted because of low disk space.
+	LowDiskSpace bool
+	// DiskOpsInFlight is the number of running file operations in the cache folder.
+	DiskOpsInFlight int64
+	// DiskWait is the total time file operations waited for the limit of WithMaxConcurrentDiskOps.
+	DiskWait time.Duration
+	// AuditDropped is the number of audit records that were dropped because the audit writer fell behind or failed.
+	AuditDropped uint64
+	// TempFilesRemoved is the number of stale temporary files removed from the cache folder by Housekeep,
+	// including the removals on start.
+	TempFilesRemoved int64
+	// CorruptFiles is the number of cache files that could not be decoded on start. They are renamed with the
+	// suffix ".corrupt" and their keys are missing.
+	CorruptFiles int64
+	// CoalescedWrites is the number of queued file operations of WithWriteBehind and WithFlushInterval that were
+	// skipped because a later write or deletion of the same key replaced them.
+	CoalescedWrites uint64
+}
+
+// Stats returns statistics about the store.
+func (d *KeyValueStore) Stats() Stats {
+	d.lazyInit()
+	d.diskMu.Lock()
+	defer d.diskMu.Unlock()
+	stats := Stats{
+		LowDiskSpace:     d.lowDiskSpace,
+		DiskOpsInFlight:  d.diskInFlight.Load(),
+		DiskWait:         time.Duration(d.diskWait.Load()),
+		TempFilesRemoved: d.tempFilesRemoved.Load(),
+		CorruptFiles:     d.corruptFiles.Load(),
+	}
+	if d.auditLog != nil {
+		stats.AuditDropped = d.auditLog.dropped.Load()
+	}
+	if d.writeBehind != nil {
+		stats.CoalescedWrites = d.writeBehind.coalesced.Load()
+	}
+	return stats
+}
+
+// checkDiskSpace returns ErrLowDiskSpace if the free disk space of the cache folder is below the minimum.
+// The free disk space is checked at most once per diskCheckInterval. If it can not be determined,
+// writes are allowed.
+func (d *KeyValueStore) checkDiskSpace() error {
+	if d.minFreeDiskBytes <= 0 {
+		return nil
+	}
+	d.diskMu.Lock()
+	defer d.diskMu.Unlock()
+	free, ok := d.freeDisk()
+	d.lowDiskSpace = ok && free < uint64(d.minFreeDiskBytes)
+	if d.lowDiskSpace {
+		return ErrLowDiskSpace
+	}
+	return nil
+}
+
+// freeDisk returns the free disk space of the cache folder, which is checked at most once per diskCheckInterval.
+// The second return value is false if it can not be determined. The caller must hold diskMu.
+func (d *KeyValueStore) freeDisk() (uint64, bool) {
+	now := d.now()
+	if d.diskCheckedAt.IsZero() || now.Sub(d.diskCheckedAt) >= diskCheckInterval {
+		d.diskFree, d.diskFreeErr = d.freeDiskSpace(d.cacheFolder)
+		d.diskCheckedAt = now
+	}
+	return d.diskFree, d.diskFreeErr == nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package goKeyValueStore
+
+import "errors"
+
+// freeDiskSpace is not supported on this platform, so the free disk space is never checked.
+func freeDiskSpace(path string) (uint64, error) {
+	return 0, errors.New("free disk space is not supported on this platform")
+}
@@ -0,0 +1,75 @@
+package goKeyValueStore_test
+
+import (
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestMinFreeDiskBytes(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	var free atomic.Uint64
+	var checks atomic.Int64
+	free.Store(2000)
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, dir,
+		goKeyValueStore.WithClock(clock.Now),
+		goKeyValueStore.WithMinFreeDiskBytes(1000),
+		goKeyValueStore.WithFreeDiskSpace(func(path string) (uint64, error) {
+			checks.Add(1)
+			return free.Load(), nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("key1", "value1", 0); err != nil {
+		t.Error(err)
+	}
+	free.Store(500)
+	if err := store.Set("key2", "value2", 0); err != nil {
+		t.Errorf("Expected the cached check to allow the write, got %v", err)
+	}
+	if checks.Load() != 1 {
+		t.Errorf("Expected 1 disk space check, got %d", checks.Load())
+	}
+	clock.Advance(2 * time.Second)
+	err = store.Set("key3", "value3", 0)
+	if !errors.Is(err, goKeyValueStore.ErrLowDiskSpace) {
+		t.Errorf("Expected ErrLowDiskSpace, got %v", err)
+	}
+	if _, ok := store.Get("key3"); !ok {
+		t.Errorf("Expected key3 to be kept in memory")
+	}
+	if !store.Stats().LowDiskSpace {
+		t.Error("Expected the low disk space stat to be set")
+	}
+	free.Store(2000)
+	clock.Advance(2 * time.Second)
+	if err := store.Set("key4", "value4", 0); err != nil {
+		t.Errorf("Expected writes to resume, got %v", err)
+	}
+	if store.Stats().LowDiskSpace {
+		t.Error("Expected the low disk space stat to be cleared")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("Expected 3 files, got %d", len(entries))
+	}
+}
+
+func TestMinFreeDiskBytesRealFilesystem(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, t.TempDir(), goKeyValueStore.WithMinFreeDiskBytes(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("key1", "value1", 0); err != nil {
+		t.Error(err)
+	}
+}
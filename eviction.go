@@ -0,0 +1,304 @@
+package goKeyValueStore
+
+import (
+	"container/heap"
+	"container/list"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// An EvictionPolicy selects the entries that are evicted when the store holds more entries or bytes than allowed.
+type EvictionPolicy int
+
+const (
+	// EvictNearestExpiry evicts the entries closest to their expiration first, so expired entries go before live
+	// ones and entries without expiration go last.
+	EvictNearestExpiry EvictionPolicy = iota
+	// EvictLRU evicts the least recently used entries first. Setting or getting a key uses it.
+	EvictLRU
+	// EvictApproxLRU approximates EvictLRU without the cost of ordering keys on every read. Reads only record the
+	// sweep of the cleaner they happened in, and victims are the least recently used of a sample of keys.
+	// Keys used within the same clean interval are equally recent.
+	EvictApproxLRU
+)
+
+// String returns the name of the policy.
+func (p EvictionPolicy) String() string {
+	switch p {
+	case EvictNearestExpiry:
+		return "nearest-expiry"
+	case EvictLRU:
+		return "lru"
+	case EvictApproxLRU:
+		return "approx-lru"
+	default:
+		return "unknown"
+	}
+}
+
+// An evictionStrategy tracks the entries of the store and picks the next victim of an EvictionPolicy.
+// put and remove are called with the write lock held. touch and tick are called with at least the read lock held.
+type evictionStrategy interface {
+	put(node *node)
+	remove(key string)
+	touch(node *node)
+	// tick is called after every sweep of the cleaner.
+	tick()
+	// victim returns the key of the next entry to evict other than protect.
+	victim(protect string) (string, bool)
+}
+
+// newEvictionStrategy creates the strategy of a policy and adds the given nodes to it.
+// The nodes are added in the order they were set, so the time they were set stands in for their last use.
+func newEvictionStrategy(policy EvictionPolicy, nodes map[string]*node) evictionStrategy {
+	var strategy evictionStrategy
+	switch policy {
+	case EvictLRU:
+		strategy = newLRUStrategy()
+	case EvictApproxLRU:
+		strategy = newApproxLRUStrategy()
+	default:
+		strategy = newNearestExpiryStrategy()
+	}
+	sorted := make([]*node, 0, len(nodes))
+	for _, node := range nodes {
+		sorted = append(sorted, node)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].UpdatedAt < sorted[j].UpdatedAt })
+	for _, node := range sorted {
+		strategy.put(node)
+	}
+	return strategy
+}
+
+// evictOverflow evicts entries while the store holds more entries or bytes than allowed. The victims are picked
+// by the eviction policy. The entry for protect is never evicted. The caller must hold the write lock.
+func (d *KeyValueStore) evictOverflow(protect string) error {
+	maxEntries := int(d.maxEntries.Load())
+	maxBytes := d.maxBytes.Load()
+	for (maxEntries > 0 && len(d.data) > maxEntries) || (maxBytes > 0 && d.bytes > maxBytes) {
+		key, ok := d.eviction.victim(protect)
+		if !ok {
+			return nil
+		}
+		d.removeNode(key)
+		d.recordEvent(key, EventEvicted, "store is full")
+		err := errors.Join(d.deleteInCache(key), d.removeDerived(key))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setEvictionPolicy replaces the eviction strategy. The caller must hold the write lock.
+func (d *KeyValueStore) setEvictionPolicy(policy EvictionPolicy) {
+	d.evictionPolicy = policy
+	d.eviction = newEvictionStrategy(policy, d.data)
+}
+
+// deadlineItem is an entry in the heap of nearestExpiryStrategy.
+type deadlineItem struct {
+	key      string
+	deadline int64
+	index    int
+}
+
+// deadlineHeap orders entries by their deadline with the nearest deadline first.
+type deadlineHeap []*deadlineItem
+
+func (h deadlineHeap) Len() int           { return len(h) }
+func (h deadlineHeap) Less(i, j int) bool { return h[i].deadline < h[j].deadline }
+func (h deadlineHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *deadlineHeap) Push(x any) {
+	item := x.(*deadlineItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *deadlineHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return item
+}
+
+// nearestExpiryStrategy implements EvictNearestExpiry with a heap of deadlines.
+type nearestExpiryStrategy struct {
+	heap  deadlineHeap
+	items map[string]*deadlineItem
+}
+
+func newNearestExpiryStrategy() *nearestExpiryStrategy {
+	return &nearestExpiryStrategy{items: map[string]*deadlineItem{}}
+}
+
+func (s *nearestExpiryStrategy) put(node *node) {
+	if item, ok := s.items[node.Key]; ok {
+		item.deadline = node.DeleteTimestamp
+		heap.Fix(&s.heap, item.index)
+		return
+	}
+	item := &deadlineItem{key: node.Key, deadline: node.DeleteTimestamp}
+	heap.Push(&s.heap, item)
+	s.items[node.Key] = item
+}
+
+func (s *nearestExpiryStrategy) remove(key string) {
+	if item, ok := s.items[key]; ok {
+		heap.Remove(&s.heap, item.index)
+		delete(s.items, key)
+	}
+}
+
+func (s *nearestExpiryStrategy) touch(node *node) {}
+
+func (s *nearestExpiryStrategy) tick() {}
+
+func (s *nearestExpiryStrategy) victim(protect string) (string, bool) {
+	if len(s.heap) == 0 {
+		return "", false
+	}
+	if s.heap[0].key != protect {
+		return s.heap[0].key, true
+	}
+	// the protected entry is at the root, so the victim is the nearer of its children
+	best := ""
+	var deadline int64
+	for _, i := range []int{1, 2} {
+		if i < len(s.heap) && (best == "" || s.heap[i].deadline < deadline) {
+			best, deadline = s.heap[i].key, s.heap[i].deadline
+		}
+	}
+	return best, best != ""
+}
+
+// walk calls fn with the deadlines in the heap, starting at the nearest. If fn returns false, the deadlines below
+// the one it was called with are skipped; they are not nearer.
+func (s *nearestExpiryStrategy) walk(fn func(deadline int64) bool) {
+	stack := []int{0}
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if i >= len(s.heap) || !fn(s.heap[i].deadline) {
+			continue
+		}
+		stack = append(stack, 2*i+1, 2*i+2)
+	}
+}
+
+// lruStrategy implements EvictLRU with a list of keys ordered by their last use, most recent first.
+// It has its own lock because reads touch keys concurrently.
+type lruStrategy struct {
+	mu       sync.Mutex
+	order    *list.List
+	elements map[string]*list.Element
+}
+
+func newLRUStrategy() *lruStrategy {
+	return &lruStrategy{order: list.New(), elements: map[string]*list.Element{}}
+}
+
+func (s *lruStrategy) put(node *node) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if element, ok := s.elements[node.Key]; ok {
+		s.order.MoveToFront(element)
+		return
+	}
+	s.elements[node.Key] = s.order.PushFront(node.Key)
+}
+
+func (s *lruStrategy) remove(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if element, ok := s.elements[key]; ok {
+		s.order.Remove(element)
+		delete(s.elements, key)
+	}
+}
+
+func (s *lruStrategy) touch(node *node) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if element, ok := s.elements[node.Key]; ok {
+		s.order.MoveToFront(element)
+	}
+}
+
+func (s *lruStrategy) tick() {}
+
+func (s *lruStrategy) victim(protect string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for element := s.order.Back(); element != nil; element = element.Prev() {
+		if key := element.Value.(string); key != protect {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// approxLRUSamples is the number of keys EvictApproxLRU compares to pick a victim.
+const approxLRUSamples = 16
+
+// approxLRUStrategy implements EvictApproxLRU. The epoch counts the sweeps of the cleaner and every node records
+// the epoch of its last use, so reads only store a number and never take a lock.
+type approxLRUStrategy struct {
+	epoch atomic.Int64
+	nodes map[string]*node
+}
+
+func newApproxLRUStrategy() *approxLRUStrategy {
+	return &approxLRUStrategy{nodes: map[string]*node{}}
+}
+
+func (s *approxLRUStrategy) put(node *node) {
+	node.lastUsed = s.epoch.Load()
+	s.nodes[node.Key] = node
+}
+
+func (s *approxLRUStrategy) remove(key string) {
+	delete(s.nodes, key)
+}
+
+func (s *approxLRUStrategy) touch(node *node) {
+	epoch := s.epoch.Load()
+	if atomic.LoadInt64(&node.lastUsed) != epoch {
+		atomic.StoreInt64(&node.lastUsed, epoch)
+	}
+}
+
+func (s *approxLRUStrategy) tick() {
+	s.epoch.Add(1)
+}
+
+// victim returns the least recently used of a sample of keys. The iteration order of maps is random,
+// so the first keys of the map are a sample.
+func (s *approxLRUStrategy) victim(protect string) (string, bool) {
+	best := ""
+	var bestUsed int64
+	sampled := 0
+	for key, node := range s.nodes {
+		if key == protect {
+			continue
+		}
+		used := atomic.LoadInt64(&node.lastUsed)
+		if best == "" || used < bestUsed {
+			best, bestUsed = key, used
+		}
+		sampled++
+		if sampled == approxLRUSamples {
+			break
+		}
+	}
+	return best, best != ""
+}
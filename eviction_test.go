@@ -0,0 +1,150 @@
+package goKeyValueStore_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func getTestStoreWithPolicy(t *testing.T, clock *fakeClock, maxEntries int, policy goKeyValueStore.EvictionPolicy) *goKeyValueStore.KeyValueStore {
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, "", goKeyValueStore.WithClock(clock.Now), goKeyValueStore.WithCleanerStopped(true),
+		goKeyValueStore.WithMaxEntries(maxEntries), goKeyValueStore.WithEvictionPolicy(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestEvictNearestExpiry(t *testing.T) {
+	clock := newFakeClock()
+	store := getTestStoreWithPolicy(t, clock, 3, goKeyValueStore.EvictNearestExpiry)
+	store.Set("forever", "value", 0)
+	store.Set("late", "value", 3000)
+	store.Set("soon", "value", 1000)
+	clock.Advance(time.Second)
+	store.Set("middle", "value", 2500)
+	store.Set("new", "value", 5000)
+	for _, key := range []string{"soon", "late"} {
+		if _, ok := store.Get(key); ok {
+			t.Errorf("Expected %s to be evicted", key)
+		}
+	}
+	for _, key := range []string{"forever", "middle", "new"} {
+		if _, ok := store.Get(key); !ok {
+			t.Errorf("Expected %s to be present", key)
+		}
+	}
+}
+
+func TestEvictLRU(t *testing.T) {
+	clock := newFakeClock()
+	store := getTestStoreWithPolicy(t, clock, 2, goKeyValueStore.EvictLRU)
+	store.Set("key1", "value1", 1000)
+	store.Set("key2", "value2", 0)
+	store.Get("key1")
+	store.Set("key3", "value3", 0)
+	if _, ok := store.Get("key2"); ok {
+		t.Errorf("Expected key2 to be evicted")
+	}
+	if _, ok := store.Get("key1"); !ok {
+		t.Errorf("Expected key1 to be present")
+	}
+}
+
+func TestReconfigureEvictionPolicy(t *testing.T) {
+	clock := newFakeClock()
+	store := getTestStoreWithPolicy(t, clock, 2, goKeyValueStore.EvictNearestExpiry)
+	store.Set("key1", "value1", 0)
+	clock.Advance(time.Second)
+	store.Set("key2", "value2", 1000)
+	policy := goKeyValueStore.EvictLRU
+	err := store.Reconfigure(goKeyValueStore.ConfigPatch{EvictionPolicy: &policy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config := store.Config(); config.EvictionPolicy != goKeyValueStore.EvictLRU {
+		t.Errorf("Expected eviction policy lru, got %s", config.EvictionPolicy)
+	}
+	store.Set("key3", "value3", 0)
+	if _, ok := store.Get("key1"); ok {
+		t.Errorf("Expected the least recently set key1 to be evicted")
+	}
+	if _, ok := store.Get("key2"); !ok {
+		t.Errorf("Expected key2 to be present")
+	}
+}
+
+func TestEvictApproxLRU(t *testing.T) {
+	clock := newFakeClock()
+	store := getTestStoreWithPolicy(t, clock, 10, goKeyValueStore.EvictApproxLRU)
+	for i := 0; i < 10; i++ {
+		store.Set(fmt.Sprintf("key%d", i), i, 0)
+	}
+	store.CleanNow()
+	for i := 5; i < 10; i++ {
+		store.Get(fmt.Sprintf("key%d", i))
+	}
+	store.CleanNow()
+	for i := 10; i < 15; i++ {
+		store.Set(fmt.Sprintf("key%d", i), i, 0)
+	}
+	for i := 0; i < 15; i++ {
+		_, ok := store.Get(fmt.Sprintf("key%d", i))
+		if i < 5 && ok {
+			t.Errorf("Expected untouched key%d to be evicted", i)
+		}
+		if i >= 5 && !ok {
+			t.Errorf("Expected recently used key%d to be present", i)
+		}
+	}
+}
+
+func TestEvictApproxLRUUnderPressure(t *testing.T) {
+	clock := newFakeClock()
+	store := getTestStoreWithPolicy(t, clock, 1000, goKeyValueStore.EvictApproxLRU)
+	for i := 0; i < 1000; i++ {
+		store.Set(fmt.Sprintf("key%d", i), i, 0)
+	}
+	for round := 0; round < 10; round++ {
+		store.CleanNow()
+		for i := 0; i < 100; i++ {
+			store.Get(fmt.Sprintf("key%d", i))
+		}
+		for i := 0; i < 50; i++ {
+			store.Set(fmt.Sprintf("new%d-%d", round, i), i, 0)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		if _, ok := store.Get(fmt.Sprintf("key%d", i)); !ok {
+			t.Errorf("Expected hot key%d to survive", i)
+		}
+	}
+}
+
+func BenchmarkGetEvictionPolicy(b *testing.B) {
+	policies := []goKeyValueStore.EvictionPolicy{goKeyValueStore.EvictNearestExpiry, goKeyValueStore.EvictLRU, goKeyValueStore.EvictApproxLRU}
+	for _, policy := range policies {
+		b.Run(policy.String(), func(b *testing.B) {
+			store, err := goKeyValueStore.NewKeyValueStore(0.5, "", goKeyValueStore.WithCleanerStopped(true),
+				goKeyValueStore.WithMaxEntries(10000), goKeyValueStore.WithEvictionPolicy(policy))
+			if err != nil {
+				b.Fatal(err)
+			}
+			keys := make([]string, 1000)
+			for i := range keys {
+				keys[i] = fmt.Sprintf("key%d", i)
+				store.Set(keys[i], i, 0)
+			}
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					store.Get(keys[i%len(keys)])
+					i++
+				}
+			})
+		})
+	}
+}
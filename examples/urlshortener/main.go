@@ -0,0 +1,87 @@
+// Command urlshortener is a small URL shortener that keeps its links in a goKeyValueStore.
+// Links are persisted in a cache folder, so they survive a restart, and expire after a TTL.
+//
+//	go run ./examples/urlshortener -dir links -ttl 24h
+//	curl -X POST 'localhost:8080/shorten?url=https://go.dev'
+//	curl -i localhost:8080/<code>
+package main
+
+import (
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+// codeAlphabet contains the characters of the generated codes.
+const codeAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// codeLength is the number of characters of a generated code.
+const codeLength = 7
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	dir := flag.String("dir", "links", "cache folder of the links")
+	ttl := flag.Duration("ttl", 24*time.Hour, "time until a link expires")
+	flag.Parse()
+	store, err := goKeyValueStore.NewKeyValueStore(60, *dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+	log.Printf("serving %d links from %s on %s", store.Length(), store.CacheFolder(), *addr)
+	log.Fatal(http.ListenAndServe(*addr, newServer(store, *ttl)))
+}
+
+// newServer returns the handler of the URL shortener. POST /shorten?url=... stores a link and responds with its
+// code, GET /{code} redirects to the link.
+func newServer(store *goKeyValueStore.KeyValueStore, ttl time.Duration) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /shorten", func(w http.ResponseWriter, r *http.Request) {
+		target, err := url.Parse(r.URL.Query().Get("url"))
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			http.Error(w, "url must be an absolute http or https URL", http.StatusBadRequest)
+			return
+		}
+		code, err := randomCode()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		err = store.Set(code, target.String(), int(ttl.Milliseconds()))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintln(w, code)
+	})
+	mux.HandleFunc("GET /{code}", func(w http.ResponseWriter, r *http.Request) {
+		target, ok := store.Get(r.PathValue("code"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, target.(string), http.StatusFound)
+	})
+	return mux
+}
+
+// randomCode returns a random code for a link.
+func randomCode() (string, error) {
+	code := make([]byte, codeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(codeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = codeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
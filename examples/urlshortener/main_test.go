@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func startServer(t *testing.T, dir string, clock *fakeClock) *goKeyValueStore.KeyValueStore {
+	store, err := goKeyValueStore.NewKeyValueStore(60, dir, goKeyValueStore.WithClock(clock.Now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func shorten(t *testing.T, handler http.Handler, target string) string {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/shorten?url="+target, nil))
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", recorder.Code, recorder.Body)
+	}
+	return strings.TrimSpace(recorder.Body.String())
+}
+
+func resolve(handler http.Handler, code string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/"+code, nil))
+	return recorder
+}
+
+func TestLinksSurviveRestart(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := startServer(t, dir, clock)
+	code := shorten(t, newServer(store, time.Hour), "https://go.dev/doc")
+	store.Close()
+
+	restarted := newServer(startServer(t, dir, clock), time.Hour)
+	recorder := resolve(restarted, code)
+	if recorder.Code != http.StatusFound || recorder.Header().Get("Location") != "https://go.dev/doc" {
+		t.Errorf("Expected a redirect to https://go.dev/doc after the restart, got %d %s", recorder.Code, recorder.Header().Get("Location"))
+	}
+}
+
+func TestLinksExpire(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := startServer(t, dir, clock)
+	code := shorten(t, newServer(store, time.Hour), "https://go.dev")
+	store.Close()
+	clock.Advance(2 * time.Hour)
+
+	restarted := newServer(startServer(t, dir, clock), time.Hour)
+	if recorder := resolve(restarted, code); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected an expired link to return 404, got %d", recorder.Code)
+	}
+}
+
+func TestShortenRejectsInvalidURLs(t *testing.T) {
+	handler := newServer(startServer(t, t.TempDir(), &fakeClock{now: time.Now()}), time.Hour)
+	for _, target := range []string{"", "go.dev", "ftp://go.dev/file"} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/shorten?url="+target, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %q, got %d", target, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Command worker computes results for the job IDs read from stdin, one per line, and caches them in a
+// goKeyValueStore. Cached results are persisted, so a restarted worker answers repeated jobs without computing
+// them again. On SIGINT or SIGTERM the worker finishes the current job and shuts the store down.
+//
+//	printf 'a\nb\na\n' | go run ./examples/worker -dir results
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func main() {
+	dir := flag.String("dir", "results", "cache folder of the results")
+	ttl := flag.Duration("ttl", time.Hour, "time until a cached result expires")
+	flag.Parse()
+	ctx, stop := shutdownContext()
+	defer stop()
+	store, err := goKeyValueStore.NewKeyValueStore(60, *dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+	w := &worker{store: store, ttl: *ttl, load: compute}
+	err = w.run(ctx, readJobs(os.Stdin), os.Stdout)
+	if err != nil && err != context.Canceled {
+		log.Print(err)
+	}
+}
+
+// shutdownContext returns a context that is canceled on SIGINT or SIGTERM.
+func shutdownContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// compute is the expensive computation of a job.
+func compute(id string) (string, error) {
+	sum := sha256.Sum256([]byte(id))
+	for i := 0; i < 100000; i++ {
+		sum = sha256.Sum256(sum[:])
+	}
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// readJobs sends the lines of r to the returned channel and closes it at the end of r.
+func readJobs(r io.Reader) <-chan string {
+	jobs := make(chan string)
+	go func() {
+		defer close(jobs)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			if scanner.Text() != "" {
+				jobs <- scanner.Text()
+			}
+		}
+	}()
+	return jobs
+}
+
+// A worker computes the results of jobs with load and caches them in store.
+type worker struct {
+	store *goKeyValueStore.KeyValueStore
+	ttl   time.Duration
+	load  func(id string) (string, error)
+}
+
+// result returns the result of a job. The second return value is true if it was cached.
+func (w *worker) result(id string) (string, bool, error) {
+	value, cached, err := w.store.GetOrSetFunc("result:"+id, int(w.ttl.Milliseconds()), func() (any, error) {
+		return w.load(id)
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return value.(string), cached, nil
+}
+
+// run writes the results of jobs to out until jobs is closed or ctx is canceled.
+func (w *worker) run(ctx context.Context, jobs <-chan string, out io.Writer) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case id, ok := <-jobs:
+			if !ok {
+				return nil
+			}
+			result, cached, err := w.result(id)
+			if err != nil {
+				return fmt.Errorf("job %s: %w", id, err)
+			}
+			fmt.Fprintf(out, "%s %s cached=%t\n", id, result, cached)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func newTestWorker(t *testing.T, dir string, loads *int) *worker {
+	store, err := goKeyValueStore.NewKeyValueStore(60, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return &worker{store: store, ttl: time.Hour, load: func(id string) (string, error) {
+		*loads++
+		return "result of " + id, nil
+	}}
+}
+
+func TestResultsSurviveRestart(t *testing.T) {
+	dir := t.TempDir()
+	loads := 0
+	w := newTestWorker(t, dir, &loads)
+	var out bytes.Buffer
+	err := w.run(context.Background(), readJobs(strings.NewReader("a\nb\na\n")), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loads != 2 {
+		t.Errorf("Expected 2 loads, got %d", loads)
+	}
+	w.store.Close()
+
+	restarted := newTestWorker(t, dir, &loads)
+	result, cached, err := restarted.result("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cached || result != "result of b" || loads != 2 {
+		t.Errorf("Expected the cached result of b after the restart, got %q cached=%t loads=%d", result, cached, loads)
+	}
+}
+
+func TestRunStopsOnShutdown(t *testing.T) {
+	ctx, stop := shutdownContext()
+	defer stop()
+	process, _ := os.FindProcess(os.Getpid())
+	if err := process.Signal(syscall.SIGTERM); err != nil {
+		t.Skipf("Can not send SIGTERM: %v", err)
+	}
+	loads := 0
+	w := newTestWorker(t, t.TempDir(), &loads)
+	done := make(chan error)
+	go func() {
+		done <- w.run(ctx, make(chan string), &bytes.Buffer{})
+	}()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected run to stop on SIGTERM")
+	}
+}
@@ -0,0 +1,174 @@
+package goKeyValueStore
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// defaultHistoryKeys is the number of keys whose events are kept by default.
+const defaultHistoryKeys = 1000
+
+// historyEventsPerKey is the number of events kept for each key.
+const historyEventsPerKey = 8
+
+// An EventKind is the kind of a lifecycle event of a key.
+type EventKind int
+
+const (
+	// EventNoHistory means that no event was recorded for the key.
+	EventNoHistory EventKind = iota
+	// EventSet means that the key was set.
+	EventSet
+	// EventExpired means that the key was removed because its TTL elapsed.
+	EventExpired
+	// EventEvicted means that the key was removed because the store was full.
+	EventEvicted
+	// EventDeleted means that the key was deleted.
+	EventDeleted
+	// EventFailed means that setting the key failed, for example because the value was rejected or could
+	// not be persisted.
+	EventFailed
+)
+
+// String returns the name of the event kind.
+func (k EventKind) String() string {
+	switch k {
+	case EventSet:
+		return "set"
+	case EventExpired:
+		return "expired"
+	case EventEvicted:
+		return "evicted"
+	case EventDeleted:
+		return "deleted"
+	case EventFailed:
+		return "failed"
+	default:
+		return "no history"
+	}
+}
+
+// An Event is a lifecycle event of a key.
+type Event struct {
+	Kind   EventKind
+	Time   time.Time
+	Reason string
+}
+
+// A KeyState is the current state of a key.
+type KeyState int
+
+const (
+	// KeyAbsent means that the key does not exist or is expired.
+	KeyAbsent KeyState = iota
+	// KeyLive means that the key exists and is not expired.
+	KeyLive
+)
+
+// An Explanation describes the current state of a key and its recent lifecycle events.
+// LastEvent has the kind EventNoHistory if no event was recorded for the key.
+type Explanation struct {
+	Key       string
+	State     KeyState
+	LastEvent Event
+	History   []Event // oldest first
+}
+
+// Explain returns the store's best knowledge about why a key exists or not. Events are only kept for the
+// most recently changed keys, see WithExplainHistory. A key that is expired but not yet swept reports an
+// expired event at its deadline.
+func (d *KeyValueStore) Explain(key string) Explanation {
+	d.lazyInit()
+	key = d.storageKey(key)
+	d.mu.RLock()
+	node, ok := d.data[key]
+	live := ok && !d.nodeIsExpired(node)
+	d.mu.RUnlock()
+	explanation := Explanation{Key: key, History: d.history.events(key)}
+	if live {
+		explanation.State = KeyLive
+	} else if ok && node.DeleteTimestamp != neverExpire {
+		explanation.History = append(explanation.History, Event{
+			Kind:   EventExpired,
+			Time:   time.UnixMilli(node.DeleteTimestamp),
+			Reason: "ttl elapsed",
+		})
+	}
+	if len(explanation.History) > 0 {
+		explanation.LastEvent = explanation.History[len(explanation.History)-1]
+	}
+	return explanation
+}
+
+// recordEvent adds an event to the history of a key and to the changes feed.
+func (d *KeyValueStore) recordEvent(key string, kind EventKind, reason string) {
+	d.history.record(key, Event{Kind: kind, Time: d.now(), Reason: reason})
+	d.feedChange(key, kind)
+}
+
+// recordSetResult records a set event, or a failed event if err is not nil.
+func (d *KeyValueStore) recordSetResult(key string, err error) {
+	if err != nil {
+		d.recordEvent(key, EventFailed, err.Error())
+		return
+	}
+	d.recordEvent(key, EventSet, "")
+}
+
+// An eventHistory keeps the recent events of the most recently changed keys.
+type eventHistory struct {
+	mu      sync.Mutex
+	maxKeys int
+	order   *list.List // of *historyEntry, most recently changed first
+	entries map[string]*list.Element
+}
+
+// A historyEntry holds the recent events of a key.
+type historyEntry struct {
+	key    string
+	events []Event
+}
+
+// newEventHistory creates an event history for at most maxKeys keys.
+func newEventHistory(maxKeys int) *eventHistory {
+	return &eventHistory{maxKeys: maxKeys, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// record adds an event to the history of a key and forgets the least recently changed key if there are too many.
+func (h *eventHistory) record(key string, event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.maxKeys <= 0 {
+		return
+	}
+	element, ok := h.entries[key]
+	if !ok {
+		element = h.order.PushFront(&historyEntry{key: key})
+		h.entries[key] = element
+	} else {
+		h.order.MoveToFront(element)
+	}
+	entry := element.Value.(*historyEntry)
+	entry.events = append(entry.events, event)
+	if len(entry.events) > historyEventsPerKey {
+		entry.events = entry.events[len(entry.events)-historyEventsPerKey:]
+	}
+	for h.order.Len() > h.maxKeys {
+		oldest := h.order.Back()
+		h.order.Remove(oldest)
+		delete(h.entries, oldest.Value.(*historyEntry).key)
+	}
+}
+
+// events returns a copy of the recorded events of a key.
+func (h *eventHistory) events(key string) []Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	element, ok := h.entries[key]
+	if !ok {
+		return nil
+	}
+	events := element.Value.(*historyEntry).events
+	return append(make([]Event, 0, len(events)+1), events...)
+}
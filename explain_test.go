@@ -0,0 +1,94 @@
+package goKeyValueStore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestExplainExpired(t *testing.T) {
+	clock := newFakeClock()
+	store := getTestStoreWithClock(t, t.TempDir(), clock)
+	store.Set("key", "value", 1000)
+	explanation := store.Explain("key")
+	if explanation.State != goKeyValueStore.KeyLive || explanation.LastEvent.Kind != goKeyValueStore.EventSet {
+		t.Errorf("Expected live key with set event, got %+v", explanation)
+	}
+	clock.Advance(2 * time.Second)
+	_, err := store.CleanNow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	explanation = store.Explain("key")
+	if explanation.State != goKeyValueStore.KeyAbsent || explanation.LastEvent.Kind != goKeyValueStore.EventExpired {
+		t.Errorf("Expected absent key with expired event, got %+v", explanation)
+	}
+	if !explanation.LastEvent.Time.Equal(clock.Now()) {
+		t.Errorf("Expected expired event at %v, got %v", clock.Now(), explanation.LastEvent.Time)
+	}
+	if len(explanation.History) != 2 {
+		t.Errorf("Expected 2 events, got %d", len(explanation.History))
+	}
+}
+
+func TestExplainExpiredBeforeSweep(t *testing.T) {
+	clock := newFakeClock()
+	store := getTestStoreWithClock(t, "", clock)
+	store.Set("key", "value", 1000)
+	deadline := clock.Now().Add(time.Second).Truncate(time.Millisecond)
+	clock.Advance(2 * time.Second)
+	explanation := store.Explain("key")
+	if explanation.State != goKeyValueStore.KeyAbsent || explanation.LastEvent.Kind != goKeyValueStore.EventExpired {
+		t.Errorf("Expected absent key with expired event, got %+v", explanation)
+	}
+	if !explanation.LastEvent.Time.Equal(deadline) {
+		t.Errorf("Expected expired event at %v, got %v", deadline, explanation.LastEvent.Time)
+	}
+}
+
+func TestExplainDeletedEvictedAndFailed(t *testing.T) {
+	store, _ := goKeyValueStore.NewKeyValueStore(1, "", goKeyValueStore.WithMaxEntries(1), goKeyValueStore.WithMaxValueSize(10))
+	store.Set("deleted", "value", 0)
+	store.Delete("deleted")
+	if kind := store.Explain("deleted").LastEvent.Kind; kind != goKeyValueStore.EventDeleted {
+		t.Errorf("Expected deleted event, got %v", kind)
+	}
+	store.Set("evicted", "value", 1000)
+	store.Set("other", "value", 0)
+	if kind := store.Explain("evicted").LastEvent.Kind; kind != goKeyValueStore.EventEvicted {
+		t.Errorf("Expected evicted event, got %v", kind)
+	}
+	store.Set("failed", "a value that is too large", 0)
+	explanation := store.Explain("failed")
+	if explanation.LastEvent.Kind != goKeyValueStore.EventFailed || explanation.LastEvent.Reason != goKeyValueStore.ErrValueTooLarge.Error() {
+		t.Errorf("Expected failed event, got %+v", explanation.LastEvent)
+	}
+}
+
+func TestExplainNoHistory(t *testing.T) {
+	store, _ := goKeyValueStore.NewKeyValueStore(1, "")
+	explanation := store.Explain("unknown")
+	if explanation.State != goKeyValueStore.KeyAbsent || explanation.LastEvent.Kind != goKeyValueStore.EventNoHistory {
+		t.Errorf("Expected no history, got %+v", explanation)
+	}
+}
+
+func TestExplainHistoryIsBounded(t *testing.T) {
+	store, _ := goKeyValueStore.NewKeyValueStore(1, "", goKeyValueStore.WithExplainHistory(2))
+	store.Set("first", "value", 0)
+	store.Set("second", "value", 0)
+	store.Set("third", "value", 0)
+	if kind := store.Explain("first").LastEvent.Kind; kind != goKeyValueStore.EventNoHistory {
+		t.Errorf("Expected the oldest key to be forgotten, got %v", kind)
+	}
+	if kind := store.Explain("third").LastEvent.Kind; kind != goKeyValueStore.EventSet {
+		t.Errorf("Expected set event, got %v", kind)
+	}
+	for i := 0; i < 20; i++ {
+		store.Set("third", i, 0)
+	}
+	if history := store.Explain("third").History; len(history) != 8 {
+		t.Errorf("Expected 8 events, got %d", len(history))
+	}
+}
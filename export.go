@@ -0,0 +1,330 @@
+package goKeyValueStore
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// exportFormat identifies export streams written by Export.
+const exportFormat = "goKeyValueStore-export"
+
+// exportVersion is the version of the export format written by Export.
+const exportVersion = 1
+
+// ErrExportVersion is returned by Import for export streams of an unknown format or version.
+var ErrExportVersion = errors.New("unsupported export format version")
+
+// ErrExportChecksum is returned by Import if the body of an export stream does not match its checksum.
+var ErrExportChecksum = errors.New("export checksum mismatch")
+
+// ExportOptions configures Export.
+type ExportOptions struct {
+	// MaxEntries is the maximum number of entries written. A value of 0 means no limit.
+	MaxEntries int
+}
+
+// An ExportResult describes a finished export.
+// Truncated is true if entries were left out because of ExportOptions.MaxEntries.
+type ExportResult struct {
+	Entries   int
+	Truncated bool
+}
+
+// An exportHeader is the first line of an export stream.
+type exportHeader struct {
+	Format    string `json:"format"`
+	Version   int    `json:"version"`
+	CreatedAt string `json:"createdAt"`
+	Entries   int    `json:"entries"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// An exportRecord is a line of the body of an export stream. The order of the fields is part of the format.
+type exportRecord struct {
+	Key             string          `json:"key"`
+	DeleteTimestamp int64           `json:"deleteTimestamp"`
+	Codec           string          `json:"codec"`
+	Value           json.RawMessage `json:"value"`
+	KeyBytes        []byte          `json:"keyBytes,omitempty"`
+}
+
+// An exportTrailer is the last line of an export stream.
+type exportTrailer struct {
+	Checksum string `json:"checksum"`
+	Entries  int    `json:"entries"`
+}
+
+// Export writes all non-expired entries to w in a deterministic format, so exports of stores with the same
+// entries are byte-identical if they are created at the same time. The stream consists of newline-delimited
+// JSON: a header with the format version, the creation time, and the number of entries, one record per entry
+// with the fields key, deleteTimestamp, codec, and value in that order, followed by keyBytes for keys that are not
+// valid UTF-8, and sorted by key, and a trailer with the SHA-256 checksum of all records. The records are written
+// as they are encoded; if encoding fails, the stream has no trailer and Import rejects it.
+func (d *KeyValueStore) Export(w io.Writer, opts ExportOptions) (ExportResult, error) {
+	d.lazyInit()
+	nodes := d.snapshot()
+	slices.SortFunc(nodes, func(a, b *node) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	result := ExportResult{Entries: len(nodes)}
+	if opts.MaxEntries > 0 && len(nodes) > opts.MaxEntries {
+		nodes = nodes[:opts.MaxEntries]
+		result = ExportResult{Entries: opts.MaxEntries, Truncated: true}
+	}
+	buffered := bufio.NewWriter(w)
+	err := writeJSONLine(buffered, exportHeader{
+		Format:    exportFormat,
+		Version:   exportVersion,
+		CreatedAt: d.now().UTC().Format(time.RFC3339Nano),
+		Entries:   result.Entries,
+		Truncated: result.Truncated,
+	})
+	if err != nil {
+		return ExportResult{}, err
+	}
+	checksum := sha256.New()
+	body := io.MultiWriter(buffered, checksum)
+	for _, node := range nodes {
+		value, err := node.value()
+		if err != nil {
+			return ExportResult{}, d.keyError("load value", node.Key, err)
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return ExportResult{}, d.keyError("encode value", node.Key, err)
+		}
+		err = writeJSONLine(body, exportRecord{
+			Key:             node.Key,
+			DeleteTimestamp: node.DeleteTimestamp,
+			Codec:           "json",
+			Value:           encoded,
+			KeyBytes:        rawKey(node.Key),
+		})
+		if err != nil {
+			return ExportResult{}, err
+		}
+	}
+	err = writeJSONLine(buffered, exportTrailer{
+		Checksum: "sha256:" + hex.EncodeToString(checksum.Sum(nil)),
+		Entries:  result.Entries,
+	})
+	if err != nil {
+		return ExportResult{}, err
+	}
+	err = buffered.Flush()
+	if err != nil {
+		return ExportResult{}, err
+	}
+	return result, nil
+}
+
+// writeJSONLine writes v as a line of JSON.
+func writeJSONLine(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Import sets all entries of an export stream written by Export and returns the number of set entries.
+// The entries keep their deadlines; entries that expired since the export are skipped. The checksum is verified
+// before any entry is set, so a damaged stream returns ErrExportChecksum and leaves the store unchanged.
+// Streams of an unknown format version return ErrExportVersion. Entries are checked like writes of Set: values
+// that are too large or rejected nil values return ErrValueTooLarge or ErrNilValue before any entry is set, every
+// entry counts as one write for the write rate limit, and a new key rejected by a prefix quota stops the import.
+func (d *KeyValueStore) Import(r io.Reader) (int, error) {
+	d.lazyInit()
+	nodes, err := d.readExport(r)
+	if err != nil {
+		return 0, err
+	}
+	return d.importNodes(nodes, false)
+}
+
+// readExport reads all records of an export stream and verifies its checksum.
+func (d *KeyValueStore) readExport(r io.Reader) ([]*node, error) {
+	reader, err := newExportReader(r)
+	if err != nil {
+		return nil, err
+	}
+	records := []exportRecord{}
+	for {
+		record, ok, err := reader.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		records = append(records, record)
+	}
+	nodes := make([]*node, 0, len(records))
+	for _, record := range records {
+		if record.Codec != "json" {
+			return nil, fmt.Errorf("%w: unknown codec %q", ErrExportVersion, record.Codec)
+		}
+		var value any
+		decoder := json.NewDecoder(bytes.NewReader(record.Value))
+		if d.useNumber {
+			decoder.UseNumber()
+		}
+		err = decoder.Decode(&value)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, &node{
+			Key:             record.Key,
+			Value:           value,
+			DeleteTimestamp: record.DeleteTimestamp,
+		})
+	}
+	return nodes, nil
+}
+
+// A SeedConflictPolicy decides which entry NewFromExport keeps if a key is in the export and in the cache folder.
+type SeedConflictPolicy int
+
+const (
+	// ExportWins overwrites the entries of the cache folder with the entries of the export.
+	ExportWins SeedConflictPolicy = iota
+	// FolderWins keeps the non-expired entries of the cache folder.
+	FolderWins
+)
+
+// NewFromExport creates a new KeyValueStore like NewKeyValueStore and imports the export stream r before it
+// returns and starts the cleaner, so the first read already sees the imported entries. Entries that expired since
+// the export are skipped and all others are saved in the cache folder. Keys that are in the export and in the
+// cache folder are resolved by WithSeedConflictPolicy. The checksum of the stream is verified before the
+// cache folder is touched, so a damaged stream returns ErrExportChecksum and leaves the folder unchanged. Entries
+// are checked like by Import.
+func NewFromExport(r io.Reader, cleanTimeout float32, cacheFolder string, opts ...Option) (*KeyValueStore, error) {
+	return newKeyValueStore(cleanTimeout, cacheFolder, opts, r)
+}
+
+// An exportReader reads the records of an export stream one by one and verifies the checksum at the end.
+type exportReader struct {
+	scanner *bufio.Scanner
+	header  exportHeader
+	hash    hash.Hash
+	records int
+}
+
+// newExportReader reads the header of an export stream.
+func newExportReader(r io.Reader) (*exportReader, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<30)
+	if !scanner.Scan() {
+		if scanner.Err() != nil {
+			return nil, scanner.Err()
+		}
+		return nil, fmt.Errorf("%w: missing header", ErrExportVersion)
+	}
+	var header exportHeader
+	err := json.Unmarshal(scanner.Bytes(), &header)
+	if err != nil || header.Format != exportFormat {
+		return nil, fmt.Errorf("%w: not an export stream", ErrExportVersion)
+	}
+	if header.Version != exportVersion {
+		return nil, fmt.Errorf("%w: %d", ErrExportVersion, header.Version)
+	}
+	return &exportReader{scanner: scanner, header: header, hash: sha256.New()}, nil
+}
+
+// next returns the next record. At the trailer it verifies the checksum and returns false.
+// Records returned before the checksum was verified may be damaged.
+func (e *exportReader) next() (exportRecord, bool, error) {
+	if !e.scanner.Scan() {
+		if e.scanner.Err() != nil {
+			return exportRecord{}, false, e.scanner.Err()
+		}
+		return exportRecord{}, false, ErrExportChecksum
+	}
+	line := e.scanner.Bytes()
+	if bytes.HasPrefix(line, []byte(`{"checksum":`)) {
+		var trailer exportTrailer
+		err := json.Unmarshal(line, &trailer)
+		if err != nil {
+			return exportRecord{}, false, err
+		}
+		if e.scanner.Scan() {
+			return exportRecord{}, false, fmt.Errorf("%w: data after trailer", ErrExportChecksum)
+		}
+		if e.scanner.Err() != nil {
+			return exportRecord{}, false, e.scanner.Err()
+		}
+		if trailer.Checksum != "sha256:"+hex.EncodeToString(e.hash.Sum(nil)) ||
+			trailer.Entries != e.records || e.header.Entries != e.records {
+			return exportRecord{}, false, ErrExportChecksum
+		}
+		return exportRecord{}, false, nil
+	}
+	e.hash.Write(line)
+	e.hash.Write([]byte{'\n'})
+	var record exportRecord
+	err := json.Unmarshal(line, &record)
+	if err != nil {
+		return exportRecord{}, false, fmt.Errorf("%w: %w", ErrExportChecksum, err)
+	}
+	record.Key = restoreKey(record.Key, record.KeyBytes)
+	e.records++
+	return record, true, nil
+}
+
+// importNodes sets nodes with absolute deadlines, skipping expired ones, and returns the number of set nodes.
+// If keepExisting is true, nodes of keys that exist are skipped as well. The nodes are checked like by set; the
+// values are checked before any node is set.
+func (d *KeyValueStore) importNodes(nodes []*node, keepExisting bool) (int, error) {
+	nodes = slices.DeleteFunc(nodes, d.nodeIsExpired)
+	for _, node := range nodes {
+		err := d.checkValue(node.Value)
+		if err != nil {
+			d.recordSetResult(node.Key, err)
+			return 0, d.keyError("import", node.Key, err)
+		}
+	}
+	err := d.takeWriteTokens(len(nodes))
+	if err != nil {
+		return 0, err
+	}
+	defer d.afterWrite()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed.Load() {
+		return 0, ErrClosed
+	}
+	imported := 0
+	for _, node := range nodes {
+		if old, ok := d.data[node.Key]; ok && keepExisting && !d.nodeIsExpired(old) {
+			continue
+		}
+		err := d.makeRoomInQuotas(node.Key)
+		if err != nil {
+			d.recordSetResult(node.Key, err)
+			return imported, d.keyError("import", node.Key, err)
+		}
+		node.Revision = d.nextRevision()
+		node.UpdatedAt = d.now().UnixMilli()
+		d.stampCreation(node)
+		d.stampWriter(node)
+		d.putNode(node)
+		err = d.saveInCache(node)
+		d.recordSetResult(node.Key, err)
+		if err != nil {
+			return imported, err
+		}
+		imported++
+	}
+	return imported, d.evictOverflow("")
+}
@@ -0,0 +1,66 @@
+package goKeyValueStore
+
+import "io"
+
+// WithReadFile replaces the function used to read files from the cache folder.
+func WithReadFile(readFile func(name string) ([]byte, error)) Option {
+	return func(d *KeyValueStore) {
+		d.fs = readFileFS{FS: d.fs, readFile: readFile}
+	}
+}
+
+// readFileFS is an FS with another ReadFile.
+type readFileFS struct {
+	FS
+	readFile func(name string) ([]byte, error)
+}
+
+func (f readFileFS) ReadFile(name string) ([]byte, error) {
+	return f.readFile(name)
+}
+
+// Sweeps returns the number of sweeps the cleaner has completed.
+func (d *KeyValueStore) Sweeps() int64 {
+	d.sweepMu.Lock()
+	defer d.sweepMu.Unlock()
+	return d.sweeps
+}
+
+// WithFreeDiskSpace replaces the function used to determine the free disk space of the cache folder.
+func WithFreeDiskSpace(freeDiskSpace func(path string) (uint64, error)) Option {
+	return func(d *KeyValueStore) {
+		d.freeDiskSpace = freeDiskSpace
+	}
+}
+
+// SyncFromDisk runs a single poll of FollowChanges.
+func (d *KeyValueStore) SyncFromDisk() (int, error) {
+	return d.syncFromDisk()
+}
+
+// SameKeyLock returns true if two keys share the mutex used by WithKeyLock.
+func (d *KeyValueStore) SameKeyLock(a, b string) bool {
+	return d.keyLock(a) == d.keyLock(b)
+}
+
+// FlushChangesFeed waits until all changes are written to the changes feed.
+func (d *KeyValueStore) FlushChangesFeed() {
+	d.changesFeed.flush()
+}
+
+// WithAuditBuffer is WithAudit with a buffer of limit records.
+func WithAuditBuffer(prefixes []string, w io.Writer, limit int) Option {
+	return func(d *KeyValueStore) {
+		d.auditLog = newAuditLog(prefixes, w, limit)
+	}
+}
+
+// FlushAudit waits until all audit records are written.
+func (d *KeyValueStore) FlushAudit() {
+	d.auditLog.flush()
+}
+
+// CompactSegments rewrites the segments of WithPackedSmallValues regardless of their garbage.
+func (d *KeyValueStore) CompactSegments() error {
+	return d.compactSegments(true)
+}
@@ -0,0 +1,322 @@
+package goKeyValueStore_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func getExportTestStore(t *testing.T, clock *fakeClock, keys ...string) *goKeyValueStore.KeyValueStore {
+	store := getTestStoreWithClock(t, "", clock)
+	for _, key := range keys {
+		store.Set(key, map[string]any{"name": key, "tags": []string{"a", "b"}}, 60000)
+	}
+	return store
+}
+
+func TestExportIsDeterministic(t *testing.T) {
+	clock := newFakeClock()
+	first := getExportTestStore(t, clock, "b", "a", "c")
+	second := getExportTestStore(t, clock, "c", "b", "a")
+	var firstExport, secondExport bytes.Buffer
+	if _, err := first.Export(&firstExport, goKeyValueStore.ExportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.Export(&secondExport, goKeyValueStore.ExportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(firstExport.Bytes(), secondExport.Bytes()) {
+		t.Errorf("Expected identical exports, got\n%s\n%s", firstExport.String(), secondExport.String())
+	}
+	lines := strings.Split(strings.TrimSpace(firstExport.String()), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[1], `{"key":"a","deleteTimestamp":`) {
+		t.Errorf("Unexpected export\n%s", firstExport.String())
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	clock := newFakeClock()
+	store := getExportTestStore(t, clock, "a", "b")
+	var export bytes.Buffer
+	store.Export(&export, goKeyValueStore.ExportOptions{})
+	target := getTestStoreWithClock(t, t.TempDir(), clock)
+	imported, err := target.Import(&export)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != 2 {
+		t.Errorf("Expected 2 imported entries, got %d", imported)
+	}
+	clock.Advance(59 * time.Second)
+	if _, ok := target.Get("a"); !ok {
+		t.Error("Expected a to keep its deadline")
+	}
+	clock.Advance(2 * time.Second)
+	if _, ok := target.Get("a"); ok {
+		t.Error("Expected a to expire at its original deadline")
+	}
+}
+
+func TestExportKeyBytesLast(t *testing.T) {
+	clock := newFakeClock()
+	store := getTestStoreWithClock(t, "", clock)
+	store.Set("key\xff", "value", 0)
+	var export bytes.Buffer
+	if _, err := store.Export(&export, goKeyValueStore.ExportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(export.String(), "\n")
+	if !strings.Contains(lines[1], `"value":"value","keyBytes":`) {
+		t.Errorf("Expected keyBytes after the value, got %s", lines[1])
+	}
+	target := getTestStoreWithClock(t, "", clock)
+	if _, err := target.Import(&export); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := target.Get("key\xff"); value != "value" {
+		t.Errorf("Expected the key to survive the round trip, got %v", value)
+	}
+}
+
+func TestImportDetectsTampering(t *testing.T) {
+	clock := newFakeClock()
+	store := getExportTestStore(t, clock, "a", "b")
+	var export bytes.Buffer
+	store.Export(&export, goKeyValueStore.ExportOptions{})
+	tampered := strings.Replace(export.String(), `"name":"a"`, `"name":"x"`, 1)
+	target := getTestStoreWithClock(t, "", clock)
+	_, err := target.Import(strings.NewReader(tampered))
+	if !errors.Is(err, goKeyValueStore.ErrExportChecksum) {
+		t.Errorf("Expected ErrExportChecksum, got %v", err)
+	}
+	if target.Length() != 0 {
+		t.Errorf("Expected nothing to be imported, got %d entries", target.Length())
+	}
+	truncated := strings.Join(strings.Split(export.String(), "\n")[:2], "\n")
+	_, err = target.Import(strings.NewReader(truncated))
+	if !errors.Is(err, goKeyValueStore.ErrExportChecksum) {
+		t.Errorf("Expected ErrExportChecksum for a stream without trailer, got %v", err)
+	}
+}
+
+func TestImportRejectsUnknownVersion(t *testing.T) {
+	store := getTestStoreWithClock(t, "", newFakeClock())
+	stream := `{"format":"goKeyValueStore-export","version":2,"createdAt":"2024-01-01T00:00:00Z","entries":0}` + "\n"
+	_, err := store.Import(strings.NewReader(stream))
+	if !errors.Is(err, goKeyValueStore.ErrExportVersion) {
+		t.Errorf("Expected ErrExportVersion, got %v", err)
+	}
+}
+
+func TestImportVersion1Fixture(t *testing.T) {
+	fixture, err := os.Open("testdata/export_v1.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fixture.Close()
+	store, err := goKeyValueStore.NewKeyValueStore(1, "", goKeyValueStore.WithUseNumber(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := store.Import(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != 2 {
+		t.Errorf("Expected 2 imported entries, got %d", imported)
+	}
+	user, _ := store.Get("user:1")
+	if id := user.(map[string]any)["id"]; id != json.Number("1152921504606846977") {
+		t.Errorf("Expected exact id, got %v", id)
+	}
+}
+
+func TestExportMaxEntries(t *testing.T) {
+	clock := newFakeClock()
+	store := getExportTestStore(t, clock, "a", "b", "c")
+	var export bytes.Buffer
+	result, err := store.Export(&export, goKeyValueStore.ExportOptions{MaxEntries: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Entries != 2 || !result.Truncated {
+		t.Errorf("Expected 2 entries and truncation, got %+v", result)
+	}
+	if !strings.Contains(strings.SplitN(export.String(), "\n", 2)[0], `"truncated":true`) {
+		t.Error("Expected the header to mark the export as truncated")
+	}
+	target := getTestStoreWithClock(t, "", clock)
+	if imported, err := target.Import(&export); err != nil || imported != 2 {
+		t.Errorf("Expected a truncated export to import, got %d, %v", imported, err)
+	}
+}
+
+func TestDiffExports(t *testing.T) {
+	clock := newFakeClock()
+	before := getExportTestStore(t, clock, "same", "value", "ttl", "removed")
+	after := getExportTestStore(t, clock, "same", "value", "ttl", "added")
+	after.Set("value", "other", 60000)
+	after.Set("ttl", map[string]any{"name": "ttl", "tags": []string{"a", "b"}}, 30000)
+	var first, second bytes.Buffer
+	before.Export(&first, goKeyValueStore.ExportOptions{})
+	after.Export(&second, goKeyValueStore.ExportOptions{})
+	entries := []goKeyValueStore.DiffEntry{}
+	diff, err := goKeyValueStore.DiffExports(&first, &second, func(entry goKeyValueStore.DiffEntry) bool {
+		entries = append(entries, entry)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Added != 1 || diff.Removed != 1 || diff.Changed != 2 {
+		t.Errorf("Expected 1 added, 1 removed, and 2 changed, got %+v", diff)
+	}
+	expected := []goKeyValueStore.DiffEntry{
+		{Key: "added", Kind: goKeyValueStore.DiffAdded},
+		{Key: "removed", Kind: goKeyValueStore.DiffRemoved},
+		{Key: "ttl", Kind: goKeyValueStore.DiffChanged, DeadlineChanged: true},
+		{Key: "value", Kind: goKeyValueStore.DiffChanged, ValueChanged: true},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, entries)
+	}
+	for i := range expected {
+		if entries[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], entries[i])
+		}
+	}
+}
+
+func TestDiffExportsStops(t *testing.T) {
+	clock := newFakeClock()
+	before := getExportTestStore(t, clock, "a", "b", "c")
+	after := getExportTestStore(t, clock)
+	var first, second bytes.Buffer
+	before.Export(&first, goKeyValueStore.ExportOptions{})
+	after.Export(&second, goKeyValueStore.ExportOptions{})
+	keys := []string{}
+	diff, err := goKeyValueStore.DiffExports(&first, &second, func(entry goKeyValueStore.DiffEntry) bool {
+		keys = append(keys, entry.Key)
+		return len(keys) < 2
+	})
+	if err != nil || diff.Removed != 2 || len(keys) != 2 {
+		t.Errorf("Expected the diff to stop after 2 entries, got %+v, %v, %v", diff, keys, err)
+	}
+}
+
+func TestDiffExportsDetectsTampering(t *testing.T) {
+	clock := newFakeClock()
+	store := getExportTestStore(t, clock, "a", "b")
+	var first, second bytes.Buffer
+	store.Export(&first, goKeyValueStore.ExportOptions{})
+	store.Export(&second, goKeyValueStore.ExportOptions{})
+	tampered := strings.Replace(second.String(), `"name":"b"`, `"name":"x"`, 1)
+	_, err := goKeyValueStore.DiffExports(&first, strings.NewReader(tampered), nil)
+	if !errors.Is(err, goKeyValueStore.ErrExportChecksum) {
+		t.Errorf("Expected ErrExportChecksum, got %v", err)
+	}
+}
+
+func TestNewFromExport(t *testing.T) {
+	clock := newFakeClock()
+	source := getExportTestStore(t, clock, "a", "b")
+	source.Set("expiring", "value", 1000)
+	var export bytes.Buffer
+	source.Export(&export, goKeyValueStore.ExportOptions{})
+	clock.Advance(2 * time.Second)
+	dir := t.TempDir()
+	store, err := goKeyValueStore.NewFromExport(&export, 0.5, dir, goKeyValueStore.WithClock(clock.Now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if keys := store.KeysSorted(); len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Errorf("Expected keys a and b, got %v", keys)
+	}
+	if files := countCacheFiles(t, dir); files != 2 {
+		t.Errorf("Expected 2 files, got %d", files)
+	}
+}
+
+func TestImportChecksValues(t *testing.T) {
+	clock := newFakeClock()
+	source := getTestStoreWithClock(t, "", clock)
+	source.Set("small", "value", 0)
+	source.Set("large", strings.Repeat("x", 100), 0)
+	var export bytes.Buffer
+	source.Export(&export, goKeyValueStore.ExportOptions{})
+
+	target, err := goKeyValueStore.NewKeyValueStore(0.5, "", goKeyValueStore.WithMaxValueSize(50))
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := target.Import(bytes.NewReader(export.Bytes()))
+	if imported != 0 || !errors.Is(err, goKeyValueStore.ErrValueTooLarge) {
+		t.Errorf("Expected ErrValueTooLarge, got %d, %v", imported, err)
+	}
+	if target.Length() != 0 {
+		t.Errorf("Expected nothing to be imported, got %d entries", target.Length())
+	}
+	_, err = goKeyValueStore.NewFromExport(bytes.NewReader(export.Bytes()), 0.5, t.TempDir(), goKeyValueStore.WithMaxValueSize(50))
+	if !errors.Is(err, goKeyValueStore.ErrValueTooLarge) {
+		t.Errorf("Expected ErrValueTooLarge from NewFromExport, got %v", err)
+	}
+}
+
+func TestNewFromExportConflicts(t *testing.T) {
+	clock := newFakeClock()
+	source := getTestStoreWithClock(t, "", clock)
+	source.Set("shared", "export", 0)
+	source.Set("exported", "export", 0)
+	var export bytes.Buffer
+	source.Export(&export, goKeyValueStore.ExportOptions{})
+	tests := map[goKeyValueStore.SeedConflictPolicy]string{
+		goKeyValueStore.ExportWins: "export",
+		goKeyValueStore.FolderWins: "folder",
+	}
+	for policy, expected := range tests {
+		dir := t.TempDir()
+		existing := getTestStoreWithClock(t, dir, clock)
+		existing.Set("shared", "folder", 0)
+		existing.Set("local", "folder", 0)
+		store, err := goKeyValueStore.NewFromExport(bytes.NewReader(export.Bytes()), 0.5, dir,
+			goKeyValueStore.WithClock(clock.Now), goKeyValueStore.WithSeedConflictPolicy(policy))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value, _ := store.Get("shared"); value != expected {
+			t.Errorf("Expected shared to be %s for policy %d, got %v", expected, policy, value)
+		}
+		if store.Length() != 3 {
+			t.Errorf("Expected 3 entries for policy %d, got %d", policy, store.Length())
+		}
+		store.Close()
+		restarted := getTestStoreWithClock(t, dir, clock)
+		if value, _ := restarted.Get("shared"); value != expected {
+			t.Errorf("Expected shared to be persisted as %s for policy %d, got %v", expected, policy, value)
+		}
+	}
+}
+
+func TestNewFromExportDetectsTampering(t *testing.T) {
+	clock := newFakeClock()
+	source := getExportTestStore(t, clock, "a")
+	var export bytes.Buffer
+	source.Export(&export, goKeyValueStore.ExportOptions{})
+	tampered := strings.Replace(export.String(), `"name":"a"`, `"name":"x"`, 1)
+	dir := filepath.Join(t.TempDir(), "cache")
+	_, err := goKeyValueStore.NewFromExport(strings.NewReader(tampered), 0.5, dir)
+	if !errors.Is(err, goKeyValueStore.ErrExportChecksum) {
+		t.Errorf("Expected ErrExportChecksum, got %v", err)
+	}
+	if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the cache folder to be left alone, got %v", err)
+	}
+}
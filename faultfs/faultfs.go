@@ -0,0 +1,328 @@
+// Package faultfs provides a goKeyValueStore.FS that injects failures and latency, so tests can check how an
+// application behaves when the disk of its store misbehaves:
+//
+//	fsys := faultfs.New(nil)
+//	store, err := goKeyValueStore.NewKeyValueStore(1, dir, goKeyValueStore.WithFilesystem(fsys))
+//	fsys.FailNextWrites(1, nil)
+//	err = store.Set("key", "value", 0) // fails with faultfs.ErrInjected
+package faultfs
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+// ErrInjected is returned by failing operations unless another error is given.
+var ErrInjected = errors.New("injected fault")
+
+// An Op is a set of filesystem operations.
+type Op int
+
+const (
+	OpRead    Op = 1 << iota // ReadFile
+	OpWrite                  // WriteFile, AppendFile, Rename, and Sync
+	OpRemove                 // Remove
+	OpReadDir                // ReadDir
+	OpMkdir                  // MkdirAll
+	OpStat                   // Stat
+	OpAll     = OpRead | OpWrite | OpRemove | OpReadDir | OpMkdir | OpStat
+)
+
+// A keyRule fails the operations on keys that match a pattern.
+type keyRule struct {
+	pattern string
+	ops     Op
+	err     error
+}
+
+// An FS wraps another FS and fails its operations as programmed. It is safe for concurrent use.
+type FS struct {
+	base     goKeyValueStore.FS
+	mu       sync.Mutex
+	failing  Op
+	failErr  error
+	next     map[Op]int
+	nextErr  map[Op]error
+	after    map[Op]int
+	afterErr map[Op]error
+	keyRules []keyRule
+	latency  time.Duration
+	calls    map[Op]int
+	keys     map[string]string
+}
+
+// New returns an FS that passes all operations to base until it is told to fail. A nil base uses
+// goKeyValueStore.OSFS.
+func New(base goKeyValueStore.FS) *FS {
+	if base == nil {
+		base = goKeyValueStore.OSFS{}
+	}
+	return &FS{base: base, next: map[Op]int{}, nextErr: map[Op]error{},
+		after: map[Op]int{}, afterErr: map[Op]error{}, calls: map[Op]int{}, keys: map[string]string{}}
+}
+
+// Fail fails all operations of ops with err until Heal is called. A nil err fails with ErrInjected.
+func (f *FS) Fail(ops Op, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failing |= ops
+	f.failErr = orInjected(err)
+}
+
+// FailNext fails the next n operations of each of ops with err.
+func (f *FS) FailNext(ops Op, n int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for op := Op(1); op <= OpAll; op <<= 1 {
+		if ops&op != 0 {
+			f.next[op] = n
+			f.nextErr[op] = orInjected(err)
+		}
+	}
+}
+
+// FailAfter lets the next n operations of each of ops succeed and fails all later ones with err until Heal is
+// called, e.g. to stop a sequence of removals halfway like a crash.
+func (f *FS) FailAfter(ops Op, n int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for op := Op(1); op <= OpAll; op <<= 1 {
+		if ops&op != 0 {
+			f.after[op] = n
+			f.afterErr[op] = orInjected(err)
+		}
+	}
+}
+
+// FailNextWrites fails the next n writes with err.
+func (f *FS) FailNextWrites(n int, err error) {
+	f.FailNext(OpWrite, n, err)
+}
+
+// FailKeys fails the operations of ops on the files of keys that match pattern, see path.Match, with err.
+// The key of a file is taken from the data written to or read from it.
+func (f *FS) FailKeys(pattern string, ops Op, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keyRules = append(f.keyRules, keyRule{pattern: pattern, ops: ops, err: orInjected(err)})
+}
+
+// SetLatency delays every operation by d.
+func (f *FS) SetLatency(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.latency = d
+}
+
+// Heal removes all failures and the latency.
+func (f *FS) Heal() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failing = 0
+	f.next = map[Op]int{}
+	f.after = map[Op]int{}
+	f.keyRules = nil
+	f.latency = 0
+}
+
+// Calls returns the number of operations of ops that were called, including failed ones.
+func (f *FS) Calls(ops Op) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	count := 0
+	for op, calls := range f.calls {
+		if ops&op != 0 {
+			count += calls
+		}
+	}
+	return count
+}
+
+// ReadFile reads a file unless reads are failing.
+func (f *FS) ReadFile(name string) ([]byte, error) {
+	err := f.check(OpRead, name, nil)
+	if err != nil {
+		return nil, pathError("read", name, err)
+	}
+	data, err := f.base.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	err = f.check(0, name, data)
+	if err != nil {
+		return nil, pathError("read", name, err)
+	}
+	return data, nil
+}
+
+// WriteFile writes a file unless writes are failing.
+func (f *FS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	err := f.check(OpWrite, name, data)
+	if err != nil {
+		return pathError("write", name, err)
+	}
+	return f.base.WriteFile(name, data, perm)
+}
+
+// AppendFile appends to a file unless writes are failing.
+func (f *FS) AppendFile(name string, data []byte, perm fs.FileMode) error {
+	err := f.check(OpWrite, "", data)
+	if err != nil {
+		return pathError("write", name, err)
+	}
+	return f.base.AppendFile(name, data, perm)
+}
+
+// Rename renames a file unless writes are failing.
+func (f *FS) Rename(oldPath, newPath string) error {
+	err := f.check(OpWrite, oldPath, nil)
+	if err != nil {
+		return &fs.PathError{Op: "rename", Path: oldPath, Err: err}
+	}
+	err = f.base.Rename(oldPath, newPath)
+	if err == nil {
+		f.mu.Lock()
+		f.keys[newPath] = f.keys[oldPath]
+		delete(f.keys, oldPath)
+		f.mu.Unlock()
+	}
+	return err
+}
+
+// Sync syncs a file or a directory unless writes are failing. It does nothing if the base FS does not implement
+// goKeyValueStore.SyncFS.
+func (f *FS) Sync(name string) error {
+	err := f.check(OpWrite, name, nil)
+	if err != nil {
+		return pathError("sync", name, err)
+	}
+	if base, ok := f.base.(goKeyValueStore.SyncFS); ok {
+		return base.Sync(name)
+	}
+	return nil
+}
+
+// Remove removes a file unless removals are failing.
+func (f *FS) Remove(name string) error {
+	err := f.check(OpRemove, name, nil)
+	if err != nil {
+		return pathError("remove", name, err)
+	}
+	return f.base.Remove(name)
+}
+
+// ReadDir reads a directory unless directory reads are failing.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	err := f.check(OpReadDir, name, nil)
+	if err != nil {
+		return nil, pathError("readdir", name, err)
+	}
+	return f.base.ReadDir(name)
+}
+
+// MkdirAll creates a directory unless directory creation is failing.
+func (f *FS) MkdirAll(name string, perm fs.FileMode) error {
+	err := f.check(OpMkdir, name, nil)
+	if err != nil {
+		return pathError("mkdir", name, err)
+	}
+	return f.base.MkdirAll(name, perm)
+}
+
+// Stat returns the file info of a file unless stats are failing.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	err := f.check(OpStat, name, nil)
+	if err != nil {
+		return nil, pathError("stat", name, err)
+	}
+	return f.base.Stat(name)
+}
+
+// check counts an operation, waits for the latency, and returns the error of the first failure that applies.
+// The key of the operation is taken from data or, if data holds none, from earlier operations on the file.
+// An op of 0 only checks the key rules of read data.
+func (f *FS) check(op Op, name string, data []byte) error {
+	f.mu.Lock()
+	latency := f.latency
+	f.mu.Unlock()
+	if op != 0 && latency > 0 {
+		time.Sleep(latency)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key, ok := keyOf(data)
+	if ok && name != "" {
+		f.keys[name] = key
+	} else if !ok {
+		key, ok = f.keys[name]
+	}
+	if op == 0 {
+		return f.keyError(OpRead, key, ok)
+	}
+	f.calls[op]++
+	if f.failing&op != 0 {
+		return f.failErr
+	}
+	if f.next[op] > 0 {
+		f.next[op]--
+		return f.nextErr[op]
+	}
+	if remaining, ok := f.after[op]; ok {
+		if remaining == 0 {
+			return f.afterErr[op]
+		}
+		f.after[op]--
+	}
+	return f.keyError(op, key, ok)
+}
+
+// keyError returns the error of the first key rule that matches an operation on a key.
+func (f *FS) keyError(op Op, key string, ok bool) error {
+	if !ok {
+		return nil
+	}
+	for _, rule := range f.keyRules {
+		if rule.ops&op == 0 {
+			continue
+		}
+		if matched, _ := path.Match(rule.pattern, key); matched {
+			return rule.err
+		}
+	}
+	return nil
+}
+
+// keyOf returns the key of a record written by the store.
+func keyOf(data []byte) (string, bool) {
+	if len(data) == 0 {
+		return "", false
+	}
+	var record struct {
+		Key      *string `json:"key"`
+		KeyBytes []byte  `json:"keyBytes"`
+	}
+	if json.Unmarshal(data, &record) != nil || record.Key == nil {
+		return "", false
+	}
+	if record.KeyBytes != nil {
+		return string(record.KeyBytes), true
+	}
+	return *record.Key, true
+}
+
+func pathError(op string, name string, err error) error {
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+func orInjected(err error) error {
+	if err == nil {
+		return ErrInjected
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package faultfs_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+	"github.com/richi0/goKeyValueStore/faultfs"
+)
+
+func getTestStore(t *testing.T, fsys *faultfs.FS) *goKeyValueStore.KeyValueStore {
+	store, err := goKeyValueStore.NewKeyValueStore(1, t.TempDir(), goKeyValueStore.WithCleanerStopped(true),
+		goKeyValueStore.WithFilesystem(fsys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestFailNextWrites(t *testing.T) {
+	fsys := faultfs.New(nil)
+	store := getTestStore(t, fsys)
+	fsys.FailNextWrites(2, nil)
+	for i := 0; i < 2; i++ {
+		if err := store.Set("key", "value", 0); !errors.Is(err, faultfs.ErrInjected) {
+			t.Errorf("Expected ErrInjected, got %v", err)
+		}
+	}
+	if err := store.Set("key", "value", 0); err != nil {
+		t.Errorf("Expected the third write to succeed, got %v", err)
+	}
+}
+
+func TestFailAfter(t *testing.T) {
+	fsys := faultfs.New(nil)
+	store := getTestStore(t, fsys)
+	store.Set("key1", "value", 0)
+	store.Set("key2", "value", 0)
+	fsys.FailAfter(faultfs.OpRemove, 1, nil)
+	if err := store.Delete("key1"); err != nil {
+		t.Errorf("Expected the first removal to succeed, got %v", err)
+	}
+	if err := store.Delete("key2"); !errors.Is(err, faultfs.ErrInjected) {
+		t.Errorf("Expected the second removal to fail, got %v", err)
+	}
+	fsys.Heal()
+	if err := store.Delete("key2"); err != nil {
+		t.Errorf("Expected removals to succeed after Heal, got %v", err)
+	}
+}
+
+func TestFailKeys(t *testing.T) {
+	fsys := faultfs.New(nil)
+	store := getTestStore(t, fsys)
+	store.Set("user:1", "value", 0)
+	fsys.FailKeys("user:*", faultfs.OpWrite|faultfs.OpRemove, nil)
+	if err := store.Set("user:2", "value", 0); !errors.Is(err, faultfs.ErrInjected) {
+		t.Errorf("Expected writes of user:2 to fail, got %v", err)
+	}
+	if err := store.Delete("user:1"); !errors.Is(err, faultfs.ErrInjected) {
+		t.Errorf("Expected the deletion of user:1 to fail, got %v", err)
+	}
+	if err := store.Set("session:1", "value", 0); err != nil {
+		t.Errorf("Expected writes of other keys to succeed, got %v", err)
+	}
+	fsys.Heal()
+	if err := store.Set("user:2", "value", 0); err != nil {
+		t.Errorf("Expected writes to succeed after Heal, got %v", err)
+	}
+}
+
+func TestLatencyAndCalls(t *testing.T) {
+	fsys := faultfs.New(nil)
+	store := getTestStore(t, fsys)
+	fsys.SetLatency(20 * time.Millisecond)
+	writes := fsys.Calls(faultfs.OpWrite)
+	start := time.Now()
+	store.Set("key", "value", 0)
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Expected the write to take at least 20ms, took %s", elapsed)
+	}
+	// the temporary file is written and renamed over the cache file
+	if calls := fsys.Calls(faultfs.OpWrite) - writes; calls != 2 {
+		t.Errorf("Expected 2 writes, got %d", calls)
+	}
+}
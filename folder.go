@@ -0,0 +1,219 @@
+package goKeyValueStore
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// folderMode is the mode used to create the cache folder.
+const folderMode = 0700
+
+// minRetryBackoff and maxRetryBackoff bound the time between attempts to resume persistence.
+const (
+	minRetryBackoff = time.Second
+	maxRetryBackoff = time.Minute
+)
+
+// ErrCacheFolderIsFile is returned by NewKeyValueStore if the cache folder is a file.
+var ErrCacheFolderIsFile = errors.New("cache folder is a file")
+
+// ErrCacheFolderNotWritable is returned by NewKeyValueStore if the cache folder can not be created or written.
+var ErrCacheFolderNotWritable = errors.New("cache folder is not writable")
+
+// ErrDegraded is returned by Health while the store can not write to its cache folder and only keeps
+// key-value pairs in memory.
+var ErrDegraded = errors.New("cache folder is not writable, persistence is paused")
+
+// CacheFolder returns the absolute path of the cache folder or "" for memory-only stores.
+func (d *KeyValueStore) CacheFolder() string {
+	d.lazyInit()
+	return d.cacheFolder
+}
+
+// resolveCacheFolder returns the absolute path of a cache folder. "" stays "".
+func resolveCacheFolder(cacheFolder string) (string, error) {
+	if cacheFolder == "" {
+		return "", nil
+	}
+	return filepath.Abs(cacheFolder)
+}
+
+// checkCacheFolder creates the cache folder if needed and makes sure that files can be written to it.
+func (d *KeyValueStore) checkCacheFolder() error {
+	info, err := d.fs.Stat(d.cacheFolder)
+	if err == nil && !info.IsDir() {
+		return fmt.Errorf("%w: %w", ErrCacheFolderIsFile, &fs.PathError{Op: "open", Path: d.cacheFolder, Err: syscall.ENOTDIR})
+	}
+	err = d.fs.MkdirAll(d.cacheFolder, folderMode)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCacheFolderNotWritable, err)
+	}
+	probe := filepath.Join(d.cacheFolder, fmt.Sprintf(".probe-%d", os.Getpid()))
+	err = d.fs.WriteFile(probe, nil, 0600)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCacheFolderNotWritable, err)
+	}
+	return d.fs.Remove(probe)
+}
+
+// Health returns nil if the store works normally. If the cache folder disappeared and could not be created
+// again, it returns an error wrapping ErrDegraded until persistence resumes. While the store is initializing or
+// warming, it returns an error wrapping ErrNotReady.
+func (d *KeyValueStore) Health() error {
+	d.lazyInit()
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.degradedErr != nil {
+		return fmt.Errorf("%w: %w", ErrDegraded, d.degradedErr)
+	}
+	return d.notReady()
+}
+
+// OnError sets a function that is called with errors that no caller receives, e.g. when the store switches
+// to memory-only mode or the cleaner fails to delete a file. Errors of the start of the store, e.g. corrupt
+// cache files, are passed to the first function that is set. The function is called without holding any lock
+// of the store. A nil function removes it.
+func (d *KeyValueStore) OnError(fn func(error)) {
+	d.lazyInit()
+	d.errorMu.Lock()
+	d.onError = fn
+	var startErrors []error
+	if fn != nil {
+		startErrors = d.startErrors
+		d.startErrors = nil
+	}
+	d.errorMu.Unlock()
+	for _, err := range startErrors {
+		fn(err)
+	}
+}
+
+// reportError calls the OnError function. The caller must not hold the lock of the store.
+func (d *KeyValueStore) reportError(err error) {
+	d.errorMu.Lock()
+	onError := d.onError
+	d.errorMu.Unlock()
+	if onError != nil {
+		onError(err)
+	}
+}
+
+// queueError keeps an error for the OnError function until the lock of the store is released.
+func (d *KeyValueStore) queueError(err error) {
+	d.errorMu.Lock()
+	defer d.errorMu.Unlock()
+	d.pendingErrors = append(d.pendingErrors, err)
+}
+
+// afterWrite delivers the notifications collected by a write. It must be called after the lock was released.
+func (d *KeyValueStore) afterWrite() {
+	d.checkThresholds()
+	d.errorMu.Lock()
+	pending := d.pendingErrors
+	d.pendingErrors = nil
+	d.errorMu.Unlock()
+	for _, err := range pending {
+		d.reportError(err)
+	}
+}
+
+// persist runs an operation on the cache folder. If the operation fails because the cache folder disappeared,
+// the folder is created again with all entries in memory and the operation is retried once. If the folder can
+// not be created, the store switches to memory-only mode and retries to resume persistence with a growing
+// backoff on later operations. The caller must hold the write lock.
+func (d *KeyValueStore) persist(op func() error) error {
+	if d.degradedErr != nil && !d.tryResume() {
+		return nil
+	}
+	err := op()
+	if err == nil || d.folderExists() {
+		return err
+	}
+	err = d.recreateFolder()
+	if err != nil {
+		d.degrade(err)
+		return nil
+	}
+	return op()
+}
+
+// tryResume creates the cache folder again if the backoff elapsed. It returns true if persistence resumed.
+func (d *KeyValueStore) tryResume() bool {
+	if d.now().Before(d.retryAt) {
+		return false
+	}
+	err := d.recreateFolder()
+	if err != nil {
+		d.degrade(err)
+		return false
+	}
+	d.degradedErr = nil
+	d.retryBackoff = 0
+	return true
+}
+
+// degrade switches to memory-only mode or doubles the backoff if the store is already in it.
+func (d *KeyValueStore) degrade(err error) {
+	if d.degradedErr == nil {
+		d.retryBackoff = minRetryBackoff
+		d.queueError(fmt.Errorf("%w: %w", ErrDegraded, err))
+	} else {
+		d.retryBackoff = min(2*d.retryBackoff, maxRetryBackoff)
+	}
+	d.degradedErr = err
+	d.retryAt = d.now().Add(d.retryBackoff)
+}
+
+// recreateFolder creates the cache folder and writes all entries in memory to it.
+// Entries whose values were never loaded from the lost files are dropped.
+func (d *KeyValueStore) recreateFolder() error {
+	err := d.fs.MkdirAll(d.cacheFolder, folderMode)
+	if err != nil {
+		return err
+	}
+	if d.packing != nil {
+		d.packing = newPacking(d.packing.threshold, d.packing.segmentSize, d.packing.compactMinBytes)
+	}
+	for key, current := range d.data {
+		if d.nodeIsExpired(current) {
+			continue
+		}
+		value, err := current.value()
+		if err != nil {
+			d.removeNode(key)
+			continue
+		}
+		restored := &node{
+			Key:             key,
+			Value:           value,
+			DeleteTimestamp: current.DeleteTimestamp,
+			Revision:        current.Revision,
+			UpdatedAt:       current.UpdatedAt,
+			CreatedAt:       current.CreatedAt,
+			Sequence:        current.Sequence,
+			Source:          current.Source,
+			Instance:        current.Instance,
+			Run:             current.Run,
+		}
+		d.putNode(restored)
+		err = d.writeNode(restored)
+		if err != nil {
+			return err
+		}
+	}
+	if d.useIndex {
+		return d.writeIndex()
+	}
+	return nil
+}
+
+// folderExists returns true if the cache folder is a directory.
+func (d *KeyValueStore) folderExists() bool {
+	info, err := d.fs.Stat(d.cacheFolder)
+	return err == nil && info.IsDir()
+}
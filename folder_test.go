@@ -0,0 +1,387 @@
+package goKeyValueStore_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+	"github.com/richi0/goKeyValueStore/faultfs"
+)
+
+func countCacheFiles(t *testing.T, dir string) int {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*.store.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(files)
+}
+
+func TestFolderRecreated(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	store := getTestStoreWithClock(t, dir, newFakeClock())
+	store.Set("key1", "value1", 0)
+	os.RemoveAll(dir)
+	err := store.Set("key2", "value2", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := countCacheFiles(t, dir); count != 2 {
+		t.Errorf("Expected both entries to be written to the recreated folder, got %d files", count)
+	}
+	if err := store.Health(); err != nil {
+		t.Errorf("Expected a healthy store, got %v", err)
+	}
+	os.RemoveAll(dir)
+	err = store.Delete("key1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := countCacheFiles(t, dir); count != 1 {
+		t.Errorf("Expected the remaining entry to be written, got %d files", count)
+	}
+}
+
+func TestFolderDegradedAndResumed(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	fsys := faultfs.New(nil)
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithClock(clock.Now),
+		goKeyValueStore.WithCleanerStopped(true), goKeyValueStore.WithFilesystem(fsys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	reported := []error{}
+	store.OnError(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, err)
+	})
+	store.Set("key1", "value1", 0)
+	// the disk is gone: the folder can neither be found nor created
+	fsys.Fail(faultfs.OpWrite|faultfs.OpStat|faultfs.OpMkdir, nil)
+	for _, key := range []string{"key2", "key3"} {
+		if err := store.Set(key, "value", 0); err != nil {
+			t.Errorf("Expected Set to succeed in memory-only mode, got %v", err)
+		}
+	}
+	if value, ok := store.Get("key2"); !ok || value != "value" {
+		t.Errorf("Expected key2 in memory, got %v", value)
+	}
+	if err := store.Health(); !errors.Is(err, goKeyValueStore.ErrDegraded) {
+		t.Errorf("Expected ErrDegraded, got %v", err)
+	}
+	mu.Lock()
+	if len(reported) != 1 || !errors.Is(reported[0], goKeyValueStore.ErrDegraded) {
+		t.Errorf("Expected one ErrDegraded notification, got %v", reported)
+	}
+	mu.Unlock()
+	fsys.Heal()
+	store.Set("key4", "value", 0)
+	if !errors.Is(store.Health(), goKeyValueStore.ErrDegraded) {
+		t.Error("Expected the store to wait for the backoff before resuming")
+	}
+	clock.Advance(2 * time.Second)
+	store.Set("key5", "value", 0)
+	if err := store.Health(); err != nil {
+		t.Errorf("Expected persistence to resume, got %v", err)
+	}
+	if count := countCacheFiles(t, dir); count != 5 {
+		t.Errorf("Expected all 5 entries to be written after resuming, got %d files", count)
+	}
+}
+
+func TestCleanerReportsErrorsInsteadOfPanicking(t *testing.T) {
+	clock := newFakeClock()
+	fsys := faultfs.New(nil)
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, t.TempDir(), goKeyValueStore.WithClock(clock.Now),
+		goKeyValueStore.WithCleanerStopped(true), goKeyValueStore.WithFilesystem(fsys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("key", "value", 10)
+	failed := make(chan error, 1)
+	store.OnError(func(err error) {
+		select {
+		case failed <- err:
+		default:
+		}
+	})
+	fsys.FailKeys("key", faultfs.OpRemove, nil)
+	clock.Advance(time.Second)
+	interval := 10 * time.Millisecond
+	store.Reconfigure(goKeyValueStore.ConfigPatch{CleanInterval: &interval})
+	store.StartCleaning()
+	defer store.StopCleaning()
+	select {
+	case err := <-failed:
+		if err == nil {
+			t.Error("Expected an error")
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Expected the cleaner to report the failed deletion")
+	}
+}
+
+func TestCacheFolderIsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(path, []byte("data"), 0600)
+	_, err := goKeyValueStore.NewKeyValueStore(1, path)
+	if !errors.Is(err, goKeyValueStore.ErrCacheFolderIsFile) {
+		t.Fatalf("Expected ErrCacheFolderIsFile, got %v", err)
+	}
+	var pathErr *fs.PathError
+	if !errors.As(err, &pathErr) || pathErr.Path != path {
+		t.Errorf("Expected a path error for %s, got %v", path, err)
+	}
+}
+
+func TestCacheFolderNotWritable(t *testing.T) {
+	parent := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(parent, []byte("data"), 0600)
+	_, err := goKeyValueStore.NewKeyValueStore(1, filepath.Join(parent, "cache"))
+	if !errors.Is(err, goKeyValueStore.ErrCacheFolderNotWritable) {
+		t.Fatalf("Expected ErrCacheFolderNotWritable, got %v", err)
+	}
+}
+
+func TestCacheFolderIsAbsolute(t *testing.T) {
+	wd, _ := os.Getwd()
+	os.Chdir(t.TempDir())
+	defer os.Chdir(wd)
+	store, err := goKeyValueStore.NewKeyValueStore(1, "cache", goKeyValueStore.WithCleanerStopped(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if folder := store.CacheFolder(); !filepath.IsAbs(folder) || filepath.Base(folder) != "cache" {
+		t.Errorf("Expected an absolute cache folder, got %s", folder)
+	}
+	memory, err := goKeyValueStore.NewKeyValueStore(1, "", goKeyValueStore.WithCleanerStopped(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if folder := memory.CacheFolder(); folder != "" {
+		t.Errorf("Expected no cache folder, got %s", folder)
+	}
+}
+
+func cacheFileOf(t *testing.T, key string, value any) (string, []byte) {
+	t.Helper()
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, newFakeClock())
+	store.Set(key, value, 0)
+	files, err := filepath.Glob(filepath.Join(dir, "*.store.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected 1 file, got %v, %v", files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Base(files[0]), data
+}
+
+func TestSetRefusesFileOfOtherKey(t *testing.T) {
+	fileA, _ := cacheFileOf(t, "keyA", "valueA")
+	_, dataB := cacheFileOf(t, "keyB", "valueB")
+	dir := t.TempDir()
+	planted := filepath.Join(dir, fileA)
+	if err := os.WriteFile(planted, dataB, 0600); err != nil {
+		t.Fatal(err)
+	}
+	store := getTestStoreWithClock(t, dir, newFakeClock())
+	err := store.Set("keyA", "valueA", 0)
+	if !errors.Is(err, goKeyValueStore.ErrFilenameCollision) {
+		t.Fatalf("Expected ErrFilenameCollision, got %v", err)
+	}
+	data, err := os.ReadFile(planted)
+	if err != nil || string(data) != string(dataB) {
+		t.Errorf("Expected the file of keyB to be left intact, got %s, %v", data, err)
+	}
+	if value, ok := store.Get("keyB"); !ok || value != "valueB" {
+		t.Errorf("Expected keyB to be loaded from the planted file, got %v", value)
+	}
+}
+
+func TestHousekeepRemovesStaleTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, newFakeClock())
+	store.Set("key", "value", 0)
+	old := time.Now().Add(-time.Hour)
+	files := map[string]bool{
+		".probe-123":                   true,
+		"index.store.tmp":              true,
+		"segment-000001.pack.tmp":      true,
+		"notes.tmp":                    false,
+		".probe-mine":                  false,
+		"user.txt":                     false,
+		"index.store.tmp.bak":          false,
+		"0123.store.json.tmp":          true,
+		"segment-000001.pack.tmp.user": false,
+	}
+	for name := range files {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte("data"), 0600)
+		os.Chtimes(path, old, old)
+	}
+	os.WriteFile(filepath.Join(dir, "fresh.store.json.tmp"), []byte("data"), 0600)
+	restarted := getTestStoreWithClock(t, dir, newFakeClock())
+	for name, stale := range files {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if stale && err == nil {
+			t.Errorf("Expected stale %s to be removed", name)
+		}
+		if !stale && err != nil {
+			t.Errorf("Expected foreign %s to be kept, got %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "fresh.store.json.tmp")); err != nil {
+		t.Errorf("Expected a fresh temporary file to be kept, got %v", err)
+	}
+	if stats := restarted.Stats(); stats.TempFilesRemoved != 4 {
+		t.Errorf("Expected 4 removed files, got %d", stats.TempFilesRemoved)
+	}
+	if value, ok := restarted.Get("key"); !ok || value != "value" {
+		t.Errorf("Expected the entries to be kept, got %v", value)
+	}
+	path := filepath.Join(dir, ".probe-456")
+	os.WriteFile(path, nil, 0600)
+	os.Chtimes(path, old, old)
+	removed, err := restarted.Housekeep()
+	if err != nil || removed != 1 {
+		t.Errorf("Expected Housekeep to remove 1 file, got %d, %v", removed, err)
+	}
+}
+
+func TestClear(t *testing.T) {
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, newFakeClock())
+	store.Set("key1", "value1", 0)
+	store.Set("key2", "value2", 1000)
+	orphan, data := cacheFileOf(t, "orphan", "value")
+	os.WriteFile(filepath.Join(dir, orphan), data, 0600)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes"), 0600)
+	if err := store.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if store.Length() != 0 || countCacheFiles(t, dir) != 0 {
+		t.Errorf("Expected no entries and no cache files, got %d and %d", store.Length(), countCacheFiles(t, dir))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Errorf("Expected other files to be left, got %v", err)
+	}
+}
+
+func TestClearReportsFailedRemovals(t *testing.T) {
+	fsys := faultfs.New(nil)
+	store, err := goKeyValueStore.NewKeyValueStore(1, t.TempDir(), goKeyValueStore.WithFilesystem(fsys),
+		goKeyValueStore.WithCleanerStopped(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("key1", "value1", 0)
+	store.Set("key2", "value2", 0)
+	fsys.FailKeys("key1", faultfs.OpRemove, nil)
+	if err := store.Clear(); !errors.Is(err, faultfs.ErrInjected) {
+		t.Errorf("Expected ErrInjected, got %v", err)
+	}
+	if store.Length() != 0 {
+		t.Errorf("Expected memory to be cleared, got %d entries", store.Length())
+	}
+}
+
+func TestLeftoverTempFileIgnored(t *testing.T) {
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, newFakeClock())
+	store.Set("key1", "value1", 0)
+	store.Close()
+	files, _ := filepath.Glob(filepath.Join(dir, "*.store.json"))
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 file, got %d", len(files))
+	}
+	// a write that was killed before the rename
+	os.WriteFile(files[0]+".tmp", []byte(`{"key":"key1","val`), 0600)
+	restarted := getTestStoreWithClock(t, dir, newFakeClock())
+	defer restarted.Close()
+	if value, ok := restarted.Get("key1"); !ok || value != "value1" {
+		t.Errorf("Expected the complete file to be loaded, got %v", value)
+	}
+	if restarted.Length() != 1 {
+		t.Errorf("Expected length to be 1, got %d", restarted.Length())
+	}
+	restarted.Set("key1", "value2", 0)
+	if _, err := os.Stat(files[0] + ".tmp"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the next write to replace the temporary file, got %v", err)
+	}
+}
+
+func TestCorruptFileQuarantined(t *testing.T) {
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, newFakeClock())
+	store.Set("key1", "value1", 0)
+	store.Close()
+	files, _ := filepath.Glob(filepath.Join(dir, "*.store.json"))
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 file, got %d", len(files))
+	}
+	store = getTestStoreWithClock(t, dir, newFakeClock())
+	store.Set("key2", "value2", 0)
+	store.Close()
+	os.WriteFile(files[0], []byte(`{"key":"key1","val`), 0600)
+
+	restarted := getTestStoreWithClock(t, dir, newFakeClock())
+	defer restarted.Close()
+	if _, ok := restarted.Get("key1"); ok {
+		t.Errorf("Expected the corrupt key to be missing")
+	}
+	if value, ok := restarted.Get("key2"); !ok || value != "value2" {
+		t.Errorf("Expected the other keys to be loaded, got %v", value)
+	}
+	if _, err := os.Stat(files[0] + ".corrupt"); err != nil {
+		t.Errorf("Expected the corrupt file to be kept, got %v", err)
+	}
+	if stats := restarted.Stats(); stats.CorruptFiles != 1 {
+		t.Errorf("Expected 1 corrupt file, got %d", stats.CorruptFiles)
+	}
+	if countCacheFiles(t, dir) != 1 {
+		t.Errorf("Expected 1 cache file, got %d", countCacheFiles(t, dir))
+	}
+}
+
+func TestCorruptFilesSkippedOnStart(t *testing.T) {
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, newFakeClock())
+	store.Set("key1", "value1", 0)
+	store.Set("key2", "value2", 0)
+	store.Close()
+	os.WriteFile(filepath.Join(dir, "empty.store.json"), nil, 0600)
+	os.WriteFile(filepath.Join(dir, "text.store.json"), []byte("not json"), 0600)
+
+	_, err := goKeyValueStore.NewKeyValueStore(60, dir, goKeyValueStore.WithStrictLoad(true))
+	if !errors.Is(err, goKeyValueStore.ErrCorruptFile) {
+		t.Errorf("Expected ErrCorruptFile in strict mode, got %v", err)
+	}
+	restarted := getTestStoreWithClock(t, dir, newFakeClock())
+	defer restarted.Close()
+	if restarted.Length() != 2 {
+		t.Errorf("Expected the 2 good entries to be loaded, got %d", restarted.Length())
+	}
+	var skipped []error
+	restarted.OnError(func(err error) {
+		skipped = append(skipped, err)
+	})
+	if len(skipped) != 2 {
+		t.Errorf("Expected 2 skipped files to be reported, got %v", skipped)
+	}
+	for _, name := range []string{"empty.store.json", "text.store.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name+".corrupt")); err != nil {
+			t.Errorf("Expected %s to be quarantined, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package goKeyValueStore
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FollowChanges polls the cache folder every interval and applies changes that other processes sharing the
+// folder made to it. Files are compared by the revision and the writing store recorded in them, so a write that
+// only changes the deadline of a key is picked up as well, and so is a write of another store that happens to
+// reach the same revision. Every poll reads all files in the cache folder. Files that can not
+// be read or decoded, e.g. because they are being written, are retried on the next poll.
+// The returned function stops following and waits for a running poll to finish.
+// FollowChanges does nothing for stores without a cache folder and after Close, which stops all followers.
+func (d *KeyValueStore) FollowChanges(interval time.Duration) (stop func()) {
+	d.lazyInit()
+	if d.cacheFolder == "" {
+		return func() {}
+	}
+	d.followMu.Lock()
+	defer d.followMu.Unlock()
+	if d.closed.Load() {
+		return func() {}
+	}
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				d.syncFromDisk()
+			}
+		}
+	}()
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			d.followMu.Lock()
+			delete(d.followers, stopCh)
+			d.followMu.Unlock()
+			close(stopCh)
+			<-done
+		})
+	}
+	if d.followers == nil {
+		d.followers = map[chan struct{}]func(){}
+	}
+	d.followers[stopCh] = stop
+	return stop
+}
+
+// syncFromDisk replaces the nodes whose files were written after the nodes in memory and removes
+// persisted nodes whose files were deleted. Keys that were changed in memory while the files were read are left
+// alone. It returns the number of changed keys.
+func (d *KeyValueStore) syncFromDisk() (int, error) {
+	d.mu.RLock()
+	known := make(map[string]*node, len(d.data))
+	for key, node := range d.data {
+		known[key] = node
+	}
+	d.mu.RUnlock()
+	entries, err := d.fs.ReadDir(d.cacheFolder)
+	if err != nil {
+		return 0, err
+	}
+	seen := map[string]bool{}
+	changed := map[string]*node{}
+	for _, file := range entries {
+		if !strings.HasSuffix(file.Name(), d.cacheFileSuffix()) {
+			continue
+		}
+		fileData, err := d.readCacheFile(filepath.Join(d.cacheFolder, file.Name()))
+		if err != nil {
+			continue
+		}
+		node := &node{size: len(fileData)}
+		err = d.decodeNode(fileData, node)
+		if err != nil {
+			continue
+		}
+		seen[node.Key] = true
+		if current, ok := known[node.Key]; ok && sameWrite(current, node) {
+			continue
+		}
+		node.Value, err = d.transformLoaded(node.Key, node.Value, node.Type)
+		if err != nil {
+			continue
+		}
+		changed[node.Key] = node
+	}
+	defer d.afterWrite()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	count := 0
+	for key, node := range changed {
+		if d.data[key] != known[key] {
+			continue // the key was changed since the files were read
+		}
+		d.putNode(node)
+		d.observeRevision(node.Revision)
+		d.recordEvent(key, EventSet, "changed by another process")
+		count++
+	}
+	for key, node := range known {
+		if seen[key] || node.size == 0 || d.data[key] != node || d.isPacked(key) {
+			continue
+		}
+		d.removeNode(key)
+		d.recordEvent(key, EventDeleted, "deleted by another process")
+		count++
+	}
+	return count, nil
+}
+
+// sameWrite returns true if two nodes come from the same write. Revisions are only unique within a store, so the
+// store that wrote the nodes is compared as well.
+func sameWrite(a *node, b *node) bool {
+	return a.Revision == b.Revision && a.Instance == b.Instance && a.Run == b.Run
+}
@@ -0,0 +1,105 @@
+package goKeyValueStore_test
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRevisionIncrements(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, clock)
+	if _, ok := store.Revision("key"); ok {
+		t.Error("Expected no revision for a missing key")
+	}
+	store.Set("key", "value1", 1000)
+	first, _ := store.Revision("key")
+	store.Set("key", "value2", 1000)
+	second, _ := store.Revision("key")
+	store.AdjustTTL(time.Hour, nil)
+	third, _ := store.Revision("key")
+	if !(first < second && second < third) {
+		t.Errorf("Expected increasing revisions, got %d, %d, %d", first, second, third)
+	}
+	restored := getTestStoreWithClock(t, dir, clock)
+	if revision, _ := restored.Revision("key"); revision != third {
+		t.Errorf("Expected revision %d after restart, got %d", third, revision)
+	}
+	restored.Set("other", "value", 0)
+	if revision, _ := restored.Revision("other"); revision <= third {
+		t.Errorf("Expected a revision larger than %d after restart, got %d", third, revision)
+	}
+	store.Delete("key")
+	if _, ok := store.Revision("key"); ok {
+		t.Error("Expected no revision for a deleted key")
+	}
+}
+
+func TestFollowerPicksUpExtendedTTL(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	writer := getTestStoreWithClock(t, dir, clock)
+	writer.Set("key", "value", 1000)
+	follower := getTestStoreWithClock(t, dir, clock)
+	writer.AdjustTTL(time.Hour, nil)
+	changed, err := follower.SyncFromDisk()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 1 {
+		t.Errorf("Expected 1 changed key, got %d", changed)
+	}
+	clock.Advance(2 * time.Second)
+	if value, ok := follower.Get("key"); !ok || value != "value" {
+		t.Errorf("Expected the follower to use the extended deadline, got %v, %v", value, ok)
+	}
+	writerRevision, _ := writer.Revision("key")
+	followerRevision, _ := follower.Revision("key")
+	if writerRevision != followerRevision {
+		t.Errorf("Expected revision %d, got %d", writerRevision, followerRevision)
+	}
+}
+
+func TestFollowerPicksUpWriteOfSameRevision(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	writer1 := getTestStoreWithClock(t, dir, clock)
+	writer2 := getTestStoreWithClock(t, dir, clock)
+	writer1.Set("key", "value1", 0)
+	follower := getTestStoreWithClock(t, dir, clock)
+	writer2.Set("key", "value2", 0)
+	revision1, _ := follower.Revision("key")
+	revision2, _ := writer2.Revision("key")
+	if revision1 != revision2 {
+		t.Fatalf("Expected both writers to reach revision %d, got %d", revision1, revision2)
+	}
+	changed, err := follower.SyncFromDisk()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 1 {
+		t.Errorf("Expected 1 changed key, got %d", changed)
+	}
+	if value, _ := follower.Get("key"); value != "value2" {
+		t.Errorf("Expected value2, got %v", value)
+	}
+}
+
+func TestFollowChanges(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	writer := getTestStoreWithClock(t, dir, clock)
+	follower := getTestStoreWithClock(t, dir, clock)
+	stop := follower.FollowChanges(10 * time.Millisecond)
+	defer stop()
+	writer.Set("key", "value", 0)
+	waitFor(t, func() bool {
+		value, ok := follower.Get("key")
+		return ok && value == "value"
+	})
+	writer.Delete("key")
+	waitFor(t, func() bool {
+		_, ok := follower.Get("key")
+		return !ok
+	})
+}
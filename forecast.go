@@ -0,0 +1,71 @@
+package goKeyValueStore
+
+import (
+	"time"
+)
+
+// NextExpiration returns the deadline of the key that expires next. If no key expires, the second return
+// value is false. Keys without expiration are ignored. With EvictNearestExpiry, the deadline is read from the
+// heap of the eviction policy and only expired keys that were not cleaned yet are visited; with other eviction
+// policies, all entries are scanned.
+func (d *KeyValueStore) NextExpiration() (time.Time, bool) {
+	d.lazyInit()
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	now := d.now().UnixMilli()
+	next := neverExpire
+	d.visitDeadlines(func(deadline int64) bool {
+		if deadline < now {
+			return true // expired, nearer deadlines may follow
+		}
+		next = min(next, deadline)
+		return false
+	})
+	if next == neverExpire {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(next), true
+}
+
+// ExpirationForecast returns the number of keys that expire in each of the next buckets windows. The first
+// window starts now. Keys without expiration and keys expiring after the last window are not counted.
+// With EvictNearestExpiry, only the keys that expire before the end of the last window are visited; with other
+// eviction policies, all entries are scanned.
+func (d *KeyValueStore) ExpirationForecast(window time.Duration, buckets int) []int {
+	d.lazyInit()
+	counts := make([]int, max(buckets, 0))
+	windowMs := window.Milliseconds()
+	if buckets <= 0 || windowMs <= 0 {
+		return counts
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	now := d.now().UnixMilli()
+	end := neverExpire
+	if windowMs < (neverExpire-now)/int64(buckets) {
+		end = now + windowMs*int64(buckets)
+	}
+	d.visitDeadlines(func(deadline int64) bool {
+		if deadline >= end {
+			return false
+		}
+		if deadline >= now {
+			counts[(deadline-now)/windowMs]++
+		}
+		return true
+	})
+	return counts
+}
+
+// visitDeadlines calls fn with the deadlines of all entries. If fn returns false, the deadline heap of
+// EvictNearestExpiry skips the later deadlines below it; other eviction policies scan all entries and call fn with
+// every deadline. The caller must hold the lock.
+func (d *KeyValueStore) visitDeadlines(fn func(deadline int64) bool) {
+	if strategy, ok := d.eviction.(*nearestExpiryStrategy); ok {
+		strategy.walk(fn)
+		return
+	}
+	for _, node := range d.data {
+		fn(node.DeleteTimestamp)
+	}
+}
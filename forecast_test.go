@@ -0,0 +1,80 @@
+package goKeyValueStore_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestExpirationForecast(t *testing.T) {
+	clock := newFakeClock()
+	store := getTestStoreWithClock(t, "", clock)
+	minute := int(time.Minute.Milliseconds())
+	store.Set("a", "value", 1*minute)
+	store.Set("b", "value", 4*minute)
+	store.Set("c", "value", 6*minute)
+	store.Set("d", "value", 14*minute)
+	store.Set("e", "value", 61*minute)
+	store.Set("never", "value", 0)
+	forecast := store.ExpirationForecast(5*time.Minute, 12)
+	expected := []int{2, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	if !slices.Equal(forecast, expected) {
+		t.Errorf("Expected forecast %v, got %v", expected, forecast)
+	}
+	clock.Advance(2 * time.Minute)
+	forecast = store.ExpirationForecast(5*time.Minute, 3)
+	expected = []int{2, 0, 1}
+	if !slices.Equal(forecast, expected) {
+		t.Errorf("Expected forecast %v after the first key expired, got %v", expected, forecast)
+	}
+}
+
+func TestNextExpiration(t *testing.T) {
+	clock := newFakeClock()
+	store := getTestStoreWithClock(t, "", clock)
+	if _, ok := store.NextExpiration(); ok {
+		t.Error("Expected no next expiration for an empty store")
+	}
+	store.Set("never", "value", 0)
+	store.Set("first", "value", 1000)
+	store.Set("second", "value", 2000)
+	next, ok := store.NextExpiration()
+	if !ok || !next.Equal(clock.Now().Add(time.Second)) {
+		t.Errorf("Expected the first deadline, got %v, %v", next, ok)
+	}
+	store.Delete("first")
+	next, ok = store.NextExpiration()
+	if !ok || !next.Equal(clock.Now().Add(2*time.Second)) {
+		t.Errorf("Expected the second deadline after deleting the first key, got %v, %v", next, ok)
+	}
+	clock.Advance(3 * time.Second)
+	if next, ok := store.NextExpiration(); ok {
+		t.Errorf("Expected no next expiration after the second key expired, got %v", next)
+	}
+}
+
+func TestExpirationForecastWithoutDeadlineHeap(t *testing.T) {
+	clock := newFakeClock()
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, "", goKeyValueStore.WithClock(clock.Now),
+		goKeyValueStore.WithCleanerStopped(true), goKeyValueStore.WithEvictionPolicy(goKeyValueStore.EvictLRU))
+	if err != nil {
+		t.Fatal(err)
+	}
+	minute := int(time.Minute.Milliseconds())
+	store.Set("expired", "value", 1*minute)
+	store.Set("a", "value", 3*minute)
+	store.Set("b", "value", 9*minute)
+	store.Set("never", "value", 0)
+	clock.Advance(2 * time.Minute)
+	forecast := store.ExpirationForecast(5*time.Minute, 2)
+	expected := []int{1, 1}
+	if !slices.Equal(forecast, expected) {
+		t.Errorf("Expected forecast %v, got %v", expected, forecast)
+	}
+	next, ok := store.NextExpiration()
+	if !ok || !next.Equal(clock.Now().Add(time.Minute)) {
+		t.Errorf("Expected the deadline of a, got %v, %v", next, ok)
+	}
+}
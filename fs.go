@@ -0,0 +1,98 @@
+package goKeyValueStore
+
+import (
+	"io/fs"
+	"os"
+	"runtime"
+)
+
+// An FS is the filesystem the cache folder is accessed through. Every read, write, and deletion in the cache
+// folder goes through it, so tests can inject failures and latency, see the faultfs package.
+type FS interface {
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	// AppendFile appends data to a file with a single write, creating the file if it does not exist.
+	AppendFile(name string, data []byte, perm fs.FileMode) error
+	Rename(oldPath, newPath string) error
+	Remove(name string) error
+	ReadDir(name string) ([]fs.DirEntry, error)
+	MkdirAll(path string, perm fs.FileMode) error
+	Stat(name string) (fs.FileInfo, error)
+}
+
+// A SyncFS is an FS that can flush files to stable storage. WithSyncWrites only syncs if the FS of the store
+// implements it.
+type SyncFS interface {
+	FS
+	// Sync flushes a file or a directory to stable storage.
+	Sync(name string) error
+}
+
+// OSFS is the FS of the operating system. It is used unless WithFilesystem sets another one.
+type OSFS struct{}
+
+// ReadFile reads a file with os.ReadFile.
+func (OSFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+// WriteFile writes a file with os.WriteFile.
+func (OSFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
+
+// AppendFile appends data to a file with a single write.
+func (OSFS) AppendFile(name string, data []byte, perm fs.FileMode) error {
+	file, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Rename renames a file with os.Rename.
+func (OSFS) Rename(oldPath, newPath string) error {
+	return os.Rename(oldPath, newPath)
+}
+
+// Remove removes a file with os.Remove.
+func (OSFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// ReadDir reads a directory with os.ReadDir.
+func (OSFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+// MkdirAll creates a directory with os.MkdirAll.
+func (OSFS) MkdirAll(path string, perm fs.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+// Sync opens a file or a directory and calls File.Sync. Directories can not be synced on Windows, so syncing
+// them does nothing there.
+func (OSFS) Sync(name string) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if runtime.GOOS == "windows" {
+		info, err := file.Stat()
+		if err != nil || info.IsDir() {
+			return err
+		}
+	}
+	return file.Sync()
+}
+
+// Stat returns the file info of a file with os.Stat.
+func (OSFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
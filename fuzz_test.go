@@ -0,0 +1,116 @@
+package goKeyValueStore_test
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+var cacheFileName = regexp.MustCompile(`^[0-9a-f]{64}\.store\.json$`)
+
+func FuzzKeyRoundTrip(f *testing.F) {
+	f.Add("key")
+	f.Add("")
+	f.Add("path/to/../key\\with:separators")
+	f.Add("nul\x00byte")
+	f.Add("invalid\xffutf8")
+	f.Add(strings.Repeat("schlüssel🔑", 1000))
+	f.Fuzz(func(t *testing.T, key string) {
+		for _, index := range []bool{false, true} {
+			dir := t.TempDir()
+			store := getFuzzStore(t, dir, index)
+			err := store.Set(key, "value", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			restored := getFuzzStore(t, dir, index)
+			value, ok := restored.Get(key)
+			if !ok || value != "value" {
+				t.Fatalf("Expected %q to survive a restart, got %v, %v", key, value, ok)
+			}
+			keys := []string{}
+			restored.Range(func(key string, value any) bool {
+				keys = append(keys, key)
+				return true
+			})
+			if len(keys) != 1 || keys[0] != key {
+				t.Fatalf("Expected key %q after restart, got %q", key, keys)
+			}
+			checkCacheFileNames(t, dir)
+		}
+	})
+}
+
+func FuzzValueRoundTrip(f *testing.F) {
+	f.Add("value", int64(1), 1.5, true)
+	f.Add("", int64(0), 0.0, false)
+	f.Add("nul\x00byte", int64(1<<60+1), -1e300, true)
+	f.Add("invalid\xffutf8   <script>", int64(-1<<63), 5e-324, false)
+	f.Fuzz(func(t *testing.T, s string, n int64, x float64, b bool) {
+		value := map[string]any{"string": s, "int": n, "float": x, "bool": b, "list": []any{s, n}}
+		dir := t.TempDir()
+		store := getFuzzStore(t, dir, false)
+		err := store.Set("map", value, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = store.Set("string", s, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		restored := getFuzzStore(t, dir, false)
+		for key, expected := range map[string]any{"map": value, "string": s} {
+			got, ok := restored.Get(key)
+			if !ok {
+				t.Fatalf("Expected %s to survive a restart", key)
+			}
+			if want := decodeLikeStore(t, expected); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Expected %s to be %#v, got %#v", key, want, got)
+			}
+		}
+		checkCacheFileNames(t, dir)
+	})
+}
+
+func getFuzzStore(t *testing.T, dir string, index bool) *goKeyValueStore.KeyValueStore {
+	store, err := goKeyValueStore.NewKeyValueStore(1, dir,
+		goKeyValueStore.WithUseNumber(true), goKeyValueStore.WithIndex(index), goKeyValueStore.WithCleanerStopped(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// decodeLikeStore applies the documented decode rules of values read from the cache folder:
+// values are JSON encoded, so strings are coerced to valid UTF-8 and numbers become json.Number.
+func decodeLikeStore(t *testing.T, value any) any {
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	var decoded any
+	err = decoder.Decode(&decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func checkCacheFileNames(t *testing.T, dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != "index.store" && !cacheFileName.MatchString(entry.Name()) {
+			t.Fatalf("Unexpected file name %q", entry.Name())
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package goKeyValueStore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrLoaderPanicked is returned by GetOrCompute to the calls that waited for a loader that panicked.
+var ErrLoaderPanicked = errors.New("loader panicked")
+
+// GetOrSet gets the value of a key or, if the key does not exist or is expired, sets it to value with a TTL in
+// milliseconds. The second return value is true if the existing value was returned. The check and the insert
+// happen while holding the write lock, so of several concurrent calls for a missing key exactly one inserts its
+// value and all others return it. The value is only saved in the cache folder if it was inserted.
+func (d *KeyValueStore) GetOrSet(key string, value any, ttl int) (any, bool, error) {
+	d.lazyInit()
+	key = d.storageKey(key)
+	if existing, ok := d.get(key); ok {
+		d.audit(context.Background(), "get", key, true, nil)
+		return existing, true, nil
+	}
+	existing, loaded, err := d.getOrSet(key, value, ttl)
+	if loaded {
+		d.audit(context.Background(), "get", key, true, nil)
+	} else {
+		d.audit(context.Background(), "set", key, true, err)
+	}
+	return existing, loaded, err
+}
+
+// getOrSet gets the live value of a key or sets it to value.
+func (d *KeyValueStore) getOrSet(key string, value any, ttl int) (any, bool, error) {
+	err := d.checkValue(value)
+	if err == nil {
+		err = d.takeWriteTokens(1)
+	}
+	if err != nil {
+		d.recordSetResult(key, err)
+		return nil, false, err
+	}
+	defer d.afterWrite()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed.Load() {
+		return nil, false, ErrClosed
+	}
+	if old, ok := d.data[key]; ok && !d.nodeIsExpired(old) {
+		existing, err := old.value()
+		if err != nil {
+			return nil, false, err
+		}
+		d.eviction.touch(old)
+		return existing, true, nil
+	}
+	err = d.makeRoomInQuotas(key)
+	if err != nil {
+		d.recordSetResult(key, err)
+		return nil, false, err
+	}
+	node := d.newNode(key, value, ttl)
+	d.putNode(node)
+	err = d.saveInCache(node)
+	d.recordSetResult(key, err)
+	if err != nil {
+		return nil, false, err
+	}
+	return value, false, d.evictOverflow(key)
+}
+
+// GetSet sets a key-value pair with a TTL in milliseconds like Set and returns the value it replaced. The second
+// return value is false if the key did not exist or was expired. Reading the old value, setting the new one, and
+// saving it in the cache folder happen while holding the write lock.
+func (d *KeyValueStore) GetSet(key string, value any, ttl int) (any, bool, error) {
+	d.lazyInit()
+	key = d.storageKey(key)
+	old, existed, err := d.getSet(key, value, ttl)
+	d.audit(context.Background(), "set", key, true, err)
+	return old, existed, err
+}
+
+// getSet sets a key-value pair and returns the live value it replaced.
+func (d *KeyValueStore) getSet(key string, value any, ttl int) (any, bool, error) {
+	err := d.checkValue(value)
+	if err == nil {
+		err = d.takeWriteTokens(1)
+	}
+	if err != nil {
+		d.recordSetResult(key, err)
+		return nil, false, err
+	}
+	defer d.afterWrite()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed.Load() {
+		return nil, false, ErrClosed
+	}
+	var old any
+	existed := false
+	if node, ok := d.data[key]; ok && !d.nodeIsExpired(node) {
+		old, err = node.value()
+		if err != nil {
+			return nil, false, d.keyError("load value", key, err)
+		}
+		existed = true
+	}
+	err = d.makeRoomInQuotas(key)
+	if err != nil {
+		d.recordSetResult(key, err)
+		return nil, false, err
+	}
+	node := d.newNode(key, value, ttl)
+	d.putNode(node)
+	err = d.saveInCache(node)
+	d.recordSetResult(key, err)
+	if err != nil {
+		return old, existed, err
+	}
+	return old, existed, d.evictOverflow(key)
+}
+
+// GetOrSetFunc gets the value of a key or, if the key does not exist, sets it to the value returned by factory
+// with a TTL in milliseconds. The second return value is true if the value was found. factory is only called on
+// a miss, and if it returns an error nothing is set and the error is returned.
+// Calls for the same key are serialized with the lock used by WithKeyLock, so factory is called once even if
+// several calls miss at the same time, while calls for other keys proceed in parallel. factory runs while
+// holding that lock, so it must be fast and must not call GetOrSetFunc or WithKeyLock.
+func (d *KeyValueStore) GetOrSetFunc(key string, ttl int, factory func() (any, error)) (any, bool, error) {
+	d.lazyInit()
+	mu := d.keyLock(key)
+	mu.Lock()
+	defer mu.Unlock()
+	value, ok := d.get(d.storageKey(key))
+	d.audit(context.Background(), "get", d.storageKey(key), ok, nil)
+	if ok {
+		return value, true, nil
+	}
+	value, err := factory()
+	if err != nil {
+		return nil, false, err
+	}
+	err = d.Set(key, value, ttl)
+	if err != nil {
+		return nil, false, err
+	}
+	return value, false, nil
+}
+
+// A flight is a running call of the loader of GetOrCompute. done is closed when value and err are set.
+type flight struct {
+	done  chan struct{}
+	value any
+	err   error
+}
+
+// GetOrCompute gets the value of a key or, if the key does not exist, sets it to the value returned by loader with
+// a TTL in milliseconds. Concurrent calls for the same missing key share a single call of loader and all return its
+// result. If loader returns an error, nothing is set and the error is returned to all calls that waited for it;
+// the next call calls loader again. If loader panics, the calls that waited for it return ErrLoaderPanicked and
+// the panic is passed on in the call that ran loader. Unlike GetOrSetFunc, loader runs without holding any lock
+// of the store.
+func (d *KeyValueStore) GetOrCompute(key string, ttl int, loader func() (any, error)) (any, error) {
+	d.lazyInit()
+	stored := d.storageKey(key)
+	value, ok := d.get(stored)
+	d.audit(context.Background(), "get", stored, ok, nil)
+	if ok {
+		return value, nil
+	}
+	d.flightMu.Lock()
+	if f, ok := d.flights[stored]; ok {
+		d.flightMu.Unlock()
+		<-f.done
+		return f.value, f.err
+	}
+	f := &flight{done: make(chan struct{})}
+	d.flights[stored] = f
+	d.flightMu.Unlock()
+	defer func() {
+		r := recover()
+		if r != nil {
+			f.value, f.err = nil, fmt.Errorf("%w: %v", ErrLoaderPanicked, r)
+		}
+		d.flightMu.Lock()
+		delete(d.flights, stored)
+		d.flightMu.Unlock()
+		close(f.done)
+		if r != nil {
+			panic(r)
+		}
+	}()
+	f.value, f.err = loader()
+	if f.err == nil {
+		f.err = d.Set(key, f.value, ttl)
+	}
+	if f.err != nil {
+		f.value = nil
+	}
+	return f.value, f.err
+}
@@ -0,0 +1,254 @@
+package goKeyValueStore_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestGetOrSetFuncHit(t *testing.T) {
+	store := getTestStore()
+	value, found, err := store.GetOrSetFunc("key1", 1000, func() (any, error) {
+		t.Error("Expected factory not to be called on a hit")
+		return nil, nil
+	})
+	if err != nil || !found || value != "value1" {
+		t.Errorf("Expected value1, got %v, %v, %v", value, found, err)
+	}
+}
+
+func TestGetOrSetFuncMiss(t *testing.T) {
+	store, _ := goKeyValueStore.NewKeyValueStore(1, "")
+	value, found, err := store.GetOrSetFunc("key", 1000, func() (any, error) {
+		return "computed", nil
+	})
+	if err != nil || found || value != "computed" {
+		t.Errorf("Expected computed, got %v, %v, %v", value, found, err)
+	}
+	if value, ok := store.Get("key"); !ok || value != "computed" {
+		t.Errorf("Expected computed to be set, got %v", value)
+	}
+}
+
+func TestGetOrSetFuncErrorNotCached(t *testing.T) {
+	store, _ := goKeyValueStore.NewKeyValueStore(1, "")
+	factoryErr := errors.New("factory failed")
+	_, _, err := store.GetOrSetFunc("key", 1000, func() (any, error) {
+		return nil, factoryErr
+	})
+	if !errors.Is(err, factoryErr) {
+		t.Errorf("Expected factory error, got %v", err)
+	}
+	if _, ok := store.Get("key"); ok {
+		t.Error("Expected nothing to be set")
+	}
+	calls := 0
+	store.GetOrSetFunc("key", 1000, func() (any, error) {
+		calls++
+		return "value", nil
+	})
+	if calls != 1 {
+		t.Errorf("Expected factory to be called again, got %d calls", calls)
+	}
+}
+
+func TestGetOrSetFuncDifferentKeysInParallel(t *testing.T) {
+	store, _ := goKeyValueStore.NewKeyValueStore(1, "")
+	other := "b"
+	for store.SameKeyLock("a", other) {
+		other += "b"
+	}
+	started := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		store.GetOrSetFunc("a", 1000, func() (any, error) {
+			close(started)
+			<-done
+			return "a", nil
+		})
+	}()
+	<-started
+	finished := make(chan struct{})
+	go func() {
+		store.GetOrSetFunc(other, 1000, func() (any, error) {
+			return other, nil
+		})
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Error("Expected a miss on another key not to wait for a running factory")
+	}
+	close(done)
+}
+
+func TestGetOrSet(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, clock)
+	value, loaded, err := store.GetOrSet("key", "first", 1000)
+	if err != nil || loaded || value != "first" {
+		t.Errorf("Expected first to be inserted, got %v, %v, %v", value, loaded, err)
+	}
+	value, loaded, err = store.GetOrSet("key", "second", 1000)
+	if err != nil || !loaded || value != "first" {
+		t.Errorf("Expected first to be loaded, got %v, %v, %v", value, loaded, err)
+	}
+	clock.Advance(1001 * time.Millisecond)
+	value, loaded, _ = store.GetOrSet("key", "third", 0)
+	if loaded || value != "third" {
+		t.Errorf("Expected the expired value to be overwritten, got %v, %v", value, loaded)
+	}
+	if value, ok := getTestStoreWithClock(t, dir, clock).Get("key"); !ok || value != "third" {
+		t.Errorf("Expected third to be persisted, got %v", value)
+	}
+}
+
+func TestGetOrSetConcurrent(t *testing.T) {
+	store, _ := goKeyValueStore.NewKeyValueStore(1, "")
+	var wg sync.WaitGroup
+	var inserts atomic.Int32
+	values := make([]any, 20)
+	for i := range values {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, loaded, _ := store.GetOrSet("key", i, 0)
+			if !loaded {
+				inserts.Add(1)
+			}
+			values[i] = value
+		}()
+	}
+	wg.Wait()
+	if inserts.Load() != 1 {
+		t.Errorf("Expected exactly one insert, got %d", inserts.Load())
+	}
+	for _, value := range values {
+		if value != values[0] {
+			t.Errorf("Expected all calls to return %v, got %v", values[0], value)
+		}
+	}
+}
+
+func TestGetOrComputeSingleFlight(t *testing.T) {
+	store, _ := goKeyValueStore.NewKeyValueStore(1, "")
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func() (any, error) {
+		calls.Add(1)
+		<-release
+		return "computed", nil
+	}
+	var wg sync.WaitGroup
+	values := make([]any, 50)
+	for i := range values {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			values[i], _ = store.GetOrCompute("key", 1000, loader)
+		}()
+	}
+	waitFor(t, func() bool { return calls.Load() == 1 })
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Errorf("Expected one loader call, got %d", calls.Load())
+	}
+	for _, value := range values {
+		if value != "computed" {
+			t.Errorf("Expected computed for every caller, got %v", value)
+		}
+	}
+}
+
+func TestGetOrComputeErrorShared(t *testing.T) {
+	store, _ := goKeyValueStore.NewKeyValueStore(1, "")
+	loaderErr := errors.New("api unavailable")
+	release := make(chan struct{})
+	var calls atomic.Int32
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = store.GetOrCompute("key", 1000, func() (any, error) {
+				calls.Add(1)
+				<-release
+				return nil, loaderErr
+			})
+		}()
+	}
+	waitFor(t, func() bool { return calls.Load() == 1 })
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	for _, err := range errs {
+		if !errors.Is(err, loaderErr) {
+			t.Errorf("Expected the loader error for every caller, got %v", err)
+		}
+	}
+	value, err := store.GetOrCompute("key", 1000, func() (any, error) { return "retried", nil })
+	if err != nil || value != "retried" {
+		t.Errorf("Expected the error to not be cached, got %v, %v", value, err)
+	}
+}
+
+func TestGetOrComputePanicShared(t *testing.T) {
+	store, _ := goKeyValueStore.NewKeyValueStore(1, "")
+	release := make(chan struct{})
+	loading := make(chan struct{})
+	recovered := make(chan any)
+	go func() {
+		defer func() { recovered <- recover() }()
+		store.GetOrCompute("key", 1000, func() (any, error) {
+			close(loading)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-loading
+	waiterErr := make(chan error)
+	go func() {
+		_, err := store.GetOrCompute("key", 1000, func() (any, error) { return "waiter", nil })
+		waiterErr <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	if r := <-recovered; r != "boom" {
+		t.Errorf("Expected the panic to be passed on, got %v", r)
+	}
+	if err := <-waiterErr; !errors.Is(err, goKeyValueStore.ErrLoaderPanicked) {
+		t.Errorf("Expected ErrLoaderPanicked for the waiter, got %v", err)
+	}
+	value, err := store.GetOrCompute("key", 1000, func() (any, error) { return "retried", nil })
+	if err != nil || value != "retried" {
+		t.Errorf("Expected the panic to not be cached, got %v, %v", value, err)
+	}
+}
+
+func TestGetSet(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, clock)
+	if old, existed, err := store.GetSet("config", "v1", 1000); err != nil || existed || old != nil {
+		t.Errorf("Expected no old value, got %v, %v, %v", old, existed, err)
+	}
+	if old, existed, _ := store.GetSet("config", "v2", 1000); !existed || old != "v1" {
+		t.Errorf("Expected v1, got %v, %v", old, existed)
+	}
+	clock.Advance(1001 * time.Millisecond)
+	if old, existed, _ := store.GetSet("config", "v3", 0); existed || old != nil {
+		t.Errorf("Expected an expired value to not exist, got %v, %v", old, existed)
+	}
+	if value, ok := getTestStoreWithClock(t, dir, clock).Get("config"); !ok || value != "v3" {
+		t.Errorf("Expected v3 to be persisted, got %v", value)
+	}
+}
@@ -0,0 +1,48 @@
+package goKeyValueStore
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+// ErrUnsupportedWithHashedKeys is returned by NewKeyValueStore if WithHashedKeys is combined with an option that
+// matches key prefixes, and by Scan and the methods that match key prefixes or patterns, because hashed keys have
+// no meaningful prefixes or order.
+var ErrUnsupportedWithHashedKeys = errors.New("operation is not supported with hashed keys")
+
+// ErrEmptyKeySalt is returned by NewKeyValueStore if WithHashedKeys is used with an empty salt.
+var ErrEmptyKeySalt = errors.New("salt of hashed keys is empty")
+
+// storageKey returns the key under which a key supplied by a caller is stored. With WithHashedKeys it is the
+// hex encoded HMAC-SHA256 of the key, otherwise the key itself.
+func (d *KeyValueStore) storageKey(key string) string {
+	if !d.hashKeys {
+		return key
+	}
+	mac := hmac.New(sha256.New, d.keySalt)
+	mac.Write([]byte(key))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// checkHashedKeys returns an error if the options of a store with hashed keys conflict.
+func (d *KeyValueStore) checkHashedKeys() error {
+	if !d.hashKeys {
+		return nil
+	}
+	if len(d.keySalt) == 0 {
+		return ErrEmptyKeySalt
+	}
+	if len(d.quotas) > 0 {
+		return ErrUnsupportedWithHashedKeys
+	}
+	if d.auditLog != nil {
+		for _, prefix := range d.auditLog.prefixes {
+			if prefix != "" {
+				return ErrUnsupportedWithHashedKeys
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package goKeyValueStore_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/richi0/goKeyValueStore"
+	"github.com/richi0/goKeyValueStore/faultfs"
+)
+
+const rawKey = "alice@example.com"
+
+func TestHashedKeysNeverStored(t *testing.T) {
+	dir := t.TempDir()
+	salt := goKeyValueStore.WithHashedKeys([]byte("salt"))
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, dir, salt, goKeyValueStore.WithCleanerStopped(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set(rawKey, "value", 0)
+	store.SetManyDetailed([]goKeyValueStore.Entry{{Key: rawKey + ".2", Value: "value"}})
+	for _, key := range store.Keys() {
+		if strings.Contains(key, "alice") {
+			t.Errorf("Expected only hashed keys, got %s", key)
+		}
+	}
+	files, _ := os.ReadDir(dir)
+	for _, file := range files {
+		data, _ := os.ReadFile(filepath.Join(dir, file.Name()))
+		if bytes.Contains(data, []byte("alice")) || strings.Contains(file.Name(), "alice") {
+			t.Errorf("Expected file %s to not contain the raw key: %s", file.Name(), data)
+		}
+	}
+	restarted, err := goKeyValueStore.NewKeyValueStore(0.5, dir, salt, goKeyValueStore.WithCleanerStopped(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := restarted.Get(rawKey); !ok || value != "value" {
+		t.Errorf("Expected value after restart, got %v", value)
+	}
+	values, ok := restarted.GetAllOrNone(rawKey, rawKey+".2")
+	if !ok || values[rawKey] != "value" || values[rawKey+".2"] != "value" {
+		t.Errorf("Expected both values by their raw keys, got %v", values)
+	}
+	restarted.Delete(rawKey)
+	if restarted.Has(rawKey) {
+		t.Errorf("Expected %s to be deleted", rawKey)
+	}
+}
+
+func TestHashedKeysNotInErrors(t *testing.T) {
+	fsys := faultfs.New(goKeyValueStore.OSFS{})
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, t.TempDir(), goKeyValueStore.WithHashedKeys([]byte("salt")),
+		goKeyValueStore.WithFilesystem(fsys), goKeyValueStore.WithCleanerStopped(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys.Fail(faultfs.OpWrite, nil)
+	err = store.Set(rawKey, "value", 0)
+	if err == nil || strings.Contains(err.Error(), "alice") {
+		t.Errorf("Expected an error without the raw key, got %v", err)
+	}
+}
+
+func TestHashedKeysRejectPrefixOptions(t *testing.T) {
+	_, err := goKeyValueStore.NewKeyValueStore(0.5, "", goKeyValueStore.WithHashedKeys(nil))
+	if !errors.Is(err, goKeyValueStore.ErrEmptyKeySalt) {
+		t.Errorf("Expected ErrEmptyKeySalt, got %v", err)
+	}
+	_, err = goKeyValueStore.NewKeyValueStore(0.5, "", goKeyValueStore.WithHashedKeys([]byte("salt")),
+		goKeyValueStore.WithPrefixQuota("user:", 10))
+	if !errors.Is(err, goKeyValueStore.ErrUnsupportedWithHashedKeys) {
+		t.Errorf("Expected ErrUnsupportedWithHashedKeys for a prefix quota, got %v", err)
+	}
+	_, err = goKeyValueStore.NewKeyValueStore(0.5, "", goKeyValueStore.WithHashedKeys([]byte("salt")),
+		goKeyValueStore.WithAudit([]string{"user:"}, &bytes.Buffer{}))
+	if !errors.Is(err, goKeyValueStore.ErrUnsupportedWithHashedKeys) {
+		t.Errorf("Expected ErrUnsupportedWithHashedKeys for audit prefixes, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package goKeyValueStore
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// staleFileAge is the age at which Housekeep removes temporary files, so files of running writes are kept.
+const staleFileAge = time.Minute
+
+// isTempFile reports whether name is a temporary file the store creates in its cache folder: a probe of
+// checkCacheFolder or a file that is written before it replaces a file of the store.
+func isTempFile(name string) bool {
+	if pid, ok := strings.CutPrefix(name, ".probe-"); ok {
+		_, err := strconv.Atoi(pid)
+		return err == nil
+	}
+	if base, ok := strings.CutSuffix(name, ".tmp"); ok {
+		_, cacheFile := cacheFileExtension(base)
+		return base == indexFileName || base == metaFileName || cacheFile ||
+			(strings.HasPrefix(base, segmentPrefix) && strings.HasSuffix(base, segmentSuffix))
+	}
+	return false
+}
+
+// corruptSuffix is appended to the names of cache files that can not be decoded.
+const corruptSuffix = ".corrupt"
+
+// ErrCorruptFile is reported to the OnError function for cache files that can not be read or decoded or do not
+// match their checksum on start. With WithStrictLoad, NewKeyValueStore returns it instead.
+var ErrCorruptFile = errors.New("corrupt cache file")
+
+// quarantine renames a cache file that can not be decoded, so it no longer fails the start of the store or
+// RebuildIndex but is kept for inspection. Stats reports the number of such files. It returns the error to report
+// to OnError for the file or the error of the rename.
+func (d *KeyValueStore) quarantine(name string, cause error) (reported error, err error) {
+	path := filepath.Join(d.cacheFolder, name)
+	err = d.diskOp(func() error {
+		return d.fs.Rename(path, path+corruptSuffix)
+	})
+	if err != nil {
+		return nil, err
+	}
+	d.corruptFiles.Add(1)
+	return fmt.Errorf("%w %s: %w", ErrCorruptFile, name, cause), nil
+}
+
+// Housekeep removes temporary files that the store left in its cache folder, e.g. after a crash, and returns
+// their number. Only temporary files of the store that are older than a minute are removed; other files are
+// never touched. NewKeyValueStore runs it on every start. Stats reports the number of all removed files.
+func (d *KeyValueStore) Housekeep() (int, error) {
+	d.lazyInit()
+	if d.cacheFolder == "" {
+		return 0, nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.housekeep()
+}
+
+// housekeep removes stale temporary files from the cache folder. The caller must hold the write lock.
+func (d *KeyValueStore) housekeep() (int, error) {
+	entries, err := d.fs.ReadDir(d.cacheFolder)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() || !isTempFile(entry.Name()) {
+			continue
+		}
+		fileName := filepath.Join(d.cacheFolder, entry.Name())
+		info, err := d.fs.Stat(fileName)
+		if err != nil || time.Since(info.ModTime()) < staleFileAge {
+			continue
+		}
+		err = d.fs.Remove(fileName)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		removed++
+	}
+	d.tempFilesRemoved.Add(int64(removed))
+	return removed, errors.Join(errs...)
+}
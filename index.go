@@ -0,0 +1,276 @@
+package goKeyValueStore
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// indexFileName is the name of the index file in the cache folder.
+const indexFileName = "index.store"
+
+// indexCompactMinRecords is the number of records the index may hold before it is compacted.
+const indexCompactMinRecords = 1000
+
+// An indexRecord is a line of the index file. It either describes a persisted node or the deletion of a key.
+type indexRecord struct {
+	Key             string `json:"key"`
+	File            string `json:"file,omitempty"`
+	DeleteTimestamp int64  `json:"deleteTimestamp,omitempty"`
+	Size            int    `json:"size,omitempty"`
+	Revision        uint64 `json:"revision,omitempty"`
+	UpdatedAt       int64  `json:"updatedAt,omitempty"`
+	CreatedAt       int64  `json:"createdAt,omitempty"`
+	Sequence        uint64 `json:"sequence,omitempty"`
+	Source          string `json:"source,omitempty"`
+	SourceBytes     []byte `json:"sourceBytes,omitempty"`
+	Instance        string `json:"instance,omitempty"`
+	Run             string `json:"run,omitempty"`
+	Deleted         bool   `json:"deleted,omitempty"`
+	KeyBytes        []byte `json:"keyBytes,omitempty"`
+	// ModTime is the modification time of the file in Unix nanoseconds. Together with Size, it tells if the file
+	// was written again after the record, e.g. by a crash between writing a file and appending its record.
+	ModTime int64 `json:"modTime,omitempty"`
+}
+
+// RebuildIndex rebuilds the index file from the files in the cache folder. Files that can not be read or decoded
+// are renamed with the suffix ".corrupt" like on start, left out of the index, and reported to the OnError function.
+// It does nothing if the index is not enabled.
+func (d *KeyValueStore) RebuildIndex() error {
+	d.lazyInit()
+	if !d.useIndex || d.cacheFolder == "" {
+		return nil
+	}
+	defer d.afterWrite()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	entries, err := d.fs.ReadDir(d.cacheFolder)
+	if err != nil {
+		return err
+	}
+	records := []indexRecord{}
+	for _, file := range entries {
+		if !strings.HasSuffix(file.Name(), d.cacheFileSuffix()) {
+			continue
+		}
+		var node node
+		fileData, err := d.readCacheFile(filepath.Join(d.cacheFolder, file.Name()))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // deleted since the folder was read
+		}
+		if err == nil {
+			err = d.decodeNode(fileData, &node)
+		}
+		if err != nil {
+			reported, err := d.quarantine(file.Name(), err)
+			if err != nil {
+				return err
+			}
+			d.queueError(reported)
+			continue
+		}
+		records = append(records, indexRecord{
+			Key:             node.Key,
+			File:            file.Name(),
+			DeleteTimestamp: node.DeleteTimestamp,
+			Size:            len(fileData),
+			Revision:        node.Revision,
+			UpdatedAt:       node.UpdatedAt,
+			CreatedAt:       node.CreatedAt,
+			Sequence:        node.Sequence,
+			Source:          node.Source,
+			Instance:        node.Instance,
+			Run:             node.Run,
+			ModTime:         modTimeOf(file),
+		})
+	}
+	return d.writeIndexRecords(records)
+}
+
+// loadIndex loads the keys and deadlines of all entries from the index file without reading their values.
+// It returns false if the index is missing, corrupted, or does not match the files in the cache folder. A file
+// matches its record if it has the recorded size and modification time, so a file that was written without its
+// record is detected without reading it.
+func (d *KeyValueStore) loadIndex() (bool, error) {
+	indexData, err := d.readCacheFile(filepath.Join(d.cacheFolder, indexFileName))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	records := map[string]indexRecord{}
+	count := 0
+	for _, line := range bytes.Split(indexData, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var record indexRecord
+		err = json.Unmarshal(line, &record)
+		if err != nil {
+			return false, nil
+		}
+		record.Key = restoreKey(record.Key, record.KeyBytes)
+		record.Source = restoreKey(record.Source, record.SourceBytes)
+		count++
+		if record.Deleted {
+			delete(records, record.Key)
+			continue
+		}
+		records[record.Key] = record
+	}
+	entries, err := d.fs.ReadDir(d.cacheFolder)
+	if err != nil {
+		return false, err
+	}
+	files := map[string]fs.DirEntry{}
+	for _, file := range entries {
+		if strings.HasSuffix(file.Name(), d.cacheFileSuffix()) {
+			files[file.Name()] = file
+		}
+	}
+	if len(files) != len(records) {
+		return false, nil
+	}
+	for key, record := range records {
+		fileName, err := d.getFileName(key)
+		if err != nil {
+			return false, err
+		}
+		file, ok := files[record.File]
+		if record.File != filepath.Base(fileName) || !ok {
+			return false, nil
+		}
+		info, err := file.Info()
+		if err != nil || info.Size() != int64(record.Size) || info.ModTime().UnixNano() != record.ModTime {
+			return false, nil
+		}
+	}
+	for key, record := range records {
+		fileName := filepath.Join(d.cacheFolder, record.File)
+		d.observeRevision(record.Revision)
+		d.putNode(&node{
+			Key:             key,
+			DeleteTimestamp: record.DeleteTimestamp,
+			Revision:        record.Revision,
+			UpdatedAt:       record.UpdatedAt,
+			CreatedAt:       record.CreatedAt,
+			Sequence:        record.Sequence,
+			Source:          record.Source,
+			Instance:        record.Instance,
+			Run:             record.Run,
+			size:            record.Size,
+			modTime:         record.ModTime,
+			lazy: &lazyValue{load: func() (any, error) {
+				return d.loadValue(key, fileName)
+			}},
+		})
+	}
+	d.indexRecords = count
+	return true, nil
+}
+
+// appendToIndex appends a record to the index file. If the index holds too many outdated records,
+// it is rewritten from the nodes in memory instead.
+func (d *KeyValueStore) appendToIndex(record indexRecord) error {
+	if !d.useIndex {
+		return nil
+	}
+	if d.indexRecords >= indexCompactMinRecords && d.indexRecords > 2*len(d.data) {
+		return d.writeIndex()
+	}
+	record.KeyBytes = rawKey(record.Key)
+	record.SourceBytes = rawKey(record.Source)
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	err = d.diskOp(func() error {
+		return d.fs.AppendFile(filepath.Join(d.cacheFolder, indexFileName), append(data, '\n'), 0600)
+	})
+	if err != nil {
+		return err
+	}
+	d.indexRecords++
+	return nil
+}
+
+// writeIndex rewrites the index file from the persisted nodes in memory.
+func (d *KeyValueStore) writeIndex() error {
+	records := make([]indexRecord, 0, len(d.data))
+	for key, node := range d.data {
+		if node.size == 0 {
+			continue // the node was never persisted
+		}
+		fileName, err := d.getFileName(key)
+		if err != nil {
+			return err
+		}
+		records = append(records, indexRecord{
+			Key:             key,
+			File:            filepath.Base(fileName),
+			DeleteTimestamp: node.DeleteTimestamp,
+			Size:            node.size,
+			Revision:        node.Revision,
+			UpdatedAt:       node.UpdatedAt,
+			CreatedAt:       node.CreatedAt,
+			Sequence:        node.Sequence,
+			Source:          node.Source,
+			Instance:        node.Instance,
+			Run:             node.Run,
+			ModTime:         node.modTime,
+		})
+	}
+	return d.writeIndexRecords(records)
+}
+
+// writeIndexRecords replaces the index file with the given records.
+// The index is written to a temporary file first so that it is never left half-written.
+func (d *KeyValueStore) writeIndexRecords(records []indexRecord) error {
+	var buf bytes.Buffer
+	for _, record := range records {
+		record.KeyBytes = rawKey(record.Key)
+		record.SourceBytes = rawKey(record.Source)
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	fileName := filepath.Join(d.cacheFolder, indexFileName)
+	err := d.diskOp(func() error {
+		err := d.fs.WriteFile(fileName+".tmp", buf.Bytes(), 0600)
+		if err != nil {
+			return err
+		}
+		return d.fs.Rename(fileName+".tmp", fileName)
+	})
+	if err != nil {
+		return err
+	}
+	d.indexRecords = len(records)
+	return nil
+}
+
+// fileModTime returns the modification time of a file in Unix nanoseconds, or 0 if it can not be determined.
+func (d *KeyValueStore) fileModTime(fileName string) int64 {
+	info, err := d.fs.Stat(fileName)
+	if err != nil {
+		return 0
+	}
+	return info.ModTime().UnixNano()
+}
+
+// modTimeOf returns the modification time of a directory entry in Unix nanoseconds, or 0 if it can not be
+// determined.
+func modTimeOf(file fs.DirEntry) int64 {
+	info, err := file.Info()
+	if err != nil {
+		return 0
+	}
+	return info.ModTime().UnixNano()
+}
@@ -0,0 +1,235 @@
+package goKeyValueStore_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func countingReadFile(counter *atomic.Int64) goKeyValueStore.Option {
+	return goKeyValueStore.WithReadFile(func(name string) ([]byte, error) {
+		counter.Add(1)
+		return os.ReadFile(name)
+	})
+}
+
+func getTestStoreWithIndex(t *testing.T, dir string, entries int) {
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithIndex(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < entries; i++ {
+		store.Set(fmt.Sprintf("key%d", i), fmt.Sprintf("value%d", i), 60000)
+	}
+	store.Delete("key0")
+}
+
+func TestIndexStartupReadsOnlyIndex(t *testing.T) {
+	dir := t.TempDir()
+	getTestStoreWithIndex(t, dir, 200)
+	var reads atomic.Int64
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithIndex(true), countingReadFile(&reads))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reads.Load() != 1 {
+		t.Errorf("Expected 1 file read at startup, got %d", reads.Load())
+	}
+	if store.Length() != 199 {
+		t.Errorf("Expected length to be 199, got %d", store.Length())
+	}
+	val, ok := store.Get("key42")
+	if !ok || val != "value42" {
+		t.Errorf("Expected value42, got %v", val)
+	}
+	store.Get("key42")
+	if reads.Load() != 2 {
+		t.Errorf("Expected 2 file reads after loading a value, got %d", reads.Load())
+	}
+}
+
+func TestIndexCorruptedFallsBackAndRebuilds(t *testing.T) {
+	dir := t.TempDir()
+	getTestStoreWithIndex(t, dir, 50)
+	os.WriteFile(filepath.Join(dir, "index.store"), []byte("not an index"), 0600)
+	var reads atomic.Int64
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithIndex(true), countingReadFile(&reads))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reads.Load() != 50 {
+		t.Errorf("Expected 50 file reads at startup, got %d", reads.Load())
+	}
+	if store.Length() != 49 {
+		t.Errorf("Expected length to be 49, got %d", store.Length())
+	}
+	reads.Store(0)
+	_, err = goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithIndex(true), countingReadFile(&reads))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reads.Load() != 1 {
+		t.Errorf("Expected the index to be rebuilt, got %d file reads", reads.Load())
+	}
+}
+
+func TestIndexMissingFileFallsBack(t *testing.T) {
+	dir := t.TempDir()
+	getTestStoreWithIndex(t, dir, 10)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != "index.store" {
+			os.Remove(filepath.Join(dir, entry.Name()))
+			break
+		}
+	}
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithIndex(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.Length() != 8 {
+		t.Errorf("Expected length to be 8, got %d", store.Length())
+	}
+}
+
+func TestIndexDetectsFileWrittenWithoutRecord(t *testing.T) {
+	dir := t.TempDir()
+	clock := newFakeClock()
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithIndex(true), goKeyValueStore.WithClock(clock.Now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("key", "old", 60000)
+	store.Close()
+
+	// a store without the index writes the file like a crash between writing a file and appending its record
+	crashed, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithClock(clock.Now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	crashed.Set("key", "new", 0)
+	crashed.Close()
+
+	store, err = goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithIndex(true), goKeyValueStore.WithClock(clock.Now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	clock.Advance(61 * time.Second)
+	if value, ok := store.Get("key"); !ok || value != "new" {
+		t.Errorf("Expected the deadline and value of the file, got %v, %v", value, ok)
+	}
+}
+
+func TestRebuildIndexQuarantinesCorruptFile(t *testing.T) {
+	dir := t.TempDir()
+	getTestStoreWithIndex(t, dir, 10)
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithIndex(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reported []error
+	store.OnError(func(err error) { reported = append(reported, err) })
+	corrupt := filepath.Join(dir, "corrupt.store.json")
+	os.WriteFile(corrupt, []byte(`{"key":"corrupt","val`), 0600)
+	err = store.RebuildIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reported) != 1 || !errors.Is(reported[0], goKeyValueStore.ErrCorruptFile) {
+		t.Errorf("Expected 1 ErrCorruptFile, got %v", reported)
+	}
+	if _, err := os.Stat(corrupt + ".corrupt"); err != nil {
+		t.Errorf("Expected the corrupt file to be kept, got %v", err)
+	}
+	var reads atomic.Int64
+	restarted, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithIndex(true), countingReadFile(&reads))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reads.Load() != 1 || restarted.Length() != 9 {
+		t.Errorf("Expected the rebuilt index to load 9 keys, got %d keys with %d reads", restarted.Length(), reads.Load())
+	}
+}
+
+func TestIndexDeadlinesMatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	getTestStoreWithIndex(t, dir, 20)
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithIndex(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(filepath.Join(dir, "index.store"))
+	err = store.RebuildIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(filepath.Join(dir, "index.store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	records := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record struct {
+			Key             string `json:"key"`
+			File            string `json:"file"`
+			DeleteTimestamp int64  `json:"deleteTimestamp"`
+		}
+		err = json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fileData, err := os.ReadFile(filepath.Join(dir, record.File))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var stored struct {
+			Key             string `json:"key"`
+			DeleteTimestamp int64  `json:"deleteTimestamp"`
+		}
+		err = json.Unmarshal(fileData, &stored)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Key != record.Key || stored.DeleteTimestamp != record.DeleteTimestamp {
+			t.Errorf("Expected index record %+v to match file %+v", record, stored)
+		}
+		records++
+	}
+	if records != 19 {
+		t.Errorf("Expected 19 index records, got %d", records)
+	}
+}
+
+func TestIndexKeepsInvalidUTF8Keys(t *testing.T) {
+	dir := t.TempDir()
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithIndex(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("invalid\xffutf8", "value", 0)
+	var reads atomic.Int64
+	restored, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithIndex(true), countingReadFile(&reads))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reads.Load() != 1 {
+		t.Errorf("Expected only the index to be read, got %d reads", reads.Load())
+	}
+	if value, ok := restored.Get("invalid\xffutf8"); !ok || value != "value" {
+		t.Errorf("Expected value, got %v, %v", value, ok)
+	}
+}
@@ -0,0 +1,133 @@
+// Package resp is a minimal Redis client for the RESP protocol, shared by redisimport and redisbackend.
+package resp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// An Error is an error reply of the Redis server.
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// A Client is a minimal Redis client that sends commands and reads replies using the RESP protocol.
+// It is not safe for concurrent use.
+type Client struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// Dial connects to the Redis server at addr.
+func Dial(ctx context.Context, addr string) (*Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Do sends a command and returns its reply. Replies are returned as string, int64, []any, or nil.
+// Error replies are returned as Error.
+func (c *Client) Do(ctx context.Context, args ...string) (any, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Time{}
+	}
+	err := c.conn.SetDeadline(deadline)
+	if err != nil {
+		return nil, err
+	}
+	buf := fmt.Appendf(nil, "*%d\r\n", len(args))
+	for _, arg := range args {
+		buf = fmt.Appendf(buf, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	_, err = c.conn.Write(buf)
+	if err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+// readReply reads a single reply from the connection.
+func (c *Client) readReply() (any, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errors.New("invalid reply")
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, Error(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		length, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		data := make([]byte, length+2)
+		_, err = io.ReadFull(c.reader, data)
+		if err != nil {
+			return nil, err
+		}
+		return string(data[:length]), nil
+	case '*':
+		length, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		items := make([]any, length)
+		for i := range items {
+			items[i], err = c.readReply()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("invalid reply type %q", kind)
+	}
+}
+
+// ReplyStrings converts an array reply of bulk strings to a slice of strings.
+func ReplyStrings(reply any) ([]string, error) {
+	items, ok := reply.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected array reply, got %T", reply)
+	}
+	values := make([]string, len(items))
+	for i, item := range items {
+		value, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string reply, got %T", item)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
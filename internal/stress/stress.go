@@ -0,0 +1,296 @@
+// Package stress runs random concurrent operations against a store and checks its invariants afterwards.
+// It is meant to be run with the race detector.
+package stress
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+// expirySlack is how long after its deadline an entry may still be readable before it counts as a violation.
+// Deadlines are stored in milliseconds, so reads within the same millisecond are not violations.
+const expirySlack = time.Millisecond
+
+// Store is the part of a store exercised by the runner.
+type Store interface {
+	goKeyValueStore.Store
+	KeysN(limit int) ([]string, bool)
+	CleanNow() (goKeyValueStore.SweepReport, error)
+	Expire(key string, ttl int) error
+}
+
+// Mix is the relative weight of each operation.
+type Mix struct {
+	Get    int
+	Set    int
+	Delete int
+	Expire int
+	Keys   int
+	Length int
+	Sweep  int
+}
+
+// DefaultMix is a read-heavy mix of all operations.
+var DefaultMix = Mix{Get: 50, Set: 27, Delete: 10, Expire: 3, Keys: 3, Length: 5, Sweep: 2}
+
+// Config configures a run.
+type Config struct {
+	Seed       int64
+	Goroutines int
+	Duration   time.Duration
+	// Keys is the number of distinct keys used.
+	Keys int
+	// MaxTTL is the maximum TTL of set entries. Entries get TTLs between 1ms and MaxTTL, or no expiration.
+	MaxTTL time.Duration
+	Mix    Mix
+	// CacheFolder is the cache folder of the store. If set, the files are checked against the entries.
+	CacheFolder string
+}
+
+// A value is stored by the runner so that reads can check which write they see.
+type value struct {
+	Key string `json:"key"`
+	ID  uint64 `json:"id"`
+}
+
+// A write is the latest finished write of a key. Deadline is the latest possible deadline of the entry:
+// the store computes it during Set, so it is at most the time Set returned plus the TTL.
+type write struct {
+	id       uint64
+	deadline time.Time // zero means no expiration
+}
+
+// A runner holds the state of a run.
+type runner struct {
+	store  Store
+	config Config
+	report func(error)
+	nextID atomic.Uint64
+	mu     sync.Mutex
+	writes map[string]write
+	// expired holds the keys whose deadlines were changed by Expire. Their deadlines are unknown from then on.
+	expired map[string]bool
+}
+
+// Run runs random operations on store from several goroutines for the configured duration and checks the
+// invariants of the store afterwards. It returns all violations found.
+func Run(store Store, config Config) error {
+	var mu sync.Mutex
+	var violations []error
+	report := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(violations) < 10 {
+			violations = append(violations, err)
+		}
+	}
+	r := &runner{store: store, config: config, report: report, writes: map[string]write{}, expired: map[string]bool{}}
+	deadline := time.Now().Add(config.Duration)
+	var wg sync.WaitGroup
+	for g := 0; g < config.Goroutines; g++ {
+		wg.Add(1)
+		go func(rng *rand.Rand) {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				r.runOperation(rng)
+			}
+		}(rand.New(rand.NewSource(config.Seed + int64(g))))
+	}
+	wg.Wait()
+	err := Check(store, config.CacheFolder)
+	if err != nil {
+		report(err)
+	}
+	keys, _ := store.KeysN(config.Keys + 1)
+	for _, key := range keys {
+		start := time.Now()
+		if got, ok := store.Get(key); ok {
+			r.checkValue(key, got, start)
+		}
+	}
+	return errors.Join(violations...)
+}
+
+// runOperation runs a single random operation.
+func (r *runner) runOperation(rng *rand.Rand) {
+	store, config, report := r.store, r.config, r.report
+	mix := config.Mix
+	key := fmt.Sprintf("key%d", rng.Intn(max(config.Keys, 1)))
+	n := rng.Intn(max(mix.Get+mix.Set+mix.Delete+mix.Expire+mix.Keys+mix.Length+mix.Sweep, 1))
+	switch {
+	case n < mix.Get:
+		start := time.Now()
+		got, ok := store.Get(key)
+		if ok {
+			r.checkValue(key, got, start)
+		}
+	case n < mix.Get+mix.Set:
+		ttl := 0
+		if config.MaxTTL > 0 && rng.Intn(4) > 0 {
+			ttl = 1 + rng.Intn(int(config.MaxTTL.Milliseconds()))
+		}
+		v := value{Key: key, ID: r.nextID.Add(1)}
+		err := store.Set(key, v, ttl)
+		if err != nil {
+			report(fmt.Errorf("set %s: %w", key, err))
+			return
+		}
+		w := write{id: v.ID}
+		if ttl > 0 {
+			w.deadline = time.Now().Add(time.Duration(ttl) * time.Millisecond)
+		}
+		r.mu.Lock()
+		if r.writes[key].id < w.id {
+			r.writes[key] = w
+		}
+		r.mu.Unlock()
+	case n < mix.Get+mix.Set+mix.Delete:
+		err := store.Delete(key)
+		if err != nil {
+			report(fmt.Errorf("delete %s: %w", key, err))
+		}
+	case n < mix.Get+mix.Set+mix.Delete+mix.Expire:
+		ttl := 0
+		if config.MaxTTL > 0 && rng.Intn(4) > 0 {
+			ttl = 1 + rng.Intn(int(config.MaxTTL.Milliseconds()))
+		}
+		r.mu.Lock()
+		r.expired[key] = true
+		r.mu.Unlock()
+		err := store.Expire(key, ttl)
+		if err != nil && !errors.Is(err, goKeyValueStore.ErrKeyNotFound) {
+			report(fmt.Errorf("expire %s: %w", key, err))
+		}
+	case n < mix.Get+mix.Set+mix.Delete+mix.Expire+mix.Keys:
+		keys, _ := store.KeysN(config.Keys + 1)
+		if len(keys) > config.Keys {
+			report(fmt.Errorf("keys returned %d keys, but only %d are used", len(keys), config.Keys))
+		}
+	case n < mix.Get+mix.Set+mix.Delete+mix.Expire+mix.Keys+mix.Length:
+		if length := store.Length(); length > config.Keys {
+			report(fmt.Errorf("length is %d, but only %d keys are used", length, config.Keys))
+		}
+	default:
+		_, err := store.CleanNow()
+		if err != nil {
+			report(fmt.Errorf("sweep: %w", err))
+		}
+	}
+}
+
+// checkValue reports a value that belongs to another key or was read after its deadline.
+// start is the time before the value was read.
+func (r *runner) checkValue(key string, got any, start time.Time) {
+	v, err := decodeValue(got)
+	if err != nil {
+		r.report(fmt.Errorf("get %s: %w", key, err))
+		return
+	}
+	if v.Key != key {
+		r.report(fmt.Errorf("get %s: got the value of %s", key, v.Key))
+	}
+	r.mu.Lock()
+	w := r.writes[key]
+	expired := r.expired[key]
+	r.mu.Unlock()
+	// only the latest finished write of a key has a known deadline
+	if w.id == v.ID && !expired && !w.deadline.IsZero() && start.After(w.deadline.Add(expirySlack)) {
+		r.report(fmt.Errorf("get %s: expired entry is readable %s after its deadline", key, start.Sub(w.deadline)))
+	}
+}
+
+// decodeValue returns a value stored by the runner.
+func decodeValue(got any) (value, error) {
+	var v value
+	switch got := got.(type) {
+	case value:
+		v = got
+	default:
+		// values restored from the cache folder are decoded into maps
+		data, err := json.Marshal(got)
+		if err == nil {
+			err = json.Unmarshal(data, &v)
+		}
+		if err != nil {
+			return value{}, fmt.Errorf("unexpected value %v", got)
+		}
+	}
+	return v, nil
+}
+
+// Check checks the invariants of a store that is not used concurrently: Length matches the number of keys, all
+// values belong to their keys, and, if cacheFolder is set, every live key has a file and every file belongs to a
+// live or expired key.
+func Check(store Store, cacheFolder string) error {
+	var errs []error
+	keys, _ := store.KeysN(int(^uint(0) >> 1))
+	// keys may expire between the calls, so only a length larger than the number of keys is a violation
+	if length := store.Length(); length > len(keys) {
+		errs = append(errs, fmt.Errorf("length is %d, but there are %d keys", length, len(keys)))
+	}
+	for _, key := range keys {
+		if got, ok := store.Get(key); ok {
+			v, err := decodeValue(got)
+			if err != nil || v.Key != key {
+				errs = append(errs, fmt.Errorf("key %s has the value %v", key, got))
+			}
+		}
+	}
+	if cacheFolder != "" {
+		errs = append(errs, checkFiles(store, cacheFolder, keys))
+	}
+	return errors.Join(errs...)
+}
+
+// checkFiles checks that the files in the cache folder match the entries of the store.
+func checkFiles(store Store, cacheFolder string, keys []string) error {
+	var errs []error
+	for _, key := range keys {
+		if _, ok := store.Get(key); !ok {
+			continue // the key expired in the meantime
+		}
+		fileName := filepath.Join(cacheFolder, fmt.Sprintf("%x.store.json", sha256.Sum256([]byte(key))))
+		if _, err := os.Stat(fileName); err != nil {
+			errs = append(errs, fmt.Errorf("live key %s has no file: %w", key, err))
+		}
+	}
+	entries, err := os.ReadDir(cacheFolder)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".store.json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(cacheFolder, entry.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		var stored struct {
+			Key             string `json:"key"`
+			DeleteTimestamp int64  `json:"deleteTimestamp"`
+		}
+		err = json.Unmarshal(data, &stored)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("file %s: %w", entry.Name(), err))
+			continue
+		}
+		_, ok := store.Get(stored.Key)
+		if !ok && time.Now().UnixMilli() <= stored.DeleteTimestamp {
+			errs = append(errs, fmt.Errorf("file %s belongs to the missing key %s", entry.Name(), stored.Key))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,35 @@
+package stress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+// neverExpiringStore is a store with an intentional bug: it ignores TTLs.
+type neverExpiringStore struct {
+	*goKeyValueStore.KeyValueStore
+}
+
+func (s neverExpiringStore) Set(key string, value any, ttl int) error {
+	return s.KeyValueStore.Set(key, value, 0)
+}
+
+func TestRunCatchesSeededBug(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Run(neverExpiringStore{store}, Config{
+		Seed:       1,
+		Goroutines: 4,
+		Duration:   300 * time.Millisecond,
+		Keys:       10000,
+		MaxTTL:     20 * time.Millisecond,
+		Mix:        Mix{Get: 95, Set: 5},
+	})
+	if err == nil {
+		t.Error("Expected the runner to find readable expired entries")
+	}
+}
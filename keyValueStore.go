@@ -3,123 +3,762 @@
 package goKeyValueStore
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"math"
-	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 )
 
 // A KeyValueStore is a simple key-value store that supports setting a key-value pair with
 // a time-to-live (TTL) in milliseconds, getting a value by key,
 // deleting a key, and getting the length of the store.
+// The zero value is an empty memory-only store without a running cleaner: expired entries are hidden, but only
+// deleted by CleanNow or after StartCleaning, which cleans every minute unless Reconfigure sets another interval.
 type KeyValueStore struct {
-	data         map[string]node
-	mu           *sync.RWMutex
-	cleanTimeout float32
-	cacheFolder  string
+	initOnce         sync.Once
+	data             map[string]*node
+	mu               *sync.RWMutex
+	cleanInterval    atomic.Int64
+	cleanReset       chan struct{}
+	cacheFolder      string
+	maxValueSize     atomic.Int64
+	maxEntries       atomic.Int64
+	useIndex         bool
+	indexRecords     int
+	fs               FS
+	redaction        RedactionMode
+	keyLocks         []sync.Mutex
+	now              func() time.Time
+	persistTransform func(key string, value any) (any, error)
+	loadTransform    func(key string, value any) (any, error)
+	rateLimiter      atomic.Pointer[rateLimiter]
+	minFreeDiskBytes int64
+	freeDiskSpace    func(path string) (uint64, error)
+	diskMu           sync.Mutex
+	diskCheckedAt    time.Time
+	diskFree         uint64
+	diskFreeErr      error
+	lowDiskSpace     bool
+	revision         uint64
+	useNumber        bool
+	maxBytes         atomic.Int64
+	evictionPolicy   EvictionPolicy
+	quotas           []*prefixQuota
+	quotaPolicy      QuotaPolicy
+	auditLog         *auditLog
+	packing          *packing
+	derived          map[string]map[string]struct{}
+	eviction         evictionStrategy
+	rejectNil        bool
+	changesFeed      *changesFeed
+	diskSem          chan struct{}
+	diskInFlight     atomic.Int64
+	diskWait         atomic.Int64
+	bytes            int64
+	thresholdMu      sync.Mutex
+	thresholds       []*threshold
+	degradedErr      error
+	retryAt          time.Time
+	retryBackoff     time.Duration
+	errorMu          sync.Mutex
+	onError          func(error)
+	pendingErrors    []error
+	startErrors      []error
+	history          *eventHistory
+	phase            atomic.Int32
+	warmDone         atomic.Int64
+	warmTotal        atomic.Int64
+	warmup           bool
+	closed           atomic.Bool
+	background       sync.WaitGroup
+	followMu         sync.Mutex
+	followers        map[chan struct{}]func()
+	readOptimized    bool
+	readView         *readView
+	seedConflicts    SeedConflictPolicy
+	hashKeys         bool
+	tempFilesRemoved atomic.Int64
+	corruptFiles     atomic.Int64
+	instanceID       string
+	runID            string
+	adoptFolder      bool
+	revalidator      *revalidator
+	codec            Codec
+	legacyCodecs     []Codec
+	compress         bool
+	syncWrites       bool
+	strictLoad       bool
+	compressionLevel int
+	types            map[string]reflect.Type
+	typeNames        map[reflect.Type]string
+	onUnknownType    func(key string, typeName string, err error)
+	flightMu         sync.Mutex
+	flights          map[string]*flight
+	keySalt          []byte
+	backend          Backend
+	writeBehind      *writeBehind
+	writeBehindSize  int
+	flushInterval    time.Duration
+	cleaner
 }
 
 // NewKeyValueStore creates a new KeyValueStore with a cleanTimeout in seconds.
-func NewKeyValueStore(cleanTimeout float32, cacheFolder string) (*KeyValueStore, error) {
-	store := &KeyValueStore{
-		data:         make(map[string]node),
-		mu:           &sync.RWMutex{},
-		cleanTimeout: cleanTimeout,
-		cacheFolder:  cacheFolder,
+// The behavior of the store can be customized with options.
+// A cacheFolder of "" keeps all key-value pairs in memory only. Otherwise the folder is resolved to an absolute
+// path and created if needed. If it is a file or not writable, ErrCacheFolderIsFile or ErrCacheFolderNotWritable
+// is returned.
+func NewKeyValueStore(cleanTimeout float32, cacheFolder string, opts ...Option) (*KeyValueStore, error) {
+	return newKeyValueStore(cleanTimeout, cacheFolder, opts, nil)
+}
+
+// newKeyValueStore creates a new KeyValueStore. If seed is not nil, the entries of the export stream are imported
+// before the store is returned.
+func newKeyValueStore(cleanTimeout float32, cacheFolder string, opts []Option, seed io.Reader) (*KeyValueStore, error) {
+	cacheFolder, err := resolveCacheFolder(cacheFolder)
+	if err != nil {
+		return nil, err
+	}
+	store := &KeyValueStore{}
+	store.lazyInit()
+	store.phase.Store(int32(PhaseInitializing))
+	store.cacheFolder = cacheFolder
+	store.cleanInterval.Store(int64(cleanTimeout * float32(time.Second)))
+	for _, opt := range opts {
+		opt(store)
+	}
+	err = store.checkHashedKeys()
+	if err != nil {
+		return nil, err
+	}
+	if store.backend != nil && store.cacheFolder != "" {
+		return nil, ErrBackendWithCacheFolder
+	}
+	store.eviction = newEvictionStrategy(store.evictionPolicy, nil)
+	store.useCompression()
+	var seedNodes []*node
+	if seed != nil {
+		seedNodes, err = store.readExport(seed)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = store.init()
+	if err != nil {
+		return nil, err
+	}
+	if store.writeBehindSize > 0 || store.flushInterval > 0 {
+		store.startWriteBehind()
+	}
+	if seed != nil {
+		_, err = store.importNodes(seedNodes, store.seedConflicts == FolderWins)
+		if err != nil {
+			return nil, err
+		}
 	}
-	err := store.init()
+	err = store.evictOverflow("")
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	if store.readOptimized {
+		store.startReadView()
+	}
+	if store.warmup && store.useIndex {
+		store.warmupInBackground()
+	} else {
+		store.phase.Store(int32(PhaseReady))
+	}
+	if !store.cleanerStopped {
+		store.StartCleaning()
 	}
-	go store.clean()
 	return store, nil
 }
 
+// defaultCleanInterval is the clean interval of a zero KeyValueStore.
+const defaultCleanInterval = time.Minute
+
+// lazyInit sets the defaults of a store once. Every exported method calls it first, so the zero value of
+// KeyValueStore is usable as a memory-only store.
+func (d *KeyValueStore) lazyInit() {
+	d.initOnce.Do(func() {
+		d.data = make(map[string]*node)
+		d.mu = &sync.RWMutex{}
+		d.cleanReset = make(chan struct{}, 1)
+		d.fs = OSFS{}
+		d.keyLocks = make([]sync.Mutex, keyLockStripes)
+		d.now = time.Now
+		d.freeDiskSpace = freeDiskSpace
+		d.history = newEventHistory(defaultHistoryKeys)
+		d.eviction = newEvictionStrategy(EvictNearestExpiry, nil)
+		d.cleanInterval.Store(int64(defaultCleanInterval))
+		d.phase.Store(int32(PhaseReady))
+		d.runID = newID()
+		d.flights = make(map[string]*flight)
+		d.codec = JSONCodec{}
+	})
+}
+
+// ErrValueTooLarge is returned when a value exceeds the maximum value size.
+var ErrValueTooLarge = errors.New("value is too large")
+
+// ErrNilValue is returned when a nil value is set and WithRejectNilValues is enabled.
+var ErrNilValue = errors.New("value is nil")
+
+// neverExpire is the deleteTimestamp of nodes that were set with a TTL of 0.
+const neverExpire int64 = math.MaxInt64
+
+// sweepBatchSize is the maximum number of entries removed while holding the write lock once.
+const sweepBatchSize = 1000
+
 // A node is a key-value pair with a deleteTimestamp.
 type node struct {
 	Key             string `json:"key"`
 	Value           any    `json:"value"`
 	DeleteTimestamp int64  `json:"deleteTimestamp"`
+	Revision        uint64 `json:"revision,omitempty"`
+	UpdatedAt       int64  `json:"updatedAt,omitempty"`
+	CreatedAt       int64  `json:"createdAt,omitempty"`
+	Sequence        uint64 `json:"sequence,omitempty"`
+	KeyBytes        []byte `json:"keyBytes,omitempty"`
+	Source          string `json:"source,omitempty"`
+	SourceBytes     []byte `json:"sourceBytes,omitempty"`
+	Instance        string `json:"instance,omitempty"`
+	Run             string `json:"run,omitempty"`
+	Type            string `json:"type,omitempty"`
+	Checksum        string `json:"checksum,omitempty"`
+	size            int
+	encodedSize     int64
+	lazy            *lazyValue
+	// modTime is the modification time of the file in Unix nanoseconds. It is only recorded with WithIndex.
+	modTime int64
+	// lastUsed is the epoch of the last use for EvictApproxLRU. It is accessed atomically.
+	lastUsed int64
+}
+
+// A lazyValue is the value of a node that is read from the cache folder on first access.
+type lazyValue struct {
+	once  sync.Once
+	load  func() (any, error)
+	value any
+	err   error
 }
 
-// newNode creates a new node with a key, value, and TTL.
-func newNode(key string, value any, ttl int) node {
-	if ttl == 0 {
-		ttl = math.MaxInt
+// value returns the value of a node. Lazy values are read from the cache folder on first access.
+func (n *node) value() (any, error) {
+	if n.lazy == nil {
+		return n.Value, nil
 	}
-	timestamp := time.Now().Add(time.Duration(ttl) * time.Millisecond).UnixMilli()
-	return node{Key: key, Value: value, DeleteTimestamp: timestamp}
+	n.lazy.once.Do(func() {
+		n.lazy.value, n.lazy.err = n.lazy.load()
+	})
+	return n.lazy.value, n.lazy.err
+}
+
+// newNode creates a new node with a key, value, and TTL. A TTL of 0 means the node never expires.
+// Nodes are never modified after they have been added to the store; changes replace the node.
+// The caller must hold the write lock.
+func (d *KeyValueStore) newNode(key string, value any, ttl int) *node {
+	now := d.now()
+	timestamp := neverExpire
+	if ttl != 0 {
+		timestamp = now.Add(time.Duration(ttl) * time.Millisecond).UnixMilli()
+	}
+	node := &node{Key: key, Value: value, DeleteTimestamp: timestamp, Revision: d.nextRevision(), UpdatedAt: now.UnixMilli()}
+	d.stampCreation(node)
+	d.stampWriter(node)
+	return node
+}
+
+// stampCreation sets when the key of a node was inserted. A live node of the same key passes its creation on,
+// so overwriting a key keeps its place in the insertion order. The caller must hold the write lock.
+func (d *KeyValueStore) stampCreation(node *node) {
+	if old, ok := d.data[node.Key]; ok && !d.nodeIsExpired(old) {
+		node.CreatedAt, node.Sequence = old.CreatedAt, old.Sequence
+		return
+	}
+	node.CreatedAt, node.Sequence = node.UpdatedAt, node.Revision
+}
+
+// nextRevision returns a new revision that is larger than all revisions in the store.
+// The caller must hold the write lock.
+func (d *KeyValueStore) nextRevision() uint64 {
+	d.revision++
+	return d.revision
+}
+
+// observeRevision makes sure that new revisions are larger than a revision loaded from the cache folder.
+func (d *KeyValueStore) observeRevision(revision uint64) {
+	d.revision = max(d.revision, revision)
+}
+
+// Revision returns the revision of a key. Every write of a key gives it a new, larger revision, so
+// comparing revisions is a cheap way to detect changes. If the key does not exist, the second return value is false.
+func (d *KeyValueStore) Revision(key string) (uint64, bool) {
+	d.lazyInit()
+	key = d.storageKey(key)
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	node, ok := d.data[key]
+	if !ok || d.nodeIsExpired(node) {
+		return 0, false
+	}
+	return node.Revision, true
+}
+
+// NoExpiration is the TTL reported for keys that never expire.
+const NoExpiration time.Duration = -1
+
+// TTL returns the time until a key expires, or NoExpiration for keys set with a TTL of 0. The second return value
+// is false if the key does not exist or is expired.
+func (d *KeyValueStore) TTL(key string) (time.Duration, bool) {
+	d.lazyInit()
+	key = d.storageKey(key)
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	node, ok := d.data[key]
+	if !ok || d.nodeIsExpired(node) || d.closed.Load() {
+		return 0, false
+	}
+	if node.DeleteTimestamp == neverExpire {
+		return NoExpiration, true
+	}
+	return time.UnixMilli(node.DeleteTimestamp).Sub(d.now()), true
 }
 
 // Set sets a key-value pair with a TTL in milliseconds.
+// If the value is larger than the configured maximum value size, ErrValueTooLarge is returned and nothing is set.
+// If a write rate limit is configured, Set waits for it or returns ErrRateLimited depending on the policy.
+// A nil value is stored like any other value, so Get returns nil and true for it, also after a restart,
+// unless WithRejectNilValues is enabled.
 func (d *KeyValueStore) Set(key string, value any, ttl int) error {
+	d.lazyInit()
+	return d.SetCtx(context.Background(), key, value, ttl)
+}
+
+// SetCtx is like Set and records the actor of ctx, see ContextWithActor, in audit records.
+func (d *KeyValueStore) SetCtx(ctx context.Context, key string, value any, ttl int) error {
+	d.lazyInit()
+	key = d.storageKey(key)
+	err := d.set(key, value, ttl)
+	d.audit(ctx, "set", key, true, err)
+	return err
+}
+
+// set sets a key-value pair with a TTL in milliseconds.
+func (d *KeyValueStore) set(key string, value any, ttl int) error {
+	err := d.checkValue(value)
+	if err == nil {
+		err = d.takeWriteTokens(1)
+	}
+	if err != nil {
+		d.recordSetResult(key, err)
+		return err
+	}
+	defer d.afterWrite()
 	d.mu.Lock()
 	defer d.mu.Unlock()
-	node := newNode(key, value, ttl)
-	d.data[key] = node
-	err := d.saveInCache(node)
+	if d.closed.Load() {
+		return ErrClosed
+	}
+	err = d.makeRoomInQuotas(key)
+	if err != nil {
+		d.recordSetResult(key, err)
+		return err
+	}
+	node := d.newNode(key, value, ttl)
+	d.putNode(node)
+	err = d.saveInCache(node)
+	d.recordSetResult(key, err)
+	if err != nil {
+		return err
+	}
+	return d.evictOverflow(key)
+}
+
+// checkValue returns an error if the value exceeds the maximum value size or is a rejected nil value.
+// The size of a value is the length of its JSON encoding.
+func (d *KeyValueStore) checkValue(value any) error {
+	if value == nil && d.rejectNil {
+		return ErrNilValue
+	}
+	maxValueSize := d.maxValueSize.Load()
+	if maxValueSize <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(value)
 	if err != nil {
 		return err
 	}
+	if int64(len(data)) > maxValueSize {
+		return ErrValueTooLarge
+	}
 	return nil
 }
 
 // saveInCache saves a node in the cache folder.
-func (d *KeyValueStore) saveInCache(node node) error {
+func (d *KeyValueStore) saveInCache(node *node) error {
+	if d.backend != nil {
+		return d.saveInBackend(node)
+	}
 	if d.cacheFolder == "" {
 		return nil
 	}
-	data, err := json.Marshal(node)
+	if d.writeBehind != nil && d.writeBehind.enqueue(node.Key, node) {
+		return nil
+	}
+	return d.persist(func() error {
+		return d.writeNode(node)
+	})
+}
+
+// encodeNode encodes a node as it is persisted in the cache folder.
+func (d *KeyValueStore) encodeNode(node *node) ([]byte, error) {
+	stored := *node
+	stored.KeyBytes = rawKey(node.Key)
+	stored.SourceBytes = rawKey(node.Source)
+	stored.Type = d.typeName(node.Value)
+	if d.persistTransform != nil {
+		value, err := d.persistTransform(node.Key, node.Value)
+		if err != nil {
+			return nil, d.keyError("transform value", node.Key, err)
+		}
+		stored.Value = value
+	}
+	data, err := d.marshalNode(d.codec, &stored)
+	if err != nil {
+		return nil, d.keyError("encode value", node.Key, err)
+	}
+	return data, nil
+}
+
+// writeNode writes a node to its file in the cache folder and adds it to the index.
+func (d *KeyValueStore) writeNode(node *node) error {
+	err := d.checkDiskSpace()
+	if err != nil {
+		return d.keyError("write cache file", node.Key, err)
+	}
+	data, err := d.encodeNode(node)
 	if err != nil {
 		return err
 	}
+	if d.packing != nil && len(data) < d.packing.threshold {
+		return d.writePacked(node, data)
+	}
 	fileName, err := d.getFileName(node.Key)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(fileName, data, 0600)
+	err = d.checkFileOwner(fileName, node.Key)
+	if err != nil {
+		return err
+	}
+	err = d.writeFileAtomic(fileName, data)
+	if err != nil {
+		return d.keyError("write cache file", node.Key, err)
+	}
+	node.size = len(data)
+	if d.useIndex {
+		node.modTime = d.fileModTime(fileName)
+	}
+	err = d.unpack(node.Key)
+	if err != nil {
+		return d.keyError("update segment", node.Key, err)
+	}
+	err = d.appendToIndex(indexRecord{
+		Key:             node.Key,
+		File:            filepath.Base(fileName),
+		DeleteTimestamp: node.DeleteTimestamp,
+		Size:            node.size,
+		Revision:        node.Revision,
+		UpdatedAt:       node.UpdatedAt,
+		CreatedAt:       node.CreatedAt,
+		Sequence:        node.Sequence,
+		Source:          node.Source,
+		Instance:        node.Instance,
+		Run:             node.Run,
+		ModTime:         node.modTime,
+	})
+	if err != nil {
+		return d.keyError("update index", node.Key, err)
+	}
+	return nil
+}
+
+// ErrFilenameCollision is returned when the file of a key holds another key, e.g. because it was copied from
+// another cache folder. The file is left unchanged.
+var ErrFilenameCollision = errors.New("file name collision")
+
+// checkFileOwner returns ErrFilenameCollision if the file of a key exists and holds another key.
+// Only the key fields of the file are decoded.
+func (d *KeyValueStore) checkFileOwner(fileName string, key string) error {
+	data, err := d.readCacheFile(fileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return d.keyError("read cache file", key, err)
+	}
+	var owner struct {
+		Key      string `json:"key"`
+		KeyBytes []byte `json:"keyBytes"`
+	}
+	if d.codec.Unmarshal(data, &owner) != nil {
+		return nil
+	}
+	other := restoreKey(owner.Key, owner.KeyBytes)
+	if other == key {
+		return nil
+	}
+	return fmt.Errorf("%w: file %s of key %s holds key %s", ErrFilenameCollision, filepath.Base(fileName), d.redactKey(key), d.redactKey(other))
 }
 
-// Get gets a value by key. If the key does not exist, the second return value is false.
+// Get gets a value by key. If the key does not exist, the second return value is false. Keys that are missing in
+// a store with a FetchBackend are fetched from the backend first.
 func (d *KeyValueStore) Get(key string) (any, bool) {
+	d.lazyInit()
+	return d.GetCtx(context.Background(), key)
+}
+
+// GetCtx is like Get and records the actor of ctx, see ContextWithActor, in audit records.
+func (d *KeyValueStore) GetCtx(ctx context.Context, key string) (any, bool) {
+	d.lazyInit()
+	rawKey := key
+	key = d.storageKey(key)
+	var value any
+	var ok bool
+	if d.readView != nil {
+		value, ok = d.getFromReadView(key)
+	} else {
+		value, ok = d.get(key)
+	}
+	if !ok && d.backend != nil {
+		value, ok = d.fetch(key)
+	}
+	d.audit(ctx, "get", key, ok, nil)
+	if ok {
+		d.revalidate(rawKey, key)
+	}
+	return value, ok
+}
+
+// get gets a value by key. If the key does not exist, the second return value is false.
+func (d *KeyValueStore) get(key string) (any, bool) {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 	val, ok := d.data[key]
-	if !ok || nodeIsExpired(val) {
+	if !ok || d.nodeIsExpired(val) || d.closed.Load() {
+		return nil, false
+	}
+	value, err := val.value()
+	if err != nil {
 		return nil, false
 	}
-	return val.Value, ok
+	d.eviction.touch(val)
+	return value, true
+}
+
+// Has reports whether a key exists and is not expired, like the second return value of Get. The value is not
+// loaded, and checking a key does not count as a use for EvictLRU and EvictApproxLRU.
+func (d *KeyValueStore) Has(key string) bool {
+	d.lazyInit()
+	key = d.storageKey(key)
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	node, ok := d.data[key]
+	return ok && !d.nodeIsExpired(node) && !d.closed.Load()
+}
+
+// GetWithAge gets a value by key together with the time since it was last set. Changing only the deadline of a
+// key, e.g. with AdjustTTL, does not change its age. Entries saved by versions without this metadata report an
+// age of 0. If the key does not exist, the third return value is false.
+func (d *KeyValueStore) GetWithAge(key string) (any, time.Duration, bool) {
+	d.lazyInit()
+	key = d.storageKey(key)
+	value, age, ok := d.getWithAge(key)
+	d.audit(context.Background(), "get", key, ok, nil)
+	return value, age, ok
+}
+
+// getWithAge gets a value by key together with the time since it was last set.
+func (d *KeyValueStore) getWithAge(key string) (any, time.Duration, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	node, ok := d.data[key]
+	if !ok || d.nodeIsExpired(node) || d.closed.Load() {
+		return nil, 0, false
+	}
+	value, err := node.value()
+	if err != nil {
+		return nil, 0, false
+	}
+	d.eviction.touch(node)
+	if node.UpdatedAt == 0 {
+		return value, 0, true
+	}
+	return value, d.now().Sub(time.UnixMilli(node.UpdatedAt)), true
 }
 
 // Delete deletes a key. If the key does not exist, this function does nothing.
 func (d *KeyValueStore) Delete(key string) error {
+	d.lazyInit()
+	return d.DeleteCtx(context.Background(), key)
+}
+
+// DeleteCtx is like Delete and records the actor of ctx, see ContextWithActor, in audit records.
+func (d *KeyValueStore) DeleteCtx(ctx context.Context, key string) error {
+	d.lazyInit()
+	key = d.storageKey(key)
+	err := d.delete(key)
+	d.audit(ctx, "delete", key, true, err)
+	return err
+}
+
+// delete deletes a key. If the key does not exist, this function does nothing.
+func (d *KeyValueStore) delete(key string) error {
+	defer d.afterWrite()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed.Load() {
+		return ErrClosed
+	}
+	if _, ok := d.data[key]; ok {
+		d.recordEvent(key, EventDeleted, "deleted")
+	}
+	d.removeNode(key)
+	return errors.Join(d.deleteInCache(key), d.removeDerived(key))
+}
+
+// Pop gets the value of a key and deletes the key in one step, so of several concurrent calls for a key exactly one
+// gets the value. The second return value is false if the key does not exist or is expired; the cache folder is
+// only changed if the key was deleted.
+func (d *KeyValueStore) Pop(key string) (any, bool, error) {
+	d.lazyInit()
+	key = d.storageKey(key)
+	value, ok, err := d.pop(key)
+	d.audit(context.Background(), "delete", key, ok, err)
+	return value, ok, err
+}
+
+// pop gets the value of a key and deletes the key.
+func (d *KeyValueStore) pop(key string) (any, bool, error) {
+	defer d.afterWrite()
 	d.mu.Lock()
 	defer d.mu.Unlock()
-	delete(d.data, key)
-	return d.deleteInCache(key)
+	if d.closed.Load() {
+		return nil, false, ErrClosed
+	}
+	node, ok := d.data[key]
+	if !ok || d.nodeIsExpired(node) {
+		return nil, false, nil
+	}
+	value, err := node.value()
+	if err != nil {
+		return nil, false, d.keyError("load value", key, err)
+	}
+	d.recordEvent(key, EventDeleted, "popped")
+	d.removeNode(key)
+	return value, true, errors.Join(d.deleteInCache(key), d.removeDerived(key))
+}
+
+// Clear deletes all key-value pairs while holding the write lock and removes all cache files from the cache
+// folder, including files of keys that are not in memory. Other files in the folder are left as they are.
+// Memory is cleared even if some files can not be removed; their errors are joined.
+func (d *KeyValueStore) Clear() error {
+	d.lazyInit()
+	defer d.afterWrite()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed.Load() {
+		return ErrClosed
+	}
+	var errs []error
+	for key := range d.data {
+		d.recordEvent(key, EventDeleted, "cleared")
+		d.removeNode(key)
+		if !d.persistent() {
+			continue
+		}
+		if err := d.deleteInCache(key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if d.cacheFolder == "" || d.degradedErr != nil {
+		return errors.Join(errs...)
+	}
+	entries, err := d.fs.ReadDir(d.cacheFolder)
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), d.cacheFileSuffix()) {
+			continue
+		}
+		err := d.diskOp(func() error {
+			return d.fs.Remove(filepath.Join(d.cacheFolder, entry.Name()))
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // deleteInCache deletes a key from the cache folder.
 func (d *KeyValueStore) deleteInCache(key string) error {
+	if d.backend != nil {
+		return d.deleteInBackend(key)
+	}
 	if d.cacheFolder == "" {
 		return nil
 	}
+	if d.writeBehind != nil {
+		// with a flush interval, deletions are not delayed, so a crash never brings back a deleted key
+		if d.flushInterval > 0 {
+			d.writeBehind.cancel(key)
+		} else if d.writeBehind.enqueue(key, nil) {
+			return nil
+		}
+	}
+	return d.persist(func() error {
+		return d.removeFile(key)
+	})
+}
+
+// removeFile removes the file of a key from the cache folder and marks the key as deleted in the index.
+func (d *KeyValueStore) removeFile(key string) error {
+	packed, err := d.removePacked(key)
+	if err != nil {
+		return d.keyError("update segment", key, err)
+	}
+	if packed {
+		return nil
+	}
 	fileName, err := d.getFileName(key)
 	if err != nil {
 		return err
 	}
-	err = os.Remove(fileName)
+	err = d.diskOp(func() error {
+		return d.fs.Remove(fileName)
+	})
+	// a missing file is fine unless the whole cache folder is missing
+	if err != nil && !(errors.Is(err, fs.ErrNotExist) && d.folderExists()) {
+		return d.keyError("delete cache file", key, err)
+	}
+	err = d.appendToIndex(indexRecord{Key: key, Deleted: true})
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return err
+		return d.keyError("update index", key, err)
 	}
 	return nil
 }
@@ -127,16 +766,17 @@ func (d *KeyValueStore) deleteInCache(key string) error {
 // getFileName returns the file name for a key in the cache folder.
 func (d *KeyValueStore) getFileName(key string) (string, error) {
 	sum := sha256.Sum256([]byte(key))
-	return filepath.Join(d.cacheFolder, fmt.Sprintf("%s.store.json", hex.EncodeToString(sum[:]))), nil
+	return filepath.Join(d.cacheFolder, hex.EncodeToString(sum[:])+d.cacheFileSuffix()), nil
 }
 
 // Length returns the number of key-value pairs in the store.
 func (d *KeyValueStore) Length() int {
+	d.lazyInit()
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 	counter := 0
 	for _, node := range d.data {
-		if !nodeIsExpired(node) {
+		if !d.nodeIsExpired(node) {
 			counter++
 		}
 	}
@@ -144,60 +784,249 @@ func (d *KeyValueStore) Length() int {
 }
 
 // init initializes the KeyValueStore by loading existing key-value pairs from the cache folder.
+// If the index is enabled and valid, only the keys and deadlines are loaded and values are read on first access.
 func (d *KeyValueStore) init() error {
 	if d.cacheFolder == "" {
+		if d.instanceID == "" {
+			d.instanceID = newID()
+		}
+		if d.backend != nil {
+			return d.loadBackend()
+		}
 		return nil
 	}
-	err := os.MkdirAll(d.cacheFolder, 0700)
+	err := d.checkCacheFolder()
 	if err != nil {
 		return err
 	}
-	entries, err := os.ReadDir(d.cacheFolder)
+	err = d.claimFolder()
 	if err != nil {
 		return err
 	}
+	// temporary files that can not be removed are left for the next start or Housekeep
+	d.housekeep()
+	err = d.checkCodec()
+	if err != nil {
+		return err
+	}
+	err = d.migrateLegacyFiles()
+	if err != nil {
+		return err
+	}
+	if d.packing != nil && d.useIndex {
+		return errors.New("packed small values can not be combined with the index")
+	}
+	if (d.writeBehindSize > 0 || d.flushInterval > 0) && (d.packing != nil || d.useIndex) {
+		return errors.New("write-behind can not be combined with the index or packed small values")
+	}
+	if d.useIndex {
+		loaded, err := d.loadIndex()
+		if err != nil {
+			return err
+		}
+		if loaded {
+			return nil
+		}
+	}
+	err = d.loadFiles()
+	if err != nil {
+		return err
+	}
+	err = d.loadSegments()
+	if err != nil {
+		return err
+	}
+	if d.useIndex {
+		return d.writeIndex()
+	}
+	return nil
+}
+
+// loadFiles loads all key-value pairs from the files in the cache folder. Files that can not be read or decoded
+// are quarantined unless WithStrictLoad is enabled.
+func (d *KeyValueStore) loadFiles() error {
+	entries, err := d.fs.ReadDir(d.cacheFolder)
+	if err != nil {
+		return err
+	}
+	entries = slices.DeleteFunc(entries, func(file fs.DirEntry) bool {
+		return !strings.HasSuffix(file.Name(), d.cacheFileSuffix())
+	})
+	d.startWarming(len(entries))
 	for _, file := range entries {
-		if !strings.HasSuffix(file.Name(), ".store.json") {
+		fileData, err := d.readCacheFile(filepath.Join(d.cacheFolder, file.Name()))
+		node := &node{size: len(fileData)}
+		if err == nil {
+			err = d.decodeNode(fileData, node)
+		}
+		if err == nil && d.useIndex {
+			node.modTime = modTimeOf(file)
+		}
+		if err != nil {
+			if d.strictLoad {
+				return fmt.Errorf("%w %s: %w", ErrCorruptFile, file.Name(), err)
+			}
+			reported, err := d.quarantine(file.Name(), err)
+			if err != nil {
+				return err
+			}
+			d.errorMu.Lock()
+			d.startErrors = append(d.startErrors, reported)
+			d.errorMu.Unlock()
+			d.warmDone.Add(1)
 			continue
 		}
-		fileData, err := os.ReadFile(filepath.Join(d.cacheFolder, file.Name()))
+		node.Value, err = d.transformLoaded(node.Key, node.Value, node.Type)
 		if err != nil {
 			return err
 		}
-		var node node
-		err = json.Unmarshal(fileData, &node)
+		// expired nodes are kept until the next clean run removes them together with their file
+		d.putNode(node)
+		if d.packing != nil {
+			d.packing.loose[node.Key] = true
+		}
+		d.observeRevision(node.Revision)
+		d.warmDone.Add(1)
+	}
+	return nil
+}
+
+// loadValue reads the value of a key from a file in the cache folder.
+func (d *KeyValueStore) loadValue(key string, fileName string) (any, error) {
+	fileData, err := d.readCacheFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var stored node
+	err = d.decodeNode(fileData, &stored)
+	if err != nil {
+		return nil, err
+	}
+	if stored.Key != key {
+		return nil, fmt.Errorf("cache file %s belongs to a different key", fileName)
+	}
+	return d.transformLoaded(key, stored.Value, stored.Type)
+}
+
+// decodeNode decodes a node read from the cache folder with the codec of the store and verifies its checksum.
+// Nodes saved without a checksum are accepted.
+func (d *KeyValueStore) decodeNode(data []byte, node *node) error {
+	err := d.unmarshal(d.codec, data, node)
+	if err != nil {
+		return err
+	}
+	if node.Checksum != "" {
+		err = verifyChecksum(d.codec, data, node.Checksum)
 		if err != nil {
 			return err
 		}
-		now := time.Now().UnixMilli()
-		timeLeft := node.DeleteTimestamp - now
-		if timeLeft < 0 {
-			timeLeft = 1
-		}
-		d.Set(node.Key, node.Value, int(timeLeft))
+		node.Checksum = ""
 	}
+	node.Key = restoreKey(node.Key, node.KeyBytes)
+	node.KeyBytes = nil
+	node.Source = restoreKey(node.Source, node.SourceBytes)
+	node.SourceBytes = nil
 	return nil
 }
 
-// clean deletes expired key-value pairs. The interval of cleaning is determined by cleanTimeout.
-func (d *KeyValueStore) clean() error {
-	for {
-		time.Sleep(time.Duration(d.cleanTimeout) * time.Second)
+// rawKey returns the bytes of a key that is not valid UTF-8. JSON strings are always valid UTF-8, so such
+// keys are saved as bytes in addition to the key string to survive a restart.
+func rawKey(key string) []byte {
+	if utf8.ValidString(key) {
+		return nil
+	}
+	return []byte(key)
+}
+
+// restoreKey returns the key saved by rawKey if there is one and the key string otherwise.
+func restoreKey(key string, raw []byte) string {
+	if raw != nil {
+		return string(raw)
+	}
+	return key
+}
+
+// transformLoaded applies the load transform to a value read from the cache folder and converts it back to the
+// registered type it was saved with.
+func (d *KeyValueStore) transformLoaded(key string, value any, typeName string) (any, error) {
+	if d.loadTransform != nil {
+		var err error
+		value, err = d.loadTransform(key, value)
+		if err != nil {
+			return nil, d.keyError("transform value", key, err)
+		}
+	}
+	return d.restoreType(key, value, typeName), nil
+}
+
+// DeleteExpiringBefore deletes all key-value pairs that expire before t, even if they are not expired yet.
+// Keys without expiration are never deleted. It returns the number of deleted keys.
+func (d *KeyValueStore) DeleteExpiringBefore(t time.Time) (int, error) {
+	d.lazyInit()
+	cutoff := t.UnixMilli()
+	result, err := d.deleteWhere(func(node *node) bool {
+		return node.DeleteTimestamp != neverExpire && node.DeleteTimestamp < cutoff
+	}, EventDeleted, "deleted by DeleteExpiringBefore")
+	return result.deleted, err
+}
+
+// A sweepResult counts the work done by deleteWhere.
+type sweepResult struct {
+	examined      int
+	deleted       int
+	fileDeletions int
+	fileFailures  int
+}
+
+// deleteWhere deletes all nodes for which shouldDelete returns true. The nodes are removed in batches of
+// sweepBatchSize and the write lock is released between batches so writers are not blocked for long.
+// Failed file deletions do not stop the removal of the remaining nodes; their errors are joined.
+// Each node is checked again and its file is deleted while holding the write lock, so a key set again during
+// the sweep keeps its new file.
+// The removal of each node is recorded as an event of the given kind and reason.
+func (d *KeyValueStore) deleteWhere(shouldDelete func(*node) bool, kind EventKind, reason string) (sweepResult, error) {
+	result := sweepResult{}
+	d.mu.RLock()
+	keys := []string{}
+	for key, node := range d.data {
+		result.examined++
+		if shouldDelete(node) {
+			keys = append(keys, key)
+		}
+	}
+	d.mu.RUnlock()
+	defer d.afterWrite()
+	var errs []error
+	for start := 0; start < len(keys); start += sweepBatchSize {
+		end := min(start+sweepBatchSize, len(keys))
 		d.mu.Lock()
-		for key, node := range d.data {
-			if nodeIsExpired(node) {
-				delete(d.data, key)
-				err := d.deleteInCache(key)
-				if err != nil {
-					panic(err) // this should never happen
-				}
+		for _, key := range keys[start:end] {
+			node, ok := d.data[key]
+			if !ok || !shouldDelete(node) {
+				continue // the key was changed since it was collected
+			}
+			d.removeNode(key)
+			d.recordEvent(key, kind, reason)
+			result.deleted++
+			if err := d.removeDerived(key); err != nil {
+				errs = append(errs, err)
+			}
+			if !d.persistent() {
+				continue
+			}
+			result.fileDeletions++
+			err := d.deleteInCache(key)
+			if err != nil {
+				result.fileFailures++
+				errs = append(errs, err)
 			}
 		}
 		d.mu.Unlock()
 	}
+	return result, errors.Join(errs...)
 }
 
 // nodeIsExpired returns true if a node is expired.
-func nodeIsExpired(node node) bool {
-	return time.Now().UnixMilli() > node.DeleteTimestamp
+func (d *KeyValueStore) nodeIsExpired(node *node) bool {
+	return d.now().UnixMilli() > node.DeleteTimestamp
 }
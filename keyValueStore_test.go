@@ -1,7 +1,12 @@
 package goKeyValueStore_test
 
 import (
+	"encoding/json"
+	"errors"
 	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -83,6 +88,54 @@ func TestKeyValueStoreGetExpiredKey(t *testing.T) {
 	}
 }
 
+func TestHas(t *testing.T) {
+	clock := newFakeClock()
+	store := getTestStoreWithClock(t, "", clock)
+	store.Set("key", "value", 1000)
+	store.Set("nil", nil, 0)
+	if !store.Has("key") || !store.Has("nil") {
+		t.Errorf("Expected key and nil to be present")
+	}
+	if store.Has("missing") {
+		t.Errorf("Expected missing to be absent")
+	}
+	clock.Advance(1001 * time.Millisecond)
+	if store.Has("key") {
+		t.Errorf("Expected expired key to be absent before the cleaner runs")
+	}
+	if store.Length() != 1 {
+		t.Errorf("Expected length to be 1, got %d", store.Length())
+	}
+	store.CleanNow()
+	if store.Has("key") {
+		t.Errorf("Expected key to be absent after the cleaner ran")
+	}
+}
+
+func TestTTL(t *testing.T) {
+	clock := newFakeClock()
+	store := getTestStoreWithClock(t, "", clock)
+	store.Set("key", "value", 3000)
+	store.Set("forever", "value", 0)
+	if ttl, ok := store.TTL("key"); !ok || ttl != 3*time.Second {
+		t.Errorf("Expected 3s, got %v, %v", ttl, ok)
+	}
+	clock.Advance(time.Second)
+	if ttl, ok := store.TTL("key"); !ok || ttl != 2*time.Second {
+		t.Errorf("Expected 2s, got %v, %v", ttl, ok)
+	}
+	if ttl, ok := store.TTL("forever"); !ok || ttl != goKeyValueStore.NoExpiration {
+		t.Errorf("Expected NoExpiration, got %v, %v", ttl, ok)
+	}
+	clock.Advance(2001 * time.Millisecond)
+	if _, ok := store.TTL("key"); ok {
+		t.Errorf("Expected key to be expired")
+	}
+	if _, ok := store.TTL("missing"); ok {
+		t.Errorf("Expected missing to be absent")
+	}
+}
+
 func TestKeyValueStoreDelete(t *testing.T) {
 	store := getTestStore()
 	store.Delete("key1")
@@ -183,3 +236,209 @@ func TestKeyValueStoreGetObject(t *testing.T) {
 		t.Errorf("Expected 3, got %d", len(value.List))
 	}
 }
+
+func TestSetWithoutTTLNeverExpires(t *testing.T) {
+	store := getTestStore()
+	store.Set("key4", "value4", 0)
+	time.Sleep(1 * time.Second)
+	val, ok := store.Get("key4")
+	if !ok {
+		t.Errorf("Expected key4 to be present")
+	}
+	if val != "value4" {
+		t.Errorf("Expected value4, got %s", val)
+	}
+}
+
+func TestDeleteExpiringBefore(t *testing.T) {
+	store := getTestStore()
+	store.Set("key1", "value1", 10000)
+	store.Set("key2", "value2", 20000)
+	store.Set("key3", "value3", 60000)
+	store.Set("key4", "value4", 0)
+	deleted, err := store.DeleteExpiringBefore(time.Now().Add(30 * time.Second))
+	if err != nil {
+		t.Error(err)
+	}
+	if deleted != 2 {
+		t.Errorf("Expected 2 deleted keys, got %d", deleted)
+	}
+	for _, key := range []string{"key1", "key2"} {
+		if _, ok := store.Get(key); ok {
+			t.Errorf("Expected %s to be deleted", key)
+		}
+	}
+	for _, key := range []string{"key3", "key4"} {
+		if _, ok := store.Get(key); !ok {
+			t.Errorf("Expected %s to be present", key)
+		}
+	}
+	entries, err := os.ReadDir(CACHE_DIR)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected 2 files, got %d", len(entries))
+	}
+}
+
+func TestInitRestoresDeleteTimestamp(t *testing.T) {
+	store := getTestStore()
+	store.Set("key4", "value4", 0)
+	restored, err := goKeyValueStore.NewKeyValueStore(0.5, CACHE_DIR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Length() != 4 {
+		t.Errorf("Expected length to be 4, got %d", restored.Length())
+	}
+	time.Sleep(1 * time.Second)
+	if _, ok := restored.Get("key4"); !ok {
+		t.Errorf("Expected key4 to be present")
+	}
+	if restored.Length() != 1 {
+		t.Errorf("Expected length to be 1, got %d", restored.Length())
+	}
+}
+
+func TestUseNumberKeepsLargeIntegers(t *testing.T) {
+	type snowflake struct {
+		ID int64 `json:"id"`
+	}
+	const id int64 = 1<<60 + 1
+	for _, index := range []bool{false, true} {
+		dir := t.TempDir()
+		store, err := goKeyValueStore.NewKeyValueStore(1, dir, goKeyValueStore.WithUseNumber(true), goKeyValueStore.WithIndex(index))
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.Set("bare", id, 0)
+		store.Set("field", snowflake{ID: id}, 0)
+		restored, err := goKeyValueStore.NewKeyValueStore(1, dir, goKeyValueStore.WithUseNumber(true), goKeyValueStore.WithIndex(index))
+		if err != nil {
+			t.Fatal(err)
+		}
+		bare, _ := restored.Get("bare")
+		if n, ok := bare.(json.Number); !ok || n.String() != strconv.FormatInt(id, 10) {
+			t.Errorf("Expected json.Number %d, got %#v", id, bare)
+		}
+		field, _ := restored.Get("field")
+		object, _ := field.(map[string]any)
+		if n, ok := object["id"].(json.Number); !ok || n.String() != strconv.FormatInt(id, 10) {
+			t.Errorf("Expected field json.Number %d, got %#v", id, field)
+		}
+	}
+}
+
+func TestGetWithAge(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, clock)
+	store.Set("key", "value", 60000)
+	clock.Advance(3 * time.Second)
+	value, age, ok := store.GetWithAge("key")
+	if !ok || value != "value" || age != 3*time.Second {
+		t.Errorf("Expected value with age 3s, got %v, %v, %v", value, age, ok)
+	}
+	store.AdjustTTL(time.Minute, nil)
+	clock.Advance(2 * time.Second)
+	restored := getTestStoreWithClock(t, dir, clock)
+	if _, age, _ := restored.GetWithAge("key"); age != 5*time.Second {
+		t.Errorf("Expected age 5s after restart, got %v", age)
+	}
+	restored.Set("key", "new value", 60000)
+	if _, age, _ := restored.GetWithAge("key"); age != 0 {
+		t.Errorf("Expected age 0 after a new Set, got %v", age)
+	}
+	if _, _, ok := restored.GetWithAge("missing"); ok {
+		t.Error("Expected missing key not to be found")
+	}
+}
+
+func TestNilValue(t *testing.T) {
+	for _, index := range []bool{false, true} {
+		dir := t.TempDir()
+		store, err := goKeyValueStore.NewKeyValueStore(1, dir, goKeyValueStore.WithIndex(index))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = store.Set("nil", nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		restored, err := goKeyValueStore.NewKeyValueStore(1, dir, goKeyValueStore.WithIndex(index))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range []*goKeyValueStore.KeyValueStore{store, restored} {
+			if value, ok := s.Get("nil"); !ok || value != nil {
+				t.Errorf("Expected nil and true, got %v, %v", value, ok)
+			}
+			if s.Length() != 1 {
+				t.Errorf("Expected length 1, got %d", s.Length())
+			}
+			if keys, _ := s.KeysN(10); len(keys) != 1 {
+				t.Errorf("Expected the nil entry in the keys, got %v", keys)
+			}
+		}
+	}
+}
+
+func TestRejectNilValues(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(1, "", goKeyValueStore.WithRejectNilValues(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("nil", nil, 0); !errors.Is(err, goKeyValueStore.ErrNilValue) {
+		t.Errorf("Expected ErrNilValue, got %v", err)
+	}
+	results := store.SetManyDetailed([]goKeyValueStore.Entry{{Key: "nil"}, {Key: "value", Value: 1}})
+	if !errors.Is(results[0].Err, goKeyValueStore.ErrNilValue) || results[1].Err != nil {
+		t.Errorf("Expected only the nil entry to be rejected, got %+v", results)
+	}
+	if store.Length() != 1 {
+		t.Errorf("Expected length 1, got %d", store.Length())
+	}
+}
+
+func TestPop(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, clock)
+	store.Set("job", "work", 0)
+	store.Set("expired", "work", 1000)
+	value, ok, err := store.Pop("job")
+	if err != nil || !ok || value != "work" {
+		t.Errorf("Expected work, got %v, %v, %v", value, ok, err)
+	}
+	if store.Has("job") || countCacheFiles(t, dir) != 1 {
+		t.Errorf("Expected job and its file to be deleted")
+	}
+	clock.Advance(1001 * time.Millisecond)
+	if _, ok, err := store.Pop("expired"); ok || err != nil {
+		t.Errorf("Expected an expired key to not be popped, got %v, %v", ok, err)
+	}
+	if countCacheFiles(t, dir) != 1 {
+		t.Errorf("Expected the file of the expired key to be left to the cleaner")
+	}
+}
+
+func TestPopConcurrent(t *testing.T) {
+	store := getTestStoreWithClock(t, t.TempDir(), newFakeClock())
+	store.Set("job", "work", 0)
+	var wg sync.WaitGroup
+	var popped atomic.Int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok, _ := store.Pop("job"); ok {
+				popped.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if popped.Load() != 1 {
+		t.Errorf("Expected exactly one consumer to get the job, got %d", popped.Load())
+	}
+}
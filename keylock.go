@@ -0,0 +1,60 @@
+package goKeyValueStore
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+)
+
+// keyLockStripes is the number of mutexes shared by all keys locked with WithKeyLock.
+const keyLockStripes = 256
+
+// A KeyHandle gives access to the key locked by WithKeyLock.
+type KeyHandle struct {
+	store *KeyValueStore
+	key   string
+}
+
+// Key returns the locked key.
+func (h KeyHandle) Key() string {
+	return h.key
+}
+
+// Get gets the value of the locked key. If the key does not exist, the second return value is false.
+// It always sees the latest value, even with WithReadOptimized.
+func (h KeyHandle) Get() (any, bool) {
+	key := h.store.storageKey(h.key)
+	value, ok := h.store.get(key)
+	h.store.audit(context.Background(), "get", key, ok, nil)
+	return value, ok
+}
+
+// Set sets the value of the locked key with a TTL in milliseconds.
+func (h KeyHandle) Set(value any, ttl int) error {
+	return h.store.Set(h.key, value, ttl)
+}
+
+// Delete deletes the locked key.
+func (h KeyHandle) Delete() error {
+	return h.store.Delete(h.key)
+}
+
+// WithKeyLock calls fn while holding a lock for key, so that all WithKeyLock calls for the same key are serialized.
+// The lock is independent of the store's internal lock: fn may take as long as it needs and calls for other
+// keys proceed in parallel. Set, Get, and Delete called directly on the store are not blocked by the lock.
+// Keys are mapped to a fixed number of mutexes, so calling WithKeyLock from within fn deadlocks if both keys
+// are the same or share a mutex. Never nest WithKeyLock calls.
+func (d *KeyValueStore) WithKeyLock(key string, fn func(h KeyHandle) error) error {
+	d.lazyInit()
+	mu := d.keyLock(key)
+	mu.Lock()
+	defer mu.Unlock()
+	return fn(KeyHandle{store: d, key: key})
+}
+
+// keyLock returns the mutex used by WithKeyLock for key.
+func (d *KeyValueStore) keyLock(key string) *sync.Mutex {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return &d.keyLocks[hash.Sum32()%keyLockStripes]
+}
@@ -0,0 +1,94 @@
+package goKeyValueStore_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestWithKeyLockSerializesSameKey(t *testing.T) {
+	store := getTestStore()
+	var running, maxRunning atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store.WithKeyLock("counter", func(h goKeyValueStore.KeyHandle) error {
+				current := running.Add(1)
+				if current > maxRunning.Load() {
+					maxRunning.Store(current)
+				}
+				val, _ := h.Get()
+				count, _ := val.(int)
+				time.Sleep(time.Millisecond)
+				running.Add(-1)
+				return h.Set(count+1, 0)
+			})
+		}()
+	}
+	wg.Wait()
+	if maxRunning.Load() != 1 {
+		t.Errorf("Expected at most 1 concurrent call, got %d", maxRunning.Load())
+	}
+	val, _ := store.Get("counter")
+	if val != 10 {
+		t.Errorf("Expected 10, got %v", val)
+	}
+}
+
+func TestWithKeyLockDifferentKeysRunInParallel(t *testing.T) {
+	store := getTestStore()
+	started := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		store.WithKeyLock("key1", func(h goKeyValueStore.KeyHandle) error {
+			close(started)
+			<-done
+			return nil
+		})
+	}()
+	<-started
+	go func() {
+		store.WithKeyLock("key2", func(h goKeyValueStore.KeyHandle) error {
+			close(done)
+			return nil
+		})
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Expected different keys to be locked in parallel")
+	}
+}
+
+func TestKeyHandle(t *testing.T) {
+	store := getTestStore()
+	err := store.WithKeyLock("key1", func(h goKeyValueStore.KeyHandle) error {
+		if h.Key() != "key1" {
+			t.Errorf("Expected key1, got %s", h.Key())
+		}
+		val, ok := h.Get()
+		if !ok || val != "value1" {
+			t.Errorf("Expected value1, got %v", val)
+		}
+		err := h.Set("changed", 100)
+		if err != nil {
+			return err
+		}
+		val, _ = store.Get("key1")
+		if val != "changed" {
+			t.Errorf("Expected changed, got %v", val)
+		}
+		return h.Delete()
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if _, ok := store.Get("key1"); ok {
+		t.Errorf("Expected key1 to be deleted")
+	}
+}
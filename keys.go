@@ -0,0 +1,213 @@
+package goKeyValueStore
+
+import (
+	"container/heap"
+	"slices"
+	"strings"
+)
+
+// Keys returns all non-expired keys in no particular order. The slice is a copy and empty for an empty store.
+func (d *KeyValueStore) Keys() []string {
+	d.lazyInit()
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	keys := make([]string, 0, len(d.data))
+	for key, node := range d.data {
+		if !d.nodeIsExpired(node) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// KeysSorted returns all non-expired keys in sorted order.
+func (d *KeyValueStore) KeysSorted() []string {
+	keys := d.Keys()
+	slices.Sort(keys)
+	return keys
+}
+
+// KeysWithPrefix returns all non-expired keys that start with prefix in no particular order. An empty prefix
+// returns all keys. With WithHashedKeys, it returns ErrUnsupportedWithHashedKeys.
+func (d *KeyValueStore) KeysWithPrefix(prefix string) ([]string, error) {
+	d.lazyInit()
+	if d.hashKeys {
+		return nil, ErrUnsupportedWithHashedKeys
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	keys := []string{}
+	for key, node := range d.data {
+		if strings.HasPrefix(key, prefix) && !d.nodeIsExpired(node) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// GetByPrefix returns the values of all non-expired keys that start with prefix while holding the read lock once.
+// An empty prefix returns all key-value pairs. With WithHashedKeys, it returns ErrUnsupportedWithHashedKeys.
+func (d *KeyValueStore) GetByPrefix(prefix string) (map[string]any, error) {
+	d.lazyInit()
+	if d.hashKeys {
+		return nil, ErrUnsupportedWithHashedKeys
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	values := map[string]any{}
+	for key, node := range d.data {
+		if !strings.HasPrefix(key, prefix) || d.nodeIsExpired(node) {
+			continue
+		}
+		value, err := node.value()
+		if err != nil {
+			continue
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// DeleteByPrefix deletes all keys that start with prefix and returns the number of deleted keys. The keys are
+// deleted in batches like expired keys by the cleaner; failed deletions of files are joined. With WithHashedKeys,
+// it returns ErrUnsupportedWithHashedKeys.
+func (d *KeyValueStore) DeleteByPrefix(prefix string) (int, error) {
+	d.lazyInit()
+	if d.hashKeys {
+		return 0, ErrUnsupportedWithHashedKeys
+	}
+	result, err := d.deleteWhere(func(node *node) bool {
+		return strings.HasPrefix(node.Key, prefix)
+	}, EventDeleted, "deleted by DeleteByPrefix")
+	return result.deleted, err
+}
+
+// KeysN returns at most limit non-expired keys in no particular order. The second return value is true if
+// the store holds more keys than were returned. A limit of 0 or less returns no keys.
+func (d *KeyValueStore) KeysN(limit int) ([]string, bool) {
+	d.lazyInit()
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	keys := make([]string, 0, min(max(limit, 0), len(d.data)))
+	for key, node := range d.data {
+		if d.nodeIsExpired(node) {
+			continue
+		}
+		if len(keys) == limit || limit <= 0 {
+			return keys, true
+		}
+		keys = append(keys, key)
+	}
+	return keys, false
+}
+
+// KeysPage returns at most limit non-expired keys in sorted order that come after cursor, and the cursor to pass
+// to the next call to get the next page. An empty cursor starts at the first key and an empty next cursor means
+// there are no more keys. Every key that exists during the whole paging is returned exactly once, while keys set
+// or deleted between calls may or may not be returned. Only limit keys are kept per call. A limit of 0 or less
+// returns no keys and the same cursor. Cursors that were not returned by KeysPage or Scan return
+// ErrInvalidCursor.
+func (d *KeyValueStore) KeysPage(cursor string, limit int) ([]string, string, error) {
+	d.lazyInit()
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	if limit <= 0 {
+		return []string{}, cursor, nil
+	}
+	// smallest holds the limit smallest keys after the cursor, and one more to know if there are more pages
+	smallest := &keyHeap{}
+	d.mu.RLock()
+	for key, node := range d.data {
+		if (cursor != "" && key <= after) || d.nodeIsExpired(node) {
+			continue
+		}
+		if smallest.Len() <= limit {
+			heap.Push(smallest, key)
+		} else if key < (*smallest)[0] {
+			(*smallest)[0] = key
+			heap.Fix(smallest, 0)
+		}
+	}
+	d.mu.RUnlock()
+	keys := []string(*smallest)
+	slices.Sort(keys)
+	if len(keys) <= limit {
+		return keys, "", nil
+	}
+	keys = keys[:limit]
+	return keys, encodeCursor(keys[limit-1]), nil
+}
+
+// KeysByInsertion returns at most limit non-expired keys in the order they were inserted, oldest first.
+// Overwriting a key keeps its place, while a key that is set again after it was deleted or expired is inserted
+// anew. The order is kept across restarts. A limit of 0 or less returns no keys.
+func (d *KeyValueStore) KeysByInsertion(limit int) []string {
+	d.lazyInit()
+	if limit <= 0 {
+		return []string{}
+	}
+	d.mu.RLock()
+	nodes := []*node{}
+	for _, node := range d.data {
+		if !d.nodeIsExpired(node) {
+			nodes = append(nodes, node)
+		}
+	}
+	d.mu.RUnlock()
+	slices.SortFunc(nodes, func(a, b *node) int {
+		if insertedBefore(a, b) {
+			return -1
+		}
+		if insertedBefore(b, a) {
+			return 1
+		}
+		return 0
+	})
+	keys := make([]string, 0, min(limit, len(nodes)))
+	for _, node := range nodes[:min(limit, len(nodes))] {
+		keys = append(keys, node.Key)
+	}
+	return keys
+}
+
+// OldestEntry returns the non-expired key-value pair that was inserted first. If the store is empty,
+// the third return value is false.
+func (d *KeyValueStore) OldestEntry() (string, any, bool) {
+	d.lazyInit()
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	var oldest *node
+	for _, node := range d.data {
+		if !d.nodeIsExpired(node) && (oldest == nil || insertedBefore(node, oldest)) {
+			oldest = node
+		}
+	}
+	if oldest == nil {
+		return "", nil, false
+	}
+	value, err := oldest.value()
+	if err != nil {
+		return "", nil, false
+	}
+	return oldest.Key, value, true
+}
+
+// insertedBefore reports whether a was inserted before b. Insertions are ordered by their time and, for the same
+// time, by their sequence number, which is unique. Nodes saved by versions without this metadata are ordered by
+// the time and revision of their last write.
+func insertedBefore(a, b *node) bool {
+	aTime, aSequence := a.CreatedAt, a.Sequence
+	if aSequence == 0 {
+		aTime, aSequence = a.UpdatedAt, a.Revision
+	}
+	bTime, bSequence := b.CreatedAt, b.Sequence
+	if bSequence == 0 {
+		bTime, bSequence = b.UpdatedAt, b.Revision
+	}
+	if aTime != bTime {
+		return aTime < bTime
+	}
+	return aSequence < bSequence
+}
@@ -0,0 +1,207 @@
+package goKeyValueStore_test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func getLargeTestStore(t *testing.T, n int) *goKeyValueStore.KeyValueStore {
+	store, err := goKeyValueStore.NewKeyValueStore(1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := make([]goKeyValueStore.Entry, n)
+	for i := range entries {
+		entries[i] = goKeyValueStore.Entry{Key: fmt.Sprintf("key%05d", i), Value: i}
+	}
+	store.SetManyDetailed(entries)
+	return store
+}
+
+func TestKeys(t *testing.T) {
+	clock := newFakeClock()
+	store := getTestStoreWithClock(t, "", clock)
+	if keys := store.Keys(); keys == nil || len(keys) != 0 {
+		t.Errorf("Expected an empty slice, got %#v", keys)
+	}
+	store.Set("b", "value", 0)
+	store.Set("a", "value", 0)
+	store.Set("expired", "value", 1000)
+	clock.Advance(2 * time.Second)
+	keys := store.KeysSorted()
+	if !slices.Equal(keys, []string{"a", "b"}) {
+		t.Errorf("Expected [a b], got %v", keys)
+	}
+	keys[0] = "changed"
+	if keys := store.Keys(); slices.Contains(keys, "changed") || len(keys) != 2 {
+		t.Errorf("Expected a copy of the keys, got %v", keys)
+	}
+}
+
+func TestKeysN(t *testing.T) {
+	store := getLargeTestStore(t, 1000)
+	keys, truncated := store.KeysN(10)
+	if len(keys) != 10 || !truncated {
+		t.Errorf("Expected 10 keys and truncation, got %d, %v", len(keys), truncated)
+	}
+	keys, truncated = store.KeysN(1000)
+	if len(keys) != 1000 || truncated {
+		t.Errorf("Expected 1000 keys without truncation, got %d, %v", len(keys), truncated)
+	}
+	keys, truncated = store.KeysN(0)
+	if len(keys) != 0 || !truncated {
+		t.Errorf("Expected no keys and truncation, got %d, %v", len(keys), truncated)
+	}
+}
+
+func TestKeysPage(t *testing.T) {
+	store := getLargeTestStore(t, 1000)
+	all := []string{}
+	cursor := ""
+	pages := 0
+	for {
+		keys, next, err := store.KeysPage(cursor, 300)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) > 300 {
+			t.Fatalf("Expected at most 300 keys, got %d", len(keys))
+		}
+		all = append(all, keys...)
+		pages++
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if pages != 4 {
+		t.Errorf("Expected 4 pages, got %d", pages)
+	}
+	if len(all) != 1000 || !slices.IsSorted(all) || all[0] != "key00000" {
+		t.Errorf("Expected all keys in sorted order, got %d keys", len(all))
+	}
+}
+
+func TestKeysPageEmptyKey(t *testing.T) {
+	store, _ := goKeyValueStore.NewKeyValueStore(1, "")
+	store.Set("", "empty", 0)
+	store.Set("a", "value", 0)
+	keys, next, _ := store.KeysPage("", 1)
+	if len(keys) != 1 || keys[0] != "" || next == "" {
+		t.Fatalf("Expected the empty key and a cursor, got %q, %q", keys, next)
+	}
+	keys, next, _ = store.KeysPage(next, 1)
+	if len(keys) != 1 || keys[0] != "a" || next != "" {
+		t.Errorf("Expected a and the last page, got %q, %q", keys, next)
+	}
+	if _, _, err := store.KeysPage("a", 1); !errors.Is(err, goKeyValueStore.ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor for a raw key, got %v", err)
+	}
+}
+
+func TestKeysByInsertion(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, clock)
+	store.Set("c", "value", 0)
+	store.Set("a", "value", 0)
+	clock.Advance(time.Second)
+	store.Set("b", "value", 0)
+	store.Set("d", "value", 0)
+	store.Delete("a")
+	store.Set("c", "updated", 0)
+	store.Set("a", "value", 0)
+	store.Set("e", "value", 1000)
+	clock.Advance(2 * time.Second)
+	expected := []string{"c", "b", "d", "a"}
+	if keys := store.KeysByInsertion(10); !slices.Equal(keys, expected) {
+		t.Errorf("Expected %v, got %v", expected, keys)
+	}
+	if keys := store.KeysByInsertion(2); !slices.Equal(keys, expected[:2]) {
+		t.Errorf("Expected %v, got %v", expected[:2], keys)
+	}
+
+	restored := getTestStoreWithClock(t, dir, clock)
+	restored.Set("f", "value", 0)
+	expected = append(expected, "f")
+	if keys := restored.KeysByInsertion(10); !slices.Equal(keys, expected) {
+		t.Errorf("Expected %v after a restart, got %v", expected, keys)
+	}
+	drained := []string{}
+	for {
+		key, value, ok := restored.OldestEntry()
+		if !ok {
+			break
+		}
+		if key == "c" && value != "updated" {
+			t.Errorf("Expected the latest value of c, got %v", value)
+		}
+		drained = append(drained, key)
+		restored.Delete(key)
+	}
+	if !slices.Equal(drained, expected) {
+		t.Errorf("Expected to drain %v, got %v", expected, drained)
+	}
+}
+
+func TestPrefixQueries(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, clock)
+	store.Set("user:1", "alice", 0)
+	store.Set("user:1:session", "s1", 0)
+	store.Set("user:10", "bob", 0)
+	store.Set("user:2", "carol", 1000)
+	store.Set("session:1", "s2", 0)
+	clock.Advance(1001 * time.Millisecond)
+	keys, err := store.KeysWithPrefix("user:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"user:1", "user:10", "user:1:session"}) {
+		t.Errorf("Expected the keys starting with user:1, got %v", keys)
+	}
+	if values, _ := store.GetByPrefix("user:1:"); len(values) != 1 || values["user:1:session"] != "s1" {
+		t.Errorf("Expected only user:1:session, got %v", values)
+	}
+	if values, _ := store.GetByPrefix("user:"); len(values) != 3 {
+		t.Errorf("Expected 3 live users, got %v", values)
+	}
+	if keys, _ := store.KeysWithPrefix(""); len(keys) != 4 {
+		t.Errorf("Expected an empty prefix to match all 4 live keys, got %v", keys)
+	}
+	deleted, err := store.DeleteByPrefix("user:1:")
+	if err != nil || deleted != 1 {
+		t.Errorf("Expected 1 deleted key, got %d, %v", deleted, err)
+	}
+	if !store.Has("user:1") || store.Has("user:1:session") {
+		t.Errorf("Expected only user:1:session to be deleted")
+	}
+}
+
+func TestPrefixQueriesWithHashedKeys(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, "", goKeyValueStore.WithHashedKeys([]byte("salt")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("user:1", "alice", 0)
+	if _, err := store.KeysWithPrefix("user:"); !errors.Is(err, goKeyValueStore.ErrUnsupportedWithHashedKeys) {
+		t.Errorf("Expected ErrUnsupportedWithHashedKeys from KeysWithPrefix, got %v", err)
+	}
+	if _, err := store.GetByPrefix("user:"); !errors.Is(err, goKeyValueStore.ErrUnsupportedWithHashedKeys) {
+		t.Errorf("Expected ErrUnsupportedWithHashedKeys from GetByPrefix, got %v", err)
+	}
+	deleted, err := store.DeleteByPrefix("user:")
+	if deleted != 0 || !errors.Is(err, goKeyValueStore.ErrUnsupportedWithHashedKeys) {
+		t.Errorf("Expected ErrUnsupportedWithHashedKeys from DeleteByPrefix, got %d, %v", deleted, err)
+	}
+	if !store.Has("user:1") {
+		t.Error("Expected user:1 to be kept")
+	}
+}
@@ -0,0 +1,187 @@
+package goKeyValueStore
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// ErrBadPattern is returned by Match for malformed patterns.
+var ErrBadPattern = errors.New("syntax error in pattern")
+
+// Match returns all non-expired keys that match a glob pattern in no particular order. In the pattern, * matches
+// any sequence of characters including none, ? matches any single character, [abc] and [a-z] match one character
+// of a class, [^abc] and [!abc] one character not in it, and a backslash matches the next character literally,
+// e.g. \* matches a *. Unlike path.Match, / is not special. Malformed patterns return ErrBadPattern. With
+// WithHashedKeys, it returns ErrUnsupportedWithHashedKeys.
+func (d *KeyValueStore) Match(pattern string) ([]string, error) {
+	d.lazyInit()
+	if d.hashKeys {
+		return nil, ErrUnsupportedWithHashedKeys
+	}
+	glob := []rune(pattern)
+	err := checkGlob(glob)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %s", ErrBadPattern, pattern, err)
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	keys := []string{}
+	for key, node := range d.data {
+		if !d.nodeIsExpired(node) && matchGlob(glob, []rune(key)) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// checkGlob returns an error if a glob pattern is malformed.
+func checkGlob(glob []rune) error {
+	for i := 0; i < len(glob); i++ {
+		switch glob[i] {
+		case '\\':
+			if i+1 == len(glob) {
+				return errors.New("trailing backslash")
+			}
+			i++
+		case '[':
+			end, err := classEnd(glob, i)
+			if err != nil {
+				return err
+			}
+			i = end - 1
+		}
+	}
+	return nil
+}
+
+// classEnd returns the index after the character class that starts at glob[start].
+func classEnd(glob []rune, start int) (int, error) {
+	i := start + 1
+	if i < len(glob) && (glob[i] == '^' || glob[i] == '!') {
+		i++
+	}
+	items := 0
+	for i < len(glob) && glob[i] != ']' {
+		lo, next, ok := classChar(glob, i)
+		if !ok {
+			return 0, errors.New("trailing backslash")
+		}
+		i = next
+		if i+1 < len(glob) && glob[i] == '-' && glob[i+1] != ']' {
+			hi, next, ok := classChar(glob, i+1)
+			if !ok {
+				return 0, errors.New("trailing backslash")
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("invalid range %c-%c", lo, hi)
+			}
+			i = next
+		}
+		items++
+	}
+	if i == len(glob) {
+		return 0, errors.New("unclosed [")
+	}
+	if items == 0 {
+		return 0, errors.New("empty []")
+	}
+	return i + 1, nil
+}
+
+// classChar returns the possibly escaped character of a class at glob[i] and the index after it.
+func classChar(glob []rune, i int) (rune, int, bool) {
+	if glob[i] == '\\' {
+		if i+1 == len(glob) {
+			return 0, 0, false
+		}
+		return glob[i+1], i + 2, true
+	}
+	return glob[i], i + 1, true
+}
+
+// matchClass reports whether r is in the well-formed character class that starts at glob[start] and returns the
+// index after the class.
+func matchClass(glob []rune, start int, r rune) (bool, int) {
+	i := start + 1
+	negate := glob[i] == '^' || glob[i] == '!'
+	if negate {
+		i++
+	}
+	matched := false
+	for glob[i] != ']' {
+		lo, next, _ := classChar(glob, i)
+		hi := lo
+		i = next
+		if glob[i] == '-' && glob[i+1] != ']' {
+			hi, i, _ = classChar(glob, i+1)
+		}
+		if lo <= r && r <= hi {
+			matched = true
+		}
+	}
+	return matched != negate, i + 1
+}
+
+// matchGlob reports whether name matches a well-formed glob pattern. A * is matched by trying the shortest
+// sequence first and extending it when the rest of the pattern does not match.
+func matchGlob(glob []rune, name []rune) bool {
+	g, n := 0, 0
+	starG, starN := -1, -1
+	for n < len(name) {
+		if g < len(glob) {
+			switch glob[g] {
+			case '*':
+				starG, starN = g, n
+				g++
+				continue
+			case '?':
+				g++
+				n++
+				continue
+			case '[':
+				if ok, next := matchClass(glob, g, name[n]); ok {
+					g = next
+					n++
+					continue
+				}
+			case '\\':
+				if glob[g+1] == name[n] {
+					g += 2
+					n++
+					continue
+				}
+			default:
+				if glob[g] == name[n] {
+					g++
+					n++
+					continue
+				}
+			}
+		}
+		if starG < 0 {
+			return false
+		}
+		starN++
+		g, n = starG+1, starN
+	}
+	for g < len(glob) && glob[g] == '*' {
+		g++
+	}
+	return g == len(glob)
+}
+
+// KeysMatching returns all non-expired keys that match re in no particular order. The keys are copied while
+// holding the read lock and matched afterwards, so slow expressions do not block writers. With WithHashedKeys, it
+// returns ErrUnsupportedWithHashedKeys.
+func (d *KeyValueStore) KeysMatching(re *regexp.Regexp) ([]string, error) {
+	d.lazyInit()
+	if d.hashKeys {
+		return nil, ErrUnsupportedWithHashedKeys
+	}
+	keys := d.Keys()
+	return slices.DeleteFunc(keys, func(key string) bool {
+		return !re.MatchString(key)
+	}), nil
+}
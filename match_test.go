@@ -0,0 +1,125 @@
+package goKeyValueStore_test
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestMatch(t *testing.T) {
+	clock := newFakeClock()
+	store := getTestStoreWithClock(t, "", clock)
+	for _, key := range []string{"user:1:profile", "user:2:profile", "user:2:settings", "user:10:profile", "a/b/c", "star*", "q?", "[x]"} {
+		store.Set(key, "value", 0)
+	}
+	store.Set("user:3:profile", "value", 1000)
+	clock.Advance(1001 * time.Millisecond)
+	tests := map[string][]string{
+		"user:*:profile":   {"user:10:profile", "user:1:profile", "user:2:profile"},
+		"user:?:*":         {"user:1:profile", "user:2:profile", "user:2:settings"},
+		"user:[12]:s*":     {"user:2:settings"},
+		"user:[^1]:*":      {"user:2:profile", "user:2:settings"},
+		"user:[0-9][0-9]*": {"user:10:profile"},
+		"a*c":              {"a/b/c"},
+		"star\\*":          {"star*"},
+		"q\\?":             {"q?"},
+		"\\[x\\]":          {"[x]"},
+		"nothing*":         {},
+		"*":                {"[x]", "a/b/c", "q?", "star*", "user:10:profile", "user:1:profile", "user:2:profile", "user:2:settings"},
+	}
+	for pattern, expected := range tests {
+		keys, err := store.Match(pattern)
+		slices.Sort(keys)
+		if err != nil || !slices.Equal(keys, expected) {
+			t.Errorf("Expected %s to match %v, got %v, %v", pattern, expected, keys, err)
+		}
+	}
+}
+
+func TestMatchBadPattern(t *testing.T) {
+	store := getTestStoreWithClock(t, "", newFakeClock())
+	for _, pattern := range []string{"[abc", "user\\", "[]", "[z-a]", "[a\\"} {
+		if _, err := store.Match(pattern); !errors.Is(err, goKeyValueStore.ErrBadPattern) {
+			t.Errorf("Expected ErrBadPattern for %q, got %v", pattern, err)
+		}
+	}
+}
+
+func TestMatchWithHashedKeys(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, "", goKeyValueStore.WithHashedKeys([]byte("salt")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("user:1", "alice", 0)
+	if _, err := store.Match("user:*"); !errors.Is(err, goKeyValueStore.ErrUnsupportedWithHashedKeys) {
+		t.Errorf("Expected ErrUnsupportedWithHashedKeys, got %v", err)
+	}
+}
+
+func TestKeysMatchingWithHashedKeys(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, "", goKeyValueStore.WithHashedKeys([]byte("salt")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("order:latest", "value", 0)
+	if _, err := store.KeysMatching(regexp.MustCompile("^order:")); !errors.Is(err, goKeyValueStore.ErrUnsupportedWithHashedKeys) {
+		t.Errorf("Expected ErrUnsupportedWithHashedKeys, got %v", err)
+	}
+}
+
+func TestKeysMatching(t *testing.T) {
+	clock := newFakeClock()
+	store := getTestStoreWithClock(t, "", clock)
+	store.Set("order:2024-01-05", "value", 0)
+	store.Set("order:2024-02-11", "value", 0)
+	store.Set("order:latest", "value", 0)
+	store.Set("order:2023-12-31", "value", 1000)
+	clock.Advance(1001 * time.Millisecond)
+	keys, err := store.KeysMatching(regexp.MustCompile(`^order:\d{4}-\d{2}-\d{2}$`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"order:2024-01-05", "order:2024-02-11"}) {
+		t.Errorf("Expected the live dated orders, got %v", keys)
+	}
+}
+
+// BenchmarkSetWhileKeysMatching measures Set while another goroutine matches 10000 keys with a slow expression
+// in a loop. Since the keys are matched without holding the lock, Set is about as fast as without matching.
+func BenchmarkSetWhileKeysMatching(b *testing.B) {
+	store, _ := goKeyValueStore.NewKeyValueStore(1, "", goKeyValueStore.WithCleanerStopped(true))
+	for i := 0; i < 10000; i++ {
+		store.Set(fmt.Sprintf("key%d", i), i, 0)
+	}
+	slow := regexp.MustCompile(`^(a|b|c|d|e|f|g|h|k|e|y)*[0-9]+(x|y|z)*$`)
+	for _, matching := range []bool{false, true} {
+		b.Run(fmt.Sprintf("matching=%v", matching), func(b *testing.B) {
+			stop := make(chan struct{})
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for matching {
+					select {
+					case <-stop:
+						return
+					default:
+						store.KeysMatching(slow)
+					}
+				}
+			}()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				store.Set("key", i, 0)
+			}
+			b.StopTimer()
+			close(stop)
+			<-done
+		})
+	}
+}
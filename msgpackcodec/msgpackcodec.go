@@ -0,0 +1,731 @@
+// Package msgpackcodec provides a goKeyValueStore.Codec that saves entries as MessagePack, which is smaller and
+// faster to encode than JSON for large nested values:
+//
+//	store, err := goKeyValueStore.NewKeyValueStore(1, dir, goKeyValueStore.WithCodec(msgpackcodec.Codec{}),
+//		goKeyValueStore.WithLegacyCodecs(goKeyValueStore.JSONCodec{}))
+//
+// Values are encoded like JSON: structs as maps of their exported fields named by their json tags, and maps
+// and slices element by element. Unlike JSON, integers keep their exact value and are decoded as int64
+// (uint64 if they do not fit), byte slices are saved as binary data, and time.Time values keep their type.
+package msgpackcodec
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidData is returned by Unmarshal for data that is not valid MessagePack or uses unsupported extensions.
+var ErrInvalidData = errors.New("invalid msgpack data")
+
+// ErrUnsupportedType is returned by Marshal for values that can not be encoded, e.g. channels and functions.
+var ErrUnsupportedType = errors.New("unsupported type")
+
+// timestampExtension is the MessagePack extension type of timestamps.
+const timestampExtension = -1
+
+var (
+	timeType   = reflect.TypeOf(time.Time{})
+	numberType = reflect.TypeOf(json.Number(""))
+)
+
+// Codec encodes entries as MessagePack. Its files have the extension "msgpack".
+type Codec struct{}
+
+// Extension returns "msgpack".
+func (Codec) Extension() string {
+	return "msgpack"
+}
+
+// Marshal encodes v as MessagePack.
+func (Codec) Marshal(v any) ([]byte, error) {
+	e := &encoder{buf: make([]byte, 0, 256)}
+	err := e.encode(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// Unmarshal decodes the MessagePack data into v, which must be a non-nil pointer.
+func (Codec) Unmarshal(data []byte, v any) error {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Pointer || target.IsNil() {
+		return fmt.Errorf("%w: %T is not a non-nil pointer", ErrUnsupportedType, v)
+	}
+	d := &decoder{data: data}
+	value, err := d.value()
+	if err != nil {
+		return err
+	}
+	if d.pos != len(d.data) {
+		return fmt.Errorf("%w: %d bytes after value", ErrInvalidData, len(d.data)-d.pos)
+	}
+	return assign(target.Elem(), value)
+}
+
+// A field is an exported field of a struct with the name of its json tag.
+type field struct {
+	index     int
+	name      string
+	omitEmpty bool
+}
+
+// fieldCache holds the fields of struct types.
+var fieldCache sync.Map
+
+// fieldsOf returns the encoded fields of a struct type.
+func fieldsOf(t reflect.Type) []field {
+	if fields, ok := fieldCache.Load(t); ok {
+		return fields.([]field)
+	}
+	fields := []field{}
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+		tag := structField.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = structField.Name
+		}
+		fields = append(fields, field{index: i, name: name, omitEmpty: slices.Contains(strings.Split(options, ","), "omitempty")})
+	}
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+// isEmpty reports whether a value is omitted by the omitempty option of JSON.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return v.IsZero()
+	}
+	return false
+}
+
+// An encoder appends encoded values to its buffer.
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.buf = append(e.buf, 0xc0)
+		return nil
+	}
+	switch v.Type() {
+	case timeType:
+		e.encodeTime(v.Interface().(time.Time))
+		return nil
+	case numberType:
+		e.encodeNumber(json.Number(v.String()))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0xc0)
+			return nil
+		}
+		return e.encode(v.Elem())
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, 0xc3)
+		} else {
+			e.buf = append(e.buf, 0xc2)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.encodeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.encodeUint(v.Uint())
+	case reflect.Float32:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xca), math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xcb), math.Float64bits(v.Float()))
+	case reflect.String:
+		e.encodeString(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0xc0)
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.encodeBytes(v.Bytes())
+			return nil
+		}
+		return e.encodeArray(v)
+	case reflect.Array:
+		return e.encodeArray(v)
+	case reflect.Map:
+		return e.encodeMap(v)
+	case reflect.Struct:
+		return e.encodeStruct(v)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedType, v.Type())
+	}
+	return nil
+}
+
+func (e *encoder) encodeInt(n int64) {
+	switch {
+	case n >= 0:
+		e.encodeUint(uint64(n))
+	case n >= -32:
+		e.buf = append(e.buf, byte(n))
+	case n >= math.MinInt8:
+		e.buf = append(e.buf, 0xd0, byte(n))
+	case n >= math.MinInt16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xd1), uint16(n))
+	case n >= math.MinInt32:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xd2), uint32(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xd3), uint64(n))
+	}
+}
+
+func (e *encoder) encodeUint(n uint64) {
+	switch {
+	case n <= math.MaxInt8:
+		e.buf = append(e.buf, byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xcc, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xcd), uint16(n))
+	case n <= math.MaxUint32:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xce), uint32(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xcf), n)
+	}
+}
+
+// encodeNumber encodes a json.Number as an integer or a float if it is one and as a string otherwise.
+func (e *encoder) encodeNumber(n json.Number) {
+	if i, err := n.Int64(); err == nil {
+		e.encodeInt(i)
+	} else if f, err := n.Float64(); err == nil {
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xcb), math.Float64bits(f))
+	} else {
+		e.encodeString(string(n))
+	}
+}
+
+func (e *encoder) encodeString(s string) {
+	n := len(s)
+	switch {
+	case n < 32:
+		e.buf = append(e.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xda), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xdb), uint32(n))
+	}
+	e.buf = append(e.buf, s...)
+}
+
+func (e *encoder) encodeBytes(b []byte) {
+	n := len(b)
+	switch {
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xc5), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xc6), uint32(n))
+	}
+	e.buf = append(e.buf, b...)
+}
+
+// encodeTime encodes a time as a 96-bit timestamp extension.
+func (e *encoder) encodeTime(t time.Time) {
+	e.buf = append(e.buf, 0xc7, 12, byte(timestampExtension&0xff))
+	e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(t.Nanosecond()))
+	e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(t.Unix()))
+}
+
+func (e *encoder) encodeArray(v reflect.Value) error {
+	e.header(v.Len(), 0x90, 0xdc, 0xdd)
+	for i := 0; i < v.Len(); i++ {
+		err := e.encode(v.Index(i))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeMap encodes a map. String keys are sorted, so equal maps have equal encodings.
+func (e *encoder) encodeMap(v reflect.Value) error {
+	if v.IsNil() {
+		e.buf = append(e.buf, 0xc0)
+		return nil
+	}
+	keys := v.MapKeys()
+	if v.Type().Key().Kind() == reflect.String {
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return strings.Compare(a.String(), b.String())
+		})
+	}
+	e.header(len(keys), 0x80, 0xde, 0xdf)
+	for _, key := range keys {
+		err := e.encode(key)
+		if err != nil {
+			return err
+		}
+		err = e.encode(v.MapIndex(key))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeStruct encodes a struct as a map of its exported fields.
+func (e *encoder) encodeStruct(v reflect.Value) error {
+	fields := fieldsOf(v.Type())
+	count := 0
+	for _, f := range fields {
+		if !f.omitEmpty || !isEmpty(v.Field(f.index)) {
+			count++
+		}
+	}
+	e.header(count, 0x80, 0xde, 0xdf)
+	for _, f := range fields {
+		value := v.Field(f.index)
+		if f.omitEmpty && isEmpty(value) {
+			continue
+		}
+		e.encodeString(f.name)
+		err := e.encode(value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// header writes the header of an array or a map with n elements.
+func (e *encoder) header(n int, fix byte, code16 byte, code32 byte) {
+	switch {
+	case n < 16:
+		e.buf = append(e.buf, fix|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, code16), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, code32), uint32(n))
+	}
+}
+
+// A decoder decodes values into nil, bool, int64, uint64, float64, string, []byte, time.Time, []any, and
+// map[string]any.
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+// next returns the next n bytes.
+func (d *decoder) next(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, fmt.Errorf("%w: unexpected end of data", ErrInvalidData)
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// length reads a big-endian length of size bytes.
+func (d *decoder) length(size int) (int, error) {
+	b, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return int(b[0]), nil
+	case 2:
+		return int(binary.BigEndian.Uint16(b)), nil
+	default:
+		return int(binary.BigEndian.Uint32(b)), nil
+	}
+}
+
+func (d *decoder) value() (any, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	code := b[0]
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code&0xf0 == 0x80:
+		return d.decodeMap(int(code & 0x0f))
+	case code&0xf0 == 0x90:
+		return d.decodeArray(int(code & 0x0f))
+	case code&0xe0 == 0xa0:
+		return d.decodeString(int(code & 0x1f))
+	}
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.length(1 << (code - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+		return slices.Clone(b), nil
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.length(1 << (code - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeExtension(n)
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.decodeExtension(1 << (code - 0xd4))
+	case 0xca:
+		b, err := d.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 0xcb:
+		b, err := d.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := d.uint(1 << (code - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if n <= math.MaxInt64 {
+			return int64(n), nil
+		}
+		return n, nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (code - 0xd0)
+		n, err := d.uint(size)
+		if err != nil {
+			return nil, err
+		}
+		// sign-extend the value of size bytes
+		shift := 64 - 8*size
+		return int64(n<<shift) >> shift, nil
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.length(1 << (code - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(n)
+	case 0xdc, 0xdd:
+		n, err := d.length(2 << (code - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(n)
+	case 0xde, 0xdf:
+		n, err := d.length(2 << (code - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(n)
+	}
+	return nil, fmt.Errorf("%w: unknown code 0x%02x", ErrInvalidData, code)
+}
+
+// uint reads a big-endian unsigned integer of size bytes.
+func (d *decoder) uint(size int) (uint64, error) {
+	b, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+	n := uint64(0)
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n, nil
+}
+
+func (d *decoder) decodeString(n int) (any, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (d *decoder) decodeArray(n int) (any, error) {
+	// every element takes at least one byte, so larger counts are invalid
+	if n > len(d.data)-d.pos {
+		return nil, fmt.Errorf("%w: unexpected end of data", ErrInvalidData)
+	}
+	values := make([]any, n)
+	for i := range values {
+		value, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func (d *decoder) decodeMap(n int) (any, error) {
+	if n > len(d.data)-d.pos {
+		return nil, fmt.Errorf("%w: unexpected end of data", ErrInvalidData)
+	}
+	values := make(map[string]any, n)
+	for i := 0; i < n; i++ {
+		key, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: map key of type %T", ErrInvalidData, key)
+		}
+		value, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// decodeExtension decodes an extension with n bytes of data. Only timestamps are supported.
+func (d *decoder) decodeExtension(n int) (any, error) {
+	b, err := d.next(1 + n)
+	if err != nil {
+		return nil, err
+	}
+	if int8(b[0]) != timestampExtension {
+		return nil, fmt.Errorf("%w: unknown extension %d", ErrInvalidData, int8(b[0]))
+	}
+	b = b[1:]
+	switch n {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(b)), 0).UTC(), nil
+	case 8:
+		n := binary.BigEndian.Uint64(b)
+		return time.Unix(int64(n&(1<<34-1)), int64(n>>34)).UTC(), nil
+	case 12:
+		return time.Unix(int64(binary.BigEndian.Uint64(b[4:])), int64(binary.BigEndian.Uint32(b))).UTC(), nil
+	}
+	return nil, fmt.Errorf("%w: timestamp of %d bytes", ErrInvalidData, n)
+}
+
+// assign sets dst to a decoded value.
+func assign(dst reflect.Value, src any) error {
+	if src == nil {
+		dst.SetZero()
+		return nil
+	}
+	mismatch := func() error {
+		return fmt.Errorf("%w: can not decode %T into %s", ErrUnsupportedType, src, dst.Type())
+	}
+	if dst.Type() == timeType {
+		t, ok := src.(time.Time)
+		if !ok {
+			return mismatch()
+		}
+		dst.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if dst.Type() == numberType {
+		switch n := src.(type) {
+		case int64:
+			dst.SetString(strconv.FormatInt(n, 10))
+		case uint64:
+			dst.SetString(strconv.FormatUint(n, 10))
+		case float64:
+			dst.SetString(strconv.FormatFloat(n, 'g', -1, 64))
+		case string:
+			dst.SetString(n)
+		default:
+			return mismatch()
+		}
+		return nil
+	}
+	switch dst.Kind() {
+	case reflect.Interface:
+		value := reflect.ValueOf(src)
+		if !value.Type().AssignableTo(dst.Type()) {
+			return mismatch()
+		}
+		dst.Set(value)
+	case reflect.Pointer:
+		ptr := reflect.New(dst.Type().Elem())
+		err := assign(ptr.Elem(), src)
+		if err != nil {
+			return err
+		}
+		dst.Set(ptr)
+	case reflect.Bool:
+		b, ok := src.(bool)
+		if !ok {
+			return mismatch()
+		}
+		dst.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		switch src := src.(type) {
+		case int64:
+			n = src
+		case uint64:
+			return mismatch()
+		case float64:
+			if src != math.Trunc(src) {
+				return mismatch()
+			}
+			n = int64(src)
+		default:
+			return mismatch()
+		}
+		if dst.OverflowInt(n) {
+			return mismatch()
+		}
+		dst.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var n uint64
+		switch src := src.(type) {
+		case int64:
+			if src < 0 {
+				return mismatch()
+			}
+			n = uint64(src)
+		case uint64:
+			n = src
+		case float64:
+			if src < 0 || src != math.Trunc(src) {
+				return mismatch()
+			}
+			n = uint64(src)
+		default:
+			return mismatch()
+		}
+		if dst.OverflowUint(n) {
+			return mismatch()
+		}
+		dst.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		switch src := src.(type) {
+		case int64:
+			dst.SetFloat(float64(src))
+		case uint64:
+			dst.SetFloat(float64(src))
+		case float64:
+			dst.SetFloat(src)
+		default:
+			return mismatch()
+		}
+	case reflect.String:
+		switch src := src.(type) {
+		case string:
+			dst.SetString(src)
+		case []byte:
+			dst.SetString(string(src))
+		default:
+			return mismatch()
+		}
+	case reflect.Slice:
+		if dst.Type().Elem().Kind() == reflect.Uint8 {
+			switch src := src.(type) {
+			case []byte:
+				dst.Set(reflect.ValueOf(src).Convert(dst.Type()))
+				return nil
+			case string:
+				dst.Set(reflect.ValueOf([]byte(src)).Convert(dst.Type()))
+				return nil
+			}
+		}
+		values, ok := src.([]any)
+		if !ok {
+			return mismatch()
+		}
+		slice := reflect.MakeSlice(dst.Type(), len(values), len(values))
+		for i, value := range values {
+			err := assign(slice.Index(i), value)
+			if err != nil {
+				return err
+			}
+		}
+		dst.Set(slice)
+	case reflect.Array:
+		values, ok := src.([]any)
+		if !ok || len(values) > dst.Len() {
+			return mismatch()
+		}
+		dst.SetZero()
+		for i, value := range values {
+			err := assign(dst.Index(i), value)
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		values, ok := src.(map[string]any)
+		if !ok || dst.Type().Key().Kind() != reflect.String {
+			return mismatch()
+		}
+		m := reflect.MakeMapWithSize(dst.Type(), len(values))
+		for key, value := range values {
+			element := reflect.New(dst.Type().Elem()).Elem()
+			err := assign(element, value)
+			if err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(key).Convert(dst.Type().Key()), element)
+		}
+		dst.Set(m)
+	case reflect.Struct:
+		values, ok := src.(map[string]any)
+		if !ok {
+			return mismatch()
+		}
+		for _, f := range fieldsOf(dst.Type()) {
+			value, ok := values[f.name]
+			if !ok {
+				continue
+			}
+			err := assign(dst.Field(f.index), value)
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return mismatch()
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package msgpackcodec_test
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+	"github.com/richi0/goKeyValueStore/msgpackcodec"
+)
+
+// A document is a representative nested value.
+type document struct {
+	ID       int64              `json:"id"`
+	Title    string             `json:"title"`
+	Created  time.Time          `json:"created"`
+	Tags     []string           `json:"tags"`
+	Scores   map[string]float64 `json:"scores"`
+	Sections []section          `json:"sections"`
+	Draft    bool               `json:"draft,omitempty"`
+}
+
+type section struct {
+	Heading string `json:"heading"`
+	Body    string `json:"body"`
+	Words   int    `json:"words"`
+}
+
+func newDocument(id int64) document {
+	doc := document{
+		ID:      id,
+		Title:   fmt.Sprintf("Document %d", id),
+		Created: time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC),
+		Tags:    []string{"cache", "store", "msgpack"},
+		Scores:  map[string]float64{"relevance": 0.75, "quality": 0.5},
+	}
+	for i := 0; i < 10; i++ {
+		doc.Sections = append(doc.Sections, section{Heading: fmt.Sprintf("Section %d", i), Body: "Lorem ipsum dolor sit amet, consectetur adipiscing elit.", Words: 8})
+	}
+	return doc
+}
+
+func TestRoundTrip(t *testing.T) {
+	codec := msgpackcodec.Codec{}
+	values := []any{
+		nil, true, false, int64(0), int64(-1), int64(-33), int64(200), int64(-200), int64(70000), int64(-70000),
+		int64(math.MaxInt64), int64(math.MinInt64), uint64(math.MaxUint64), 1.5, "",
+		"a string that is longer than thirty-one bytes", []byte{1, 2, 3},
+		time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC),
+		[]any{int64(1), "two", []any{}}, map[string]any{"a": map[string]any{"b": nil}},
+	}
+	for _, value := range values {
+		data, err := codec.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got any
+		err = codec.Unmarshal(data, &got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, value) {
+			t.Errorf("Expected %#v, got %#v", value, got)
+		}
+	}
+	want := newDocument(1)
+	data, err := codec.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got document
+	err = codec.Unmarshal(data, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+func TestInvalidData(t *testing.T) {
+	codec := msgpackcodec.Codec{}
+	var value any
+	for _, data := range [][]byte{{}, {0xc1}, {0x92, 0x01}, {0xdd, 0xff, 0xff, 0xff, 0xff}, {0x01, 0x02}} {
+		err := codec.Unmarshal(data, &value)
+		if !errors.Is(err, msgpackcodec.ErrInvalidData) {
+			t.Errorf("Expected ErrInvalidData for %x, got %v", data, err)
+		}
+	}
+	_, err := codec.Marshal(make(chan int))
+	if !errors.Is(err, msgpackcodec.ErrUnsupportedType) {
+		t.Errorf("Expected ErrUnsupportedType, got %v", err)
+	}
+}
+
+func TestStoreRestart(t *testing.T) {
+	dir := t.TempDir()
+	opts := []goKeyValueStore.Option{goKeyValueStore.WithCodec(msgpackcodec.Codec{}), goKeyValueStore.WithType("document", document{})}
+	store, err := goKeyValueStore.NewKeyValueStore(60, dir, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := newDocument(math.MaxInt64)
+	store.Set("document", want, 0)
+	store.Set("number", int64(math.MaxInt64), 0)
+	store.Close()
+	files, _ := filepath.Glob(filepath.Join(dir, "*.store.msgpack"))
+	if len(files) != 2 {
+		t.Errorf("Expected 2 msgpack files, got %d", len(files))
+	}
+
+	store, err = goKeyValueStore.NewKeyValueStore(60, dir, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if value, _ := store.Get("document"); !reflect.DeepEqual(value, want) {
+		t.Errorf("Expected %+v, got %+v", want, value)
+	}
+	if value, _ := store.Get("number"); value != int64(math.MaxInt64) {
+		t.Errorf("Expected int64 %d, got %#v", int64(math.MaxInt64), value)
+	}
+}
+
+func TestMigrateFromJSON(t *testing.T) {
+	dir := t.TempDir()
+	store, err := goKeyValueStore.NewKeyValueStore(60, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("key1", "value1", 0)
+	store.Set("key2", map[string]any{"a": 1.5}, 0)
+	store.Close()
+
+	_, err = goKeyValueStore.NewKeyValueStore(60, dir, goKeyValueStore.WithCodec(msgpackcodec.Codec{}))
+	if !errors.Is(err, goKeyValueStore.ErrCodecMismatch) {
+		t.Fatalf("Expected ErrCodecMismatch without legacy codecs, got %v", err)
+	}
+	store, err = goKeyValueStore.NewKeyValueStore(60, dir, goKeyValueStore.WithCodec(msgpackcodec.Codec{}),
+		goKeyValueStore.WithLegacyCodecs(goKeyValueStore.JSONCodec{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if value, _ := store.Get("key1"); value != "value1" {
+		t.Errorf("Expected value1, got %#v", value)
+	}
+	if value, _ := store.Get("key2"); !reflect.DeepEqual(value, map[string]any{"a": 1.5}) {
+		t.Errorf("Expected the map of key2, got %#v", value)
+	}
+	legacy, _ := filepath.Glob(filepath.Join(dir, "*.store.json"))
+	migrated, _ := filepath.Glob(filepath.Join(dir, "*.store.msgpack"))
+	if len(legacy) != 0 || len(migrated) != 2 {
+		t.Errorf("Expected 2 migrated files and no JSON files, got %d and %d", len(migrated), len(legacy))
+	}
+}
+
+// BenchmarkSet compares writing a document with JSON and MessagePack and reports the size of the files.
+func BenchmarkSet(b *testing.B) {
+	codecs := []goKeyValueStore.Codec{goKeyValueStore.JSONCodec{}, msgpackcodec.Codec{}}
+	for _, codec := range codecs {
+		b.Run(codec.Extension(), func(b *testing.B) {
+			dir := b.TempDir()
+			store, err := goKeyValueStore.NewKeyValueStore(60, dir, goKeyValueStore.WithCodec(codec))
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer store.Close()
+			doc := newDocument(1)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				err := store.Set(fmt.Sprintf("key%d", i%1000), doc, 0)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+			files, _ := filepath.Glob(filepath.Join(dir, "*.store."+codec.Extension()))
+			size := int64(0)
+			for _, file := range files {
+				info, err := os.Stat(file)
+				if err != nil {
+					b.Fatal(err)
+				}
+				size += info.Size()
+			}
+			b.ReportMetric(float64(size)/float64(len(files)), "disk-B/entry")
+		})
+	}
+}
@@ -0,0 +1,421 @@
+package goKeyValueStore
+
+import (
+	"context"
+	"encoding/gob"
+	"io"
+	"math"
+	"reflect"
+	"time"
+)
+
+// An Option configures a KeyValueStore.
+type Option func(*KeyValueStore)
+
+// WithCleanInterval sets the interval in which expired key-value pairs are deleted.
+// It overrides the cleanTimeout passed to NewKeyValueStore.
+func WithCleanInterval(interval time.Duration) Option {
+	return func(d *KeyValueStore) {
+		d.cleanInterval.Store(int64(interval))
+	}
+}
+
+// WithMaxValueSize limits the size of values to maxBytes bytes of their JSON encoding.
+// A maxBytes of 0 means values are not limited.
+func WithMaxValueSize(maxBytes int) Option {
+	return func(d *KeyValueStore) {
+		d.maxValueSize.Store(int64(maxBytes))
+	}
+}
+
+// WithMaxEntries limits the number of entries in the store. When a new entry exceeds the limit,
+// entries are evicted according to the eviction policy, see WithEvictionPolicy.
+// A maxEntries of 0 means the number of entries is not limited.
+func WithMaxEntries(maxEntries int) Option {
+	return func(d *KeyValueStore) {
+		d.maxEntries.Store(int64(maxEntries))
+	}
+}
+
+// WithEvictionPolicy sets which entries are evicted when the store exceeds WithMaxEntries or WithMaxBytes.
+// The default is EvictNearestExpiry.
+func WithEvictionPolicy(policy EvictionPolicy) Option {
+	return func(d *KeyValueStore) {
+		d.evictionPolicy = policy
+	}
+}
+
+// WithPrefixQuota limits the number of entries whose keys start with prefix. It can be used several times.
+// A key that would exceed the quota of one of its prefixes is handled according to WithQuotaPolicy.
+// Expired entries count until the cleaner deleted them. Use StatsForPrefix to monitor the quota.
+func WithPrefixQuota(prefix string, maxEntries int) Option {
+	return func(d *KeyValueStore) {
+		d.quotas = append(d.quotas, &prefixQuota{prefix: prefix, stats: PrefixStats{MaxEntries: maxEntries}})
+	}
+}
+
+// WithQuotaPolicy sets what happens when a new key would exceed a prefix quota. The default is QuotaReject.
+func WithQuotaPolicy(policy QuotaPolicy) Option {
+	return func(d *KeyValueStore) {
+		d.quotaPolicy = policy
+	}
+}
+
+// WithMaxBytes sets the maximum size of all entries in bytes. The size of an entry is the length of its JSON
+// encoding. If the limit is exceeded, entries are evicted like with WithMaxEntries. A value of 0 means no limit.
+// Sizes are only tracked while a limit is set; setting a limit with Reconfigure computes the sizes of all entries.
+func WithMaxBytes(maxBytes int64) Option {
+	return func(d *KeyValueStore) {
+		d.maxBytes.Store(maxBytes)
+	}
+}
+
+// WithRejectNilValues makes Set and SetManyDetailed return ErrNilValue for nil values instead of storing them.
+func WithRejectNilValues(enabled bool) Option {
+	return func(d *KeyValueStore) {
+		d.rejectNil = enabled
+	}
+}
+
+// WithChangesFeed maintains a file at path with a JSON line for every set, deletion, expiration, and eviction,
+// e.g. to purge the matching paths of a CDN. The lines are written in the background and lines older than
+// retention are pruned regularly. Use ReadChangesFeed to read the file.
+func WithChangesFeed(path string, retention time.Duration) Option {
+	return func(d *KeyValueStore) {
+		d.changesFeed = newChangesFeed(path, retention, func() time.Time { return d.now() })
+	}
+}
+
+// WithMaxConcurrentDiskOps limits the number of file reads, writes, and deletions in the cache folder that run
+// at the same time. Other operations wait until one of them finished. A value of 0 means no limit.
+func WithMaxConcurrentDiskOps(n int) Option {
+	return func(d *KeyValueStore) {
+		d.diskSem = nil
+		if n > 0 {
+			d.diskSem = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithAudit writes an AuditRecord as a JSON line to w for every set, get, and deletion of a key that starts with
+// one of the prefixes, e.g. for compliance. The actor of an operation is taken from the context passed to SetCtx,
+// GetCtx, or DeleteCtx. Records are written in the background. If w falls behind, new records are dropped and
+// counted in Stats.AuditDropped.
+func WithAudit(prefixes []string, w io.Writer) Option {
+	return func(d *KeyValueStore) {
+		d.auditLog = newAuditLog(prefixes, w, auditBufferSize)
+	}
+}
+
+// WithPackedSmallValues appends key-value pairs whose encoding is smaller than threshold bytes to shared segment
+// files of about segmentSize bytes instead of writing a file per key, which saves disk space for many small values.
+// Larger values keep their own files. Deleted and overwritten pairs leave garbage in the segments, which the
+// cleaner removes by rewriting the segments when more than half of them is garbage.
+// It can not be combined with WithIndex, and FollowChanges only follows the files of large values.
+func WithPackedSmallValues(threshold, segmentSize int) Option {
+	return func(d *KeyValueStore) {
+		d.packing = newPacking(threshold, int64(segmentSize), packedCompactMinBytes)
+	}
+}
+
+// WithAppendLog persists all key-value pairs by appending records to a log of segment files instead of writing
+// a file per key, which makes writes faster. Set appends the encoded entry and Delete a tombstone with a single
+// write each, and a start replays the log, ignoring a record that was torn by a crash. Once the log is larger
+// than compactSize bytes and more than half of it is superseded, deleted, or expired, the cleaner rewrites it
+// with the current entries only. It is WithPackedSmallValues for values of any size with segments of
+// compactSize bytes and has the same restrictions.
+func WithAppendLog(compactSize int) Option {
+	return func(d *KeyValueStore) {
+		d.packing = newPacking(math.MaxInt, int64(compactSize), int64(compactSize))
+	}
+}
+
+// WithBackend saves all key-value pairs in backend instead of a cache folder. The records of the backend are
+// loaded when the store is created. It can only be used with a cacheFolder of ""; NewKeyValueStore returns
+// ErrBackendWithCacheFolder otherwise. Options of the cache folder like WithIndex or WithCodec have no effect.
+func WithBackend(backend Backend) Option {
+	return func(d *KeyValueStore) {
+		d.backend = backend
+	}
+}
+
+// WithFilesystem sets the filesystem the cache folder is accessed through. The default is OSFS.
+func WithFilesystem(fsys FS) Option {
+	return func(d *KeyValueStore) {
+		d.fs = fsys
+	}
+}
+
+// WithIndex enables an index file in the cache folder that records the key, file, and deadline of every entry.
+// With a valid index, startup reads only the index and values are loaded from their files on first access.
+// The index is advisory: if it does not match the cache folder, all files are read and the index is rebuilt.
+func WithIndex(enabled bool) Option {
+	return func(d *KeyValueStore) {
+		d.useIndex = enabled
+	}
+}
+
+// WithWarmup loads the values of WithIndex in the background after the store is created. The store is warming
+// until all values are loaded. Without it, a store with an index is ready right after reading the index.
+func WithWarmup(enabled bool) Option {
+	return func(d *KeyValueStore) {
+		d.warmup = enabled
+	}
+}
+
+// WithReadOptimized lets Get read a copy of the store without taking a lock, so reads scale with the number
+// of cores. Writes only mark the copy as outdated, and a goroutine copies the whole store at most every 10ms.
+// So Get may miss the changes of the last 10ms plus the time of a copy, while all other reads and all writes
+// see the current state. Reads with Get do not count as a use for EvictLRU and EvictApproxLRU.
+// Close stops the goroutine.
+func WithReadOptimized(enabled bool) Option {
+	return func(d *KeyValueStore) {
+		d.readOptimized = enabled
+	}
+}
+
+// WithSeedConflictPolicy sets which entry NewFromExport keeps if a key is in the export and in the cache folder.
+// The default is ExportWins.
+func WithSeedConflictPolicy(policy SeedConflictPolicy) Option {
+	return func(d *KeyValueStore) {
+		d.seedConflicts = policy
+	}
+}
+
+// WithHashedKeys stores every key as its HMAC-SHA256 with salt, so keys never appear in memory, in the cache
+// folder, or in errors. Methods taking a key accept the original key, while methods listing keys, like Keys and
+// Range, return the hashed keys. The salt must not be empty, and options that match key prefixes, like
+// WithPrefixQuota, can not be used; NewKeyValueStore returns ErrEmptyKeySalt or ErrUnsupportedWithHashedKeys
+// otherwise. Scan and the methods that match key prefixes or patterns, like KeysWithPrefix and Match, return
+// ErrUnsupportedWithHashedKeys. The same salt must be used for a cache folder on every start.
+func WithHashedKeys(salt []byte) Option {
+	return func(d *KeyValueStore) {
+		d.hashKeys = true
+		d.keySalt = salt
+	}
+}
+
+// WithInstanceID sets the ID of the logical store. Every entry records the instance ID and the run ID of the store
+// that wrote it, see GetMetadata. The instance ID is recorded in a meta file of the cache folder, and a store with
+// another instance ID returns ErrFolderOwned for the folder unless WithAdoptFolder is used. Without this option,
+// the instance ID is derived from the path of the cache folder and the folder is not claimed.
+func WithInstanceID(id string) Option {
+	return func(d *KeyValueStore) {
+		d.instanceID = id
+	}
+}
+
+// WithAdoptFolder lets a store with WithInstanceID take over a cache folder of another instance.
+func WithAdoptFolder(adopt bool) Option {
+	return func(d *KeyValueStore) {
+		d.adoptFolder = adopt
+	}
+}
+
+// WithValidator sets a function that checks whether a value is still valid, e.g. with a cheap request to its
+// origin. Get returns entries that were updated longer than WithRevalidateAfter ago immediately and validates
+// them in the background. Invalid entries are deleted and the update time of valid ones is set to now. Errors of
+// the validator are reported to the OnError function and the key is validated again after a growing backoff.
+// The context of the validator is canceled by Close.
+func WithValidator(validate func(ctx context.Context, key string, value any) (stillValid bool, err error)) Option {
+	return func(d *KeyValueStore) {
+		if d.revalidator == nil {
+			d.revalidator = newRevalidator()
+		}
+		d.revalidator.validate = validate
+	}
+}
+
+// WithRevalidateAfter sets the age of entries that Get validates with the function of WithValidator.
+func WithRevalidateAfter(after time.Duration) Option {
+	return func(d *KeyValueStore) {
+		if d.revalidator == nil {
+			d.revalidator = newRevalidator()
+		}
+		d.revalidator.after = after
+	}
+}
+
+// WithType registers the type of sample under name, so values of the type keep it across restarts. Values of
+// registered types are saved with the name, and values read from the cache folder are decoded into the type
+// instead of the maps and float64 values of JSON. Register a pointer type separately if values are stored as
+// pointers. Names are saved in the cache folder, so they must stay the same when the type is renamed.
+// The type is registered with gob.Register as well, so values of the type can be saved with GobCodec.
+func WithType(name string, sample any) Option {
+	return func(d *KeyValueStore) {
+		if d.types == nil {
+			d.types = map[string]reflect.Type{}
+			d.typeNames = map[reflect.Type]string{}
+		}
+		gob.Register(sample)
+		t := reflect.TypeOf(sample)
+		d.types[name] = t
+		d.typeNames[t] = name
+	}
+}
+
+// WithUnknownTypeHandler sets a function that is called for values read from the cache folder whose type name is
+// not registered with WithType, with ErrUnregisteredType, or that can not be decoded into their registered type.
+// Such values are loaded as they were before types were registered.
+func WithUnknownTypeHandler(fn func(key string, typeName string, err error)) Option {
+	return func(d *KeyValueStore) {
+		d.onUnknownType = fn
+	}
+}
+
+// WithKeyRedaction sets how keys are shown in error messages and other diagnostic output.
+// Functional APIs like Get always use the original keys.
+func WithKeyRedaction(mode RedactionMode) Option {
+	return func(d *KeyValueStore) {
+		d.redaction = mode
+	}
+}
+
+// WithClock replaces the clock used to compute and check the expiration of entries.
+func WithClock(now func() time.Time) Option {
+	return func(d *KeyValueStore) {
+		d.now = now
+	}
+}
+
+// WithCleanerStopped creates the store without starting the cleaner. It can be started with StartCleaning.
+func WithCleanerStopped(stopped bool) Option {
+	return func(d *KeyValueStore) {
+		d.cleanerStopped = stopped
+	}
+}
+
+// WithCodec sets the codec that encodes the entries saved in the cache folder. The default is JSONCodec.
+// A cache folder can only be opened with the codec its files were written with, unless it is passed to
+// WithLegacyCodecs.
+func WithCodec(codec Codec) Option {
+	return func(d *KeyValueStore) {
+		d.codec = codec
+	}
+}
+
+// WithLegacyCodecs allows cache folders with files of other codecs, e.g. of JSONCodec after a switch to another
+// codec with WithCodec. On start, their files are rewritten with the codec of the store and removed.
+func WithLegacyCodecs(codecs ...Codec) Option {
+	return func(d *KeyValueStore) {
+		d.legacyCodecs = codecs
+	}
+}
+
+// WithCompression compresses the files of the store with gzip at a level of compress/gzip, where 0 means
+// gzip.DefaultCompression. The files get the extension of the codec followed by ".gz", e.g. ".store.json.gz".
+// Uncompressed files of the codec are still read and compressed on start. Compression can not be combined with
+// WithPackedSmallValues.
+func WithCompression(level int) Option {
+	return func(d *KeyValueStore) {
+		d.compress = true
+		d.compressionLevel = level
+	}
+}
+
+// WithSyncWrites syncs every cache file to stable storage before a write returns, so the value survives a power
+// loss and not only a crash of the process. The file is synced before it is renamed into place and the cache
+// folder after the rename. Syncing makes writes to a cache folder several times slower, and much slower on
+// disks that flush slowly; compare with BenchmarkSetSyncWrites on the target disk. The index, segments of
+// WithPackedSmallValues, and deletions are not synced. It has no effect if the FS of the store does not implement
+// SyncFS.
+func WithSyncWrites(enabled bool) Option {
+	return func(d *KeyValueStore) {
+		d.syncWrites = enabled
+	}
+}
+
+// WithWriteBehind writes and deletes cache files in a background goroutine instead of while holding the write
+// lock, so disk latency does not slow down other reads and writes. Writes queue their file and return; a later
+// write of the same key replaces the queued file, and a deletion replaces it with the deletion of the file. Up to
+// queueSize keys are queued; further writes wait for the writer. Flush waits until the queued files are written
+// and Close writes them before it returns. Until then, queued entries are lost if the process crashes.
+// Errors of the writer are delivered to the OnError function with the next write, Flush, or Close, and do not
+// switch the store to memory-only mode. It can not be combined with WithIndex or WithPackedSmallValues, and
+// FollowChanges does not detect the deletion of entries written by it.
+func WithWriteBehind(queueSize int) Option {
+	return func(d *KeyValueStore) {
+		d.writeBehindSize = queueSize
+	}
+}
+
+// WithFlushInterval coalesces the writes of cache files in memory and writes only the latest one of every key,
+// once per interval, in a background goroutine like WithWriteBehind. Flush and Close write the queued files at
+// once. If the process crashes, the writes of at most the last interval are lost. Files are replaced atomically,
+// so a crash never leaves a torn file. Deletions are not delayed: they drop the queued write of their key and
+// remove its file before they return, so a crash never brings back a deleted key. Stats counts the skipped writes
+// in CoalescedWrites. It can be combined with WithWriteBehind to cap the number of queued keys; a full queue is
+// written before the interval elapsed. It can not be combined with WithIndex or WithPackedSmallValues.
+func WithFlushInterval(interval time.Duration) Option {
+	return func(d *KeyValueStore) {
+		d.flushInterval = interval
+	}
+}
+
+// WithStrictLoad makes NewKeyValueStore fail with ErrCorruptFile if a cache file can not be read or decoded.
+// By default, such files are renamed with the suffix ".corrupt", reported to the first OnError function, and
+// counted in Stats, and all other entries are loaded.
+func WithStrictLoad(enabled bool) Option {
+	return func(d *KeyValueStore) {
+		d.strictLoad = enabled
+	}
+}
+
+// WithUseNumber decodes numbers in values read from the cache folder as json.Number instead of float64,
+// so integers larger than 2^53 keep their exact value after a restart. Values that were set in this process
+// keep their original types.
+func WithUseNumber(enabled bool) Option {
+	return func(d *KeyValueStore) {
+		d.useNumber = enabled
+	}
+}
+
+// WithExplainHistory sets the number of keys whose recent events are kept for Explain.
+// The events of the least recently changed keys are forgotten first. A value of 0 disables the history.
+func WithExplainHistory(keys int) Option {
+	return func(d *KeyValueStore) {
+		d.history = newEventHistory(keys)
+	}
+}
+
+// WithPersistTransform sets a function that transforms values before they are saved in the cache folder,
+// e.g. to strip fields that must not be written to disk. Values in memory are not changed.
+// If the function returns an error, the value is not persisted and the error is returned by the write.
+func WithPersistTransform(transform func(key string, value any) (any, error)) Option {
+	return func(d *KeyValueStore) {
+		d.persistTransform = transform
+	}
+}
+
+// WithLoadTransform sets a function that transforms values after they are read from the cache folder.
+func WithLoadTransform(transform func(key string, value any) (any, error)) Option {
+	return func(d *KeyValueStore) {
+		d.loadTransform = transform
+	}
+}
+
+// WithGlobalWriteRateLimit limits writes to opsPerSecond with bursts of up to burst writes.
+// Writes exceeding the limit block until they are allowed unless RateLimitReject is used. Reads are never limited.
+func WithGlobalWriteRateLimit(opsPerSecond int, burst int) Option {
+	return func(d *KeyValueStore) {
+		d.setRateLimit(opsPerSecond, burst, RateLimitBlock)
+	}
+}
+
+// WithRateLimitPolicy sets what happens to writes that exceed the write rate limit.
+// It must be used after WithGlobalWriteRateLimit.
+func WithRateLimitPolicy(policy RateLimitPolicy) Option {
+	return func(d *KeyValueStore) {
+		if limiter := d.rateLimiter.Load(); limiter != nil {
+			d.setRateLimit(limiter.opsPerSecond, limiter.burst, policy)
+		}
+	}
+}
+
+// WithMinFreeDiskBytes skips persisting new key-value pairs while the filesystem of the cache folder has less
+// than n bytes of free space. Such writes are kept in memory and return ErrLowDiskSpace.
+func WithMinFreeDiskBytes(n int64) Option {
+	return func(d *KeyValueStore) {
+		d.minFreeDiskBytes = n
+	}
+}
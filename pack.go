@@ -0,0 +1,362 @@
+package goKeyValueStore
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// segmentPrefix and segmentSuffix surround the number in the names of segment files.
+const (
+	segmentPrefix = "segment-"
+	segmentSuffix = ".pack"
+)
+
+// packedCompactMinBytes is the size all segments must reach before they are compacted, unless WithAppendLog
+// sets another size.
+const packedCompactMinBytes = 64 << 10
+
+// packing holds the state of WithPackedSmallValues. It is guarded by the write lock of the store.
+type packing struct {
+	threshold   int
+	segmentSize int64
+	// compactMinBytes is the size all segments must reach before they are compacted.
+	compactMinBytes int64
+	// active is the number of the segment new records are appended to.
+	active int
+	// slots holds the location of every key whose current record is in a segment.
+	slots    map[string]packedSlot
+	segments map[int]*segmentStats
+	// loose holds the keys with their own files.
+	loose map[string]bool
+}
+
+// A packedSlot is the location of the record of a key in a segment.
+type packedSlot struct {
+	segment  int
+	length   int64
+	revision uint64
+}
+
+// segmentStats counts the bytes of a segment and how many of them belong to outdated records.
+type segmentStats struct {
+	bytes int64
+	dead  int64
+}
+
+// A segmentTombstone marks the record of a key in a segment as deleted.
+type segmentTombstone struct {
+	Key      string `json:"key"`
+	KeyBytes []byte `json:"keyBytes,omitempty"`
+	Deleted  bool   `json:"deleted"`
+	Revision uint64 `json:"revision,omitempty"`
+}
+
+func newPacking(threshold int, segmentSize int64, compactMinBytes int64) *packing {
+	return &packing{
+		threshold:       threshold,
+		segmentSize:     segmentSize,
+		compactMinBytes: compactMinBytes,
+		active:          1,
+		slots:           map[string]packedSlot{},
+		segments:        map[int]*segmentStats{},
+		loose:           map[string]bool{},
+	}
+}
+
+// segmentFile returns the path of a segment.
+func (d *KeyValueStore) segmentFile(segment int) string {
+	return filepath.Join(d.cacheFolder, fmt.Sprintf("%s%06d%s", segmentPrefix, segment, segmentSuffix))
+}
+
+// isPacked reports whether the current record of a key is in a segment. The caller must hold the lock.
+func (d *KeyValueStore) isPacked(key string) bool {
+	if d.packing == nil {
+		return false
+	}
+	_, ok := d.packing.slots[key]
+	return ok
+}
+
+// appendRecord appends a record to the active segment with a single write and returns the segment and the
+// number of written bytes. A new segment is started when the active one is full.
+func (d *KeyValueStore) appendRecord(record []byte) (int, int64, error) {
+	p := d.packing
+	if stats, ok := p.segments[p.active]; ok && stats.bytes >= p.segmentSize {
+		p.active++
+	}
+	line := append(record, '\n')
+	err := d.diskOp(func() error {
+		return d.fs.AppendFile(d.segmentFile(p.active), line, 0600)
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	if p.segments[p.active] == nil {
+		p.segments[p.active] = &segmentStats{}
+	}
+	p.segments[p.active].bytes += int64(len(line))
+	return p.active, int64(len(line)), nil
+}
+
+// writePacked appends the encoded node to the active segment. The own file of the key, if any, is removed
+// afterwards; if that fails, the record in the segment still wins because of its higher revision.
+func (d *KeyValueStore) writePacked(node *node, data []byte) error {
+	segment, length, err := d.appendRecord(data)
+	if err != nil {
+		return d.keyError("write segment", node.Key, err)
+	}
+	d.markDead(node.Key)
+	d.packing.slots[node.Key] = packedSlot{segment: segment, length: length, revision: node.Revision}
+	node.size = len(data)
+	if d.packing.loose[node.Key] {
+		fileName, err := d.getFileName(node.Key)
+		if err != nil {
+			return err
+		}
+		err = d.diskOp(func() error {
+			return d.fs.Remove(fileName)
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return d.keyError("delete cache file", node.Key, err)
+		}
+		delete(d.packing.loose, node.Key)
+	}
+	return nil
+}
+
+// unpack is called after a key was written to its own file. It marks the record of the key in a segment as
+// deleted with the revision of that record, so the file wins when the cache folder is loaded.
+func (d *KeyValueStore) unpack(key string) error {
+	if d.packing == nil {
+		return nil
+	}
+	_, err := d.removePacked(key)
+	d.packing.loose[key] = true
+	return err
+}
+
+// removePacked marks the record of a key in a segment as deleted. It returns false if the key is not packed.
+func (d *KeyValueStore) removePacked(key string) (bool, error) {
+	if !d.isPacked(key) {
+		if d.packing != nil {
+			delete(d.packing.loose, key)
+		}
+		return false, nil
+	}
+	slot := d.packing.slots[key]
+	data, err := json.Marshal(segmentTombstone{Key: key, KeyBytes: rawKey(key), Deleted: true, Revision: slot.revision})
+	if err != nil {
+		return true, err
+	}
+	segment, length, err := d.appendRecord(data)
+	if err != nil {
+		return true, err
+	}
+	d.markDead(key)
+	d.packing.segments[segment].dead += length
+	return true, nil
+}
+
+// markDead counts the current record of a key in a segment as garbage and forgets it.
+func (d *KeyValueStore) markDead(key string) {
+	if slot, ok := d.packing.slots[key]; ok {
+		if stats, ok := d.packing.segments[slot.segment]; ok {
+			stats.dead += slot.length
+		}
+		delete(d.packing.slots, key)
+	}
+}
+
+// segmentNumbers returns the numbers of the segment files in the cache folder in ascending order.
+func (d *KeyValueStore) segmentNumbers() ([]int, error) {
+	entries, err := d.fs.ReadDir(d.cacheFolder)
+	if err != nil {
+		return nil, err
+	}
+	numbers := []int{}
+	for _, file := range entries {
+		name := file.Name()
+		if !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		number, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix))
+		if err == nil {
+			numbers = append(numbers, number)
+		}
+	}
+	slices.Sort(numbers)
+	return numbers, nil
+}
+
+// loadSegments loads the records of all segments. Later records of a key replace earlier ones, and a torn
+// last line, left by a crash while it was appended, is truncated from its segment. A key with its own file keeps
+// it if the file has a higher revision than the record in the segments; otherwise the file is outdated and
+// removed. New records are appended to a new segment.
+func (d *KeyValueStore) loadSegments() error {
+	if d.packing == nil {
+		return nil
+	}
+	numbers, err := d.segmentNumbers()
+	if err != nil {
+		return err
+	}
+	type latest struct {
+		node    *node
+		slot    packedSlot
+		deleted bool
+	}
+	records := map[string]latest{}
+	for _, segment := range numbers {
+		data, err := d.readCacheFile(d.segmentFile(segment))
+		if err != nil {
+			return err
+		}
+		stats := &segmentStats{bytes: int64(len(data))}
+		d.packing.segments[segment] = stats
+		lines := bytes.Split(data, []byte("\n"))
+		for i, line := range lines {
+			if len(line) == 0 {
+				continue
+			}
+			length := int64(len(line) + 1)
+			var tombstone segmentTombstone
+			err = json.Unmarshal(line, &tombstone)
+			if err != nil {
+				if i == len(lines)-1 {
+					data = data[:len(data)-len(line)]
+					stats.bytes = int64(len(data))
+					err = d.writeFileAtomic(d.segmentFile(segment), data)
+					if err != nil {
+						return err
+					}
+					break
+				}
+				return fmt.Errorf("segment %d line %d: %w", segment, i+1, err)
+			}
+			key := restoreKey(tombstone.Key, tombstone.KeyBytes)
+			if previous, ok := records[key]; ok {
+				d.packing.segments[previous.slot.segment].dead += previous.slot.length
+			}
+			if tombstone.Deleted {
+				stats.dead += length
+				records[key] = latest{slot: packedSlot{segment: segment, length: 0, revision: tombstone.Revision}, deleted: true}
+				continue
+			}
+			node := &node{size: len(line)}
+			err = d.decodeNode(line, node)
+			if err != nil {
+				return fmt.Errorf("segment %d line %d: %w", segment, i+1, err)
+			}
+			records[key] = latest{node: node, slot: packedSlot{segment: segment, length: length, revision: node.Revision}}
+		}
+		d.packing.active = segment + 1
+	}
+	for key, record := range records {
+		d.observeRevision(record.slot.revision)
+		if loose, ok := d.data[key]; ok && d.packing.loose[key] {
+			if loose.Revision > record.slot.revision {
+				if !record.deleted {
+					d.packing.segments[record.slot.segment].dead += record.slot.length
+				}
+				continue
+			}
+			d.removeNode(key)
+			fileName, err := d.getFileName(key)
+			if err != nil {
+				return err
+			}
+			err = d.diskOp(func() error {
+				return d.fs.Remove(fileName)
+			})
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			delete(d.packing.loose, key)
+		}
+		if record.deleted {
+			continue
+		}
+		record.node.Value, err = d.transformLoaded(key, record.node.Value, record.node.Type)
+		if err != nil {
+			return err
+		}
+		d.putNode(record.node)
+		d.packing.slots[key] = record.slot
+	}
+	return nil
+}
+
+// compactSegments rewrites the current records of all segments to new segments and removes the old ones.
+// Unless forced, it only runs if the segments are large enough and more than half of them is garbage.
+// If it is interrupted, the old segments are left in place; their records are older than those of the new
+// segments, so loading the cache folder gives the same result. The old segments are removed oldest first, so a
+// segment with the tombstone of a key is never removed before an older segment with a record of the key.
+func (d *KeyValueStore) compactSegments(force bool) error {
+	if d.packing == nil {
+		return nil
+	}
+	defer d.afterWrite()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var total, dead int64
+	for _, stats := range d.packing.segments {
+		total += stats.bytes
+		dead += stats.dead
+	}
+	if !force && (total < d.packing.compactMinBytes || dead*2 <= total) {
+		return nil
+	}
+	old := d.packing.segments
+	d.packing.segments = map[int]*segmentStats{}
+	d.packing.active++
+	err := d.rewriteSlots()
+	if err != nil {
+		// the old segments are still needed, so keep counting them
+		for segment, stats := range old {
+			d.packing.segments[segment] = stats
+		}
+		return err
+	}
+	segments := make([]int, 0, len(old))
+	for segment := range old {
+		segments = append(segments, segment)
+	}
+	slices.Sort(segments)
+	for _, segment := range segments {
+		err := d.diskOp(func() error {
+			return d.fs.Remove(d.segmentFile(segment))
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteSlots appends the current records of all packed keys to the active segment.
+func (d *KeyValueStore) rewriteSlots() error {
+	for key := range d.packing.slots {
+		node, ok := d.data[key]
+		if !ok {
+			delete(d.packing.slots, key)
+			continue
+		}
+		data, err := d.encodeNode(node)
+		if err != nil {
+			return err
+		}
+		segment, length, err := d.appendRecord(data)
+		if err != nil {
+			return err
+		}
+		d.packing.slots[key] = packedSlot{segment: segment, length: length, revision: node.Revision}
+	}
+	return nil
+}
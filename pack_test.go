@@ -0,0 +1,270 @@
+package goKeyValueStore_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+	"github.com/richi0/goKeyValueStore/faultfs"
+)
+
+func getPackedTestStore(t *testing.T, dir string) *goKeyValueStore.KeyValueStore {
+	store, err := goKeyValueStore.NewKeyValueStore(1, dir, goKeyValueStore.WithCleanerStopped(true),
+		goKeyValueStore.WithPackedSmallValues(320, 16<<10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// allocatedSize returns the size of all files in a folder rounded up to blocks of 4 KB.
+func allocatedSize(t *testing.T, dir string) int64 {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var size int64
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		size += (info.Size() + 4095) / 4096 * 4096
+	}
+	return size
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*.pack"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestPackedSmallValuesSaveSpace(t *testing.T) {
+	looseDir := t.TempDir()
+	packedDir := t.TempDir()
+	loose, err := goKeyValueStore.NewKeyValueStore(1, looseDir, goKeyValueStore.WithCleanerStopped(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	packed := getPackedTestStore(t, packedDir)
+	for i := 0; i < 500; i++ {
+		key, value := fmt.Sprintf("key%d", i), fmt.Sprintf("a value of 30 bytes number %03d", i)
+		loose.Set(key, value, 0)
+		packed.Set(key, value, 0)
+	}
+	packed.Set("large", strings.Repeat("x", 1000), 0)
+	looseSize, packedSize := allocatedSize(t, looseDir), allocatedSize(t, packedDir)
+	if packedSize*10 > looseSize {
+		t.Errorf("Expected packed values to use less than a tenth of %d bytes, got %d bytes", looseSize, packedSize)
+	}
+	if count := countCacheFiles(t, packedDir); count != 1 {
+		t.Errorf("Expected only the large value to have its own file, got %d files", count)
+	}
+	restored := getPackedTestStore(t, packedDir)
+	if restored.Length() != 501 {
+		t.Errorf("Expected 501 entries after a restart, got %d", restored.Length())
+	}
+	if value, _ := restored.Get("key42"); value != "a value of 30 bytes number 042" {
+		t.Errorf("Expected the value of key42, got %v", value)
+	}
+}
+
+func TestPackedSmallValuesTornTail(t *testing.T) {
+	dir := t.TempDir()
+	store := getPackedTestStore(t, dir)
+	store.Set("key1", "value1", 0)
+	store.Set("key2", "value2", 0)
+	segments := segmentFiles(t, dir)
+	file, err := os.OpenFile(segments[len(segments)-1], os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"key":"key3","val`)
+	file.Close()
+	restored := getPackedTestStore(t, dir)
+	if restored.Length() != 2 {
+		t.Errorf("Expected the torn record to be ignored, got length %d", restored.Length())
+	}
+	data, err := os.ReadFile(segments[len(segments)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(data), "}\n") {
+		t.Errorf("Expected the torn record to be truncated, got %q", data)
+	}
+	restored.Set("key3", "value3", 0)
+	restored = getPackedTestStore(t, dir)
+	if value, ok := restored.Get("key3"); !ok || value != "value3" {
+		t.Errorf("Expected key3 to be written after the torn record, got %v", value)
+	}
+}
+
+func TestPackedSmallValuesDeleteAndCompact(t *testing.T) {
+	dir := t.TempDir()
+	store := getPackedTestStore(t, dir)
+	for i := 0; i < 200; i++ {
+		store.Set(fmt.Sprintf("key%d", i), "value", 0)
+	}
+	for i := 0; i < 190; i++ {
+		store.Delete(fmt.Sprintf("key%d", i))
+	}
+	store.Set("key199", "updated", 0)
+	before := allocatedSize(t, dir)
+	if err := store.CompactSegments(); err != nil {
+		t.Fatal(err)
+	}
+	if after := allocatedSize(t, dir); after >= before {
+		t.Errorf("Expected compaction to shrink the folder from %d bytes, got %d bytes", before, after)
+	}
+	restored := getPackedTestStore(t, dir)
+	if restored.Length() != 10 {
+		t.Errorf("Expected 10 entries after compaction and a restart, got %d", restored.Length())
+	}
+	if value, _ := restored.Get("key199"); value != "updated" {
+		t.Errorf("Expected the updated value of key199, got %v", value)
+	}
+}
+
+func TestPackedSmallValuesCompactionCrash(t *testing.T) {
+	dir := t.TempDir()
+	fsys := faultfs.New(goKeyValueStore.OSFS{})
+	store, err := goKeyValueStore.NewKeyValueStore(1, dir, goKeyValueStore.WithCleanerStopped(true),
+		goKeyValueStore.WithPackedSmallValues(320, 16<<10), goKeyValueStore.WithFilesystem(fsys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	for i := 1; i <= 5; i++ {
+		store.Set(fmt.Sprintf("deleted%d", i), "value", 0)
+	}
+	// the tombstone of deletedN is in the N+1th segment, while all their records are in the first one
+	for i := 1; i <= 5; i++ {
+		for filler := 0; len(segmentFiles(t, dir)) == i; filler++ {
+			store.Set(fmt.Sprintf("filler%d-%d", i, filler), "a value of a filler record", 0)
+		}
+		store.Delete(fmt.Sprintf("deleted%d", i))
+	}
+	fsys.FailAfter(faultfs.OpRemove, 1, nil)
+	if err := store.CompactSegments(); !errors.Is(err, faultfs.ErrInjected) {
+		t.Fatalf("Expected the compaction to stop after the first removal, got %v", err)
+	}
+	fsys.Heal()
+	restored := getPackedTestStore(t, dir)
+	for i := 1; i <= 5; i++ {
+		if restored.Has(fmt.Sprintf("deleted%d", i)) {
+			t.Errorf("Expected deleted%d to stay deleted after the interrupted compaction", i)
+		}
+	}
+}
+
+func TestPackedSmallValuesChangeSize(t *testing.T) {
+	dir := t.TempDir()
+	store := getPackedTestStore(t, dir)
+	large := strings.Repeat("x", 1000)
+	store.Set("key", "small", 0)
+	store.Set("key", large, 0)
+	if value, _ := getPackedTestStore(t, dir).Get("key"); value != large {
+		t.Errorf("Expected the large value after a restart, got %v", value)
+	}
+	store.Set("key", "small again", 0)
+	if count := countCacheFiles(t, dir); count != 0 {
+		t.Errorf("Expected the file of the large value to be removed, got %d files", count)
+	}
+	if value, _ := getPackedTestStore(t, dir).Get("key"); value != "small again" {
+		t.Errorf("Expected the small value after a restart, got %v", value)
+	}
+	store.Delete("key")
+	if length := getPackedTestStore(t, dir).Length(); length != 0 {
+		t.Errorf("Expected the deletion to survive a restart, got length %d", length)
+	}
+}
+
+func getAppendLogTestStore(t testing.TB, dir string, clock *fakeClock) *goKeyValueStore.KeyValueStore {
+	store, err := goKeyValueStore.NewKeyValueStore(1, dir, goKeyValueStore.WithCleanerStopped(true),
+		goKeyValueStore.WithClock(clock.Now), goKeyValueStore.WithAppendLog(8<<10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestAppendLog(t *testing.T) {
+	dir := t.TempDir()
+	clock := newFakeClock()
+	store := getAppendLogTestStore(t, dir, clock)
+	for i := 0; i < 200; i++ {
+		store.Set(fmt.Sprintf("key%d", i), strings.Repeat("x", 100), 0)
+	}
+	store.Set("large", strings.Repeat("y", 10000), 0)
+	store.Set("expiring", "value", 1000)
+	for i := 0; i < 190; i++ {
+		store.Delete(fmt.Sprintf("key%d", i))
+	}
+	if count := countCacheFiles(t, dir); count != 0 {
+		t.Errorf("Expected no files per key, got %d", count)
+	}
+	segments := segmentFiles(t, dir)
+	file, err := os.OpenFile(segments[len(segments)-1], os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"key":"torn","val`)
+	file.Close()
+
+	store = getAppendLogTestStore(t, dir, clock)
+	if store.Length() != 12 {
+		t.Errorf("Expected 12 entries after replaying the log, got %d", store.Length())
+	}
+	if value, _ := store.Get("large"); value != strings.Repeat("y", 10000) {
+		t.Errorf("Expected the large value to be replayed")
+	}
+	before := allocatedSize(t, dir)
+	clock.Advance(2 * time.Second)
+	_, err = store.CleanNow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after := allocatedSize(t, dir); after >= before {
+		t.Errorf("Expected the cleaner to compact the log from %d bytes, got %d bytes", before, after)
+	}
+	store = getAppendLogTestStore(t, dir, clock)
+	if store.Length() != 11 {
+		t.Errorf("Expected 11 entries after compaction, got %d", store.Length())
+	}
+	if _, ok := store.Get("expiring"); ok {
+		t.Errorf("Expected the expired entry to be dropped")
+	}
+}
+
+func BenchmarkSetAppendLog(b *testing.B) {
+	value := strings.Repeat("x", 100)
+	b.Run("files", func(b *testing.B) {
+		store, err := goKeyValueStore.NewKeyValueStore(1, b.TempDir(), goKeyValueStore.WithCleanerStopped(true))
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer store.Close()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			store.Set(fmt.Sprintf("key%d", i%1000), value, 0)
+		}
+	})
+	b.Run("log", func(b *testing.B) {
+		store := getAppendLogTestStore(b, b.TempDir(), newFakeClock())
+		defer store.Close()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			store.Set(fmt.Sprintf("key%d", i%1000), value, 0)
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package goKeyValueStore
+
+import "time"
+
+// SessionProfile returns the options used by NewSessionStore:
+// expired sessions are removed every minute, keys are hashed in error messages because session IDs are
+// secrets, and the index is enabled so restarts only read the index and load sessions on first access.
+// Values can be encrypted on disk with WithPersistTransform and WithLoadTransform.
+// The store has no sliding expiration, so pass the session TTL to Set on every access.
+func SessionProfile() []Option {
+	return []Option{
+		WithCleanInterval(time.Minute),
+		WithKeyRedaction(RedactionHash),
+		WithIndex(true),
+	}
+}
+
+// MemoryCacheProfile returns the options used by NewMemoryCache:
+// the store holds at most maxEntries entries and expired entries are removed every second.
+// When the store is full, the least recently used entry is evicted.
+func MemoryCacheProfile(maxEntries int) []Option {
+	return []Option{
+		WithCleanInterval(time.Second),
+		WithMaxEntries(maxEntries),
+		WithEvictionPolicy(EvictLRU),
+	}
+}
+
+// PersistentCacheProfile returns the options used by NewPersistentCache:
+// expired entries are removed every minute and the index is enabled so restarts only read the index and
+// load values on first access.
+func PersistentCacheProfile() []Option {
+	return []Option{
+		WithCleanInterval(time.Minute),
+		WithIndex(true),
+	}
+}
+
+// NewSessionStore creates a store for sessions persisted in folder using SessionProfile.
+// The options are applied after the profile, so they can override it.
+func NewSessionStore(folder string, opts ...Option) (*KeyValueStore, error) {
+	return NewKeyValueStore(0, folder, append(SessionProfile(), opts...)...)
+}
+
+// NewMemoryCache creates a memory-only cache with at most maxEntries entries using MemoryCacheProfile.
+// The options are applied after the profile, so they can override it.
+func NewMemoryCache(maxEntries int, opts ...Option) (*KeyValueStore, error) {
+	return NewKeyValueStore(0, "", append(MemoryCacheProfile(maxEntries), opts...)...)
+}
+
+// NewPersistentCache creates a cache persisted in folder using PersistentCacheProfile.
+// The options are applied after the profile, so they can override it.
+func NewPersistentCache(folder string, opts ...Option) (*KeyValueStore, error) {
+	return NewKeyValueStore(0, folder, append(PersistentCacheProfile(), opts...)...)
+}
@@ -0,0 +1,71 @@
+package goKeyValueStore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestSessionStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := goKeyValueStore.NewSessionStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := store.Config()
+	if config.CleanInterval != time.Minute || config.CacheFolder != dir || !config.Index || config.KeyRedaction != goKeyValueStore.RedactionHash {
+		t.Errorf("Unexpected session store config %+v", config)
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	store, err := goKeyValueStore.NewMemoryCache(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := store.Config()
+	if config.CleanInterval != time.Second || config.CacheFolder != "" || config.MaxEntries != 100 ||
+		config.EvictionPolicy != goKeyValueStore.EvictLRU {
+		t.Errorf("Unexpected memory cache config %+v", config)
+	}
+}
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	store, err := goKeyValueStore.NewMemoryCache(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	// key1 expires first, so only LRU keeps it
+	store.Set("key1", "value1", 1000)
+	store.Set("key2", "value2", 60000)
+	store.Get("key1")
+	store.Set("key3", "value3", 60000)
+	if !store.Has("key1") || store.Has("key2") {
+		t.Error("Expected key2 to be evicted as the least recently used entry")
+	}
+}
+
+func TestPersistentCache(t *testing.T) {
+	dir := t.TempDir()
+	store, err := goKeyValueStore.NewPersistentCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := store.Config()
+	if config.CleanInterval != time.Minute || config.CacheFolder != dir || !config.Index {
+		t.Errorf("Unexpected persistent cache config %+v", config)
+	}
+}
+
+func TestProfileOverride(t *testing.T) {
+	store, err := goKeyValueStore.NewMemoryCache(100, goKeyValueStore.WithMaxEntries(10), goKeyValueStore.WithCleanInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := store.Config()
+	if config.MaxEntries != 10 || config.CleanInterval != time.Hour {
+		t.Errorf("Expected options to override the profile, got %+v", config)
+	}
+}
@@ -0,0 +1,120 @@
+package goKeyValueStore
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// metaFileName is the name of the file in the cache folder that records the instance ID of the store.
+const metaFileName = "meta.store"
+
+// ErrFolderOwned is returned by NewKeyValueStore if the cache folder belongs to a store with another instance ID
+// and WithAdoptFolder is not used.
+var ErrFolderOwned = errors.New("cache folder belongs to another store")
+
+// A folderMeta is the content of the meta file.
+type folderMeta struct {
+	Instance string `json:"instance"`
+}
+
+// Metadata describes the current entry of a key. Instance and Run identify the store that wrote it, see
+// WithInstanceID. They are empty for entries written by versions without provenance.
+type Metadata struct {
+	Revision  uint64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// ExpiresAt is the zero time for entries without expiration.
+	ExpiresAt time.Time
+	Source    string
+	Instance  string
+	Run       string
+}
+
+// GetMetadata returns the metadata of a key. The second return value is false if the key does not exist or
+// is expired.
+func (d *KeyValueStore) GetMetadata(key string) (Metadata, bool) {
+	d.lazyInit()
+	key = d.storageKey(key)
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	node, ok := d.data[key]
+	if !ok || d.nodeIsExpired(node) || d.closed.Load() {
+		return Metadata{}, false
+	}
+	metadata := Metadata{
+		Revision:  node.Revision,
+		CreatedAt: time.UnixMilli(node.CreatedAt),
+		UpdatedAt: time.UnixMilli(node.UpdatedAt),
+		Source:    node.Source,
+		Instance:  node.Instance,
+		Run:       node.Run,
+	}
+	if node.DeleteTimestamp != neverExpire {
+		metadata.ExpiresAt = time.UnixMilli(node.DeleteTimestamp)
+	}
+	return metadata, true
+}
+
+// newID returns a random ID.
+func newID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// stampWriter records the store that writes a node.
+func (d *KeyValueStore) stampWriter(node *node) {
+	node.Instance, node.Run = d.instanceID, d.runID
+}
+
+// folderInstanceID returns the instance ID of a store without WithInstanceID, which is derived from the path of
+// its cache folder, so it is stable across restarts.
+func folderInstanceID(cacheFolder string) string {
+	sum := sha256.Sum256([]byte(cacheFolder))
+	return hex.EncodeToString(sum[:8])
+}
+
+// claimFolder sets the instance ID of the store. With WithInstanceID, the ID is recorded in the meta file of the
+// cache folder. If the folder belongs to another instance, ErrFolderOwned is returned unless the folder is adopted.
+func (d *KeyValueStore) claimFolder() error {
+	if d.instanceID == "" {
+		d.instanceID = folderInstanceID(d.cacheFolder)
+		return nil
+	}
+	fileName := filepath.Join(d.cacheFolder, metaFileName)
+	data, err := d.readCacheFile(fileName)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		var meta folderMeta
+		err = json.Unmarshal(data, &meta)
+		if err != nil {
+			return fmt.Errorf("meta file: %w", err)
+		}
+		if meta.Instance == d.instanceID {
+			return nil
+		}
+		if !d.adoptFolder {
+			return fmt.Errorf("%w: instance %s, not %s", ErrFolderOwned, meta.Instance, d.instanceID)
+		}
+	}
+	data, err = json.Marshal(folderMeta{Instance: d.instanceID})
+	if err != nil {
+		return err
+	}
+	return d.diskOp(func() error {
+		err := d.fs.WriteFile(fileName+".tmp", data, 0600)
+		if err != nil {
+			return err
+		}
+		return d.fs.Rename(fileName+".tmp", fileName)
+	})
+}
@@ -0,0 +1,62 @@
+package goKeyValueStore_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestProvenanceOfTwoStores(t *testing.T) {
+	dir := t.TempDir()
+	first, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithCleanerStopped(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Set("key1", "value1", 0)
+	first.Close()
+	second, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithCleanerStopped(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	second.Set("key2", "value2", 0)
+	old, ok := second.GetMetadata("key1")
+	if !ok {
+		t.Fatal("Expected metadata of key1")
+	}
+	recent, _ := second.GetMetadata("key2")
+	config := second.Config()
+	if old.Instance != config.InstanceID || recent.Instance != config.InstanceID {
+		t.Errorf("Expected instance %s for both keys, got %s and %s", config.InstanceID, old.Instance, recent.Instance)
+	}
+	if old.Run == "" || old.Run == recent.Run || recent.Run != config.RunID {
+		t.Errorf("Expected distinct runs, got %q for key1 and %q for key2 of run %q", old.Run, recent.Run, config.RunID)
+	}
+}
+
+func TestAdoptFolder(t *testing.T) {
+	dir := t.TempDir()
+	first, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithInstanceID("first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Set("key1", "value1", 0)
+	first.Close()
+	_, err = goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithInstanceID("second"))
+	if !errors.Is(err, goKeyValueStore.ErrFolderOwned) {
+		t.Fatalf("Expected ErrFolderOwned, got %v", err)
+	}
+	second, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithInstanceID("second"), goKeyValueStore.WithAdoptFolder(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	if metadata, _ := second.GetMetadata("key1"); metadata.Instance != "first" {
+		t.Errorf("Expected key1 to be written by first, got %q", metadata.Instance)
+	}
+	_, err = goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithInstanceID("first"))
+	if !errors.Is(err, goKeyValueStore.ErrFolderOwned) {
+		t.Errorf("Expected the folder to belong to second, got %v", err)
+	}
+}
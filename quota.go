@@ -0,0 +1,107 @@
+package goKeyValueStore
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrQuotaExceeded is returned when a new key would exceed the quota of its prefix and QuotaReject is used.
+var ErrQuotaExceeded = errors.New("prefix quota exceeded")
+
+// A QuotaPolicy selects what happens when a new key would exceed the quota of its prefix.
+type QuotaPolicy int
+
+const (
+	// QuotaReject rejects the new key with ErrQuotaExceeded.
+	QuotaReject QuotaPolicy = iota
+	// QuotaEvict evicts the entry of the prefix closest to its expiration to make room for the new key.
+	QuotaEvict
+)
+
+// A PrefixStats holds statistics about the keys with a prefix that has a quota.
+type PrefixStats struct {
+	// Entries is the number of entries with the prefix, including expired entries the cleaner did not delete yet.
+	Entries    int
+	MaxEntries int
+	// Evicted is the number of entries evicted and Rejected the number of keys rejected because of the quota.
+	Evicted  uint64
+	Rejected uint64
+}
+
+// prefixQuota is the quota of a prefix with its counters.
+type prefixQuota struct {
+	prefix string
+	stats  PrefixStats
+}
+
+// StatsForPrefix returns the statistics of a prefix set with WithPrefixQuota.
+// The second return value is false if the prefix has no quota.
+func (d *KeyValueStore) StatsForPrefix(prefix string) (PrefixStats, bool) {
+	d.lazyInit()
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, quota := range d.quotas {
+		if quota.prefix == prefix {
+			return quota.stats, true
+		}
+	}
+	return PrefixStats{}, false
+}
+
+// countInQuotas adds delta to the number of entries of all quotas whose prefix matches key.
+// The caller must hold the write lock.
+func (d *KeyValueStore) countInQuotas(key string, delta int) {
+	for _, quota := range d.quotas {
+		if strings.HasPrefix(key, quota.prefix) {
+			quota.stats.Entries += delta
+		}
+	}
+}
+
+// makeRoomInQuotas makes sure that a new key does not exceed the quotas of its prefixes. It returns
+// ErrQuotaExceeded or evicts entries of the prefixes, depending on the quota policy. Existing keys are always
+// allowed. The caller must hold the write lock.
+func (d *KeyValueStore) makeRoomInQuotas(key string) error {
+	if _, ok := d.data[key]; ok {
+		return nil
+	}
+	for _, quota := range d.quotas {
+		if !strings.HasPrefix(key, quota.prefix) || quota.stats.Entries < quota.stats.MaxEntries {
+			continue
+		}
+		if d.quotaPolicy == QuotaReject {
+			quota.stats.Rejected++
+			return ErrQuotaExceeded
+		}
+	}
+	for _, quota := range d.quotas {
+		if !strings.HasPrefix(key, quota.prefix) {
+			continue
+		}
+		for quota.stats.Entries >= quota.stats.MaxEntries {
+			victim := d.nearestExpiryWithPrefix(quota.prefix)
+			if victim == nil {
+				return ErrQuotaExceeded
+			}
+			d.removeNode(victim.Key)
+			d.recordEvent(victim.Key, EventEvicted, "prefix quota is full")
+			quota.stats.Evicted++
+			err := errors.Join(d.deleteInCache(victim.Key), d.removeDerived(victim.Key))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// nearestExpiryWithPrefix returns the entry with the prefix closest to its expiration or nil if there is none.
+func (d *KeyValueStore) nearestExpiryWithPrefix(prefix string) *node {
+	var victim *node
+	for key, node := range d.data {
+		if strings.HasPrefix(key, prefix) && (victim == nil || node.DeleteTimestamp < victim.DeleteTimestamp) {
+			victim = node
+		}
+	}
+	return victim
+}
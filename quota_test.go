@@ -0,0 +1,114 @@
+package goKeyValueStore_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func getTestStoreWithQuota(t *testing.T, clock *fakeClock, opts ...goKeyValueStore.Option) *goKeyValueStore.KeyValueStore {
+	opts = append([]goKeyValueStore.Option{goKeyValueStore.WithClock(clock.Now), goKeyValueStore.WithCleanerStopped(true),
+		goKeyValueStore.WithPrefixQuota("feature:", 2)}, opts...)
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, t.TempDir(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestPrefixQuotaRejects(t *testing.T) {
+	store := getTestStoreWithQuota(t, newFakeClock())
+	store.Set("feature:1", "value1", 0)
+	store.Set("feature:2", "value2", 0)
+	err := store.Set("feature:3", "value3", 0)
+	if !errors.Is(err, goKeyValueStore.ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+	if err := store.Set("feature:1", "updated", 0); err != nil {
+		t.Errorf("Expected existing keys to be updated, got %v", err)
+	}
+	if err := store.Set("other", "value", 0); err != nil {
+		t.Errorf("Expected keys without the prefix to be set, got %v", err)
+	}
+	results := store.SetManyDetailed([]goKeyValueStore.Entry{{Key: "feature:4", Value: "value4"}})
+	if results[0].Applied || !errors.Is(results[0].Err, goKeyValueStore.ErrQuotaExceeded) {
+		t.Errorf("Expected SetManyDetailed to reject feature:4, got %+v", results[0])
+	}
+	stats, ok := store.StatsForPrefix("feature:")
+	if !ok || stats.Entries != 2 || stats.MaxEntries != 2 || stats.Rejected != 2 {
+		t.Errorf("Expected 2 entries and 2 rejected keys, got %+v", stats)
+	}
+	if _, ok := store.StatsForPrefix("other"); ok {
+		t.Errorf("Expected no stats for a prefix without quota")
+	}
+}
+
+func TestPrefixQuotaEvicts(t *testing.T) {
+	store := getTestStoreWithQuota(t, newFakeClock(), goKeyValueStore.WithQuotaPolicy(goKeyValueStore.QuotaEvict))
+	store.Set("feature:1", "value1", 0)
+	store.Set("feature:2", "value2", 1000)
+	store.Set("other", "value", 10)
+	err := store.Set("feature:3", "value3", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.Get("feature:2"); ok {
+		t.Errorf("Expected feature:2 to be evicted")
+	}
+	for _, key := range []string{"feature:1", "feature:3", "other"} {
+		if _, ok := store.Get(key); !ok {
+			t.Errorf("Expected %s to be present", key)
+		}
+	}
+	stats, _ := store.StatsForPrefix("feature:")
+	if stats.Entries != 2 || stats.Evicted != 1 {
+		t.Errorf("Expected 2 entries and 1 evicted entry, got %+v", stats)
+	}
+}
+
+func TestPrefixQuotaCountsExpiredAndDeleted(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithClock(clock.Now),
+		goKeyValueStore.WithCleanerStopped(true), goKeyValueStore.WithPrefixQuota("feature:", 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("feature:1", "value1", 1000)
+	store.Set("feature:2", "value2", 0)
+	clock.Advance(2 * time.Second)
+	if _, err := store.CleanNow(); err != nil {
+		t.Fatal(err)
+	}
+	store.Delete("feature:2")
+	if stats, _ := store.StatsForPrefix("feature:"); stats.Entries != 0 {
+		t.Errorf("Expected 0 entries after expiry and deletion, got %d", stats.Entries)
+	}
+	store.Set("feature:3", "value3", 0)
+	restored, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithClock(clock.Now),
+		goKeyValueStore.WithCleanerStopped(true), goKeyValueStore.WithPrefixQuota("feature:", 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats, _ := restored.StatsForPrefix("feature:"); stats.Entries != 1 {
+		t.Errorf("Expected 1 entry after a restart, got %d", stats.Entries)
+	}
+}
+
+func TestPrefixQuotaWithGlobalCap(t *testing.T) {
+	store := getTestStoreWithQuota(t, newFakeClock(), goKeyValueStore.WithMaxEntries(2))
+	store.Set("feature:1", "value1", 1000)
+	store.Set("other:1", "value", 0)
+	store.Set("other:2", "value", 0)
+	if _, ok := store.Get("feature:1"); ok {
+		t.Errorf("Expected feature:1 to be evicted by the global cap")
+	}
+	if stats, _ := store.StatsForPrefix("feature:"); stats.Entries != 0 {
+		t.Errorf("Expected 0 entries after the global eviction, got %d", stats.Entries)
+	}
+	if err := store.Set("feature:2", "value2", 0); err != nil {
+		t.Errorf("Expected feature:2 to be within the quota, got %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package goKeyValueStore
+
+// Range calls fn for every non-expired key-value pair until fn returns false.
+// Range operates on a snapshot taken when it is called, so fn may safely call other methods of the store.
+// Keys set after Range was called are never visited. Keys deleted or changed after Range was called may still be
+// visited with the value they had when Range was called.
+func (d *KeyValueStore) Range(fn func(key string, value any) bool) {
+	d.lazyInit()
+	for _, node := range d.snapshot() {
+		value, err := node.value()
+		if err != nil {
+			continue
+		}
+		if !fn(node.Key, value) {
+			return
+		}
+	}
+}
+
+// snapshot returns all non-expired nodes of the store.
+func (d *KeyValueStore) snapshot() []*node {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	nodes := make([]*node, 0, len(d.data))
+	for _, node := range d.data {
+		if !d.nodeIsExpired(node) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,79 @@
+package goKeyValueStore_test
+
+import (
+	"testing"
+)
+
+func TestRange(t *testing.T) {
+	store := getTestStore()
+	visited := map[string]any{}
+	store.Range(func(key string, value any) bool {
+		visited[key] = value
+		return true
+	})
+	if len(visited) != 3 {
+		t.Errorf("Expected 3 visited keys, got %d", len(visited))
+	}
+	if visited["key1"] != "value1" {
+		t.Errorf("Expected value1, got %v", visited["key1"])
+	}
+}
+
+func TestRangeSetDuringIteration(t *testing.T) {
+	store := getTestStore()
+	visited := map[string]int{}
+	store.Range(func(key string, value any) bool {
+		visited[key]++
+		store.Set("derived:"+key, value, 100)
+		return true
+	})
+	if len(visited) != 3 {
+		t.Errorf("Expected 3 visited keys, got %d", len(visited))
+	}
+	for key, count := range visited {
+		if count != 1 {
+			t.Errorf("Expected %s to be visited once, got %d", key, count)
+		}
+	}
+	if store.Length() != 6 {
+		t.Errorf("Expected length to be 6, got %d", store.Length())
+	}
+}
+
+func TestRangeValueChangedDuringIteration(t *testing.T) {
+	store := getTestStore()
+	store.Range(func(key string, value any) bool {
+		store.Set("key1", "changed", 100)
+		store.Set("key2", "changed", 100)
+		store.Set("key3", "changed", 100)
+		if value == "changed" {
+			t.Errorf("Expected %s to be visited with its value at call time", key)
+		}
+		return true
+	})
+}
+
+func TestRangeStopsEarly(t *testing.T) {
+	store := getTestStore()
+	visited := 0
+	store.Range(func(key string, value any) bool {
+		visited++
+		return false
+	})
+	if visited != 1 {
+		t.Errorf("Expected Range to stop after 1 key, got %d", visited)
+	}
+}
+
+func TestRangeDeleteDuringIteration(t *testing.T) {
+	store := getTestStore()
+	visited := 0
+	store.Range(func(key string, value any) bool {
+		visited++
+		store.Delete(key)
+		return true
+	})
+	if visited != 3 || store.Length() != 0 {
+		t.Errorf("Expected all 3 keys to be visited and deleted, got %d visited and %d left", visited, store.Length())
+	}
+}
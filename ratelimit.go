@@ -0,0 +1,73 @@
+package goKeyValueStore
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrRateLimited is returned by writes that exceed the write rate limit when RateLimitReject is used.
+var ErrRateLimited = errors.New("write rate limit exceeded")
+
+// A RateLimitPolicy determines what happens to writes that exceed the write rate limit.
+type RateLimitPolicy int
+
+const (
+	// RateLimitBlock blocks writes until the rate limit allows them.
+	RateLimitBlock RateLimitPolicy = iota
+	// RateLimitReject rejects writes with ErrRateLimited.
+	RateLimitReject
+)
+
+// A rateLimiter is a token bucket implemented as a generic cell rate algorithm. The whole state is the
+// theoretical arrival time of the next write, which is updated with compare-and-swap so writers never lock.
+type rateLimiter struct {
+	opsPerSecond int
+	burst        int
+	interval     int64
+	policy       RateLimitPolicy
+	tat          atomic.Int64
+}
+
+// newRateLimiter creates a rateLimiter allowing opsPerSecond writes with bursts of up to burst writes.
+func newRateLimiter(opsPerSecond int, burst int, policy RateLimitPolicy) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		opsPerSecond: opsPerSecond,
+		burst:        burst,
+		interval:     int64(time.Second) / int64(opsPerSecond),
+		policy:       policy,
+	}
+}
+
+// take takes n tokens. Depending on the policy, it waits until the tokens are available or returns
+// ErrRateLimited without taking any token.
+func (l *rateLimiter) take(n int) error {
+	for {
+		now := time.Now().UnixNano()
+		tat := l.tat.Load()
+		next := max(tat, now) + int64(n)*l.interval
+		allowAt := next - int64(l.burst)*l.interval
+		if allowAt > now && l.policy == RateLimitReject {
+			return ErrRateLimited
+		}
+		if !l.tat.CompareAndSwap(tat, next) {
+			continue
+		}
+		if allowAt > now {
+			time.Sleep(time.Duration(allowAt - now))
+		}
+		return nil
+	}
+}
+
+// takeWriteTokens takes n tokens from the write rate limiter if one is configured.
+func (d *KeyValueStore) takeWriteTokens(n int) error {
+	limiter := d.rateLimiter.Load()
+	if limiter == nil || n == 0 {
+		return nil
+	}
+	return limiter.take(n)
+}
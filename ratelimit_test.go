@@ -0,0 +1,93 @@
+package goKeyValueStore_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestWriteRateLimitThroughput(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, "", goKeyValueStore.WithGlobalWriteRateLimit(100, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	for i := 0; i < 30; i++ {
+		store.Set(fmt.Sprintf("key%d", i), i, 0)
+	}
+	elapsed := time.Since(start)
+	if elapsed < 180*time.Millisecond || elapsed > 400*time.Millisecond {
+		t.Errorf("Expected 30 writes at 100/s with a burst of 10 to take about 200ms, took %s", elapsed)
+	}
+}
+
+func TestWriteRateLimitBurst(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, "", goKeyValueStore.WithGlobalWriteRateLimit(1, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		store.Set(fmt.Sprintf("key%d", i), i, 0)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("Expected a burst of 10 writes to be immediate, took %s", elapsed)
+	}
+}
+
+func TestWriteRateLimitReject(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, "",
+		goKeyValueStore.WithGlobalWriteRateLimit(1, 2),
+		goKeyValueStore.WithRateLimitPolicy(goKeyValueStore.RateLimitReject))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := store.Set(fmt.Sprintf("key%d", i), i, 0); err != nil {
+			t.Errorf("Expected write %d to be allowed, got %v", i, err)
+		}
+	}
+	err = store.Set("key2", 2, 0)
+	if !errors.Is(err, goKeyValueStore.ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, got %v", err)
+	}
+	results := store.SetManyDetailed([]goKeyValueStore.Entry{{Key: "key3", Value: 3}})
+	if results[0].Applied || !errors.Is(results[0].Err, goKeyValueStore.ErrRateLimited) {
+		t.Errorf("Expected entry to be rate limited, got %+v", results[0])
+	}
+	if store.Length() != 2 {
+		t.Errorf("Expected length to be 2, got %d", store.Length())
+	}
+	limit := 0
+	err = store.Reconfigure(goKeyValueStore.ConfigPatch{WriteRateLimit: &limit})
+	if err != nil {
+		t.Error(err)
+	}
+	if err := store.Set("key2", 2, 0); err != nil {
+		t.Errorf("Expected write to be allowed without limit, got %v", err)
+	}
+}
+
+func TestWriteRateLimitDoesNotThrottleGet(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, "", goKeyValueStore.WithGlobalWriteRateLimit(2, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("key1", "value1", 0)
+	go func() {
+		for i := 0; i < 3; i++ {
+			store.Set(fmt.Sprintf("other%d", i), i, 0)
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	start := time.Now()
+	for i := 0; i < 1000; i++ {
+		store.Get("key1")
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Expected reads to be unaffected by throttled writes, took %s", elapsed)
+	}
+}
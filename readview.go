@@ -0,0 +1,87 @@
+package goKeyValueStore
+
+import (
+	"maps"
+	"sync/atomic"
+	"time"
+)
+
+// readViewInterval is the minimum time between two copies of the map for WithReadOptimized. It bounds how long
+// Get may miss a change.
+const readViewInterval = 10 * time.Millisecond
+
+// A readView is an immutable copy of the map that Get reads without a lock. Writes mark it as dirty and a
+// goroutine publishes a new copy at most every readViewInterval.
+type readView struct {
+	data  atomic.Pointer[map[string]*node]
+	dirty chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// startReadView publishes the first copy of the map and starts the goroutine that publishes later copies.
+func (d *KeyValueStore) startReadView() {
+	view := &readView{dirty: make(chan struct{}, 1), stop: make(chan struct{}), done: make(chan struct{})}
+	d.readView = view
+	d.publishReadView()
+	go d.refreshReadView(view)
+}
+
+// refreshReadView publishes a new copy of the map after changes until the view is stopped.
+func (d *KeyValueStore) refreshReadView(view *readView) {
+	defer close(view.done)
+	for {
+		select {
+		case <-view.stop:
+			return
+		case <-view.dirty:
+			d.publishReadView()
+		}
+		select {
+		case <-view.stop:
+			return
+		case <-time.After(readViewInterval):
+		}
+	}
+}
+
+// publishReadView copies the map and replaces the view with the copy.
+func (d *KeyValueStore) publishReadView() {
+	d.mu.RLock()
+	data := maps.Clone(d.data)
+	d.mu.RUnlock()
+	d.readView.data.Store(&data)
+}
+
+// markReadViewDirty tells the goroutine of the view that the map changed. It never blocks.
+func (d *KeyValueStore) markReadViewDirty() {
+	if d.readView == nil {
+		return
+	}
+	select {
+	case d.readView.dirty <- struct{}{}:
+	default:
+	}
+}
+
+// stopReadView stops the goroutine of the view.
+func (d *KeyValueStore) stopReadView() {
+	if d.readView == nil {
+		return
+	}
+	close(d.readView.stop)
+	<-d.readView.done
+}
+
+// getFromReadView gets a value by key from the view without a lock.
+func (d *KeyValueStore) getFromReadView(key string) (any, bool) {
+	node, ok := (*d.readView.data.Load())[key]
+	if !ok || d.nodeIsExpired(node) || d.closed.Load() {
+		return nil, false
+	}
+	value, err := node.value()
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
@@ -0,0 +1,102 @@
+package goKeyValueStore_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func getReadOptimizedStore(t testing.TB, clock *fakeClock) *goKeyValueStore.KeyValueStore {
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, "", goKeyValueStore.WithClock(clock.Now),
+		goKeyValueStore.WithCleanerStopped(true), goKeyValueStore.WithReadOptimized(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestReadOptimizedStaleness(t *testing.T) {
+	store := getReadOptimizedStore(t, newFakeClock())
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key%d", i)
+		start := time.Now()
+		store.Set(key, i, 0)
+		waitFor(t, func() bool {
+			_, ok := store.Get(key)
+			return ok
+		})
+		if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+			t.Errorf("Expected the set of %s to be visible within 100ms, took %s", key, elapsed)
+		}
+	}
+	store.Set("locked", "value", 0)
+	store.WithKeyLock("locked", func(h goKeyValueStore.KeyHandle) error {
+		if _, ok := h.Get(); !ok {
+			t.Errorf("Expected KeyHandle.Get to see the latest value")
+		}
+		return nil
+	})
+	calls := 0
+	factory := func() (any, error) {
+		calls++
+		return "value", nil
+	}
+	store.GetOrSetFunc("once", 0, factory)
+	store.GetOrSetFunc("once", 0, factory)
+	if calls != 1 {
+		t.Errorf("Expected the factory to be called once, got %d", calls)
+	}
+}
+
+func TestReadOptimizedDeleteAndExpiry(t *testing.T) {
+	clock := newFakeClock()
+	store := getReadOptimizedStore(t, clock)
+	store.Set("deleted", "value", 0)
+	store.Set("expiring", "value", 1000)
+	waitFor(t, func() bool {
+		_, ok := store.Get("deleted")
+		return ok
+	})
+	store.Delete("deleted")
+	waitFor(t, func() bool {
+		_, ok := store.Get("deleted")
+		return !ok
+	})
+	clock.Advance(2 * time.Second)
+	if _, ok := store.Get("expiring"); ok {
+		t.Errorf("Expected expiring to be expired")
+	}
+	store.CleanNow()
+	if store.Length() != 0 {
+		t.Errorf("Expected the cleaner to remove expiring, got %d entries", store.Length())
+	}
+}
+
+func BenchmarkGetParallel(b *testing.B) {
+	for _, readOptimized := range []bool{false, true} {
+		b.Run(fmt.Sprintf("readOptimized=%v", readOptimized), func(b *testing.B) {
+			store, err := goKeyValueStore.NewKeyValueStore(0.5, "", goKeyValueStore.WithCleanerStopped(true),
+				goKeyValueStore.WithReadOptimized(readOptimized))
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer store.Close()
+			keys := make([]string, 1000)
+			for i := range keys {
+				keys[i] = fmt.Sprintf("key%d", i)
+				store.Set(keys[i], i, 0)
+			}
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					store.Get(keys[i%len(keys)])
+					i++
+				}
+			})
+		})
+	}
+}
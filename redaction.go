@@ -0,0 +1,44 @@
+package goKeyValueStore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// A RedactionMode determines how keys appear in error messages and other diagnostic output.
+type RedactionMode int
+
+const (
+	// RedactionNone shows keys unchanged.
+	RedactionNone RedactionMode = iota
+	// RedactionHash replaces keys with a short stable digest so they can still be correlated.
+	RedactionHash
+	// RedactionTruncate shows only the first few characters of keys.
+	RedactionTruncate
+)
+
+// redactTruncateLength is the number of characters of a key shown by RedactionTruncate.
+const redactTruncateLength = 4
+
+// redactKey returns the key as it should appear in diagnostic output.
+func (d *KeyValueStore) redactKey(key string) string {
+	switch d.redaction {
+	case RedactionHash:
+		sum := sha256.Sum256([]byte(key))
+		return "sha256:" + hex.EncodeToString(sum[:6])
+	case RedactionTruncate:
+		runes := []rune(key)
+		if len(runes) <= redactTruncateLength {
+			return "..."
+		}
+		return string(runes[:redactTruncateLength]) + "..."
+	default:
+		return key
+	}
+}
+
+// keyError wraps an error of an operation on a key. The key is redacted according to the redaction mode.
+func (d *KeyValueStore) keyError(op string, key string, err error) error {
+	return fmt.Errorf("failed to %s for key %s: %w", op, d.redactKey(key), err)
+}
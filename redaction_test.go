@@ -0,0 +1,62 @@
+package goKeyValueStore_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/richi0/goKeyValueStore"
+	"github.com/richi0/goKeyValueStore/faultfs"
+)
+
+const SECRET_KEY = "user:alice@example.com"
+
+func TestKeyRedactionNone(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Set(SECRET_KEY, math.NaN(), 100)
+	if err == nil || !strings.Contains(err.Error(), SECRET_KEY) {
+		t.Errorf("Expected error to contain the key, got %v", err)
+	}
+}
+
+func TestKeyRedactionHash(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, t.TempDir(), goKeyValueStore.WithKeyRedaction(goKeyValueStore.RedactionHash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Set(SECRET_KEY, math.NaN(), 100)
+	if err == nil || strings.Contains(err.Error(), "alice") {
+		t.Errorf("Expected error without the key, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "sha256:") {
+		t.Errorf("Expected error to contain a key digest, got %v", err)
+	}
+	otherErr := store.Set(SECRET_KEY, math.Inf(1), 100)
+	prefix := strings.SplitN(err.Error(), ": json", 2)[0]
+	if otherErr == nil || !strings.HasPrefix(otherErr.Error(), prefix) {
+		t.Errorf("Expected the same digest for the same key, got %v and %v", err, otherErr)
+	}
+}
+
+func TestKeyRedactionTruncateWriteError(t *testing.T) {
+	fsys := faultfs.New(nil)
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, t.TempDir(), goKeyValueStore.WithKeyRedaction(goKeyValueStore.RedactionTruncate),
+		goKeyValueStore.WithFilesystem(fsys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys.FailKeys(SECRET_KEY, faultfs.OpWrite, nil)
+	err = store.Set(SECRET_KEY, "value", 100)
+	if err == nil || strings.Contains(err.Error(), "alice") {
+		t.Errorf("Expected error without the key, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "user...") {
+		t.Errorf("Expected error to contain the truncated key, got %v", err)
+	}
+	if _, ok := store.Get(SECRET_KEY); !ok {
+		t.Errorf("Expected %s to be present", SECRET_KEY)
+	}
+}
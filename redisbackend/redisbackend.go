@@ -0,0 +1,266 @@
+// Package redisbackend provides a goKeyValueStore.Backend that mirrors a store to Redis, so several stores, for
+// example the replicas of a service, share a warm cache.
+package redisbackend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+	"github.com/richi0/goKeyValueStore/internal/resp"
+)
+
+// defaultPrefix is the prefix of the Redis keys if Options.Prefix is empty.
+const defaultPrefix = "goKeyValueStore:"
+
+// defaultTimeout is the timeout of a command if Options.Timeout is 0.
+const defaultTimeout = time.Second
+
+// defaultRetryInterval is the time Redis is skipped after a connection error if Options.RetryInterval is 0.
+const defaultRetryInterval = time.Second
+
+// scanCount is the number of keys requested per SCAN call by Load.
+const scanCount = 100
+
+// Options configures a Backend.
+type Options struct {
+	// Addr is the address of the Redis server.
+	Addr string
+	// Prefix is prepended to the keys in Redis, so stores that do not share entries can use the same server.
+	// The default is "goKeyValueStore:".
+	Prefix string
+	// ReadThrough makes Get fetch keys the store does not have from Redis, for example keys set by another store.
+	ReadThrough bool
+	// Timeout limits the connection and every command. The default is one second.
+	Timeout time.Duration
+	// RetryInterval is the time after a connection error during which Redis is skipped. The default is one second.
+	RetryInterval time.Duration
+	// Now is the clock the TTLs of the Redis keys are computed with. It must be the clock of the store, see
+	// goKeyValueStore.WithClock. The default is time.Now.
+	Now func() time.Time
+}
+
+// A Backend mirrors Set to SET with PX matching the TTL of the entry and Delete to DEL. A store with it loads all
+// keys with the prefix when it is created.
+//
+// Connection errors do not fail writes: they are returned wrapped in goKeyValueStore.ErrBackendUnavailable, which
+// the store reports to its OnError function, and Redis is skipped for RetryInterval, so the store keeps working
+// in memory only until Redis is reachable again. Writes made while Redis is skipped are not mirrored.
+type Backend struct {
+	opts    Options
+	mu      sync.Mutex
+	client  *resp.Client
+	retryAt time.Time
+}
+
+// A record is the value of a key in Redis.
+type record struct {
+	Value           any    `json:"value"`
+	DeleteTimestamp int64  `json:"deleteTimestamp"`
+	Revision        uint64 `json:"revision,omitempty"`
+	UpdatedAt       int64  `json:"updatedAt,omitempty"`
+	CreatedAt       int64  `json:"createdAt,omitempty"`
+}
+
+// errSkipped is returned by do while Redis is skipped after a connection error.
+var errSkipped = errors.New("redis is skipped after a connection error")
+
+// New creates a Backend. It connects to Redis on first use.
+func New(opts Options) *Backend {
+	if opts.Prefix == "" {
+		opts.Prefix = defaultPrefix
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = defaultRetryInterval
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Backend{opts: opts}
+}
+
+// Load loads all keys with the prefix.
+func (b *Backend) Load(fn func(goKeyValueStore.Record) error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	cursor := "0"
+	for {
+		reply, err := b.do("SCAN", cursor, "MATCH", escapePattern(b.opts.Prefix)+"*", "COUNT", strconv.Itoa(scanCount))
+		if err != nil {
+			return err
+		}
+		page, ok := reply.([]any)
+		if !ok || len(page) != 2 {
+			return fmt.Errorf("invalid SCAN reply %v", reply)
+		}
+		cursor, ok = page[0].(string)
+		if !ok {
+			return fmt.Errorf("invalid SCAN cursor %v", page[0])
+		}
+		keys, err := resp.ReplyStrings(page[1])
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			record, found, err := b.get(strings.TrimPrefix(key, b.opts.Prefix))
+			if err != nil {
+				return err
+			}
+			if !found {
+				continue // the key expired or was deleted after it was scanned
+			}
+			err = fn(record)
+			if err != nil {
+				return err
+			}
+		}
+		if cursor == "0" {
+			return nil
+		}
+	}
+}
+
+// Fetch gets the record of key from Redis if Options.ReadThrough is set.
+func (b *Backend) Fetch(key string) (goKeyValueStore.Record, bool, error) {
+	if !b.opts.ReadThrough {
+		return goKeyValueStore.Record{}, false, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	record, found, err := b.get(key)
+	if errors.Is(err, errSkipped) {
+		return goKeyValueStore.Record{}, false, nil
+	}
+	return record, found, err
+}
+
+// Save sets the Redis key of the record with the remaining TTL. Expired records are deleted instead.
+func (b *Backend) Save(r goKeyValueStore.Record) error {
+	data, err := json.Marshal(record{
+		Value:           r.Value,
+		DeleteTimestamp: r.DeleteTimestamp,
+		Revision:        r.Revision,
+		UpdatedAt:       r.UpdatedAt,
+		CreatedAt:       r.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	args := []string{"SET", b.opts.Prefix + r.Key, string(data)}
+	if r.DeleteTimestamp != math.MaxInt64 {
+		ttl := r.DeleteTimestamp - b.opts.Now().UnixMilli()
+		if ttl <= 0 {
+			args = []string{"DEL", b.opts.Prefix + r.Key}
+		} else {
+			args = append(args, "PX", strconv.FormatInt(ttl, 10))
+		}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, err = b.do(args...)
+	if errors.Is(err, errSkipped) {
+		return nil
+	}
+	return err
+}
+
+// Delete deletes the Redis key of key.
+func (b *Backend) Delete(key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, err := b.do("DEL", b.opts.Prefix+key)
+	if errors.Is(err, errSkipped) {
+		return nil
+	}
+	return err
+}
+
+// Close closes the connection to Redis.
+func (b *Backend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.client == nil {
+		return nil
+	}
+	err := b.client.Close()
+	b.client = nil
+	return err
+}
+
+// get reads the record of key. The caller must hold mu.
+func (b *Backend) get(key string) (goKeyValueStore.Record, bool, error) {
+	reply, err := b.do("GET", b.opts.Prefix+key)
+	if err != nil || reply == nil {
+		return goKeyValueStore.Record{}, false, err
+	}
+	data, ok := reply.(string)
+	if !ok {
+		return goKeyValueStore.Record{}, false, fmt.Errorf("invalid GET reply %v", reply)
+	}
+	var stored record
+	err = json.Unmarshal([]byte(data), &stored)
+	if err != nil {
+		return goKeyValueStore.Record{}, false, &goKeyValueStore.RecordError{Key: key, Err: fmt.Errorf("%w: %w", goKeyValueStore.ErrCorruptFile, err)}
+	}
+	return goKeyValueStore.Record{
+		Key:             key,
+		Value:           stored.Value,
+		DeleteTimestamp: stored.DeleteTimestamp,
+		Revision:        stored.Revision,
+		UpdatedAt:       stored.UpdatedAt,
+		CreatedAt:       stored.CreatedAt,
+	}, true, nil
+}
+
+// do sends a command and connects first if needed. Connection errors close the connection, skip Redis for
+// RetryInterval, and are returned wrapped in goKeyValueStore.ErrBackendUnavailable. The caller must hold mu.
+func (b *Backend) do(args ...string) (any, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.opts.Timeout)
+	defer cancel()
+	if b.client == nil {
+		if time.Now().Before(b.retryAt) {
+			return nil, errSkipped
+		}
+		client, err := resp.Dial(ctx, b.opts.Addr)
+		if err != nil {
+			return nil, b.unavailable(err)
+		}
+		b.client = client
+	}
+	reply, err := b.client.Do(ctx, args...)
+	var replyErr resp.Error
+	if err != nil && !errors.As(err, &replyErr) {
+		b.client.Close()
+		b.client = nil
+		return nil, b.unavailable(err)
+	}
+	return reply, err
+}
+
+// unavailable skips Redis for RetryInterval and wraps err in goKeyValueStore.ErrBackendUnavailable.
+func (b *Backend) unavailable(err error) error {
+	b.retryAt = time.Now().Add(b.opts.RetryInterval)
+	return fmt.Errorf("%w: %w", goKeyValueStore.ErrBackendUnavailable, err)
+}
+
+// escapePattern escapes the characters of s that have a meaning in the glob-style patterns of SCAN MATCH.
+func escapePattern(s string) string {
+	var escaped strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			escaped.WriteByte('\\')
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
@@ -0,0 +1,315 @@
+package redisbackend_test
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+	"github.com/richi0/goKeyValueStore/redisbackend"
+	"github.com/richi0/goKeyValueStore/storetest"
+)
+
+// stubServer is an in-process server speaking just enough RESP for the backend. Keys do not expire; their
+// PX argument is recorded.
+type stubServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	values   map[string]string
+	px       map[string]int64
+	conns    []net.Conn
+}
+
+func newStubServer(t *testing.T) *stubServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &stubServer{listener: listener, values: map[string]string{}, px: map[string]int64{}}
+	t.Cleanup(server.stop)
+	go server.serve(listener)
+	return server
+}
+
+func (s *stubServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+// stop closes the listener and all connections.
+func (s *stubServer) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listener.Close()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+// restart listens again on the same address.
+func (s *stubServer) restart(t *testing.T) {
+	listener, err := net.Listen("tcp", s.addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+	go s.serve(listener)
+}
+
+func (s *stubServer) serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *stubServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		conn.Write([]byte(s.reply(args)))
+	}
+}
+
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(line[1 : len(line)-2])
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		line, err = reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		length, err := strconv.Atoi(line[1 : len(line)-2])
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, length+2)
+		_, err = io.ReadFull(reader, data)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:length])
+	}
+	return args, nil
+}
+
+func bulk(value string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+}
+
+func (s *stubServer) reply(args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch args[0] {
+	case "SCAN":
+		names := []string{}
+		for name := range s.values {
+			if matched, _ := path.Match(args[3], name); matched {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		reply := "*2\r\n" + bulk("0") + fmt.Sprintf("*%d\r\n", len(names))
+		for _, name := range names {
+			reply += bulk(name)
+		}
+		return reply
+	case "GET":
+		value, ok := s.values[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(value)
+	case "SET":
+		s.values[args[1]] = args[2]
+		delete(s.px, args[1])
+		if len(args) == 5 && args[3] == "PX" {
+			s.px[args[1]], _ = strconv.ParseInt(args[4], 10, 64)
+		}
+		return "+OK\r\n"
+	case "DEL":
+		_, ok := s.values[args[1]]
+		delete(s.values, args[1])
+		delete(s.px, args[1])
+		if ok {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	}
+	return "-ERR unknown command\r\n"
+}
+
+// openStore opens a store that mirrors to the Redis server at addr.
+func openStore(t *testing.T, opts redisbackend.Options, storeOpts ...goKeyValueStore.Option) *goKeyValueStore.KeyValueStore {
+	t.Helper()
+	backend := redisbackend.New(opts)
+	store, err := goKeyValueStore.NewKeyValueStore(1, "", append(storeOpts, goKeyValueStore.WithBackend(backend))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestConformance(t *testing.T) {
+	storetest.RunConformance(t, func() storetest.StoreUnderTest {
+		server := newStubServer(t)
+		clock := storetest.NewClock()
+		opts := redisbackend.Options{Addr: server.addr(), Now: clock.Now}
+		storeOpts := []goKeyValueStore.Option{goKeyValueStore.WithClock(clock.Now), goKeyValueStore.WithCleanerStopped(true)}
+		store := openStore(t, opts, storeOpts...)
+		return storetest.StoreUnderTest{Store: store, Advance: clock.Advance, Close: store.Close,
+			Restart: func() (goKeyValueStore.Store, error) {
+				store.Close()
+				return openStore(t, opts, storeOpts...), nil
+			}}
+	})
+}
+
+func TestMirror(t *testing.T) {
+	server := newStubServer(t)
+	clock := storetest.NewClock()
+	store := openStore(t, redisbackend.Options{Addr: server.addr(), Prefix: "app:", Now: clock.Now},
+		goKeyValueStore.WithClock(clock.Now))
+	store.Set("session", "alice", 5000)
+	store.Set("config", "value", 0)
+	store.Set("deleted", "value", 0)
+	store.Delete("deleted")
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.values) != 2 {
+		t.Errorf("Expected 2 keys in Redis, got %v", server.values)
+	}
+	if px, ok := server.px["app:session"]; !ok || px != 5000 {
+		t.Errorf("Expected PX 5000 for the session, got %d", px)
+	}
+	if _, ok := server.px["app:config"]; ok {
+		t.Error("Expected no PX for a key without TTL")
+	}
+}
+
+func TestSharedWarmCache(t *testing.T) {
+	server := newStubServer(t)
+	opts := redisbackend.Options{Addr: server.addr(), ReadThrough: true}
+	replica1 := openStore(t, opts)
+	replica2 := openStore(t, opts)
+	replica1.Set("user:1", "alice", 60000)
+	if value, ok := replica2.Get("user:1"); !ok || value != "alice" {
+		t.Errorf("Expected replica2 to fetch alice from Redis, got %v", value)
+	}
+	if ttl, _ := replica2.TTL("user:1"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected the fetched key to keep its TTL, got %v", ttl)
+	}
+	if _, ok := replica2.Get("missing"); ok {
+		t.Error("Expected a miss for a key that is not in Redis")
+	}
+	replica3 := openStore(t, redisbackend.Options{Addr: server.addr()})
+	if replica3.Length() != 1 {
+		t.Errorf("Expected a new replica to load 1 key, got %d", replica3.Length())
+	}
+	withoutReadThrough := openStore(t, redisbackend.Options{Addr: server.addr()})
+	replica1.Set("user:2", "bob", 0)
+	if _, ok := withoutReadThrough.Get("user:2"); ok {
+		t.Error("Expected no fetch without ReadThrough")
+	}
+}
+
+func TestConnectionErrors(t *testing.T) {
+	server := newStubServer(t)
+	store := openStore(t, redisbackend.Options{Addr: server.addr(), ReadThrough: true, RetryInterval: 200 * time.Millisecond})
+	var mu sync.Mutex
+	var reported []error
+	store.OnError(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, err)
+	})
+	store.Set("key1", "value1", 0)
+	server.stop()
+
+	err := store.Set("key2", "value2", 0)
+	if err != nil {
+		t.Errorf("Expected Set to succeed without Redis, got %v", err)
+	}
+	if value, _ := store.Get("key2"); value != "value2" {
+		t.Errorf("Expected value2 from memory, got %v", value)
+	}
+	if _, ok := store.Get("missing"); ok {
+		t.Error("Expected a miss without Redis")
+	}
+	mu.Lock()
+	if len(reported) != 1 || !errors.Is(reported[0], goKeyValueStore.ErrBackendUnavailable) {
+		t.Errorf("Expected 1 ErrBackendUnavailable, got %v", reported)
+	}
+	mu.Unlock()
+
+	server.restart(t)
+	time.Sleep(250 * time.Millisecond)
+	err = store.Set("key3", "value3", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.mu.Lock()
+	if _, ok := server.values["goKeyValueStore:key3"]; !ok {
+		t.Error("Expected key3 to be mirrored after Redis is reachable again")
+	}
+	server.mu.Unlock()
+}
+
+func TestUnavailableOnStart(t *testing.T) {
+	server := newStubServer(t)
+	server.stop()
+	backend := redisbackend.New(redisbackend.Options{Addr: server.addr()})
+	store, err := goKeyValueStore.NewKeyValueStore(1, "", goKeyValueStore.WithBackend(backend))
+	if err != nil {
+		t.Fatalf("Expected the store to start without Redis, got %v", err)
+	}
+	defer store.Close()
+	var reported error
+	store.OnError(func(err error) { reported = err })
+	if !errors.Is(reported, goKeyValueStore.ErrBackendUnavailable) {
+		t.Errorf("Expected ErrBackendUnavailable on start, got %v", reported)
+	}
+}
+
+func TestCorruptRecordRedactsKey(t *testing.T) {
+	server := newStubServer(t)
+	server.values["app:secret-key"] = "not json"
+	backend := redisbackend.New(redisbackend.Options{Addr: server.addr(), Prefix: "app:"})
+	_, err := goKeyValueStore.NewKeyValueStore(1, "", goKeyValueStore.WithBackend(backend),
+		goKeyValueStore.WithKeyRedaction(goKeyValueStore.RedactionHash))
+	if !errors.Is(err, goKeyValueStore.ErrCorruptFile) {
+		t.Fatalf("Expected ErrCorruptFile, got %v", err)
+	}
+	if strings.Contains(err.Error(), "secret-key") {
+		t.Errorf("Expected the key to be redacted, got %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+// Package redisimport seeds a KeyValueStore with the keys of a Redis server.
+package redisimport
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/richi0/goKeyValueStore"
+	"github.com/richi0/goKeyValueStore/internal/resp"
+)
+
+// defaultScanCount is the number of keys requested per SCAN call if ImportOptions.ScanCount is 0.
+const defaultScanCount = 100
+
+// ImportOptions configures an import.
+type ImportOptions struct {
+	// Pattern selects the keys to import with the glob-style syntax of SCAN MATCH. Empty means all keys.
+	Pattern string
+	// ScanCount is the number of keys requested per SCAN call.
+	ScanCount int
+	// Collections imports hashes as map[string]any and lists and sets as []any.
+	// Without it, only string keys are imported.
+	Collections bool
+}
+
+// A Report lists the outcome of an import.
+type Report struct {
+	Imported []string
+	// Skipped holds keys with unsupported types and keys that disappeared during the import.
+	Skipped []string
+	Failed  map[string]error
+}
+
+// Import copies the keys of the Redis server at addr into the store. Keys keep their remaining TTL and keys
+// without TTL never expire. Errors of single keys are reported in the Report, while errors that stop the
+// import, like a failed connection or a canceled context, are returned.
+func Import(ctx context.Context, store *goKeyValueStore.KeyValueStore, addr string, opts ImportOptions) (Report, error) {
+	report := Report{Failed: map[string]error{}}
+	c, err := resp.Dial(ctx, addr)
+	if err != nil {
+		return report, err
+	}
+	defer c.Close()
+	pattern := opts.Pattern
+	if pattern == "" {
+		pattern = "*"
+	}
+	count := opts.ScanCount
+	if count <= 0 {
+		count = defaultScanCount
+	}
+	cursor := "0"
+	for {
+		reply, err := c.Do(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", strconv.Itoa(count))
+		if err != nil {
+			return report, err
+		}
+		page, ok := reply.([]any)
+		if !ok || len(page) != 2 {
+			return report, fmt.Errorf("invalid SCAN reply %v", reply)
+		}
+		cursor, ok = page[0].(string)
+		if !ok {
+			return report, fmt.Errorf("invalid SCAN cursor %v", page[0])
+		}
+		keys, err := resp.ReplyStrings(page[1])
+		if err != nil {
+			return report, err
+		}
+		for _, key := range keys {
+			if err := ctx.Err(); err != nil {
+				return report, err
+			}
+			imported, err := importKey(ctx, c, store, key, opts)
+			switch {
+			case err != nil:
+				if _, ok := err.(keyError); !ok {
+					return report, err
+				}
+				report.Failed[key] = err
+			case imported:
+				report.Imported = append(report.Imported, key)
+			default:
+				report.Skipped = append(report.Skipped, key)
+			}
+		}
+		if cursor == "0" {
+			return report, nil
+		}
+	}
+}
+
+// A keyError is an error that prevents a single key from being imported without stopping the import.
+type keyError struct {
+	err error
+}
+
+func (e keyError) Error() string {
+	return e.err.Error()
+}
+
+func (e keyError) Unwrap() error {
+	return e.err
+}
+
+// importKey copies a single key into the store. It returns false if the key was skipped.
+// Errors of the Redis server for the key and errors of the store are returned as keyError.
+func importKey(ctx context.Context, c *resp.Client, store *goKeyValueStore.KeyValueStore, key string, opts ImportOptions) (bool, error) {
+	imported, err := readAndSet(ctx, c, store, key, opts)
+	if _, ok := err.(resp.Error); ok {
+		return false, keyError{err}
+	}
+	return imported, err
+}
+
+// readAndSet reads a single key from the Redis server and sets it in the store.
+func readAndSet(ctx context.Context, c *resp.Client, store *goKeyValueStore.KeyValueStore, key string, opts ImportOptions) (bool, error) {
+	reply, err := c.Do(ctx, "TYPE", key)
+	if err != nil {
+		return false, err
+	}
+	var value any
+	switch reply {
+	case "string":
+		value, err = c.Do(ctx, "GET", key)
+	case "hash":
+		if !opts.Collections {
+			return false, nil
+		}
+		value, err = readHash(ctx, c, key)
+	case "list":
+		if !opts.Collections {
+			return false, nil
+		}
+		value, err = readCollection(ctx, c, "LRANGE", key, "0", "-1")
+	case "set":
+		if !opts.Collections {
+			return false, nil
+		}
+		value, err = readCollection(ctx, c, "SMEMBERS", key)
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if value == nil {
+		return false, nil // the key was deleted after it was scanned
+	}
+	reply, err = c.Do(ctx, "PTTL", key)
+	if err != nil {
+		return false, err
+	}
+	pttl, ok := reply.(int64)
+	if !ok {
+		return false, fmt.Errorf("invalid PTTL reply %v", reply)
+	}
+	ttl := 0
+	switch {
+	case pttl == -2:
+		return false, nil // the key expired after it was read
+	case pttl > 0:
+		ttl = int(pttl)
+	}
+	err = store.Set(key, value, ttl)
+	if err != nil {
+		return false, keyError{err}
+	}
+	return true, nil
+}
+
+// readHash reads a hash as a map.
+func readHash(ctx context.Context, c *resp.Client, key string) (any, error) {
+	reply, err := c.Do(ctx, "HGETALL", key)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := resp.ReplyStrings(reply)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	hash := make(map[string]any, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		hash[fields[i]] = fields[i+1]
+	}
+	return hash, nil
+}
+
+// readCollection reads a list or set with the given command as a slice.
+func readCollection(ctx context.Context, c *resp.Client, args ...string) (any, error) {
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	members, err := resp.ReplyStrings(reply)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+	values := make([]any, len(members))
+	for i, member := range members {
+		values[i] = member
+	}
+	return values, nil
+}
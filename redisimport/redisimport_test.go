@@ -0,0 +1,262 @@
+package redisimport_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+	"github.com/richi0/goKeyValueStore/redisimport"
+)
+
+type stubKey struct {
+	kind  string
+	value any
+	pttl  int64
+}
+
+// stubServer is an in-process server speaking just enough RESP for the importer.
+type stubServer struct {
+	listener net.Listener
+	keys     map[string]stubKey
+	mu       sync.Mutex
+	scans    int
+}
+
+func newStubServer(t *testing.T, keys map[string]stubKey) *stubServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &stubServer{listener: listener, keys: keys}
+	t.Cleanup(func() { listener.Close() })
+	go server.serve()
+	return server
+}
+
+func (s *stubServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *stubServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		conn.Write([]byte(s.reply(args)))
+	}
+}
+
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(line[1 : len(line)-2])
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		line, err = reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		length, err := strconv.Atoi(line[1 : len(line)-2])
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, length+2)
+		_, err = io.ReadFull(reader, data)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:length])
+	}
+	return args, nil
+}
+
+func bulk(value string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+}
+
+func array(values []string) string {
+	reply := fmt.Sprintf("*%d\r\n", len(values))
+	for _, value := range values {
+		reply += bulk(value)
+	}
+	return reply
+}
+
+func (s *stubServer) reply(args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var key stubKey
+	var ok bool
+	if len(args) > 1 {
+		key, ok = s.keys[args[1]]
+	}
+	switch args[0] {
+	case "SCAN":
+		s.scans++
+		cursor, _ := strconv.Atoi(args[1])
+		count, _ := strconv.Atoi(args[5])
+		names := []string{}
+		for name := range s.keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		page := []string{}
+		for _, name := range names[cursor:min(cursor+count, len(names))] {
+			if matched, _ := path.Match(args[3], name); matched {
+				page = append(page, name)
+			}
+		}
+		next := cursor + count
+		if next >= len(names) {
+			next = 0
+		}
+		return "*2\r\n" + bulk(strconv.Itoa(next)) + array(page)
+	case "TYPE":
+		if !ok {
+			return "+none\r\n"
+		}
+		return "+" + key.kind + "\r\n"
+	case "PTTL":
+		if !ok {
+			return ":-2\r\n"
+		}
+		return fmt.Sprintf(":%d\r\n", key.pttl)
+	case "GET":
+		if key.value == nil {
+			return "-ERR broken key\r\n"
+		}
+		return bulk(key.value.(string))
+	case "HGETALL", "LRANGE", "SMEMBERS":
+		return array(key.value.([]string))
+	}
+	return "-ERR unknown command\r\n"
+}
+
+func TestImport(t *testing.T) {
+	keys := map[string]stubKey{
+		"session:1": {kind: "string", value: "alice", pttl: 5000},
+		"session:2": {kind: "string", value: "bob", pttl: -1},
+		"profile:1": {kind: "hash", value: []string{"name", "alice", "age", "30"}, pttl: -1},
+		"queue":     {kind: "list", value: []string{"a", "b"}, pttl: 60000},
+		"tags":      {kind: "set", value: []string{"x"}, pttl: -1},
+		"ranking":   {kind: "zset", pttl: -1},
+		"broken":    {kind: "string", pttl: -1},
+	}
+	for i := 0; i < 20; i++ {
+		keys[fmt.Sprintf("counter:%02d", i)] = stubKey{kind: "string", value: strconv.Itoa(i), pttl: -1}
+	}
+	server := newStubServer(t, keys)
+	now := time.Now()
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, "",
+		goKeyValueStore.WithClock(func() time.Time { return now }),
+		goKeyValueStore.WithCleanerStopped(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := redisimport.Import(context.Background(), store, server.listener.Addr().String(), redisimport.ImportOptions{
+		ScanCount:   10,
+		Collections: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.mu.Lock()
+	if server.scans != 3 {
+		t.Errorf("Expected 3 SCAN pages, got %d", server.scans)
+	}
+	server.mu.Unlock()
+	if len(report.Imported) != 25 {
+		t.Errorf("Expected 25 imported keys, got %d", len(report.Imported))
+	}
+	if len(report.Skipped) != 1 || report.Skipped[0] != "ranking" {
+		t.Errorf("Expected ranking to be skipped, got %v", report.Skipped)
+	}
+	if len(report.Failed) != 1 || report.Failed["broken"] == nil {
+		t.Errorf("Expected broken to fail, got %v", report.Failed)
+	}
+	val, _ := store.Get("profile:1")
+	if profile, ok := val.(map[string]any); !ok || profile["name"] != "alice" {
+		t.Errorf("Expected the hash as a map, got %v", val)
+	}
+	val, _ = store.Get("queue")
+	if queue, ok := val.([]any); !ok || len(queue) != 2 {
+		t.Errorf("Expected the list as a slice, got %v", val)
+	}
+	now = now.Add(10 * time.Second)
+	if _, ok := store.Get("session:1"); ok {
+		t.Errorf("Expected session:1 to expire with its Redis TTL")
+	}
+	if _, ok := store.Get("session:2"); !ok {
+		t.Errorf("Expected session:2 to never expire")
+	}
+	if _, ok := store.Get("queue"); !ok {
+		t.Errorf("Expected queue to be present")
+	}
+}
+
+func TestImportPatternWithoutCollections(t *testing.T) {
+	server := newStubServer(t, map[string]stubKey{
+		"session:1": {kind: "string", value: "alice", pttl: -1},
+		"session:2": {kind: "hash", value: []string{"name", "bob"}, pttl: -1},
+		"user:1":    {kind: "string", value: "carol", pttl: -1},
+	})
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := redisimport.Import(context.Background(), store, server.listener.Addr().String(), redisimport.ImportOptions{
+		Pattern: "session:*",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Imported) != 1 || report.Imported[0] != "session:1" {
+		t.Errorf("Expected only session:1 to be imported, got %v", report.Imported)
+	}
+	if len(report.Skipped) != 1 || report.Skipped[0] != "session:2" {
+		t.Errorf("Expected session:2 to be skipped, got %v", report.Skipped)
+	}
+	if store.Length() != 1 {
+		t.Errorf("Expected length to be 1, got %d", store.Length())
+	}
+}
+
+func TestImportConnectionError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = redisimport.Import(context.Background(), store, addr, redisimport.ImportOptions{})
+	if err == nil {
+		t.Error("Expected a connection error")
+	}
+}
@@ -0,0 +1,114 @@
+package goKeyValueStore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// A revalidator holds the state of WithValidator.
+type revalidator struct {
+	validate func(ctx context.Context, key string, value any) (bool, error)
+	after    time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+	mu       sync.Mutex
+	running  map[string]bool
+	retries  map[string]revalidateRetry
+}
+
+// A revalidateRetry delays the next validation of a key after a failed one.
+type revalidateRetry struct {
+	at      time.Time
+	backoff time.Duration
+}
+
+// newRevalidator creates the state of WithValidator.
+func newRevalidator() *revalidator {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &revalidator{
+		ctx:     ctx,
+		cancel:  cancel,
+		running: map[string]bool{},
+		retries: map[string]revalidateRetry{},
+	}
+}
+
+// revalidate starts a validation of a key in the background if its entry was updated longer than the
+// revalidation age ago. Only one validation of a key runs at a time, and failed keys wait for their backoff.
+func (d *KeyValueStore) revalidate(rawKey string, key string) {
+	if d.revalidator == nil || d.revalidator.validate == nil || d.revalidator.after <= 0 {
+		return
+	}
+	now := d.now()
+	d.mu.RLock()
+	node, ok := d.data[key]
+	if !ok || d.nodeIsExpired(node) || now.Sub(time.UnixMilli(node.UpdatedAt)) < d.revalidator.after {
+		d.mu.RUnlock()
+		return
+	}
+	revision := node.Revision
+	value, err := node.value()
+	d.mu.RUnlock()
+	if err != nil {
+		return
+	}
+	r := d.revalidator
+	r.mu.Lock()
+	if r.running[key] || now.Before(r.retries[key].at) || d.closed.Load() {
+		r.mu.Unlock()
+		return
+	}
+	r.running[key] = true
+	d.background.Add(1)
+	r.mu.Unlock()
+	go func() {
+		defer d.background.Done()
+		valid, err := r.validate(r.ctx, rawKey, value)
+		r.mu.Lock()
+		delete(r.running, key)
+		if err != nil {
+			retry := r.retries[key]
+			retry.backoff = min(max(2*retry.backoff, minRetryBackoff), maxRetryBackoff)
+			retry.at = d.now().Add(retry.backoff)
+			r.retries[key] = retry
+		} else {
+			delete(r.retries, key)
+		}
+		r.mu.Unlock()
+		if err != nil {
+			d.reportError(d.keyError("revalidate", key, err))
+			return
+		}
+		err = d.applyValidation(key, revision, valid)
+		if err != nil {
+			d.reportError(err)
+		}
+	}()
+}
+
+// applyValidation bumps the update time of a valid entry or deletes an invalid one. Entries that were changed
+// since the validation started are left as they are.
+func (d *KeyValueStore) applyValidation(key string, revision uint64, valid bool) error {
+	defer d.afterWrite()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	node, ok := d.data[key]
+	if !ok || node.Revision != revision || d.closed.Load() {
+		return nil
+	}
+	if !valid {
+		d.recordEvent(key, EventDeleted, "invalidated by validator")
+		d.removeNode(key)
+		return errors.Join(d.deleteInCache(key), d.removeDerived(key))
+	}
+	updated, err := node.withDeleteTimestamp(node.DeleteTimestamp)
+	if err != nil {
+		return err
+	}
+	updated.Revision = node.Revision
+	updated.UpdatedAt = d.now().UnixMilli()
+	d.putNode(updated)
+	return d.saveInCache(updated)
+}
@@ -0,0 +1,122 @@
+package goKeyValueStore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+// A scriptedValidator answers validations with the results sent to it.
+type scriptedValidator struct {
+	calls   chan string
+	results chan error
+}
+
+// errInvalid makes a scriptedValidator report the value as invalid.
+var errInvalid = errors.New("invalid")
+
+func newScriptedValidator() *scriptedValidator {
+	return &scriptedValidator{calls: make(chan string, 10), results: make(chan error)}
+}
+
+func (v *scriptedValidator) validate(ctx context.Context, key string, value any) (bool, error) {
+	v.calls <- key
+	select {
+	case err := <-v.results:
+		if errors.Is(err, errInvalid) {
+			return false, nil
+		}
+		return err == nil, err
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+func getTestStoreWithValidator(t *testing.T, clock *fakeClock, validator *scriptedValidator) *goKeyValueStore.KeyValueStore {
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, t.TempDir(), goKeyValueStore.WithClock(clock.Now),
+		goKeyValueStore.WithCleanerStopped(true), goKeyValueStore.WithValidator(validator.validate),
+		goKeyValueStore.WithRevalidateAfter(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestRevalidateValid(t *testing.T) {
+	clock := newFakeClock()
+	validator := newScriptedValidator()
+	store := getTestStoreWithValidator(t, clock, validator)
+	store.Set("page", "etag1", 0)
+	store.Get("page")
+	if len(validator.calls) != 0 {
+		t.Fatal("Expected no validation of a fresh entry")
+	}
+	clock.Advance(2 * time.Minute)
+	start := time.Now()
+	if value, ok := store.Get("page"); !ok || value != "etag1" {
+		t.Errorf("Expected the value while it is validated, got %v", value)
+	}
+	if key := <-validator.calls; key != "page" {
+		t.Errorf("Expected page to be validated, got %s", key)
+	}
+	store.Get("page")
+	if time.Since(start) > 100*time.Millisecond {
+		t.Errorf("Expected Get to not wait for the validator, took %s", time.Since(start))
+	}
+	if len(validator.calls) != 0 {
+		t.Errorf("Expected one validation at a time")
+	}
+	validator.results <- nil
+	waitFor(t, func() bool {
+		metadata, _ := store.GetMetadata("page")
+		return metadata.UpdatedAt.Equal(clock.Now())
+	})
+}
+
+func TestRevalidateInvalid(t *testing.T) {
+	clock := newFakeClock()
+	validator := newScriptedValidator()
+	store := getTestStoreWithValidator(t, clock, validator)
+	store.Set("page", "etag1", 0)
+	clock.Advance(2 * time.Minute)
+	store.Get("page")
+	<-validator.calls
+	validator.results <- errInvalid
+	waitFor(t, func() bool { return !store.Has("page") })
+	if explanation := store.Explain("page"); explanation.LastEvent.Kind != goKeyValueStore.EventDeleted {
+		t.Errorf("Expected page to be deleted, got %v", explanation.LastEvent.Kind)
+	}
+}
+
+func TestRevalidateErrorBacksOff(t *testing.T) {
+	clock := newFakeClock()
+	validator := newScriptedValidator()
+	store := getTestStoreWithValidator(t, clock, validator)
+	errs := make(chan error, 10)
+	store.OnError(func(err error) { errs <- err })
+	store.Set("page", "etag1", 0)
+	clock.Advance(2 * time.Minute)
+	store.Get("page")
+	<-validator.calls
+	validator.results <- errors.New("origin unavailable")
+	<-errs
+	store.Get("page")
+	clock.Advance(500 * time.Millisecond)
+	store.Get("page")
+	select {
+	case <-validator.calls:
+		t.Fatal("Expected no validation during the backoff")
+	case <-time.After(50 * time.Millisecond):
+	}
+	clock.Advance(time.Second)
+	store.Get("page")
+	<-validator.calls
+	validator.results <- nil
+	if _, ok := store.Get("page"); !ok {
+		t.Errorf("Expected page to be kept after an error")
+	}
+}
@@ -0,0 +1,265 @@
+package s3backend
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash is the SHA-256 hash of an empty request body.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// HTTPClientOptions configures an HTTPClient.
+type HTTPClientOptions struct {
+	// Endpoint is the URL of the service, for example "https://s3.eu-central-1.amazonaws.com".
+	Endpoint string
+	// Region is the region requests are signed for, for example "eu-central-1".
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is the token of temporary credentials. It is empty for long-term credentials.
+	SessionToken string
+	// HTTPClient sends the requests. The default is http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// An HTTPClient is a Client for S3 and compatible object stores. It signs requests with AWS Signature Version 4
+// and addresses the bucket in the path of the URL.
+type HTTPClient struct {
+	opts HTTPClientOptions
+	now  func() time.Time
+}
+
+// An HTTPError is a response of the service with an unexpected status.
+type HTTPError struct {
+	StatusCode int
+	// Code is the error code of the response body, for example "AccessDenied", if it has one.
+	Code string
+}
+
+func (e *HTTPError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("s3: status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("s3: status %d: %s", e.StatusCode, e.Code)
+}
+
+// Temporary returns true for server errors and throttling, which are worth a retry.
+func (e *HTTPError) Temporary() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+}
+
+// NewHTTPClient creates an HTTPClient.
+func NewHTTPClient(opts HTTPClientOptions) *HTTPClient {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	opts.Endpoint = strings.TrimSuffix(opts.Endpoint, "/")
+	return &HTTPClient{opts: opts, now: time.Now}
+}
+
+// PutObject uploads an object.
+func (c *HTTPClient) PutObject(ctx context.Context, key string, data []byte) error {
+	response, err := c.do(ctx, http.MethodPut, key, nil, data)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	return nil
+}
+
+// GetObject downloads an object.
+func (c *HTTPClient) GetObject(ctx context.Context, key string) ([]byte, error) {
+	response, err := c.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	return io.ReadAll(response.Body)
+}
+
+// DeleteObject deletes an object.
+func (c *HTTPClient) DeleteObject(ctx context.Context, key string) error {
+	response, err := c.do(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	return nil
+}
+
+// A listBucketResult is the response body of ListObjectsV2.
+type listBucketResult struct {
+	Contents []struct {
+		Key string
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+// ListObjects lists the keys of all objects with prefix with ListObjectsV2, page by page.
+func (c *HTTPClient) ListObjects(ctx context.Context, prefix string) ([]string, error) {
+	keys := []string{}
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	for {
+		response, err := c.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(response.Body).Decode(&result)
+		response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, content := range result.Contents {
+			keys = append(keys, content.Key)
+		}
+		if !result.IsTruncated {
+			return keys, nil
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+// do sends a signed request for an object key, or for the bucket if key is empty. Responses with a status
+// other than 2xx are returned as HTTPError, and 404 as ErrNotFound.
+func (c *HTTPClient) do(ctx context.Context, method string, key string, query url.Values, body []byte) (*http.Response, error) {
+	path := "/" + c.opts.Bucket + "/"
+	if key != "" {
+		path += key
+	}
+	target := c.opts.Endpoint + escapePath(path)
+	if len(query) > 0 {
+		target += "?" + canonicalQuery(query)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	payloadHash := emptyPayloadHash
+	if body != nil {
+		sum := sha256.Sum256(body)
+		payloadHash = hex.EncodeToString(sum[:])
+	}
+	if c.opts.SessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", c.opts.SessionToken)
+	}
+	c.sign(request, payloadHash, c.now())
+	response, err := c.opts.HTTPClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode/100 == 2 {
+		return response, nil
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	var errorBody struct {
+		Code string
+	}
+	// the error code is informational, a body that is not XML is ignored
+	xml.NewDecoder(io.LimitReader(response.Body, 64*1024)).Decode(&errorBody)
+	return nil, &HTTPError{StatusCode: response.StatusCode, Code: errorBody.Code}
+}
+
+// sign adds the headers of AWS Signature Version 4 to a request. All headers of the request and the host are
+// signed.
+func (c *HTTPClient) sign(request *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	headers := map[string]string{"host": request.URL.Host}
+	for name, values := range request.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		escapePath(request.URL.Path),
+		canonicalQuery(request.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + c.opts.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+	key := hmacSHA256([]byte("AWS4"+c.opts.SecretAccessKey), date)
+	for _, part := range []string{c.opts.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+c.opts.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes a query sorted by key with the URI encoding of Signature Version 4.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := []string{}
+	for _, key := range keys {
+		values := append([]string{}, query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapePath encodes a path with the URI encoding of Signature Version 4, keeping the slashes.
+func escapePath(path string) string {
+	return uriEncode(path, false)
+}
+
+// uriEncode encodes all bytes of s except the unreserved characters, and slashes unless encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			encoded.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			encoded.WriteByte(c)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", c)
+		}
+	}
+	return encoded.String()
+}
@@ -0,0 +1,279 @@
+// Package s3backend provides a goKeyValueStore.Backend that saves every entry as an object in an S3 bucket, for
+// stores that run without durable local disk. The S3 client is an interface, so tests can use a fake and other
+// object stores can be plugged in; NewHTTPClient returns a client for S3 and compatible services.
+package s3backend
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+// ErrNotFound is returned by a Client for objects that do not exist.
+var ErrNotFound = errors.New("object not found")
+
+// objectSuffix is the suffix of the objects of entries, the suffix of the files of a cache folder.
+const objectSuffix = ".store.json"
+
+// defaultTimeout is the timeout of a call if Options.Timeout is 0.
+const defaultTimeout = 10 * time.Second
+
+// defaultAttempts is the number of attempts of a call if Options.Attempts is 0.
+const defaultAttempts = 3
+
+// defaultRetryBackoff is the wait before the first retry if Options.RetryBackoff is 0.
+const defaultRetryBackoff = 100 * time.Millisecond
+
+// A Client reads and writes the objects of a bucket. Calls must return when ctx is done.
+type Client interface {
+	PutObject(ctx context.Context, key string, data []byte) error
+	// GetObject returns ErrNotFound if the object does not exist.
+	GetObject(ctx context.Context, key string) ([]byte, error)
+	// DeleteObject deletes an object. Deleting an object that does not exist is not an error.
+	DeleteObject(ctx context.Context, key string) error
+	// ListObjects returns the keys of all objects that start with prefix.
+	ListObjects(ctx context.Context, prefix string) ([]string, error)
+}
+
+// Options configures a Backend.
+type Options struct {
+	// Prefix is prepended to the object keys, for example "cache/".
+	Prefix string
+	// Timeout limits every call of the Client including its retries. The default is ten seconds.
+	Timeout time.Duration
+	// Attempts is the number of attempts of a call that fails with a transient error. The default is three.
+	Attempts int
+	// RetryBackoff is the wait before the first retry. It doubles with every retry. The default is 100ms.
+	RetryBackoff time.Duration
+	// MaxEagerLoad caps the number of objects loaded when the store is created. The other objects are loaded when
+	// their key is read for the first time. A value of 0 loads all objects.
+	MaxEagerLoad int
+}
+
+// A Backend saves every entry as a JSON object named by the SHA-256 hash of its key, in the format of a file of
+// a cache folder. Calls that fail with transient errors, like timeouts, network errors, and 5xx responses, are
+// retried with a growing backoff. If all attempts fail, the error wraps goKeyValueStore.ErrBackendUnavailable, so
+// the store keeps the entry in memory and reports the error to its OnError function. The store calls Save and
+// Delete while holding its write lock, so retries delay other writes.
+type Backend struct {
+	client Client
+	opts   Options
+	mu     sync.Mutex
+	// unloaded holds the object keys that were not loaded because of MaxEagerLoad.
+	unloaded map[string]bool
+}
+
+// A record is the content of an object.
+type record struct {
+	Key             string `json:"key"`
+	Value           any    `json:"value"`
+	DeleteTimestamp int64  `json:"deleteTimestamp"`
+	Revision        uint64 `json:"revision,omitempty"`
+	UpdatedAt       int64  `json:"updatedAt,omitempty"`
+	CreatedAt       int64  `json:"createdAt,omitempty"`
+	KeyBytes        []byte `json:"keyBytes,omitempty"`
+}
+
+// New creates a Backend that uses client.
+func New(client Client, opts Options) *Backend {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.Attempts <= 0 {
+		opts.Attempts = defaultAttempts
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = defaultRetryBackoff
+	}
+	return &Backend{client: client, opts: opts, unloaded: map[string]bool{}}
+}
+
+// Load lists the objects with the prefix and loads up to MaxEagerLoad of them.
+func (b *Backend) Load(fn func(goKeyValueStore.Record) error) error {
+	var keys []string
+	err := b.call(func(ctx context.Context) error {
+		var err error
+		keys, err = b.client.ListObjects(ctx, b.opts.Prefix)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	loaded := 0
+	for _, key := range keys {
+		if !strings.HasSuffix(key, objectSuffix) {
+			continue
+		}
+		if b.opts.MaxEagerLoad > 0 && loaded >= b.opts.MaxEagerLoad {
+			b.mu.Lock()
+			b.unloaded[key] = true
+			b.mu.Unlock()
+			continue
+		}
+		record, found, err := b.get(key)
+		if err != nil {
+			// the entry key of an object that can not be decoded is unknown, so the hashed object key is named
+			return fmt.Errorf("object %s: %w", key, err)
+		}
+		if !found {
+			continue // the object was deleted after it was listed
+		}
+		err = fn(record)
+		if err != nil {
+			return err
+		}
+		loaded++
+	}
+	return nil
+}
+
+// Fetch loads the object of key if it was left out by Load because of MaxEagerLoad.
+func (b *Backend) Fetch(key string) (goKeyValueStore.Record, bool, error) {
+	objectKey := b.objectKey(key)
+	b.mu.Lock()
+	unloaded := b.unloaded[objectKey]
+	b.mu.Unlock()
+	if !unloaded {
+		return goKeyValueStore.Record{}, false, nil
+	}
+	record, found, err := b.get(objectKey)
+	if err != nil {
+		return goKeyValueStore.Record{}, false, err
+	}
+	b.forget(objectKey)
+	return record, found, nil
+}
+
+// Save puts the object of the record.
+func (b *Backend) Save(r goKeyValueStore.Record) error {
+	stored := record{
+		Key:             r.Key,
+		Value:           r.Value,
+		DeleteTimestamp: r.DeleteTimestamp,
+		Revision:        r.Revision,
+		UpdatedAt:       r.UpdatedAt,
+		CreatedAt:       r.CreatedAt,
+	}
+	if !utf8.ValidString(r.Key) {
+		stored.KeyBytes = []byte(r.Key)
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	objectKey := b.objectKey(r.Key)
+	b.forget(objectKey)
+	return b.call(func(ctx context.Context) error {
+		return b.client.PutObject(ctx, objectKey, data)
+	})
+}
+
+// Delete deletes the object of key.
+func (b *Backend) Delete(key string) error {
+	objectKey := b.objectKey(key)
+	b.forget(objectKey)
+	return b.call(func(ctx context.Context) error {
+		err := b.client.DeleteObject(ctx, objectKey)
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	})
+}
+
+// Close does nothing; every call is complete when it returns.
+func (b *Backend) Close() error {
+	return nil
+}
+
+// get reads and decodes an object. The second return value is false if the object does not exist.
+func (b *Backend) get(objectKey string) (goKeyValueStore.Record, bool, error) {
+	var data []byte
+	err := b.call(func(ctx context.Context) error {
+		var err error
+		data, err = b.client.GetObject(ctx, objectKey)
+		return err
+	})
+	if errors.Is(err, ErrNotFound) {
+		return goKeyValueStore.Record{}, false, nil
+	}
+	if err != nil {
+		return goKeyValueStore.Record{}, false, err
+	}
+	var stored record
+	err = json.Unmarshal(data, &stored)
+	if err != nil {
+		return goKeyValueStore.Record{}, false, fmt.Errorf("%w: %w", goKeyValueStore.ErrCorruptFile, err)
+	}
+	if stored.KeyBytes != nil {
+		stored.Key = string(stored.KeyBytes)
+	}
+	return goKeyValueStore.Record{
+		Key:             stored.Key,
+		Value:           stored.Value,
+		DeleteTimestamp: stored.DeleteTimestamp,
+		Revision:        stored.Revision,
+		UpdatedAt:       stored.UpdatedAt,
+		CreatedAt:       stored.CreatedAt,
+	}, true, nil
+}
+
+// forget removes an object from the objects left out by Load, because the store has a newer state of its key.
+func (b *Backend) forget(objectKey string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.unloaded, objectKey)
+}
+
+// call runs op with the timeout and retries it after transient errors. If all attempts fail with transient
+// errors, the last error is wrapped in goKeyValueStore.ErrBackendUnavailable.
+func (b *Backend) call(op func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), b.opts.Timeout)
+	defer cancel()
+	backoff := b.opts.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := op(ctx)
+		if err == nil || !transient(err) {
+			return err
+		}
+		if attempt == b.opts.Attempts || ctx.Err() != nil {
+			return fmt.Errorf("%w: %w", goKeyValueStore.ErrBackendUnavailable, err)
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w", goKeyValueStore.ErrBackendUnavailable, err)
+		}
+		backoff *= 2
+	}
+}
+
+// transient returns true for errors that may not happen again: timeouts, network errors, and errors with a
+// Temporary method that returns true, like an HTTPError with a 5xx status.
+func transient(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var temporary interface{ Temporary() bool }
+	return errors.As(err, &temporary) && temporary.Temporary()
+}
+
+// objectKey returns the key of the object of an entry.
+func (b *Backend) objectKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return b.opts.Prefix + hex.EncodeToString(sum[:]) + objectSuffix
+}
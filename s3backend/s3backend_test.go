@@ -0,0 +1,214 @@
+package s3backend_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+	"github.com/richi0/goKeyValueStore/s3backend"
+	"github.com/richi0/goKeyValueStore/storetest"
+)
+
+// A temporaryError is a transient error of fakeClient.
+type temporaryError struct{}
+
+func (temporaryError) Error() string   { return "service unavailable" }
+func (temporaryError) Temporary() bool { return true }
+
+// fakeClient keeps objects in memory. failures is the number of calls that fail with a temporaryError before
+// calls succeed again; a negative number fails all calls.
+type fakeClient struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	failures int
+	calls    map[string]int
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{objects: map[string][]byte{}, calls: map[string]int{}}
+}
+
+func (c *fakeClient) call(op string) error {
+	c.calls[op]++
+	if c.failures == 0 {
+		return nil
+	}
+	if c.failures > 0 {
+		c.failures--
+	}
+	return temporaryError{}
+}
+
+func (c *fakeClient) PutObject(ctx context.Context, key string, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call("put"); err != nil {
+		return err
+	}
+	c.objects[key] = data
+	return nil
+}
+
+func (c *fakeClient) GetObject(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call("get"); err != nil {
+		return nil, err
+	}
+	data, ok := c.objects[key]
+	if !ok {
+		return nil, s3backend.ErrNotFound
+	}
+	return data, nil
+}
+
+func (c *fakeClient) DeleteObject(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call("delete"); err != nil {
+		return err
+	}
+	delete(c.objects, key)
+	return nil
+}
+
+func (c *fakeClient) ListObjects(ctx context.Context, prefix string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.call("list"); err != nil {
+		return nil, err
+	}
+	keys := []string{}
+	for key := range c.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// openStore opens a store on the objects of client.
+func openStore(t *testing.T, client s3backend.Client, opts s3backend.Options, storeOpts ...goKeyValueStore.Option) *goKeyValueStore.KeyValueStore {
+	t.Helper()
+	opts.RetryBackoff = time.Millisecond
+	store, err := goKeyValueStore.NewKeyValueStore(1, "", append(storeOpts, goKeyValueStore.WithBackend(s3backend.New(client, opts)))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestConformance(t *testing.T) {
+	storetest.RunConformance(t, func() storetest.StoreUnderTest {
+		client := newFakeClient()
+		clock := storetest.NewClock()
+		storeOpts := []goKeyValueStore.Option{goKeyValueStore.WithClock(clock.Now), goKeyValueStore.WithCleanerStopped(true)}
+		store := openStore(t, client, s3backend.Options{Prefix: "cache/"}, storeOpts...)
+		return storetest.StoreUnderTest{Store: store, Advance: clock.Advance, Close: store.Close,
+			Restart: func() (goKeyValueStore.Store, error) {
+				store.Close()
+				return openStore(t, client, s3backend.Options{Prefix: "cache/"}, storeOpts...), nil
+			}}
+	})
+}
+
+func TestObjects(t *testing.T) {
+	client := newFakeClient()
+	client.objects["cache/readme.txt"] = []byte("not an entry")
+	store := openStore(t, client, s3backend.Options{Prefix: "cache/"})
+	store.Set("key1", "value1", 0)
+	store.Set("key2", "value2", 0)
+	store.Delete("key2")
+	if len(client.objects) != 2 {
+		t.Errorf("Expected the object of key1 and readme.txt, got %d objects", len(client.objects))
+	}
+	for key, data := range client.objects {
+		if key != "cache/readme.txt" && (!strings.HasSuffix(key, ".store.json") || !strings.Contains(string(data), `"key":"key1"`)) {
+			t.Errorf("Unexpected object %s: %s", key, data)
+		}
+	}
+}
+
+func TestRetryTransientErrors(t *testing.T) {
+	client := newFakeClient()
+	store := openStore(t, client, s3backend.Options{})
+	var reported []error
+	store.OnError(func(err error) { reported = append(reported, err) })
+
+	client.failures = 2
+	err := store.Set("key1", "value1", 0)
+	if err != nil || client.calls["put"] != 3 || len(client.objects) != 1 {
+		t.Errorf("Expected the put to succeed on the third attempt, got %v after %d calls", err, client.calls["put"])
+	}
+
+	client.failures = -1
+	err = store.Set("key2", "value2", 0)
+	if err != nil {
+		t.Errorf("Expected Set to succeed in memory, got %v", err)
+	}
+	if len(reported) != 1 || !errors.Is(reported[0], goKeyValueStore.ErrBackendUnavailable) {
+		t.Errorf("Expected 1 ErrBackendUnavailable, got %v", reported)
+	}
+	if client.calls["put"] != 6 {
+		t.Errorf("Expected 3 attempts of the second put, got %d", client.calls["put"]-3)
+	}
+}
+
+// A blockingClient blocks every call until its context is done.
+type blockingClient struct {
+	fakeClient
+}
+
+func (c *blockingClient) PutObject(ctx context.Context, key string, data []byte) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestTimeout(t *testing.T) {
+	client := &blockingClient{*newFakeClient()}
+	store := openStore(t, client, s3backend.Options{Timeout: 20 * time.Millisecond})
+	var reported error
+	store.OnError(func(err error) { reported = err })
+	start := time.Now()
+	store.Set("key", "value", 0)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the put to time out, took %v", elapsed)
+	}
+	if !errors.Is(reported, context.DeadlineExceeded) {
+		t.Errorf("Expected a deadline error, got %v", reported)
+	}
+}
+
+func TestMaxEagerLoad(t *testing.T) {
+	client := newFakeClient()
+	store := openStore(t, client, s3backend.Options{})
+	for i := 0; i < 10; i++ {
+		store.Set(fmt.Sprintf("key%d", i), i, 0)
+	}
+	store.Close()
+
+	client.calls = map[string]int{}
+	store = openStore(t, client, s3backend.Options{MaxEagerLoad: 3})
+	if store.Length() != 3 || client.calls["get"] != 3 {
+		t.Errorf("Expected 3 loaded keys, got %d after %d gets", store.Length(), client.calls["get"])
+	}
+	for i := 0; i < 10; i++ {
+		if value, ok := store.Get(fmt.Sprintf("key%d", i)); !ok || value != float64(i) {
+			t.Errorf("Expected key%d to be loaded on first access, got %v", i, value)
+		}
+	}
+	if store.Length() != 10 || client.calls["get"] != 10 {
+		t.Errorf("Expected 10 keys after 10 gets, got %d after %d gets", store.Length(), client.calls["get"])
+	}
+	if _, ok := store.Get("missing"); ok || client.calls["get"] != 10 {
+		t.Errorf("Expected a miss without a call, got %d gets", client.calls["get"])
+	}
+}
@@ -0,0 +1,59 @@
+package goKeyValueStore
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidCursor is returned by KeysPage and Scan for cursors that were not returned by them.
+var ErrInvalidCursor = errors.New("invalid scan cursor")
+
+// Scan returns at most count non-expired keys and the cursor to pass to the next call, like KeysPage, for callers
+// used to the SCAN command of Redis. An empty cursor starts the scan and an empty next cursor ends it. A count of
+// 0 or less returns no keys and the same cursor. With WithHashedKeys, it returns ErrUnsupportedWithHashedKeys.
+func (d *KeyValueStore) Scan(cursor string, count int) ([]string, string, error) {
+	d.lazyInit()
+	if d.hashKeys {
+		return nil, "", ErrUnsupportedWithHashedKeys
+	}
+	return d.KeysPage(cursor, count)
+}
+
+// cursorPrefix starts every cursor of KeysPage, so the cursor after the empty key is not empty.
+const cursorPrefix = "k"
+
+// encodeCursor returns the cursor of a page that continues after key.
+func encodeCursor(key string) string {
+	return cursorPrefix + base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+// decodeCursor returns the key after which a page continues. The empty cursor returns "".
+func decodeCursor(cursor string) (string, error) {
+	if cursor == "" {
+		return "", nil
+	}
+	encoded, ok := strings.CutPrefix(cursor, cursorPrefix)
+	if !ok {
+		return "", ErrInvalidCursor
+	}
+	key, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalidCursor
+	}
+	return string(key), nil
+}
+
+// A keyHeap is a max-heap of keys.
+type keyHeap []string
+
+func (h keyHeap) Len() int           { return len(h) }
+func (h keyHeap) Less(i, j int) bool { return h[i] > h[j] }
+func (h keyHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *keyHeap) Push(x any)        { *h = append(*h, x.(string)) }
+func (h *keyHeap) Pop() any {
+	old := *h
+	key := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return key
+}
@@ -0,0 +1,74 @@
+package goKeyValueStore_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestScan(t *testing.T) {
+	store := getLargeTestStore(t, 10000)
+	seen := map[string]int{}
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			t.Fatal("Expected the scan to end after 100 pages")
+		}
+		keys, next, err := store.Scan(cursor, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) > 100 {
+			t.Fatalf("Expected at most 100 keys, got %d", len(keys))
+		}
+		for _, key := range keys {
+			seen[key]++
+		}
+		// change the store between pages: the keys present during the whole scan must still be returned
+		store.Set(fmt.Sprintf("new%d", pages), pages, 0)
+		store.Delete(fmt.Sprintf("key%05d", 9999-pages))
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	for i := 0; i < 9899; i++ {
+		if key := fmt.Sprintf("key%05d", i); seen[key] != 1 {
+			t.Errorf("Expected %s to be returned once, got %d", key, seen[key])
+		}
+	}
+}
+
+func TestScanEmptyKey(t *testing.T) {
+	store, _ := goKeyValueStore.NewKeyValueStore(1, "")
+	store.Set("", "empty", 0)
+	store.Set("a", "value", 0)
+	keys, next, _ := store.Scan("", 1)
+	if len(keys) != 1 || keys[0] != "" || next == "" {
+		t.Fatalf("Expected the empty key and a cursor, got %q, %q", keys, next)
+	}
+	keys, next, _ = store.Scan(next, 1)
+	if len(keys) != 1 || keys[0] != "a" || next != "" {
+		t.Errorf("Expected a and the end of the scan, got %q, %q", keys, next)
+	}
+}
+
+func TestScanInvalidCursor(t *testing.T) {
+	store, _ := goKeyValueStore.NewKeyValueStore(1, "")
+	if _, _, err := store.Scan("not a cursor", 10); !errors.Is(err, goKeyValueStore.ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestScanWithHashedKeys(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(1, "", goKeyValueStore.WithHashedKeys([]byte("salt")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("user:1", "alice", 0)
+	if _, _, err := store.Scan("", 10); !errors.Is(err, goKeyValueStore.ErrUnsupportedWithHashedKeys) {
+		t.Errorf("Expected ErrUnsupportedWithHashedKeys, got %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package goKeyValueStore
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store is the common interface of KeyValueStore and ScopedStore.
+type Store interface {
+	Set(key string, value any, ttl int) error
+	Get(key string) (any, bool)
+	Delete(key string) error
+	Length() int
+}
+
+var (
+	_ Store = (*KeyValueStore)(nil)
+	_ Store = (*ScopedStore)(nil)
+)
+
+// A ScopedStore is a small in-memory store for the lifetime of a request. Writes stay in the scope and reads
+// of keys that were not written in the scope fall through to the parent store. A ScopedStore has no cleaner and
+// no cache folder: expired entries are dropped when they are read and everything is dropped by Release.
+type ScopedStore struct {
+	parent  *KeyValueStore
+	now     func() time.Time
+	mu      sync.Mutex
+	entries map[string]*node
+	deleted map[string]bool
+}
+
+// NewRequestScoped creates a ScopedStore on top of parent. If parent is nil, reads never fall through.
+func NewRequestScoped(parent *KeyValueStore) *ScopedStore {
+	now := time.Now
+	if parent != nil {
+		parent.lazyInit()
+		now = parent.now
+	}
+	return &ScopedStore{
+		parent:  parent,
+		now:     now,
+		entries: map[string]*node{},
+		deleted: map[string]bool{},
+	}
+}
+
+// NewRequestScopedContext creates a ScopedStore on top of parent that is released when ctx is done.
+func NewRequestScopedContext(ctx context.Context, parent *KeyValueStore) *ScopedStore {
+	scoped := NewRequestScoped(parent)
+	context.AfterFunc(ctx, scoped.Release)
+	return scoped
+}
+
+// Set sets a key-value pair with a TTL in milliseconds in the scope. The parent store is not changed.
+func (s *ScopedStore) Set(key string, value any, ttl int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleteTimestamp := neverExpire
+	if ttl != 0 {
+		deleteTimestamp = s.now().Add(time.Duration(ttl) * time.Millisecond).UnixMilli()
+	}
+	s.entries[key] = &node{Key: key, Value: value, DeleteTimestamp: deleteTimestamp}
+	delete(s.deleted, key)
+	return nil
+}
+
+// Get gets a value by key from the scope. Keys that were neither set nor deleted in the scope, or whose value in
+// the scope expired, are read from the parent store. If the key does not exist, the second return value is false.
+func (s *ScopedStore) Get(key string) (any, bool) {
+	s.mu.Lock()
+	node, ok := s.entries[key]
+	if ok && s.now().UnixMilli() > node.DeleteTimestamp {
+		delete(s.entries, key)
+		ok = false
+	}
+	hidden := s.deleted[key]
+	s.mu.Unlock()
+	if ok {
+		return node.Value, true
+	}
+	if hidden || s.parent == nil {
+		return nil, false
+	}
+	return s.parent.Get(key)
+}
+
+// Delete deletes a key in the scope. The key is hidden from reads through the scope but is not deleted in the
+// parent store.
+func (s *ScopedStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	s.deleted[key] = true
+	return nil
+}
+
+// Length returns the number of non-expired key-value pairs set in the scope. Keys of the parent store are not
+// counted.
+func (s *ScopedStore) Length() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now().UnixMilli()
+	counter := 0
+	for _, node := range s.entries {
+		if now <= node.DeleteTimestamp {
+			counter++
+		}
+	}
+	return counter
+}
+
+// Release drops all key-value pairs and deletions of the scope. The scope can still be used afterwards.
+func (s *ScopedStore) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = map[string]*node{}
+	s.deleted = map[string]bool{}
+}
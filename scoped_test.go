@@ -0,0 +1,88 @@
+package goKeyValueStore_test
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestScopedWritesInvisibleToParent(t *testing.T) {
+	parent := getTestStore()
+	scoped := goKeyValueStore.NewRequestScoped(parent)
+	scoped.Set("key1", "scoped", 0)
+	scoped.Set("key4", "scoped", 0)
+	scoped.Delete("key2")
+	if value, _ := parent.Get("key1"); value != "value1" {
+		t.Errorf("Expected parent value1, got %v", value)
+	}
+	if _, ok := parent.Get("key4"); ok {
+		t.Error("Expected key4 not to be set in the parent")
+	}
+	if _, ok := parent.Get("key2"); !ok {
+		t.Error("Expected key2 not to be deleted in the parent")
+	}
+	if value, _ := scoped.Get("key1"); value != "scoped" {
+		t.Errorf("Expected scoped value, got %v", value)
+	}
+	if _, ok := scoped.Get("key2"); ok {
+		t.Error("Expected key2 to be deleted in the scope")
+	}
+}
+
+func TestScopedReadsFallThrough(t *testing.T) {
+	parent := getTestStore()
+	scoped := goKeyValueStore.NewRequestScoped(parent)
+	if value, ok := scoped.Get("key3"); !ok || value != "value3" {
+		t.Errorf("Expected value3 from the parent, got %v", value)
+	}
+	isolated := goKeyValueStore.NewRequestScoped(nil)
+	if _, ok := isolated.Get("key3"); ok {
+		t.Error("Expected no fall through without parent")
+	}
+}
+
+func TestScopedExpiry(t *testing.T) {
+	clock := newFakeClock()
+	parent := getTestStoreWithClock(t, "", clock)
+	parent.Set("key", "parent", 0)
+	scoped := goKeyValueStore.NewRequestScoped(parent)
+	scoped.Set("key", "scoped", 1000)
+	clock.Advance(2 * time.Second)
+	if value, _ := scoped.Get("key"); value != "parent" {
+		t.Errorf("Expected the parent value after the scoped value expired, got %v", value)
+	}
+	if scoped.Length() != 0 {
+		t.Errorf("Expected length 0, got %d", scoped.Length())
+	}
+}
+
+func TestScopedZeroValueParent(t *testing.T) {
+	scoped := goKeyValueStore.NewRequestScoped(&goKeyValueStore.KeyValueStore{})
+	scoped.Set("key", "scoped", 1000)
+	if value, _ := scoped.Get("key"); value != "scoped" {
+		t.Errorf("Expected scoped, got %v", value)
+	}
+}
+
+func TestScopedRelease(t *testing.T) {
+	parent := getTestStore()
+	goroutines := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	scoped := goKeyValueStore.NewRequestScopedContext(ctx, parent)
+	for i := 0; i < 100; i++ {
+		scoped.Set("scoped", i, 0)
+		scoped.Set(string(rune('a'+i%26)), i, 0)
+	}
+	if parent.Length() != 3 {
+		t.Errorf("Expected the parent to keep 3 entries, got %d", parent.Length())
+	}
+	cancel()
+	waitFor(t, func() bool { return scoped.Length() == 0 })
+	if value, _ := scoped.Get("key1"); value != "value1" {
+		t.Errorf("Expected the released scope to read through, got %v", value)
+	}
+	waitFor(t, func() bool { return runtime.NumGoroutine() <= goroutines })
+}
@@ -0,0 +1,100 @@
+package goKeyValueStore
+
+import (
+	"context"
+	"errors"
+	"reflect"
+)
+
+// ErrNotASlice is returned by AppendToSlice and SliceLen if the value of the key is not a slice.
+var ErrNotASlice = errors.New("value is not a slice")
+
+// AppendToSlice appends items to the slice stored at key and returns the new length. If the slice is longer than
+// max, the oldest items are removed from the front; a max of 0 or less keeps all items. The whole operation holds
+// the write lock, so concurrent appends never lose items. A missing or expired key starts a new slice that never
+// expires; an existing key keeps its deadline. Slices of any type are stored as []any, which is also the type of
+// slices read from the cache folder. Values that are not slices return ErrNotASlice and are left unchanged.
+func (d *KeyValueStore) AppendToSlice(key string, max int, items ...any) (int, error) {
+	d.lazyInit()
+	key = d.storageKey(key)
+	length, err := d.appendToSlice(key, max, items)
+	d.audit(context.Background(), "set", key, true, err)
+	return length, err
+}
+
+// appendToSlice appends items to the slice stored at key and returns the new length.
+func (d *KeyValueStore) appendToSlice(key string, max int, items []any) (int, error) {
+	err := d.takeWriteTokens(1)
+	if err != nil {
+		d.recordSetResult(key, err)
+		return 0, err
+	}
+	defer d.afterWrite()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed.Load() {
+		return 0, ErrClosed
+	}
+	slice := []any{}
+	deadline := neverExpire
+	if old, ok := d.data[key]; ok && !d.nodeIsExpired(old) {
+		value, err := old.value()
+		if err != nil {
+			return 0, d.keyError("load value", key, err)
+		}
+		slice, ok = toSlice(value)
+		if !ok {
+			return 0, d.keyError("append to slice", key, ErrNotASlice)
+		}
+		deadline = old.DeleteTimestamp
+	}
+	slice = append(slice, items...)
+	if max > 0 && len(slice) > max {
+		slice = slice[len(slice)-max:]
+	}
+	err = d.checkValue(slice)
+	if err == nil {
+		err = d.makeRoomInQuotas(key)
+	}
+	if err != nil {
+		d.recordSetResult(key, err)
+		return 0, err
+	}
+	node := d.newNode(key, slice, 0)
+	node.DeleteTimestamp = deadline
+	d.putNode(node)
+	err = d.saveInCache(node)
+	d.recordSetResult(key, err)
+	if err != nil {
+		return len(slice), err
+	}
+	return len(slice), d.evictOverflow(key)
+}
+
+// SliceLen returns the length of the slice stored at key. The second return value is false if the key does not
+// exist or its value is not a slice.
+func (d *KeyValueStore) SliceLen(key string) (int, bool) {
+	d.lazyInit()
+	value, ok := d.get(d.storageKey(key))
+	if !ok {
+		return 0, false
+	}
+	slice, ok := toSlice(value)
+	return len(slice), ok
+}
+
+// toSlice copies a slice of any type to a new []any.
+func toSlice(value any) ([]any, bool) {
+	if value == nil {
+		return nil, false
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice {
+		return nil, false
+	}
+	slice := make([]any, v.Len())
+	for i := range slice {
+		slice[i] = v.Index(i).Interface()
+	}
+	return slice, true
+}
@@ -0,0 +1,76 @@
+package goKeyValueStore_test
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestAppendToSliceConcurrently(t *testing.T) {
+	store := getTestStoreWithClock(t, t.TempDir(), newFakeClock())
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if _, err := store.AppendToSlice("events", 0, i*100+j); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if length, ok := store.SliceLen("events"); !ok || length != 200 {
+		t.Errorf("Expected 200 items, got %d", length)
+	}
+}
+
+func TestAppendToSliceTrimsFront(t *testing.T) {
+	store := getTestStoreWithClock(t, "", newFakeClock())
+	store.Set("events", []string{"a", "b"}, 0)
+	length, err := store.AppendToSlice("events", 3, "c", "d")
+	if err != nil || length != 3 {
+		t.Errorf("Expected length 3, got %d, %v", length, err)
+	}
+	value, _ := store.Get("events")
+	if expected := []any{"b", "c", "d"}; !reflect.DeepEqual(value, expected) {
+		t.Errorf("Expected %v, got %v", expected, value)
+	}
+	store.Set("text", "value", 0)
+	if _, err := store.AppendToSlice("text", 3, "x"); !errors.Is(err, goKeyValueStore.ErrNotASlice) {
+		t.Errorf("Expected ErrNotASlice, got %v", err)
+	}
+	if value, _ := store.Get("text"); value != "value" {
+		t.Errorf("Expected text to be unchanged, got %v", value)
+	}
+	if _, ok := store.SliceLen("text"); ok {
+		t.Errorf("Expected SliceLen to reject a string")
+	}
+}
+
+func TestAppendToSliceAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	clock := newFakeClock()
+	store := getTestStoreWithClock(t, dir, clock)
+	store.AppendToSlice("events", 3, 1, 2)
+	store.Set("ttl", []int{1}, 1000)
+	restarted := getTestStoreWithClock(t, dir, clock)
+	length, err := restarted.AppendToSlice("events", 3, 3, 4)
+	if err != nil || length != 3 {
+		t.Errorf("Expected length 3, got %d, %v", length, err)
+	}
+	value, _ := restarted.Get("events")
+	if expected := []any{float64(2), 3, 4}; !reflect.DeepEqual(value, expected) {
+		t.Errorf("Expected %v, got %v", expected, value)
+	}
+	restarted.AppendToSlice("ttl", 0, 2)
+	clock.Advance(1001 * time.Millisecond)
+	if _, ok := restarted.SliceLen("ttl"); ok {
+		t.Errorf("Expected appending to keep the deadline")
+	}
+}
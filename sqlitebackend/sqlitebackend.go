@@ -0,0 +1,121 @@
+// Package sqlitebackend provides a goKeyValueStore.Backend that saves all entries in a SQLite database, so they
+// can be inspected with standard SQL tooling. It uses the cgo-free driver modernc.org/sqlite and is a module of
+// its own, so the store itself stays free of dependencies.
+//
+// The entries are rows of the table entries:
+//
+//	key              TEXT PRIMARY KEY
+//	value            BLOB     the value encoded as JSON
+//	delete_timestamp INTEGER  the deadline in Unix milliseconds, 9223372036854775807 for entries without TTL
+//	revision, updated_at, created_at INTEGER
+package sqlitebackend
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/richi0/goKeyValueStore"
+	_ "modernc.org/sqlite"
+)
+
+// busyTimeout is the time in milliseconds a write waits for another connection or process that holds the lock of
+// the database before it fails with "database is locked".
+const busyTimeout = 10000
+
+// schema creates the table of the entries.
+const schema = `CREATE TABLE IF NOT EXISTS entries (
+	key TEXT PRIMARY KEY,
+	value BLOB NOT NULL,
+	delete_timestamp INTEGER NOT NULL,
+	revision INTEGER NOT NULL DEFAULT 0,
+	updated_at INTEGER NOT NULL DEFAULT 0,
+	created_at INTEGER NOT NULL DEFAULT 0
+)`
+
+// A Backend saves every entry as a row of a SQLite database.
+type Backend struct {
+	db *sql.DB
+}
+
+// Open opens the database file at path and creates it and the table if needed. The database uses write-ahead
+// logging and waits for locks held by other stores or processes on the same file, so they can write concurrently.
+func Open(path string) (*Backend, error) {
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() + "?_pragma=busy_timeout(" + fmt.Sprint(busyTimeout) +
+		")&_pragma=journal_mode(WAL)&_txlock=immediate"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// a single connection serializes the writes of this backend instead of letting them wait for each other's locks
+	db.SetMaxOpenConns(1)
+	_, err = db.Exec(schema)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Backend{db: db}, nil
+}
+
+// Load reads all rows of the table.
+func (b *Backend) Load(fn func(goKeyValueStore.Record) error) error {
+	rows, err := b.db.Query("SELECT key, value, delete_timestamp, revision, updated_at, created_at FROM entries")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	records := []goKeyValueStore.Record{}
+	for rows.Next() {
+		var record goKeyValueStore.Record
+		var value []byte
+		err := rows.Scan(&record.Key, &value, &record.DeleteTimestamp, &record.Revision, &record.UpdatedAt, &record.CreatedAt)
+		if err != nil {
+			return err
+		}
+		err = json.Unmarshal(value, &record.Value)
+		if err != nil {
+			return &goKeyValueStore.RecordError{Key: record.Key, Err: fmt.Errorf("%w: %w", goKeyValueStore.ErrCorruptFile, err)}
+		}
+		records = append(records, record)
+	}
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+	// fn is called after the rows are closed, so it may use the database
+	rows.Close()
+	for _, record := range records {
+		err := fn(record)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save inserts the row of the record or replaces the row of the same key.
+func (b *Backend) Save(record goKeyValueStore.Record) error {
+	value, err := json.Marshal(record.Value)
+	if err != nil {
+		return err
+	}
+	_, err = b.db.Exec(`INSERT INTO entries (key, value, delete_timestamp, revision, updated_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value, delete_timestamp = excluded.delete_timestamp,
+			revision = excluded.revision, updated_at = excluded.updated_at, created_at = excluded.created_at`,
+		record.Key, value, record.DeleteTimestamp, record.Revision, record.UpdatedAt, record.CreatedAt)
+	return err
+}
+
+// Delete deletes the row of key. The cleaner of the store calls it for every expired entry in the same sweep that
+// removes the entry from memory.
+func (b *Backend) Delete(key string) error {
+	_, err := b.db.Exec("DELETE FROM entries WHERE key = ?", key)
+	return err
+}
+
+// Close closes the database.
+func (b *Backend) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,149 @@
+package sqlitebackend_test
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+	"github.com/richi0/goKeyValueStore/sqlitebackend"
+	"github.com/richi0/goKeyValueStore/storetest"
+)
+
+// openStore opens a store on the database at path.
+func openStore(t *testing.T, path string, opts ...goKeyValueStore.Option) *goKeyValueStore.KeyValueStore {
+	t.Helper()
+	backend, err := sqlitebackend.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := goKeyValueStore.NewKeyValueStore(1, "", append(opts, goKeyValueStore.WithBackend(backend))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// countRows returns the number of rows in the database at path.
+func countRows(t *testing.T, path string) int {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var count int
+	err = db.QueryRow("SELECT COUNT(*) FROM entries").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestConformance(t *testing.T) {
+	storetest.RunConformance(t, func() storetest.StoreUnderTest {
+		clock := storetest.NewClock()
+		path := filepath.Join(t.TempDir(), "store.db")
+		opts := []goKeyValueStore.Option{goKeyValueStore.WithClock(clock.Now), goKeyValueStore.WithCleanerStopped(true)}
+		store := openStore(t, path, opts...)
+		return storetest.StoreUnderTest{Store: store, Advance: clock.Advance, Close: store.Close,
+			Restart: func() (goKeyValueStore.Store, error) {
+				store.Close()
+				restarted := openStore(t, path, opts...)
+				t.Cleanup(func() { restarted.Close() })
+				return restarted, nil
+			}}
+	})
+}
+
+func TestRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.db")
+	store := openStore(t, path)
+	store.Set("key1", "value1", 0)
+	store.Set("key2", map[string]any{"a": []any{1.5, "b"}}, 60000)
+	store.Set("key3", "value3", 0)
+	store.Delete("key3")
+	err := store.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store = openStore(t, path)
+	defer store.Close()
+	if value, _ := store.Get("key1"); value != "value1" {
+		t.Errorf("Expected value1, got %v", value)
+	}
+	if value, _ := store.Get("key2"); fmt.Sprint(value) != "map[a:[1.5 b]]" {
+		t.Errorf("Expected the map of key2, got %v", value)
+	}
+	if _, ok := store.Get("key3"); ok {
+		t.Error("Expected key3 to stay deleted")
+	}
+	if ttl, _ := store.TTL("key2"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected key2 to keep its deadline, got a TTL of %v", ttl)
+	}
+}
+
+func TestCleanerPrunesRows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.db")
+	clock := storetest.NewClock()
+	store := openStore(t, path, goKeyValueStore.WithClock(clock.Now), goKeyValueStore.WithCleanerStopped(true))
+	defer store.Close()
+	store.Set("live", "value", 0)
+	store.Set("expired1", "value", 1000)
+	store.Set("expired2", "value", 1000)
+	clock.Advance(2 * time.Second)
+	report, err := store.CleanNow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Expired != 2 || report.FileDeletions != 2 {
+		t.Errorf("Expected 2 expired entries and 2 deleted rows, got %+v", report)
+	}
+	if rows := countRows(t, path); rows != 1 {
+		t.Errorf("Expected 1 row, got %d", rows)
+	}
+}
+
+func TestConcurrentStores(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.db")
+	stores := []*goKeyValueStore.KeyValueStore{openStore(t, path), openStore(t, path)}
+	var wg sync.WaitGroup
+	errs := make(chan error, 1000)
+	for i, store := range stores {
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func(store *goKeyValueStore.KeyValueStore, prefix string) {
+				defer wg.Done()
+				for n := 0; n < 50; n++ {
+					key := fmt.Sprintf("%s:%d", prefix, n)
+					if err := store.Set(key, n, 0); err != nil {
+						errs <- err
+					}
+					if n%2 == 0 {
+						if err := store.Delete(key); err != nil {
+							errs <- err
+						}
+					}
+				}
+			}(store, fmt.Sprintf("store%d:goroutine%d", i, g))
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	for _, store := range stores {
+		err := store.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rows := countRows(t, path); rows != 2*4*25 {
+		t.Errorf("Expected %d rows, got %d", 2*4*25, rows)
+	}
+}
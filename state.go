@@ -0,0 +1,109 @@
+package goKeyValueStore
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A StorePhase is a step in the startup of a store.
+type StorePhase int32
+
+const (
+	// PhaseInitializing stores check their cache folder and look for entries.
+	PhaseInitializing StorePhase = iota
+	// PhaseWarming stores load the entries of their cache folder. Entries that are not loaded yet are missing.
+	PhaseWarming
+	// PhaseReady stores serve all entries of their cache folder.
+	PhaseReady
+)
+
+// String returns the name of the phase.
+func (p StorePhase) String() string {
+	switch p {
+	case PhaseInitializing:
+		return "initializing"
+	case PhaseWarming:
+		return "warming"
+	case PhaseReady:
+		return "ready"
+	default:
+		return "unknown"
+	}
+}
+
+// A StoreState is the phase of a store. While warming, Progress is the loaded fraction of the entries
+// between 0 and 1.
+type StoreState struct {
+	Phase    StorePhase
+	Progress float64
+}
+
+// ErrNotReady is returned by Health while the store is initializing or warming.
+var ErrNotReady = errors.New("store is not ready")
+
+// State returns the phase of the store. Stores become ready once all files of the cache folder are loaded.
+// With WithIndex, a store is ready after reading the index unless WithWarmup is used.
+func (d *KeyValueStore) State() StoreState {
+	d.lazyInit()
+	state := StoreState{Phase: StorePhase(d.phase.Load())}
+	switch state.Phase {
+	case PhaseWarming:
+		total := d.warmTotal.Load()
+		if total > 0 {
+			state.Progress = float64(d.warmDone.Load()) / float64(total)
+		}
+	case PhaseReady:
+		state.Progress = 1
+	}
+	return state
+}
+
+// Warmup loads all values that WithIndex has not loaded yet, so later reads do not access the cache folder.
+// The store is warming until it returns.
+func (d *KeyValueStore) Warmup() error {
+	d.lazyInit()
+	nodes := d.snapshot()
+	d.startWarming(len(nodes))
+	defer d.phase.Store(int32(PhaseReady))
+	var errs []error
+	for _, node := range nodes {
+		if d.closed.Load() {
+			break
+		}
+		_, err := node.value()
+		if err != nil {
+			errs = append(errs, d.keyError("load value", node.Key, err))
+		}
+		d.warmDone.Add(1)
+	}
+	return errors.Join(errs...)
+}
+
+// startWarming switches to PhaseWarming with total entries to load.
+func (d *KeyValueStore) startWarming(total int) {
+	d.warmDone.Store(0)
+	d.warmTotal.Store(int64(total))
+	d.phase.Store(int32(PhaseWarming))
+}
+
+// warmupInBackground runs Warmup in a goroutine and reports its errors to the OnError function.
+func (d *KeyValueStore) warmupInBackground() {
+	d.startWarming(0)
+	d.background.Add(1)
+	go func() {
+		defer d.background.Done()
+		err := d.Warmup()
+		if err != nil {
+			d.reportError(err)
+		}
+	}()
+}
+
+// notReady returns an error wrapping ErrNotReady if the store is not ready.
+func (d *KeyValueStore) notReady() error {
+	state := d.State()
+	if state.Phase == PhaseReady {
+		return nil
+	}
+	return fmt.Errorf("%w: %s, %.0f%% loaded", ErrNotReady, state.Phase, state.Progress*100)
+}
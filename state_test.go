@@ -0,0 +1,103 @@
+package goKeyValueStore_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestStateDuringSlowInit(t *testing.T) {
+	dir := t.TempDir()
+	source, err := goKeyValueStore.NewKeyValueStore(0.5, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		source.Set(fmt.Sprintf("key%d", i), i, 60000)
+	}
+	source.StopCleaning()
+	var store *goKeyValueStore.KeyValueStore
+	capture := func(d *goKeyValueStore.KeyValueStore) { store = d }
+	states := []goKeyValueStore.StoreState{}
+	healthErrors := []error{}
+	slowRead := goKeyValueStore.WithReadFile(func(name string) ([]byte, error) {
+		states = append(states, store.State())
+		healthErrors = append(healthErrors, store.Health())
+		return os.ReadFile(name)
+	})
+	_, err = goKeyValueStore.NewKeyValueStore(0.5, dir, capture, slowRead, goKeyValueStore.WithCleanerStopped(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(states) != 4 {
+		t.Fatalf("Expected 4 reads, got %d", len(states))
+	}
+	for i, state := range states {
+		if state.Phase != goKeyValueStore.PhaseWarming || state.Progress != float64(i)/4 {
+			t.Errorf("Expected warming with progress %v, got %+v", float64(i)/4, state)
+		}
+		if !errors.Is(healthErrors[i], goKeyValueStore.ErrNotReady) || errors.Is(healthErrors[i], goKeyValueStore.ErrDegraded) {
+			t.Errorf("Expected ErrNotReady, got %v", healthErrors[i])
+		}
+	}
+	if state := store.State(); state.Phase != goKeyValueStore.PhaseReady || state.Progress != 1 {
+		t.Errorf("Expected the store to be ready, got %+v", state)
+	}
+	if err := store.Health(); err != nil {
+		t.Errorf("Expected a healthy store, got %v", err)
+	}
+}
+
+func TestStateWithIndex(t *testing.T) {
+	dir := t.TempDir()
+	getTestStoreWithIndex(t, dir, 20)
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithIndex(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := store.State(); state.Phase != goKeyValueStore.PhaseReady {
+		t.Errorf("Expected a store with index to be ready without warmup, got %+v", state)
+	}
+	release := make(chan struct{})
+	var reads atomic.Int64
+	blockingRead := goKeyValueStore.WithReadFile(func(name string) ([]byte, error) {
+		if reads.Add(1) > 1 {
+			<-release
+		}
+		return os.ReadFile(name)
+	})
+	store, err = goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithIndex(true), goKeyValueStore.WithWarmup(true), blockingRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := store.State(); state.Phase != goKeyValueStore.PhaseWarming {
+		t.Errorf("Expected the store to be warming, got %+v", state)
+	}
+	if err := store.Health(); !errors.Is(err, goKeyValueStore.ErrNotReady) {
+		t.Errorf("Expected ErrNotReady, got %v", err)
+	}
+	progress := 0.0
+	for i := 0; i < 19; i++ {
+		release <- struct{}{}
+		waitFor(t, func() bool {
+			return store.State().Progress > progress || store.State().Phase == goKeyValueStore.PhaseReady
+		})
+		progress = store.State().Progress
+	}
+	waitFor(t, func() bool { return store.State().Phase == goKeyValueStore.PhaseReady })
+	if err := store.Health(); err != nil {
+		t.Errorf("Expected a healthy store, got %v", err)
+	}
+	before := reads.Load()
+	close(release)
+	store.Get("key7")
+	time.Sleep(10 * time.Millisecond)
+	if reads.Load() != before {
+		t.Errorf("Expected values to be loaded by the warmup, got %d more reads", reads.Load()-before)
+	}
+}
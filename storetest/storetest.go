@@ -0,0 +1,301 @@
+// Package storetest checks that an implementation of goKeyValueStore.Store follows the contract of the
+// KeyValueStore, so wrappers and stores with other backends behave the same as the default store:
+//
+//	func TestConformance(t *testing.T) {
+//		storetest.RunConformance(t, func() storetest.StoreUnderTest {
+//			clock := storetest.NewClock()
+//			store, _ := goKeyValueStore.NewKeyValueStore(1, "", goKeyValueStore.WithClock(clock.Now))
+//			return storetest.StoreUnderTest{Store: store, Advance: clock.Advance, Close: store.Close}
+//		})
+//	}
+package storetest
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+// A StoreUnderTest is a new, empty store checked by RunConformance together with the functions that control it.
+type StoreUnderTest struct {
+	Store goKeyValueStore.Store
+	// Advance moves the clock of the store forward. If it is nil, the tests of expiration are skipped.
+	Advance func(d time.Duration)
+	// Restart closes the store and opens a new one on the same data with the same clock. If it is nil, the tests
+	// of restart recovery are skipped.
+	Restart func() (goKeyValueStore.Store, error)
+	// Close closes the store. Afterwards Set must return an error and Get must find no keys. If it is nil, the
+	// tests of closed stores are skipped.
+	Close func() error
+}
+
+// A Clock is a manual clock for the Advance function of a StoreUnderTest.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a Clock at a fixed time.
+func NewClock() *Clock {
+	return &Clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// Now returns the time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// RunConformance runs the conformance tests as subtests of t. factory is called once per subtest and must return
+// a new, empty store. Stores whose Close is set are closed at the end of each subtest.
+func RunConformance(t *testing.T, factory func() StoreUnderTest) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, sut StoreUnderTest)
+	}{
+		{"SetGetDelete", testSetGetDelete},
+		{"Overwrite", testOverwrite},
+		{"Values", testValues},
+		{"NilValue", testNilValue},
+		{"Expiry", testExpiry},
+		{"NeverExpire", testNeverExpire},
+		{"Restart", testRestart},
+		{"Concurrent", testConcurrent},
+		{"Closed", testClosed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sut := factory()
+			if sut.Close != nil {
+				t.Cleanup(func() { sut.Close() })
+			}
+			test.test(t, sut)
+		})
+	}
+}
+
+// expectValue fails the test if key does not have value.
+func expectValue(t *testing.T, store goKeyValueStore.Store, key string, value any) {
+	t.Helper()
+	got, ok := store.Get(key)
+	if !ok {
+		t.Errorf("Expected %s to exist", key)
+	} else if !reflect.DeepEqual(got, value) {
+		t.Errorf("Expected %s to be %#v, got %#v", key, value, got)
+	}
+}
+
+// expectMissing fails the test if key exists.
+func expectMissing(t *testing.T, store goKeyValueStore.Store, key string) {
+	t.Helper()
+	if got, ok := store.Get(key); ok {
+		t.Errorf("Expected %s to not exist, got %#v", key, got)
+	}
+}
+
+// expectLength fails the test if the store does not have length entries.
+func expectLength(t *testing.T, store goKeyValueStore.Store, length int) {
+	t.Helper()
+	if got := store.Length(); got != length {
+		t.Errorf("Expected length to be %d, got %d", length, got)
+	}
+}
+
+func testSetGetDelete(t *testing.T, sut StoreUnderTest) {
+	store := sut.Store
+	expectMissing(t, store, "key")
+	expectLength(t, store, 0)
+	if err := store.Set("key", "value", 0); err != nil {
+		t.Fatal(err)
+	}
+	expectValue(t, store, "key", "value")
+	expectLength(t, store, 1)
+	if err := store.Delete("key"); err != nil {
+		t.Fatal(err)
+	}
+	expectMissing(t, store, "key")
+	expectLength(t, store, 0)
+	if err := store.Delete("missing"); err != nil {
+		t.Errorf("Expected deleting a missing key to succeed, got %v", err)
+	}
+}
+
+func testOverwrite(t *testing.T, sut StoreUnderTest) {
+	store := sut.Store
+	store.Set("key", "first", 0)
+	if err := store.Set("key", "second", 0); err != nil {
+		t.Fatal(err)
+	}
+	expectValue(t, store, "key", "second")
+	expectLength(t, store, 1)
+}
+
+// values are set by testValues and testRestart. They are chosen so that they survive a JSON round trip.
+var values = map[string]any{
+	"string": "value",
+	"number": 42.5,
+	"bool":   true,
+	"slice":  []any{"a", 1.0},
+	"map":    map[string]any{"nested": []any{}},
+	"empty":  "",
+}
+
+func testValues(t *testing.T, sut StoreUnderTest) {
+	for key, value := range values {
+		if err := sut.Store.Set(key, value, 0); err != nil {
+			t.Fatalf("Set %s: %v", key, err)
+		}
+	}
+	for key, value := range values {
+		expectValue(t, sut.Store, key, value)
+	}
+	expectLength(t, sut.Store, len(values))
+}
+
+func testNilValue(t *testing.T, sut StoreUnderTest) {
+	if err := sut.Store.Set("nil", nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	expectValue(t, sut.Store, "nil", nil)
+}
+
+func testExpiry(t *testing.T, sut StoreUnderTest) {
+	if sut.Advance == nil {
+		t.Skip("store has no clock")
+	}
+	store := sut.Store
+	store.Set("short", "value", 1000)
+	store.Set("long", "value", 5000)
+	sut.Advance(999 * time.Millisecond)
+	expectValue(t, store, "short", "value")
+	sut.Advance(2 * time.Millisecond)
+	expectMissing(t, store, "short")
+	expectValue(t, store, "long", "value")
+	expectLength(t, store, 1)
+	store.Set("short", "again", 1000)
+	expectValue(t, store, "short", "again")
+}
+
+func testNeverExpire(t *testing.T, sut StoreUnderTest) {
+	if sut.Advance == nil {
+		t.Skip("store has no clock")
+	}
+	sut.Store.Set("key", "value", 1000)
+	sut.Store.Set("key", "forever", 0)
+	sut.Advance(100 * 365 * 24 * time.Hour)
+	expectValue(t, sut.Store, "key", "forever")
+}
+
+func testRestart(t *testing.T, sut StoreUnderTest) {
+	if sut.Restart == nil {
+		t.Skip("store does not persist")
+	}
+	store := sut.Store
+	for key, value := range values {
+		store.Set(key, value, 0)
+	}
+	store.Set("deleted", "value", 0)
+	store.Delete("deleted")
+	store.Set("overwritten", "first", 0)
+	store.Set("overwritten", "second", 0)
+	if sut.Advance != nil {
+		store.Set("expiring", "value", 1000)
+	}
+	restarted, err := sut.Restart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range values {
+		expectValue(t, restarted, key, value)
+	}
+	expectMissing(t, restarted, "deleted")
+	expectValue(t, restarted, "overwritten", "second")
+	if sut.Advance != nil {
+		expectValue(t, restarted, "expiring", "value")
+		sut.Advance(1001 * time.Millisecond)
+		expectMissing(t, restarted, "expiring")
+	}
+	expectLength(t, restarted, len(values)+1)
+}
+
+// concurrentGoroutines, concurrentKeys, and concurrentOps size the load of testConcurrent.
+const (
+	concurrentGoroutines = 8
+	concurrentKeys       = 16
+	concurrentOps        = 500
+)
+
+func testConcurrent(t *testing.T, sut StoreUnderTest) {
+	store := sut.Store
+	var wg sync.WaitGroup
+	for g := 0; g < concurrentGoroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < concurrentOps; i++ {
+				key := fmt.Sprintf("key%d", (g*7+i)%concurrentKeys)
+				switch i % 4 {
+				case 0:
+					store.Delete(key)
+				case 1, 2:
+					if err := store.Set(key, fmt.Sprintf("%s:%d:%d", key, g, i), 0); err != nil {
+						t.Errorf("Set %s: %v", key, err)
+						return
+					}
+				default:
+					if value, ok := store.Get(key); ok {
+						checkConcurrentValue(t, key, value)
+					}
+					store.Length()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	length := 0
+	for k := 0; k < concurrentKeys; k++ {
+		key := fmt.Sprintf("key%d", k)
+		if value, ok := store.Get(key); ok {
+			checkConcurrentValue(t, key, value)
+			length++
+		}
+	}
+	expectLength(t, store, length)
+}
+
+// checkConcurrentValue fails the test if value was not written for key by testConcurrent.
+func checkConcurrentValue(t *testing.T, key string, value any) {
+	if s, ok := value.(string); !ok || !strings.HasPrefix(s, key+":") {
+		t.Errorf("Expected a value of %s, got %#v", key, value)
+	}
+}
+
+func testClosed(t *testing.T, sut StoreUnderTest) {
+	if sut.Close == nil {
+		t.Skip("store can not be closed")
+	}
+	sut.Store.Set("key", "value", 0)
+	if err := sut.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sut.Store.Set("key", "other", 0); err == nil {
+		t.Errorf("Expected Set after Close to fail")
+	}
+	expectMissing(t, sut.Store, "key")
+	if err := sut.Close(); err != nil {
+		t.Errorf("Expected a second Close to succeed, got %v", err)
+	}
+}
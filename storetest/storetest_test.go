@@ -0,0 +1,102 @@
+package storetest_test
+
+import (
+	"testing"
+
+	"github.com/richi0/goKeyValueStore"
+	"github.com/richi0/goKeyValueStore/storetest"
+)
+
+// newStore returns a factory of stores with the options. If folder is true, each store gets its own cache folder
+// and can be restarted.
+func newStore(t *testing.T, folder bool, opts ...goKeyValueStore.Option) func() storetest.StoreUnderTest {
+	return func() storetest.StoreUnderTest {
+		clock := storetest.NewClock()
+		dir := ""
+		if folder {
+			dir = t.TempDir()
+		}
+		opts := append([]goKeyValueStore.Option{goKeyValueStore.WithClock(clock.Now),
+			goKeyValueStore.WithCleanerStopped(true)}, opts...)
+		open := func() (*goKeyValueStore.KeyValueStore, error) {
+			return goKeyValueStore.NewKeyValueStore(1, dir, opts...)
+		}
+		store, err := open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sut := storetest.StoreUnderTest{Store: store, Advance: clock.Advance, Close: store.Close}
+		if folder {
+			sut.Restart = func() (goKeyValueStore.Store, error) {
+				store.Close()
+				restarted, err := open()
+				if err == nil {
+					t.Cleanup(func() { restarted.Close() })
+				}
+				return restarted, err
+			}
+		}
+		return sut
+	}
+}
+
+func TestMemoryConformance(t *testing.T) {
+	storetest.RunConformance(t, newStore(t, false))
+}
+
+func TestFolderConformance(t *testing.T) {
+	storetest.RunConformance(t, newStore(t, true))
+}
+
+func TestIndexConformance(t *testing.T) {
+	storetest.RunConformance(t, newStore(t, true, goKeyValueStore.WithIndex(true)))
+}
+
+func TestPackedConformance(t *testing.T) {
+	storetest.RunConformance(t, newStore(t, true, goKeyValueStore.WithPackedSmallValues(64, 4096)))
+}
+
+// newBackendStore returns a factory of stores that save their entries in the backends returned by newBackend.
+// Each store gets its own folder, which newBackend opens again on a restart.
+func newBackendStore(t *testing.T, newBackend func(folder string) (goKeyValueStore.Backend, error), restart bool) func() storetest.StoreUnderTest {
+	return func() storetest.StoreUnderTest {
+		clock := storetest.NewClock()
+		dir := t.TempDir()
+		open := func() (*goKeyValueStore.KeyValueStore, error) {
+			backend, err := newBackend(dir)
+			if err != nil {
+				return nil, err
+			}
+			return goKeyValueStore.NewKeyValueStore(1, "", goKeyValueStore.WithClock(clock.Now),
+				goKeyValueStore.WithCleanerStopped(true), goKeyValueStore.WithBackend(backend))
+		}
+		store, err := open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sut := storetest.StoreUnderTest{Store: store, Advance: clock.Advance, Close: store.Close}
+		if restart {
+			sut.Restart = func() (goKeyValueStore.Store, error) {
+				store.Close()
+				restarted, err := open()
+				if err == nil {
+					t.Cleanup(func() { restarted.Close() })
+				}
+				return restarted, err
+			}
+		}
+		return sut
+	}
+}
+
+func TestFileBackendConformance(t *testing.T) {
+	storetest.RunConformance(t, newBackendStore(t, func(folder string) (goKeyValueStore.Backend, error) {
+		return goKeyValueStore.NewFileBackend(folder, nil)
+	}, true))
+}
+
+func TestNopBackendConformance(t *testing.T) {
+	storetest.RunConformance(t, newBackendStore(t, func(string) (goKeyValueStore.Backend, error) {
+		return goKeyValueStore.NopBackend{}, nil
+	}, false))
+}
@@ -0,0 +1,39 @@
+package goKeyValueStore_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+	"github.com/richi0/goKeyValueStore/internal/stress"
+)
+
+var stressLong = flag.Bool("stress.long", false, "run the stress test for a minute instead of a fraction of a second")
+
+func TestStress(t *testing.T) {
+	duration := 300 * time.Millisecond
+	if *stressLong {
+		duration = time.Minute
+	}
+	for _, folder := range []string{"", t.TempDir()} {
+		store, err := goKeyValueStore.NewKeyValueStore(0.01, folder, goKeyValueStore.WithIndex(folder != ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = stress.Run(store, stress.Config{
+			Seed:        time.Now().UnixNano(),
+			Goroutines:  8,
+			Duration:    duration,
+			Keys:        50,
+			MaxTTL:      50 * time.Millisecond,
+			Mix:         stress.DefaultMix,
+			CacheFolder: folder,
+		})
+		if err != nil {
+			t.Errorf("Stress run with cache folder %q failed: %v", folder, err)
+		}
+		// the cleaner must not write to the folder while the test removes it
+		store.Close()
+	}
+}
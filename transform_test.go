@@ -0,0 +1,105 @@
+package goKeyValueStore_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+type credentials struct {
+	User     string
+	Password string
+}
+
+func stripPassword(key string, value any) (any, error) {
+	creds, ok := value.(credentials)
+	if !ok {
+		return value, nil
+	}
+	return credentials{User: creds.User}, nil
+}
+
+func TestPersistTransform(t *testing.T) {
+	dir := t.TempDir()
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithPersistTransform(stripPassword))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Set("key1", credentials{User: "alice", Password: "secret"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	val, _ := store.Get("key1")
+	if val.(credentials).Password != "secret" {
+		t.Errorf("Expected the in-memory value to keep the password, got %+v", val)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileData, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(fileData), "secret") {
+		t.Errorf("Expected the password to be stripped on disk, got %s", fileData)
+	}
+	restored, err := goKeyValueStore.NewKeyValueStore(0.5, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	val, _ = restored.Get("key1")
+	if val.(map[string]any)["Password"] != "" {
+		t.Errorf("Expected the restored value to have no password, got %+v", val)
+	}
+}
+
+func TestPersistTransformError(t *testing.T) {
+	dir := t.TempDir()
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithPersistTransform(func(key string, value any) (any, error) {
+		return nil, errors.New("can not redact")
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Set("key1", "value1", 0)
+	if err == nil {
+		t.Error("Expected the transform error to be returned")
+	}
+	if _, ok := store.Get("key1"); !ok {
+		t.Errorf("Expected key1 to be present in memory")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no files, got %d", len(entries))
+	}
+}
+
+func TestLoadTransform(t *testing.T) {
+	dir := t.TempDir()
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithIndex(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("key1", "value1", 0)
+	upper := goKeyValueStore.WithLoadTransform(func(key string, value any) (any, error) {
+		return strings.ToUpper(value.(string)), nil
+	})
+	for _, index := range []bool{false, true} {
+		restored, err := goKeyValueStore.NewKeyValueStore(0.5, dir, goKeyValueStore.WithIndex(index), upper)
+		if err != nil {
+			t.Fatal(err)
+		}
+		val, _ := restored.Get("key1")
+		if val != "VALUE1" {
+			t.Errorf("Expected VALUE1, got %v", val)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package goKeyValueStore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrWrongType is returned by GetAs and TypedStore.Get if a value can not be converted to the requested type.
+var ErrWrongType = errors.New("value has the wrong type")
+
+// A TypedStore is a KeyValueStore for values of type V. Values that were read from the cache folder are decoded
+// from their JSON form into V, so structs and other types come back as V after a restart.
+type TypedStore[V any] struct {
+	store *KeyValueStore
+}
+
+// NewTypedStore creates a new TypedStore like NewKeyValueStore.
+func NewTypedStore[V any](cleanTimeout float32, cacheFolder string, opts ...Option) (*TypedStore[V], error) {
+	store, err := NewKeyValueStore(cleanTimeout, cacheFolder, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &TypedStore[V]{store: store}, nil
+}
+
+// Store returns the underlying KeyValueStore.
+func (s *TypedStore[V]) Store() *KeyValueStore {
+	return s.store
+}
+
+// Set sets a key-value pair with a TTL in milliseconds.
+func (s *TypedStore[V]) Set(key string, value V, ttl int) error {
+	return s.store.Set(key, value, ttl)
+}
+
+// Get gets a value by key. If the key does not exist, the second return value is false. If the value can not be
+// converted to V, an error wrapping ErrWrongType is returned.
+func (s *TypedStore[V]) Get(key string) (V, bool, error) {
+	return GetAs[V](s.store, key)
+}
+
+// Delete deletes a key. If the key does not exist, this function does nothing.
+func (s *TypedStore[V]) Delete(key string) error {
+	return s.store.Delete(key)
+}
+
+// Length returns the number of key-value pairs in the store.
+func (s *TypedStore[V]) Length() int {
+	return s.store.Length()
+}
+
+// Close closes the store, see KeyValueStore.Close.
+func (s *TypedStore[V]) Close() error {
+	return s.store.Close()
+}
+
+// GetAs gets a value by key and converts it to T. Values of type T are returned as they are; all others, e.g. the
+// maps and float64 values read from the cache folder, are decoded from their JSON encoding into T. If the key does
+// not exist, the second return value is false. If the value can not be converted, an error wrapping ErrWrongType
+// names the stored and the requested type.
+func GetAs[T any](store *KeyValueStore, key string) (T, bool, error) {
+	value, ok := store.Get(key)
+	if !ok {
+		var zero T
+		return zero, false, nil
+	}
+	typed, err := convertValue[T](value)
+	if err != nil {
+		return typed, false, store.keyError("get", store.storageKey(key), err)
+	}
+	return typed, true, nil
+}
+
+// convertValue converts a value to V. Values of type V are returned as they are; all others, e.g. the maps and
+// float64 values read from the cache folder, are converted through their JSON encoding.
+func convertValue[V any](value any) (V, error) {
+	if typed, ok := value.(V); ok {
+		return typed, nil
+	}
+	var typed V
+	data, err := json.Marshal(value)
+	if err == nil {
+		err = json.Unmarshal(data, &typed)
+	}
+	if err != nil {
+		var zero V
+		return zero, fmt.Errorf("%w: stored %T, requested %s: %w", ErrWrongType, value, reflect.TypeFor[V](), err)
+	}
+	return typed, nil
+}
@@ -0,0 +1,88 @@
+package goKeyValueStore_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+type profile struct {
+	Name    string
+	Age     int64
+	Created time.Time
+	Tags    []string
+	Scores  map[string]float64
+	Address *address
+}
+
+type address struct {
+	City string `json:"city"`
+}
+
+func TestTypedStoreRestart(t *testing.T) {
+	dir := t.TempDir()
+	store, err := goKeyValueStore.NewTypedStore[profile](1, dir, goKeyValueStore.WithCleanerStopped(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := profile{
+		Name:    "alice",
+		Age:     1 << 53,
+		Created: time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+		Tags:    []string{"admin"},
+		Scores:  map[string]float64{"go": 9.5},
+		Address: &address{City: "Zurich"},
+	}
+	store.Set("alice", alice, 0)
+	if got, ok, err := store.Get("alice"); err != nil || !ok || !reflect.DeepEqual(got, alice) {
+		t.Errorf("Expected alice from memory, got %+v, %v, %v", got, ok, err)
+	}
+	store.Close()
+	restarted, err := goKeyValueStore.NewTypedStore[profile](1, dir, goKeyValueStore.WithCleanerStopped(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok, err := restarted.Get("alice"); err != nil || !ok || !reflect.DeepEqual(got, alice) {
+		t.Errorf("Expected alice after a restart, got %+v, %v, %v", got, ok, err)
+	}
+	if _, ok, err := restarted.Get("missing"); ok || err != nil {
+		t.Errorf("Expected a missing key, got %v, %v", ok, err)
+	}
+}
+
+func TestTypedStoreWrongType(t *testing.T) {
+	store, _ := goKeyValueStore.NewTypedStore[int](1, "")
+	store.Store().Set("key", "text", 0)
+	if _, _, err := store.Get("key"); !errors.Is(err, goKeyValueStore.ErrWrongType) {
+		t.Errorf("Expected ErrWrongType, got %v", err)
+	}
+}
+
+func TestGetAs(t *testing.T) {
+	dir := t.TempDir()
+	store := getTestStoreWithClock(t, dir, newFakeClock())
+	alice := profile{Name: "alice", Tags: []string{"admin"}, Address: &address{City: "Zurich"}}
+	store.Set("alice", alice, 0)
+	store.Set("count", int64(1)<<60+1, 0)
+	if got, ok, err := goKeyValueStore.GetAs[profile](store, "alice"); err != nil || !ok || !reflect.DeepEqual(got, alice) {
+		t.Errorf("Expected alice from memory, got %+v, %v, %v", got, ok, err)
+	}
+	restarted, err := goKeyValueStore.NewKeyValueStore(1, dir, goKeyValueStore.WithCleanerStopped(true), goKeyValueStore.WithUseNumber(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok, err := goKeyValueStore.GetAs[profile](restarted, "alice"); err != nil || !ok || !reflect.DeepEqual(got, alice) {
+		t.Errorf("Expected alice after a restart, got %+v, %v, %v", got, ok, err)
+	}
+	if got, _, err := goKeyValueStore.GetAs[int64](restarted, "count"); err != nil || got != int64(1)<<60+1 {
+		t.Errorf("Expected the exact number after a restart, got %d, %v", got, err)
+	}
+	_, _, err = goKeyValueStore.GetAs[int](restarted, "alice")
+	if !errors.Is(err, goKeyValueStore.ErrWrongType) || !strings.Contains(err.Error(), "map[string]interface {}") || !strings.Contains(err.Error(), "requested int") {
+		t.Errorf("Expected an error naming both types, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package goKeyValueStore
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+)
+
+// ErrUnregisteredType is passed to the function of WithUnknownTypeHandler for values saved with a type name that
+// is not registered with WithType.
+var ErrUnregisteredType = errors.New("type is not registered")
+
+// typeName returns the name registered with WithType for the type of value or "" if it is not registered.
+func (d *KeyValueStore) typeName(value any) string {
+	if value == nil || d.typeNames == nil {
+		return ""
+	}
+	return d.typeNames[reflect.TypeOf(value)]
+}
+
+// restoreType converts a value read from the cache folder back to the registered type it was saved with.
+// If the type is unknown or the value does not fit it, the value is returned as it is and the function of
+// WithUnknownTypeHandler is called.
+func (d *KeyValueStore) restoreType(key string, value any, typeName string) any {
+	if typeName == "" {
+		return value
+	}
+	t, ok := d.types[typeName]
+	if !ok {
+		d.unknownType(key, typeName, ErrUnregisteredType)
+		return value
+	}
+	if reflect.TypeOf(value) == t {
+		return value
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		d.unknownType(key, typeName, err)
+		return value
+	}
+	typed := reflect.New(t)
+	err = json.Unmarshal(data, typed.Interface())
+	if err != nil {
+		d.unknownType(key, typeName, err)
+		return value
+	}
+	return typed.Elem().Interface()
+}
+
+// unknownType calls the function of WithUnknownTypeHandler.
+func (d *KeyValueStore) unknownType(key string, typeName string, err error) {
+	if d.onUnknownType != nil {
+		d.onUnknownType(d.redactKey(key), typeName, err)
+	}
+}
@@ -0,0 +1,84 @@
+package goKeyValueStore_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+func TestWithTypeRestoresTypes(t *testing.T) {
+	dir := t.TempDir()
+	opts := []goKeyValueStore.Option{
+		goKeyValueStore.WithType("profile", profile{}),
+		goKeyValueStore.WithType("address", &address{}),
+	}
+	store, err := goKeyValueStore.NewKeyValueStore(60, dir, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := profile{
+		Name:    "Ada",
+		Age:     36,
+		Created: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Tags:    []string{"a", "b"},
+		Scores:  map[string]float64{"x": 1.5},
+	}
+	store.Set("profile", want, 0)
+	store.Set("address", &address{City: "Zurich"}, 0)
+	store.Set("plain", map[string]any{"a": 1.0}, 0)
+	store.Close()
+
+	store, err = goKeyValueStore.NewKeyValueStore(60, dir, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	value, _ := store.Get("profile")
+	got, ok := value.(profile)
+	if !ok {
+		t.Fatalf("Expected a profile, got %T", value)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+	value, _ = store.Get("address")
+	if got, ok := value.(*address); !ok || got.City != "Zurich" {
+		t.Errorf("Expected an *address in Zurich, got %#v", value)
+	}
+	value, _ = store.Get("plain")
+	if _, ok := value.(map[string]any); !ok {
+		t.Errorf("Expected an unregistered map to stay a map, got %T", value)
+	}
+}
+
+func TestWithTypeUnknownType(t *testing.T) {
+	dir := t.TempDir()
+	store, err := goKeyValueStore.NewKeyValueStore(60, dir, goKeyValueStore.WithType("profile", profile{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("profile", profile{Name: "Ada"}, 0)
+	store.Close()
+
+	var unknown []string
+	store, err = goKeyValueStore.NewKeyValueStore(60, dir, goKeyValueStore.WithUnknownTypeHandler(func(key string, typeName string, err error) {
+		if !errors.Is(err, goKeyValueStore.ErrUnregisteredType) {
+			t.Errorf("Expected ErrUnregisteredType, got %v", err)
+		}
+		unknown = append(unknown, key+" "+typeName)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	value, _ := store.Get("profile")
+	if got, ok := value.(map[string]any); !ok || got["Name"] != "Ada" {
+		t.Errorf("Expected the profile as a map, got %#v", value)
+	}
+	if !reflect.DeepEqual(unknown, []string{"profile profile"}) {
+		t.Errorf("Expected the handler to be called for profile, got %v", unknown)
+	}
+}
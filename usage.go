@@ -0,0 +1,169 @@
+package goKeyValueStore
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// thresholdHysteresis is the fraction of the limit usage has to drop below a threshold before the threshold
+// fires again, so usage moving around the threshold does not fire it on every write.
+const thresholdHysteresis = 0.05
+
+// A Metric is a measure of the usage of a store.
+type Metric int
+
+const (
+	// MetricEntries is the number of entries, limited by WithMaxEntries.
+	MetricEntries Metric = iota
+	// MetricBytes is the size of all entries, limited by WithMaxBytes.
+	MetricBytes
+	// MetricDisk is the free disk space required by WithMinFreeDiskBytes relative to the free disk space of the
+	// cache folder. Current is the required and Limit the free space, so usage reaches the limit when new entries
+	// stop being persisted. The free disk space is checked at most once per second.
+	MetricDisk
+)
+
+// String returns the name of the metric.
+func (m Metric) String() string {
+	switch m {
+	case MetricEntries:
+		return "entries"
+	case MetricBytes:
+		return "bytes"
+	case MetricDisk:
+		return "disk"
+	default:
+		return "unknown"
+	}
+}
+
+// Usage is the usage of a store for a metric when a threshold was crossed.
+// Above is true if usage rose above the threshold and false if it dropped below it again.
+type Usage struct {
+	Metric  Metric
+	Current int64
+	Limit   int64
+	Above   bool
+}
+
+// A threshold is a function registered with OnThreshold.
+type threshold struct {
+	metric   Metric
+	fraction float64
+	fn       func(Usage)
+	above    bool
+}
+
+// OnThreshold registers fn to be called when the usage of metric rises to fraction of its limit, e.g. 0.8 for
+// 80%, and when it drops below it again. To avoid repeated calls around the threshold, usage has to drop 5% of
+// the limit below the threshold before it counts as dropped. fn is called without holding any lock of the store,
+// after the write that crossed the threshold. Metrics without a limit never cross a threshold.
+func (d *KeyValueStore) OnThreshold(metric Metric, fraction float64, fn func(Usage)) {
+	d.lazyInit()
+	d.thresholdMu.Lock()
+	defer d.thresholdMu.Unlock()
+	d.thresholds = append(d.thresholds, &threshold{metric: metric, fraction: fraction, fn: fn})
+}
+
+// checkThresholds calls the functions of all thresholds crossed since the last check.
+// The caller must not hold the lock of the store.
+func (d *KeyValueStore) checkThresholds() {
+	d.thresholdMu.Lock()
+	if len(d.thresholds) == 0 {
+		d.thresholdMu.Unlock()
+		return
+	}
+	d.mu.RLock()
+	current := map[Metric]int64{MetricEntries: int64(len(d.data)), MetricBytes: d.bytes}
+	d.mu.RUnlock()
+	limits := map[Metric]int64{MetricEntries: d.maxEntries.Load(), MetricBytes: d.maxBytes.Load()}
+	if d.hasThreshold(MetricDisk) && d.minFreeDiskBytes > 0 {
+		d.diskMu.Lock()
+		free, ok := d.freeDisk()
+		d.diskMu.Unlock()
+		if ok {
+			current[MetricDisk] = d.minFreeDiskBytes
+			// a full disk has a limit of one byte, so it is above every threshold
+			limits[MetricDisk] = int64(max(min(free, math.MaxInt64), 1))
+		}
+	}
+	crossed := []func(){}
+	for _, t := range d.thresholds {
+		usage := Usage{Metric: t.metric, Current: current[t.metric], Limit: limits[t.metric]}
+		if usage.Limit <= 0 {
+			continue
+		}
+		level := float64(usage.Current) / float64(usage.Limit)
+		switch {
+		case !t.above && level >= t.fraction:
+			t.above = true
+		case t.above && level < t.fraction-thresholdHysteresis:
+			t.above = false
+		default:
+			continue
+		}
+		usage.Above = t.above
+		fn := t.fn
+		crossed = append(crossed, func() { fn(usage) })
+	}
+	d.thresholdMu.Unlock()
+	for _, fn := range crossed {
+		fn()
+	}
+}
+
+// hasThreshold returns true if a threshold is registered for metric. The caller must hold thresholdMu.
+func (d *KeyValueStore) hasThreshold(metric Metric) bool {
+	for _, t := range d.thresholds {
+		if t.metric == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// putNode adds a node to the store, replacing the node of the same key, and updates the size of all entries.
+// The caller must hold the write lock.
+func (d *KeyValueStore) putNode(node *node) {
+	if d.maxBytes.Load() > 0 {
+		node.encodedSize = encodedSize(node)
+		if old, ok := d.data[node.Key]; ok {
+			d.bytes -= old.encodedSize
+		}
+		d.bytes += node.encodedSize
+	}
+	old, ok := d.data[node.Key]
+	if !ok {
+		d.countInQuotas(node.Key, 1)
+	}
+	d.trackDerived(old, node)
+	d.data[node.Key] = node
+	d.eviction.put(node)
+	d.markReadViewDirty()
+}
+
+// removeNode removes the node of a key from the store and updates the size of all entries.
+// The caller must hold the write lock.
+func (d *KeyValueStore) removeNode(key string) {
+	if old, ok := d.data[key]; ok {
+		d.bytes -= old.encodedSize
+		delete(d.data, key)
+		d.eviction.remove(key)
+		d.countInQuotas(key, -1)
+		d.trackDerived(old, nil)
+		d.markReadViewDirty()
+	}
+}
+
+// encodedSize returns the size of a node, which is the length of its JSON encoding. The size of nodes whose
+// value was not loaded yet is the size of their file.
+func encodedSize(node *node) int64 {
+	if node.lazy != nil {
+		return int64(node.size)
+	}
+	data, err := json.Marshal(node)
+	if err != nil {
+		return 0
+	}
+	return int64(len(data))
+}
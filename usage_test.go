@@ -0,0 +1,150 @@
+package goKeyValueStore_test
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+type usageRecorder struct {
+	mu     sync.Mutex
+	usages []goKeyValueStore.Usage
+}
+
+func (r *usageRecorder) record(usage goKeyValueStore.Usage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.usages = append(r.usages, usage)
+}
+
+func (r *usageRecorder) crossings() (up int, down int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, usage := range r.usages {
+		if usage.Above {
+			up++
+		} else {
+			down++
+		}
+	}
+	return up, down
+}
+
+func TestThresholdEntries(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(1, "", goKeyValueStore.WithMaxEntries(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := &usageRecorder{}
+	store.OnThreshold(goKeyValueStore.MetricEntries, 0.8, recorder.record)
+	for i := 0; i < 9; i++ {
+		store.Set(fmt.Sprintf("key%d", i), i, 0)
+	}
+	// moving around the threshold must not fire it again
+	store.Delete("key8")
+	store.Set("key8", 8, 0)
+	for i := 0; i < 9; i++ {
+		store.Delete(fmt.Sprintf("key%d", i))
+	}
+	up, down := recorder.crossings()
+	if up != 1 || down != 1 {
+		t.Errorf("Expected 1 up and 1 down crossing, got %d and %d", up, down)
+	}
+	first := recorder.usages[0]
+	if first.Metric != goKeyValueStore.MetricEntries || first.Current != 8 || first.Limit != 10 || !first.Above {
+		t.Errorf("Unexpected usage %+v", first)
+	}
+}
+
+func TestThresholdBytes(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(1, "", goKeyValueStore.WithMaxBytes(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := &usageRecorder{}
+	store.OnThreshold(goKeyValueStore.MetricBytes, 0.8, recorder.record)
+	store.Set("key", make([]int, 100), 0)
+	if up, _ := recorder.crossings(); up != 0 {
+		t.Errorf("Expected no crossing, got %d", up)
+	}
+	store.Set("key", make([]int, 400), 0)
+	store.Set("key", make([]int, 390), 0)
+	store.Set("key", make([]int, 10), 0)
+	up, down := recorder.crossings()
+	if up != 1 || down != 1 {
+		t.Errorf("Expected 1 up and 1 down crossing, got %d and %d", up, down)
+	}
+	if usage := recorder.usages[0]; usage.Metric != goKeyValueStore.MetricBytes || usage.Current < 800 || usage.Limit != 1000 {
+		t.Errorf("Unexpected usage %+v", usage)
+	}
+}
+
+func TestThresholdDisk(t *testing.T) {
+	clock := newFakeClock()
+	var free atomic.Uint64
+	free.Store(2000)
+	store, err := goKeyValueStore.NewKeyValueStore(0.5, t.TempDir(),
+		goKeyValueStore.WithClock(clock.Now),
+		goKeyValueStore.WithMinFreeDiskBytes(1000),
+		goKeyValueStore.WithFreeDiskSpace(func(path string) (uint64, error) {
+			return free.Load(), nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := &usageRecorder{}
+	store.OnThreshold(goKeyValueStore.MetricDisk, 0.8, recorder.record)
+	store.Set("key1", "value", 0)
+	if up, _ := recorder.crossings(); up != 0 {
+		t.Errorf("Expected no crossing, got %d", up)
+	}
+	free.Store(1200)
+	clock.Advance(2 * time.Second)
+	store.Set("key2", "value", 0)
+	free.Store(1300)
+	clock.Advance(2 * time.Second)
+	store.Set("key3", "value", 0)
+	free.Store(2000)
+	clock.Advance(2 * time.Second)
+	store.Set("key4", "value", 0)
+	up, down := recorder.crossings()
+	if up != 1 || down != 1 {
+		t.Errorf("Expected 1 up and 1 down crossing, got %d and %d", up, down)
+	}
+	if usage := recorder.usages[0]; usage.Metric != goKeyValueStore.MetricDisk || usage.Current != 1000 || usage.Limit != 1200 {
+		t.Errorf("Unexpected usage %+v", usage)
+	}
+}
+
+func TestMaxBytesEvicts(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(1, "", goKeyValueStore.WithMaxBytes(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("old", make([]int, 150), 1000)
+	store.Set("new", make([]int, 150), 0)
+	if _, ok := store.Get("old"); ok {
+		t.Error("Expected old to be evicted")
+	}
+	if _, ok := store.Get("new"); !ok {
+		t.Error("Expected new to be present")
+	}
+}
+
+func TestThresholdCalledWithoutLock(t *testing.T) {
+	store, err := goKeyValueStore.NewKeyValueStore(1, "", goKeyValueStore.WithMaxEntries(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.OnThreshold(goKeyValueStore.MetricEntries, 0.5, func(usage goKeyValueStore.Usage) {
+		store.Set("from-callback", usage.Current, 0)
+	})
+	store.Set("key", "value", 0)
+	if _, ok := store.Get("from-callback"); !ok {
+		t.Error("Expected the callback to be able to write to the store")
+	}
+}
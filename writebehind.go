@@ -0,0 +1,272 @@
+package goKeyValueStore
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A writeBehind writes and deletes the files of a store in the background, see WithWriteBehind and
+// WithFlushInterval.
+type writeBehind struct {
+	// limit is the maximum number of queued keys. A value of 0 means no limit.
+	limit int
+	// interval is the time between batches. A value of 0 writes batches as soon as operations are queued.
+	interval time.Duration
+	mu       sync.Mutex
+	// changed is signaled when an operation is queued or finished, when a batch is due, and when the writer stops.
+	changed *sync.Cond
+	// pending holds the latest operation of every key in order of the first queued operation.
+	pending   map[string]*pendingWrite
+	order     []string
+	inFlight  []*pendingWrite
+	seq       uint64
+	due       bool
+	flushing  int
+	errs      []error
+	stopping  bool
+	ticker    *time.Ticker
+	done      chan struct{}
+	coalesced atomic.Uint64
+}
+
+// A pendingWrite is a queued operation on the file of a key. A nil node deletes the file.
+type pendingWrite struct {
+	key  string
+	node *node
+	seq  uint64
+}
+
+// newWriteBehind creates a queue of up to limit keys that is written every interval.
+func newWriteBehind(limit int, interval time.Duration) *writeBehind {
+	w := &writeBehind{limit: limit, interval: interval, pending: map[string]*pendingWrite{}, done: make(chan struct{})}
+	w.changed = sync.NewCond(&w.mu)
+	return w
+}
+
+// enqueue queues the operation on the file of key, replacing a queued operation of the same key. It blocks while
+// the queue is full. It returns false if the writer stopped; the caller must then write the file itself.
+func (w *writeBehind) enqueue(key string, node *node) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for !w.stopping && w.pending[key] == nil && w.full() {
+		w.changed.Wait()
+	}
+	if w.stopping {
+		return false
+	}
+	w.seq++
+	if op, ok := w.pending[key]; ok {
+		// the operation keeps its place and sequence number, so a flush waiting for it is not delayed by later writes
+		op.node = node
+		w.coalesced.Add(1)
+	} else {
+		w.pending[key] = &pendingWrite{key: key, node: node, seq: w.seq}
+		w.order = append(w.order, key)
+	}
+	w.changed.Broadcast()
+	return true
+}
+
+// cancel drops the queued operation on the file of key and waits until an operation on it in flight is done, so
+// the caller can delete the file itself without a queued write bringing it back.
+func (w *writeBehind) cancel(key string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for w.writing(key) {
+		w.changed.Wait()
+	}
+	if _, ok := w.pending[key]; !ok {
+		return
+	}
+	delete(w.pending, key)
+	w.order = slices.DeleteFunc(w.order, func(queued string) bool { return queued == key })
+	w.coalesced.Add(1)
+	w.changed.Broadcast()
+}
+
+// writing reports whether an operation on the file of key is in flight. The caller must hold mu.
+func (w *writeBehind) writing(key string) bool {
+	for _, op := range w.inFlight {
+		if op.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// full reports whether the queue holds the maximum number of keys. The caller must hold mu.
+func (w *writeBehind) full() bool {
+	return w.limit > 0 && len(w.pending) >= w.limit
+}
+
+// next removes all queued operations once a batch is due and marks them in flight. A batch is due at once
+// without an interval and otherwise when the interval elapsed, the queue is full, a flush waits, or the writer
+// stops. next returns nil when the writer stopped and the queue is empty.
+func (w *writeBehind) next() []*pendingWrite {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for !w.stopping && (len(w.order) == 0 || (w.interval > 0 && !w.due && w.flushing == 0 && !w.full())) {
+		w.changed.Wait()
+	}
+	if len(w.order) == 0 {
+		return nil
+	}
+	batch := make([]*pendingWrite, len(w.order))
+	for i, key := range w.order {
+		batch[i] = w.pending[key]
+	}
+	w.pending = map[string]*pendingWrite{}
+	w.order = nil
+	w.inFlight = batch
+	w.due = false
+	w.changed.Broadcast()
+	return batch
+}
+
+// finish marks the batch in flight as done.
+func (w *writeBehind) finish(errs []error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.inFlight = nil
+	w.errs = append(w.errs, errs...)
+	w.changed.Broadcast()
+}
+
+// tick marks a batch as due every interval until the writer stops.
+func (w *writeBehind) tick() {
+	for {
+		select {
+		case <-w.ticker.C:
+			w.mu.Lock()
+			w.due = true
+			w.changed.Broadcast()
+			w.mu.Unlock()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// flush waits until all operations queued before it are done and returns the errors of the operations that
+// failed since the last flush.
+func (w *writeBehind) flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	target := w.seq
+	w.flushing++
+	w.changed.Broadcast()
+	for w.waitsFor(target) {
+		w.changed.Wait()
+	}
+	w.flushing--
+	err := errors.Join(w.errs...)
+	w.errs = nil
+	return err
+}
+
+// waitsFor reports whether an operation queued up to seq is queued or in flight. The caller must hold mu.
+func (w *writeBehind) waitsFor(seq uint64) bool {
+	for _, op := range w.inFlight {
+		if op.seq <= seq {
+			return true
+		}
+	}
+	for _, op := range w.pending {
+		if op.seq <= seq {
+			return true
+		}
+	}
+	return false
+}
+
+// close lets the writer finish all queued operations and waits until it stopped.
+func (w *writeBehind) close() {
+	w.mu.Lock()
+	w.stopping = true
+	w.changed.Broadcast()
+	w.mu.Unlock()
+	if w.ticker != nil {
+		w.ticker.Stop()
+	}
+	<-w.done
+}
+
+// writeBehindLoop performs the queued operations until the write-behind queue is closed and empty. It does not
+// hold the lock of the store, so reads and writes do not wait for the disk. Errors are delivered to the OnError
+// function by the next write, Flush, or Close.
+func (d *KeyValueStore) writeBehindLoop(w *writeBehind) {
+	defer close(w.done)
+	for {
+		batch := w.next()
+		if batch == nil {
+			return
+		}
+		var errs []error
+		for _, op := range batch {
+			var err error
+			if op.node == nil {
+				err = d.removeFile(op.key)
+			} else {
+				err = d.writeBehindFile(op.node)
+			}
+			if err != nil {
+				d.queueError(err)
+				errs = append(errs, err)
+			}
+		}
+		w.finish(errs)
+	}
+}
+
+// writeBehindFile writes the file of a node without the lock of the store. Unlike writeNode, it does not record
+// the size of the file in the node.
+func (d *KeyValueStore) writeBehindFile(node *node) error {
+	err := d.checkDiskSpace()
+	if err != nil {
+		return d.keyError("write cache file", node.Key, err)
+	}
+	data, err := d.encodeNode(node)
+	if err != nil {
+		return err
+	}
+	fileName, err := d.getFileName(node.Key)
+	if err != nil {
+		return err
+	}
+	err = d.checkFileOwner(fileName, node.Key)
+	if err != nil {
+		return err
+	}
+	err = d.writeFileAtomic(fileName, data)
+	if err != nil {
+		return d.keyError("write cache file", node.Key, err)
+	}
+	return nil
+}
+
+// startWriteBehind starts the writer of WithWriteBehind and WithFlushInterval.
+func (d *KeyValueStore) startWriteBehind() {
+	d.writeBehind = newWriteBehind(d.writeBehindSize, d.flushInterval)
+	if d.flushInterval > 0 {
+		d.writeBehind.ticker = time.NewTicker(d.flushInterval)
+		go d.writeBehind.tick()
+	}
+	go d.writeBehindLoop(d.writeBehind)
+}
+
+// Flush waits until all files queued by WithWriteBehind or WithFlushInterval before the call are written or
+// deleted, so the entries survive a crash from then on. It returns the errors of the files that could not be
+// written since the last Flush; they are also delivered to the OnError function. Without WithWriteBehind and
+// WithFlushInterval, Flush does nothing.
+func (d *KeyValueStore) Flush() error {
+	d.lazyInit()
+	if d.writeBehind == nil {
+		return nil
+	}
+	err := d.writeBehind.flush()
+	d.afterWrite()
+	return err
+}
@@ -0,0 +1,243 @@
+package goKeyValueStore_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+	"github.com/richi0/goKeyValueStore/faultfs"
+)
+
+func TestWriteBehindOrdering(t *testing.T) {
+	dir := t.TempDir()
+	fsys := faultfs.New(goKeyValueStore.OSFS{})
+	store, err := goKeyValueStore.NewKeyValueStore(60, dir, goKeyValueStore.WithWriteBehind(16), goKeyValueStore.WithFilesystem(fsys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	fsys.SetLatency(time.Millisecond)
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key%d", i%5)
+		store.Set(key, i, 0)
+		store.Delete(key)
+	}
+	store.Set("kept", "first", 0)
+	store.Delete("kept")
+	store.Set("kept", "second", 0)
+	err = store.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := countCacheFiles(t, dir); count != 1 {
+		t.Errorf("Expected only the file of kept, got %d files", count)
+	}
+	store.Close()
+
+	store = getTestStoreWithClock(t, dir, newFakeClock())
+	defer store.Close()
+	if value, _ := store.Get("kept"); value != "second" {
+		t.Errorf("Expected second, got %v", value)
+	}
+}
+
+func TestWriteBehindCloseDrainsQueue(t *testing.T) {
+	dir := t.TempDir()
+	fsys := faultfs.New(goKeyValueStore.OSFS{})
+	store, err := goKeyValueStore.NewKeyValueStore(60, dir, goKeyValueStore.WithWriteBehind(100), goKeyValueStore.WithFilesystem(fsys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys.SetLatency(5 * time.Millisecond)
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		store.Set(fmt.Sprintf("key%d", i), i, 0)
+	}
+	if elapsed := time.Since(start); elapsed > 25*time.Millisecond {
+		t.Errorf("Expected Set not to wait for the disk, took %v", elapsed)
+	}
+	store.Close()
+	if count := countCacheFiles(t, dir); count != 10 {
+		t.Errorf("Expected 10 files after Close, got %d", count)
+	}
+}
+
+func TestWriteBehindErrors(t *testing.T) {
+	fsys := faultfs.New(goKeyValueStore.OSFS{})
+	store, err := goKeyValueStore.NewKeyValueStore(60, t.TempDir(), goKeyValueStore.WithWriteBehind(16), goKeyValueStore.WithFilesystem(fsys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	var reported []error
+	store.OnError(func(err error) { reported = append(reported, err) })
+	injected := errors.New("disk is full")
+	fsys.FailNextWrites(1, injected)
+	err = store.Set("key", "value", 0)
+	if err != nil {
+		t.Errorf("Expected Set to queue the file, got %v", err)
+	}
+	err = store.Flush()
+	if !errors.Is(err, injected) {
+		t.Errorf("Expected Flush to return the write error, got %v", err)
+	}
+	if len(reported) != 1 || !errors.Is(reported[0], injected) {
+		t.Errorf("Expected the write error to be reported, got %v", reported)
+	}
+	if err := store.Flush(); err != nil {
+		t.Errorf("Expected no error from a second Flush, got %v", err)
+	}
+}
+
+func TestWriteBehindWithIndex(t *testing.T) {
+	_, err := goKeyValueStore.NewKeyValueStore(60, t.TempDir(), goKeyValueStore.WithWriteBehind(16), goKeyValueStore.WithIndex(true))
+	if err == nil {
+		t.Error("Expected an error for write-behind with the index")
+	}
+	_, err = goKeyValueStore.NewKeyValueStore(60, t.TempDir(), goKeyValueStore.WithFlushInterval(time.Second), goKeyValueStore.WithIndex(true))
+	if err == nil {
+		t.Error("Expected an error for a flush interval with the index")
+	}
+}
+
+func TestFlushIntervalCoalesces(t *testing.T) {
+	dir := t.TempDir()
+	store, err := goKeyValueStore.NewKeyValueStore(60, dir, goKeyValueStore.WithFlushInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	for i := 0; i < 10; i++ {
+		store.Set("key", i, 0)
+	}
+	store.Set("deleted", "value", 0)
+	store.Delete("deleted")
+	if count := countCacheFiles(t, dir); count != 0 {
+		t.Errorf("Expected no files before the interval, got %d", count)
+	}
+	if coalesced := store.Stats().CoalescedWrites; coalesced != 10 {
+		t.Errorf("Expected 10 coalesced writes, got %d", coalesced)
+	}
+
+	// a store opened now sees the folder as after a crash: the writes of the interval are lost
+	crashed := getTestStoreWithClock(t, dir, newFakeClock())
+	if _, ok := crashed.Get("key"); ok {
+		t.Error("Expected key to be missing before the flush")
+	}
+	crashed.Close()
+
+	err = store.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := countCacheFiles(t, dir); count != 1 {
+		t.Errorf("Expected only the file of key after Flush, got %d files", count)
+	}
+	crashed = getTestStoreWithClock(t, dir, newFakeClock())
+	defer crashed.Close()
+	if value, _ := crashed.Get("key"); value != float64(9) {
+		t.Errorf("Expected the latest value 9, got %v", value)
+	}
+	if _, ok := crashed.Get("deleted"); ok {
+		t.Error("Expected deleted to stay deleted")
+	}
+}
+
+func TestFlushIntervalWritesPeriodically(t *testing.T) {
+	dir := t.TempDir()
+	store, err := goKeyValueStore.NewKeyValueStore(60, dir, goKeyValueStore.WithFlushInterval(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	store.Set("key", "value", 0)
+	deadline := time.Now().Add(2 * time.Second)
+	for countCacheFiles(t, dir) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the file to be written after the interval")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestFlushIntervalNeverResurrectsDeletedKeys(t *testing.T) {
+	dir := t.TempDir()
+	fsys := faultfs.New(goKeyValueStore.OSFS{})
+	store, err := goKeyValueStore.NewKeyValueStore(60, dir, goKeyValueStore.WithFlushInterval(time.Millisecond), goKeyValueStore.WithFilesystem(fsys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys.SetLatency(2 * time.Millisecond)
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key%d", i%3)
+		store.Set(key, i, 0)
+		time.Sleep(time.Duration(i%3) * time.Millisecond)
+		store.Delete(key)
+	}
+	store.Close()
+	if count := countCacheFiles(t, dir); count != 0 {
+		t.Errorf("Expected no files of deleted keys, got %d", count)
+	}
+}
+
+func TestFlushIntervalDeletesAtOnce(t *testing.T) {
+	dir := t.TempDir()
+	store, err := goKeyValueStore.NewKeyValueStore(60, dir, goKeyValueStore.WithFlushInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	store.Set("key", "value", 0)
+	err = store.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Delete("key")
+
+	// a store opened now sees the folder as after a crash before the next interval
+	crashed := getTestStoreWithClock(t, dir, newFakeClock())
+	defer crashed.Close()
+	if _, ok := crashed.Get("key"); ok {
+		t.Error("Expected the deleted key to stay deleted after a crash")
+	}
+}
+
+func TestFlushIntervalWithQueueSize(t *testing.T) {
+	dir := t.TempDir()
+	store, err := goKeyValueStore.NewKeyValueStore(60, dir, goKeyValueStore.WithFlushInterval(time.Hour), goKeyValueStore.WithWriteBehind(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	for i := 0; i < 3; i++ {
+		store.Set(fmt.Sprintf("key%d", i), i, 0)
+	}
+	// the full queue is written long before the interval elapsed
+	deadline := time.Now().Add(2 * time.Second)
+	for countCacheFiles(t, dir) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the files of the full queue to be written")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// BenchmarkSetWriteBehind compares the latency of Set with synchronous writes and with write-behind.
+func BenchmarkSetWriteBehind(b *testing.B) {
+	for _, queueSize := range []int{0, 1024} {
+		b.Run(fmt.Sprintf("queue=%d", queueSize), func(b *testing.B) {
+			store, err := goKeyValueStore.NewKeyValueStore(60, b.TempDir(), goKeyValueStore.WithWriteBehind(queueSize))
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				store.Set(fmt.Sprintf("key%d", i%100), "value", 0)
+			}
+			b.StopTimer()
+			store.Close()
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package goKeyValueStore_test
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/richi0/goKeyValueStore"
+)
+
+type service struct {
+	cache goKeyValueStore.KeyValueStore
+}
+
+func TestZeroValueMethods(t *testing.T) {
+	tests := map[string]func(store *goKeyValueStore.KeyValueStore){
+		"AdjustTTL": func(store *goKeyValueStore.KeyValueStore) { store.AdjustTTL(time.Second, nil) },
+		"SetManyDetailed": func(store *goKeyValueStore.KeyValueStore) {
+			store.SetManyDetailed([]goKeyValueStore.Entry{{Key: "key"}})
+		},
+		"GetAllOrNone":  func(store *goKeyValueStore.KeyValueStore) { store.GetAllOrNone("key") },
+		"StartCleaning": func(store *goKeyValueStore.KeyValueStore) { store.StartCleaning(); store.StopCleaning() },
+		"StopCleaning":  func(store *goKeyValueStore.KeyValueStore) { store.StopCleaning() },
+		"CleanerStatus": func(store *goKeyValueStore.KeyValueStore) { store.CleanerStatus() },
+		"LastSweep":     func(store *goKeyValueStore.KeyValueStore) { store.LastSweep() },
+		"OnSweep":       func(store *goKeyValueStore.KeyValueStore) { store.OnSweep(func(goKeyValueStore.SweepReport) {}) },
+		"CleanNow":      func(store *goKeyValueStore.KeyValueStore) { store.CleanNow() },
+		"Compact":       func(store *goKeyValueStore.KeyValueStore) { store.Compact() },
+		"Flush":         func(store *goKeyValueStore.KeyValueStore) { store.Flush() },
+		"Config":        func(store *goKeyValueStore.KeyValueStore) { store.Config() },
+		"Reconfigure": func(store *goKeyValueStore.KeyValueStore) {
+			maxEntries := 1
+			store.Reconfigure(goKeyValueStore.ConfigPatch{MaxEntries: &maxEntries})
+		},
+		"Stats":   func(store *goKeyValueStore.KeyValueStore) { store.Stats() },
+		"Explain": func(store *goKeyValueStore.KeyValueStore) { store.Explain("key") },
+		"Export": func(store *goKeyValueStore.KeyValueStore) {
+			store.Export(&bytes.Buffer{}, goKeyValueStore.ExportOptions{})
+		},
+		"Import":             func(store *goKeyValueStore.KeyValueStore) { store.Import(strings.NewReader("")) },
+		"CacheFolder":        func(store *goKeyValueStore.KeyValueStore) { store.CacheFolder() },
+		"Health":             func(store *goKeyValueStore.KeyValueStore) { store.Health() },
+		"State":              func(store *goKeyValueStore.KeyValueStore) { store.State() },
+		"Close":              func(store *goKeyValueStore.KeyValueStore) { store.Close() },
+		"AppendToSlice":      func(store *goKeyValueStore.KeyValueStore) { store.AppendToSlice("key", 1, "item") },
+		"SliceLen":           func(store *goKeyValueStore.KeyValueStore) { store.SliceLen("key") },
+		"Warmup":             func(store *goKeyValueStore.KeyValueStore) { store.Warmup() },
+		"OnError":            func(store *goKeyValueStore.KeyValueStore) { store.OnError(func(error) {}) },
+		"FollowChanges":      func(store *goKeyValueStore.KeyValueStore) { store.FollowChanges(time.Second)() },
+		"NextExpiration":     func(store *goKeyValueStore.KeyValueStore) { store.NextExpiration() },
+		"ExpirationForecast": func(store *goKeyValueStore.KeyValueStore) { store.ExpirationForecast(time.Hour, 2) },
+		"GetOrSetFunc": func(store *goKeyValueStore.KeyValueStore) {
+			store.GetOrSetFunc("key", 0, func() (any, error) { return 1, nil })
+		},
+		"RebuildIndex":         func(store *goKeyValueStore.KeyValueStore) { store.RebuildIndex() },
+		"Revision":             func(store *goKeyValueStore.KeyValueStore) { store.Revision("key") },
+		"Set":                  func(store *goKeyValueStore.KeyValueStore) { store.Set("key", "value", 0) },
+		"SetDerived":           func(store *goKeyValueStore.KeyValueStore) { store.SetDerived("key", "value", "source") },
+		"SetDerivedTTL":        func(store *goKeyValueStore.KeyValueStore) { store.SetDerivedTTL("key", "value", "source", 1) },
+		"SetCtx":               func(store *goKeyValueStore.KeyValueStore) { store.SetCtx(context.Background(), "key", "value", 0) },
+		"Get":                  func(store *goKeyValueStore.KeyValueStore) { store.Get("key") },
+		"GetCtx":               func(store *goKeyValueStore.KeyValueStore) { store.GetCtx(context.Background(), "key") },
+		"GetWithAge":           func(store *goKeyValueStore.KeyValueStore) { store.GetWithAge("key") },
+		"Delete":               func(store *goKeyValueStore.KeyValueStore) { store.Delete("key") },
+		"DeleteCtx":            func(store *goKeyValueStore.KeyValueStore) { store.DeleteCtx(context.Background(), "key") },
+		"Length":               func(store *goKeyValueStore.KeyValueStore) { store.Length() },
+		"DeleteExpiringBefore": func(store *goKeyValueStore.KeyValueStore) { store.DeleteExpiringBefore(time.Now()) },
+		"WithKeyLock": func(store *goKeyValueStore.KeyValueStore) {
+			store.WithKeyLock("key", func(h goKeyValueStore.KeyHandle) error { return h.Set("value", 0) })
+		},
+		"GetOrCompute": func(store *goKeyValueStore.KeyValueStore) {
+			store.GetOrCompute("key", 0, func() (any, error) { return "value", nil })
+		},
+		"Housekeep":       func(store *goKeyValueStore.KeyValueStore) { store.Housekeep() },
+		"Expire":          func(store *goKeyValueStore.KeyValueStore) { store.Expire("key", 1000) },
+		"TTL":             func(store *goKeyValueStore.KeyValueStore) { store.TTL("key") },
+		"Has":             func(store *goKeyValueStore.KeyValueStore) { store.Has("key") },
+		"GetMetadata":     func(store *goKeyValueStore.KeyValueStore) { store.GetMetadata("key") },
+		"Persist":         func(store *goKeyValueStore.KeyValueStore) { store.Persist("key") },
+		"GetOrSet":        func(store *goKeyValueStore.KeyValueStore) { store.GetOrSet("key", "value", 0) },
+		"CompareAndSwap":  func(store *goKeyValueStore.KeyValueStore) { store.CompareAndSwap("key", "old", "new", 0) },
+		"Incr":            func(store *goKeyValueStore.KeyValueStore) { store.Incr("key", 1, 0) },
+		"Decr":            func(store *goKeyValueStore.KeyValueStore) { store.Decr("key", 1, 0) },
+		"Append":          func(store *goKeyValueStore.KeyValueStore) { store.Append("key", "suffix", 0) },
+		"Pop":             func(store *goKeyValueStore.KeyValueStore) { store.Pop("key") },
+		"GetSet":          func(store *goKeyValueStore.KeyValueStore) { store.GetSet("key", "value", 0) },
+		"Clear":           func(store *goKeyValueStore.KeyValueStore) { store.Clear() },
+		"SetMany":         func(store *goKeyValueStore.KeyValueStore) { store.SetMany(nil) },
+		"GetMany":         func(store *goKeyValueStore.KeyValueStore) { store.GetMany([]string{"key"}) },
+		"DeleteMany":      func(store *goKeyValueStore.KeyValueStore) { store.DeleteMany([]string{"key"}) },
+		"KeysWithPrefix":  func(store *goKeyValueStore.KeyValueStore) { store.KeysWithPrefix("k") },
+		"GetByPrefix":     func(store *goKeyValueStore.KeyValueStore) { store.GetByPrefix("k") },
+		"DeleteByPrefix":  func(store *goKeyValueStore.KeyValueStore) { store.DeleteByPrefix("k") },
+		"Match":           func(store *goKeyValueStore.KeyValueStore) { store.Match("k*") },
+		"Scan":            func(store *goKeyValueStore.KeyValueStore) { store.Scan("", 1) },
+		"KeysMatching":    func(store *goKeyValueStore.KeyValueStore) { store.KeysMatching(regexp.MustCompile("k")) },
+		"Keys":            func(store *goKeyValueStore.KeyValueStore) { store.Keys() },
+		"KeysSorted":      func(store *goKeyValueStore.KeyValueStore) { store.KeysSorted() },
+		"KeysN":           func(store *goKeyValueStore.KeyValueStore) { store.KeysN(1) },
+		"KeysPage":        func(store *goKeyValueStore.KeyValueStore) { store.KeysPage("", 1) },
+		"KeysByInsertion": func(store *goKeyValueStore.KeyValueStore) { store.KeysByInsertion(1) },
+		"OldestEntry":     func(store *goKeyValueStore.KeyValueStore) { store.OldestEntry() },
+		"StatsForPrefix":  func(store *goKeyValueStore.KeyValueStore) { store.StatsForPrefix("key") },
+		"Range":           func(store *goKeyValueStore.KeyValueStore) { store.Range(func(string, any) bool { return true }) },
+		"OnThreshold": func(store *goKeyValueStore.KeyValueStore) {
+			store.OnThreshold(goKeyValueStore.MetricEntries, 0.5, func(goKeyValueStore.Usage) {})
+		},
+	}
+	for name, method := range tests {
+		t.Run(name, func(t *testing.T) {
+			var s service
+			method(&s.cache)
+		})
+	}
+}
+
+func TestZeroValueIsMemoryStore(t *testing.T) {
+	var s service
+	if err := s.cache.Set("key1", "value1", 0); err != nil {
+		t.Fatal(err)
+	}
+	s.cache.Set("key2", "value2", 1)
+	time.Sleep(5 * time.Millisecond)
+	if value, ok := s.cache.Get("key1"); !ok || value != "value1" {
+		t.Errorf("Expected value1, got %v", value)
+	}
+	if _, ok := s.cache.Get("key2"); ok {
+		t.Errorf("Expected key2 to be expired")
+	}
+	if state := s.cache.State(); state.Phase != goKeyValueStore.PhaseReady {
+		t.Errorf("Expected the zero value to be ready, got %+v", state)
+	}
+	report, err := s.cache.CleanNow()
+	if err != nil || report.Expired != 1 {
+		t.Errorf("Expected CleanNow to remove key2, got %+v, %v", report, err)
+	}
+	if config := s.cache.Config(); config.CacheFolder != "" || config.CleanInterval != time.Minute {
+		t.Errorf("Expected a memory-only store with a clean interval of one minute, got %+v", config)
+	}
+	if running, _, _, _ := s.cache.CleanerStatus(); running {
+		t.Errorf("Expected the cleaner of the zero value to be stopped")
+	}
+}